@@ -12,7 +12,6 @@ import (
 	"github.com/dhruvsoni1802/browser-query-ai/internal/config"
 	"github.com/dhruvsoni1802/browser-query-ai/internal/pool"
 	"github.com/dhruvsoni1802/browser-query-ai/internal/session"
-	"github.com/dhruvsoni1802/browser-query-ai/internal/storage"
 )
 
 func main() {
@@ -30,51 +29,80 @@ func main() {
 	slog.Info("configuration loaded",
 		"chromium_path", cfg.ChromiumPath,
 		"server_port", cfg.ServerPort,
+		"min_browsers", cfg.MinBrowsers,
 		"max_browsers", cfg.MaxBrowsers,
-		"redis_addr", cfg.RedisAddr,
+		"session_store", cfg.SessionStore,
 		"session_ttl", cfg.SessionTTL,
 	)
 
-	// Create Redis client
-	redisClient, err := storage.NewRedisClient(
-		cfg.RedisAddr,
-		cfg.RedisPassword,
-		cfg.RedisDB,
-	)
+	// Open the session store (Redis, in-memory or embedded database file)
+	sessionStore, err := OpenSessionStore(cfg)
 	if err != nil {
-		slog.Error("failed to connect to Redis", "error", err)
+		slog.Error("failed to open session store", "backend", cfg.SessionStore, "error", err)
 		os.Exit(1)
 	}
-	defer redisClient.Close()
-
-	slog.Info("Redis connected", "addr", cfg.RedisAddr)
-
-	// Create session repository
-	sessionRepo := storage.NewSessionRepository(redisClient, cfg.SessionTTL)
-
-	// Create process pool
-	processPool, err := pool.NewProcessPool(cfg.ChromiumPath, cfg.MaxBrowsers)
+	defer sessionStore.Close()
+
+	// Create process pool, it starts with MinBrowsers processes and grows with the sessions
+	processPool, err := pool.NewProcessPool(cfg.ChromiumPath, pool.ScalingConfig{
+		MinProcesses:             cfg.MinBrowsers,
+		MaxProcesses:             cfg.MaxBrowsers,
+		TargetSessionsPerProcess: int64(cfg.SessionsPerBrowser),
+		IdleCooldown:             cfg.ScaleDownCooldown,
+		ScaleUpWait:              cfg.ScaleUpWait,
+	})
 	if err != nil {
 		slog.Error("failed to create process pool", "error", err)
 		os.Exit(1)
 	}
 	defer processPool.Shutdown()
 
-	slog.Info("process pool created", "size", cfg.MaxBrowsers)
+	slog.Info("process pool created", "min_size", cfg.MinBrowsers, "max_size", cfg.MaxBrowsers)
 
 	// Create load balancer
 	loadBalancer := pool.NewLoadBalancer(processPool)
 	slog.Info("load balancer initialized")
 
-	// Create session manager with Redis repository
-	manager := session.NewManager(sessionRepo)
+	// Create session manager with the session store
+	manager := session.NewManager(sessionStore)
 	defer manager.Close()
 
+	// Resume sessions whose browser was stopped on one picked like for a new session
+	manager.SetResumePort(loadBalancer.ResumePort)
+
 	// Start cleanup worker (check every 5 min, timeout after 30 min)
 	manager.StartCleanupWorker(5*time.Minute, 30*time.Minute)
 
 	slog.Info("session manager initialized with cleanup worker")
 
+	// Reconcile the session store with the fresh browser processes before serving, then periodically
+	if cfg.ReconcileInterval > 0 {
+		manager.Reconcile(loadBalancer.HealthyPorts())
+		manager.StartReconcileWorker(cfg.ReconcileInterval, loadBalancer.HealthyPorts)
+	}
+
+	// Restart crashed or hung browsers and move their sessions onto the replacements
+	var supervisor *pool.Supervisor
+	if cfg.HealthCheckInterval > 0 {
+		supervisorConfig := pool.DefaultSupervisorConfig()
+		supervisorConfig.Interval = cfg.HealthCheckInterval
+
+		supervisor = pool.NewSupervisor(processPool, supervisorConfig)
+		supervisor.OnEvent(func(event pool.ProcessEvent) {
+			switch event.Type {
+			case pool.ProcessDown:
+				manager.HandleProcessDown(event.Port)
+			case pool.ProcessRestarted:
+				manager.HandleProcessRestarted(event.Port, event.NewPort)
+			}
+		})
+		supervisor.Start()
+	}
+
+	// Stop browsers that stayed idle for the cool-down, their clients are closed with them
+	processPool.OnProcessRemoved(manager.HandleProcessRemoved)
+	processPool.StartAutoscaler(manager.HasSessionsOnPort)
+
 	// Create and start HTTP API server
 	apiServer := api.NewServer(cfg.ServerPort, manager, loadBalancer)
 
@@ -94,8 +122,8 @@ func main() {
 
 	slog.Info("service ready",
 		"http_port", cfg.ServerPort,
-		"browser_processes", cfg.MaxBrowsers,
-		"redis", cfg.RedisAddr,
+		"browser_processes", processPool.GetProcessCount(),
+		"session_store", cfg.SessionStore,
 		"status", "press Ctrl+C to shutdown",
 	)
 
@@ -112,6 +140,11 @@ func main() {
 		slog.Error("HTTP server shutdown error", "error", err)
 	}
 
+	// Stop health checks first, stopping the browsers would look like crashes
+	if supervisor != nil {
+		supervisor.Stop()
+	}
+
 	// Close session manager (stops cleanup worker)
 	if err := manager.Close(); err != nil {
 		slog.Error("session manager close error", "error", err)
@@ -122,9 +155,9 @@ func main() {
 		slog.Error("process pool shutdown error", "error", err)
 	}
 
-	// Close Redis connection
-	if err := redisClient.Close(); err != nil {
-		slog.Error("Redis close error", "error", err)
+	// Close the session store
+	if err := sessionStore.Close(); err != nil {
+		slog.Error("session store close error", "error", err)
 	}
 
 	slog.Info("shutdown complete")
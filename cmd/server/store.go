@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/dhruvsoni1802/browser-query-ai/internal/config"
+	"github.com/dhruvsoni1802/browser-query-ai/internal/storage"
+)
+
+// Function to open the session store selected in the configuration
+func OpenSessionStore(cfg *config.Config) (storage.SessionStore, error) {
+	switch cfg.SessionStore {
+	case storage.BackendRedis:
+		redisClient, err := storage.NewRedisClient(cfg.RedisAddr, cfg.RedisPassword, cfg.RedisDB)
+		if err != nil {
+			return nil, err
+		}
+		slog.Info("Redis connected", "addr", cfg.RedisAddr)
+		return storage.NewSessionRepository(redisClient, cfg.SessionTTL), nil
+
+	case storage.BackendMemory:
+		// Sessions are lost on restart, closed sessions can only be resumed while the server runs
+		slog.Warn("using in-memory session store, sessions will not survive a restart")
+		return storage.NewMemoryStore(cfg.SessionTTL), nil
+
+	case storage.BackendBolt:
+		store, err := storage.NewBoltStore(cfg.SessionStorePath, cfg.SessionTTL)
+		if err != nil {
+			return nil, err
+		}
+		slog.Info("session database opened", "path", cfg.SessionStorePath)
+		return store, nil
+
+	default:
+		return nil, fmt.Errorf("%w: %s", storage.ErrUnknownBackend, cfg.SessionStore)
+	}
+}
@@ -1,11 +1,20 @@
 package api
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
+	"github.com/dhruvsoni1802/browser-query-ai/internal/extract"
 	"github.com/dhruvsoni1802/browser-query-ai/internal/pool"
 	"github.com/dhruvsoni1802/browser-query-ai/internal/session"
 	"github.com/go-chi/chi/v5"
@@ -25,6 +34,18 @@ func NewHandlers(manager *session.Manager, loadBalancer *pool.LoadBalancer) *Han
 	}
 }
 
+// requestContext derives the context for browser work from the HTTP request
+// The work is cancelled when the client disconnects or the server shuts down,
+// and timeoutMs (if set) bounds it further, capped at MaxRequestTimeout
+func requestContext(r *http.Request, timeoutMs int) (context.Context, context.CancelFunc) {
+	if timeoutMs <= 0 {
+		return context.WithCancel(r.Context())
+	}
+
+	timeout := min(time.Duration(timeoutMs)*time.Millisecond, MaxRequestTimeout)
+	return context.WithTimeout(r.Context(), timeout)
+}
+
 // CreateSession handles POST /sessions
 func (h *Handlers) CreateSession(w http.ResponseWriter, r *http.Request) {
 	var req CreateSessionRequest
@@ -39,6 +60,17 @@ func (h *Handlers) CreateSession(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
+	// Reject bad rules before a browser context is created for them
+	if err := session.ValidateInterceptionRules(req.Interception); err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidInterception, err.Error())
+		return
+	}
+
+	if err := req.Emulation.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidEmulation, err.Error())
+		return
+	}
+
 	// Select port (use provided or load balance)
 	port := req.BrowserPort
 	if port == 0 {
@@ -55,12 +87,12 @@ func (h *Handlers) CreateSession(w http.ResponseWriter, r *http.Request) {
 	sess, err := h.sessionManager.CreateSessionWithName(req.AgentID, req.SessionName, port)
 	if err != nil {
 		// Check for specific errors
-		if err == session.ErrSessionNameConflict {
+		if errors.Is(err, session.ErrSessionNameConflict) {
 			writeError(w, http.StatusConflict, "SESSION_NAME_CONFLICT", 
 				fmt.Sprintf("Session name '%s' already exists", req.SessionName))
 			return
 		}
-		if err == session.ErrSessionLimitReached {
+		if errors.Is(err, session.ErrSessionLimitReached) {
 			writeError(w, http.StatusTooManyRequests, "SESSION_LIMIT_REACHED", err.Error())
 			return
 		}
@@ -70,6 +102,24 @@ func (h *Handlers) CreateSession(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
+	// Apply interception rules, the session has no pages yet so this cannot touch the browser
+	if len(req.Interception) > 0 {
+		if _, err := sess.SetInterceptionRules(r.Context(), req.Interception); err != nil {
+			h.discardSession(sess.ID)
+			writeError(w, http.StatusInternalServerError, ErrCodeSessionCreateFailed, err.Error())
+			return
+		}
+	}
+
+	// Apply and persist emulation, pages opened later start out emulated
+	if !req.Emulation.IsZero() {
+		if err := h.sessionManager.SetEmulation(r.Context(), sess.ID, req.Emulation); err != nil {
+			h.discardSession(sess.ID)
+			writeError(w, http.StatusInternalServerError, ErrCodeSessionCreateFailed, err.Error())
+			return
+		}
+	}
+
 	// Increment session count on process
 	processes := h.loadBalancer.GetProcesses()
 	for _, process := range processes {
@@ -81,7 +131,7 @@ func (h *Handlers) CreateSession(w http.ResponseWriter, r *http.Request) {
 	
 	response := CreateSessionResponse{
 		SessionID:   sess.ID,
-		SessionName: sess.Name,
+		SessionName: sess.GetName(),
 		AgentID:     sess.AgentID,
 		ContextID:   sess.ContextID,
 		CreatedAt:   sess.CreatedAt,
@@ -90,6 +140,14 @@ func (h *Handlers) CreateSession(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusCreated, response)
 }
 
+// discardSession destroys a session whose setup failed, freeing its browser context, name and agent slot
+// The session was never counted on its browser process, so there is no count to decrement.
+func (h *Handlers) discardSession(sessionID string) {
+	if err := h.sessionManager.DestroySession(sessionID); err != nil {
+		slog.Warn("failed to discard session after failed setup", "session_id", sessionID, "error", err)
+	}
+}
+
 // DestroySession handles DELETE /sessions/{id}
 func (h *Handlers) DestroySession(w http.ResponseWriter, r *http.Request) {
 	sessionID := chi.URLParam(r, "id")
@@ -134,14 +192,15 @@ func (h *Handlers) GetSession(w http.ResponseWriter, r *http.Request) {
 
 	response := GetSessionResponse{
 		SessionID:    sess.ID,
-		SessionName:  sess.Name,
+		SessionName:  sess.GetName(),
 		AgentID:      sess.AgentID,
 		ContextID:    sess.ContextID,
-		PageIDs:      sess.PageIDs,
-		PageCount:    len(sess.PageIDs),
+		PageIDs:      sess.Pages(),
+		PageCount:    len(sess.Pages()),
 		CreatedAt:    sess.CreatedAt,
 		LastActivity: sess.LastActivity,
 		Status:       sess.Status,
+		Emulation:    sess.Emulation(),
 	}
 
 	writeJSON(w, http.StatusOK, response)
@@ -155,10 +214,10 @@ func (h *Handlers) ListSessions(w http.ResponseWriter, r *http.Request) {
 	for _, sess := range sessions {
 		sessionInfos = append(sessionInfos, SessionInfo{
 			SessionID:    sess.ID,
-			SessionName:  sess.Name,
+			SessionName:  sess.GetName(),
 			AgentID:      sess.AgentID,
 			ContextID:    sess.ContextID,
-			PageCount:    len(sess.PageIDs),
+			PageCount:    len(sess.Pages()),
 			CreatedAt:    sess.CreatedAt,
 			LastActivity: sess.LastActivity,
 			Status:       sess.Status,
@@ -188,10 +247,27 @@ func (h *Handlers) Navigate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	pageID, err := h.sessionManager.Navigate(sessionID, req.URL)
+	if req.TimeoutMs < 0 {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "timeout_ms must not be negative")
+		return
+	}
+
+	ctx, cancel := requestContext(r, req.TimeoutMs)
+	defer cancel()
+
+	navigation, err := h.sessionManager.Navigate(ctx, sessionID, req.URL, req.PageID, req.WaitFor)
 	if err != nil {
+		var waitErr *session.WaitTimeoutError
 		if err.Error() == "failed to get session: session not found: "+sessionID {
 			writeError(w, http.StatusNotFound, ErrCodeSessionNotFound, "Session not found")
+		} else if err.Error() == "page not found in session: "+req.PageID {
+			writeError(w, http.StatusNotFound, ErrCodePageNotFound, "Page not found in session")
+		} else if errors.Is(err, session.ErrInvalidWaitCondition) {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidWait, err.Error())
+		} else if errors.As(err, &waitErr) {
+			writeErrorDetails(w, http.StatusGatewayTimeout, ErrCodeWaitTimeout, err.Error(), waitErr.Diagnostics)
+		} else if errors.Is(err, context.DeadlineExceeded) {
+			writeError(w, http.StatusGatewayTimeout, ErrCodeTimeout, err.Error())
 		} else {
 			writeError(w, http.StatusInternalServerError, ErrCodeNavigationFailed, err.Error())
 		}
@@ -200,8 +276,10 @@ func (h *Handlers) Navigate(w http.ResponseWriter, r *http.Request) {
 
 	response := NavigateResponse{
 		SessionID: sessionID,
-		PageID:    pageID,
-		URL:       req.URL,
+		PageID:    navigation.PageID,
+		URL:       navigation.URL,
+		Status:    navigation.Status,
+		ErrorText: navigation.ErrorText,
 	}
 
 	writeJSON(w, http.StatusOK, response)
@@ -226,12 +304,22 @@ func (h *Handlers) ExecuteJS(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := h.sessionManager.ExecuteJavascript(sessionID, req.PageID, req.Script)
+	if req.TimeoutMs < 0 {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "timeout_ms must not be negative")
+		return
+	}
+
+	ctx, cancel := requestContext(r, req.TimeoutMs)
+	defer cancel()
+
+	result, err := h.sessionManager.ExecuteJavascript(ctx, sessionID, req.PageID, req.Script)
 	if err != nil {
 		if err.Error() == "failed to get session: session not found: "+sessionID {
 			writeError(w, http.StatusNotFound, ErrCodeSessionNotFound, "Session not found")
 		} else if err.Error() == "page not found in session: "+req.PageID {
 			writeError(w, http.StatusNotFound, ErrCodePageNotFound, "Page not found in session")
+		} else if errors.Is(err, context.DeadlineExceeded) {
+			writeError(w, http.StatusGatewayTimeout, ErrCodeTimeout, err.Error())
 		} else {
 			writeError(w, http.StatusInternalServerError, ErrCodeExecutionFailed, err.Error())
 		}
@@ -262,58 +350,660 @@ func (h *Handlers) CaptureScreenshot(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	screenshotBytes, err := h.sessionManager.CaptureScreenshot(sessionID, req.PageID)
+	// Accept: image/png (or jpeg, webp) returns the raw image, and picks the format when none is set
+	offers := []string{imageMediaTypes[session.ScreenshotPNG], imageMediaTypes[session.ScreenshotJPEG], imageMediaTypes[session.ScreenshotWebP]}
+	if req.Format != "" {
+		offers = []string{imageMediaTypes[req.Format]}
+	}
+	mediaType, ok := negotiate(r, offers...)
+	if !ok {
+		writeError(w, http.StatusNotAcceptable, ErrCodeNotAcceptable, "Accept must allow application/json or "+strings.Join(offers, ", "))
+		return
+	}
+	if mediaType != "" && req.Annotate {
+		writeError(w, http.StatusNotAcceptable, ErrCodeNotAcceptable, "Annotated screenshots are JSON only, the legend does not fit a raw image")
+		return
+	}
+	for format, imageType := range imageMediaTypes {
+		if mediaType == imageType {
+			req.Format = format
+		}
+	}
+
+	screenshot, err := h.sessionManager.CaptureScreenshot(r.Context(), sessionID, req.PageID, req.ScreenshotOptions)
 	if err != nil {
 		if err.Error() == "failed to get session: session not found: "+sessionID {
 			writeError(w, http.StatusNotFound, ErrCodeSessionNotFound, "Session not found")
 		} else if err.Error() == "page not found in session: "+req.PageID {
 			writeError(w, http.StatusNotFound, ErrCodePageNotFound, "Page not found in session")
+		} else if errors.Is(err, session.ErrInvalidScreenshotOptions) || errors.Is(err, session.ErrInvalidAction) {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidScreenshot, err.Error())
+		} else if errors.Is(err, session.ErrElementNotFound) {
+			writeError(w, http.StatusNotFound, ErrCodeElementNotFound, err.Error())
+		} else if errors.Is(err, session.ErrElementNotVisible) {
+			writeError(w, http.StatusConflict, ErrCodeElementNotVisible, err.Error())
+		} else if errors.Is(err, session.ErrStaleElementRef) {
+			writeError(w, http.StatusConflict, ErrCodeStaleElementRef, err.Error())
 		} else {
 			writeError(w, http.StatusInternalServerError, ErrCodeScreenshotFailed, err.Error())
 		}
 		return
 	}
 
-	encoded := base64.StdEncoding.EncodeToString(screenshotBytes)
-
-	format := req.Format
-	if format == "" {
-		format = "png"
+	if mediaType != "" {
+		w.Header().Set("X-Image-Width", strconv.Itoa(screenshot.Width))
+		w.Header().Set("X-Image-Height", strconv.Itoa(screenshot.Height))
+		writeBinary(w, mediaType, screenshot.Data)
+		return
 	}
 
 	response := ScreenshotResponse{
 		SessionID:  sessionID,
 		PageID:     req.PageID,
-		Screenshot: encoded,
-		Format:     format,
-		Size:       len(screenshotBytes),
+		Screenshot: base64.StdEncoding.EncodeToString(screenshot.Data),
+		Format:     screenshot.Format,
+		Width:      screenshot.Width,
+		Height:     screenshot.Height,
+		Size:       len(screenshot.Data),
+		Marks:      screenshot.Marks,
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// PrintToPDF handles POST /sessions/{id}/pdf
+// Returns the raw document when the client sends Accept: application/pdf, JSON with base64 otherwise
+func (h *Handlers) PrintToPDF(w http.ResponseWriter, r *http.Request) {
+	sessionID := chi.URLParam(r, "id")
+
+	var req PDFRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "Invalid JSON body")
+		return
+	}
+
+	if req.PageID == "" {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "page_id is required")
+		return
+	}
+
+	if req.TimeoutMs < 0 {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "timeout_ms must not be negative")
+		return
+	}
+
+	mediaType, ok := negotiate(r, "application/pdf")
+	if !ok {
+		writeError(w, http.StatusNotAcceptable, ErrCodeNotAcceptable, "Accept must allow application/json or application/pdf")
+		return
+	}
+
+	ctx, cancel := requestContext(r, req.TimeoutMs)
+	defer cancel()
+
+	pdf, err := h.sessionManager.PrintToPDF(ctx, sessionID, req.PageID, req.PDFOptions)
+	if err != nil {
+		if err.Error() == "failed to get session: session not found: "+sessionID {
+			writeError(w, http.StatusNotFound, ErrCodeSessionNotFound, "Session not found")
+		} else if err.Error() == "page not found in session: "+req.PageID {
+			writeError(w, http.StatusNotFound, ErrCodePageNotFound, "Page not found in session")
+		} else if errors.Is(err, session.ErrInvalidPDFOptions) {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidPDF, err.Error())
+		} else if errors.Is(err, context.DeadlineExceeded) {
+			writeError(w, http.StatusGatewayTimeout, ErrCodeTimeout, err.Error())
+		} else {
+			writeError(w, http.StatusInternalServerError, ErrCodePDFFailed, err.Error())
+		}
+		return
+	}
+
+	if mediaType != "" {
+		writeBinary(w, mediaType, pdf)
+		return
+	}
+
+	response := PDFResponse{
+		SessionID: sessionID,
+		PageID:    req.PageID,
+		PDF:       base64.StdEncoding.EncodeToString(pdf),
+		Size:      len(pdf),
 	}
 
 	writeJSON(w, http.StatusOK, response)
 }
 
-// GetPageContent handles GET /sessions/{id}/pages/{pageId}/content
+// GetPageContent handles GET /sessions/{id}/pages/{pageId}/content?format=html|text|markdown|readability
 func (h *Handlers) GetPageContent(w http.ResponseWriter, r *http.Request) {
 	sessionID := chi.URLParam(r, "id")
 	pageID := chi.URLParam(r, "pageId")
+	format := r.URL.Query().Get("format")
 
-	content, err := h.sessionManager.GetPageContent(sessionID, pageID)
+	// Accept: text/html, text/markdown or text/plain returns the raw content, and picks the format when none is set
+	offers := []string{contentMediaTypes[extract.FormatHTML], contentMediaTypes[extract.FormatMarkdown], contentMediaTypes[extract.FormatText]}
+	if mediaType, ok := contentMediaTypes[format]; ok {
+		offers = []string{mediaType}
+	}
+	mediaType, ok := negotiate(r, offers...)
+	if !ok {
+		writeError(w, http.StatusNotAcceptable, ErrCodeNotAcceptable, "Accept must allow application/json or "+strings.Join(offers, ", "))
+		return
+	}
+	if format == "" {
+		for contentFormat, contentType := range contentMediaTypes {
+			// text/markdown picks markdown, readability has to be asked for
+			if mediaType == contentType && contentFormat != extract.FormatReadability {
+				format = contentFormat
+			}
+		}
+	}
+
+	content, err := h.sessionManager.ExtractPageContent(r.Context(), sessionID, pageID, format)
 	if err != nil {
 		if err.Error() == "failed to get session: session not found: "+sessionID {
 			writeError(w, http.StatusNotFound, ErrCodeSessionNotFound, "Session not found")
 		} else if err.Error() == "page not found in session: "+pageID {
 			writeError(w, http.StatusNotFound, ErrCodePageNotFound, "Page not found in session")
+		} else if errors.Is(err, session.ErrInvalidContentFormat) {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidFormat, err.Error())
+		} else if errors.Is(err, context.DeadlineExceeded) {
+			writeError(w, http.StatusGatewayTimeout, ErrCodeTimeout, err.Error())
 		} else {
 			writeError(w, http.StatusInternalServerError, ErrCodeInternalError, err.Error())
 		}
 		return
 	}
 
+	if mediaType != "" {
+		w.Header().Set("X-Page-URL", content.URL)
+		w.Header().Set("X-Content-Format", content.Format)
+		writeText(w, mediaType+"; charset=utf-8", content.Content)
+		return
+	}
+
 	response := GetPageContentResponse{
 		SessionID: sessionID,
 		PageID:    pageID,
-		Content:   content,
-		Length:    len(content),
+		URL:       content.URL,
+		Format:    content.Format,
+		Content:   content.Content,
+		Length:    len(content.Content),
+	}
+
+	// Big pages are streamed rather than encoded in one piece, content comes last
+	if len(content.Content) > StreamContentThreshold {
+		writeJSONStream(w, http.StatusOK, pageContentHead(response), "content", content.Content)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// GetConsoleLog handles GET /sessions/{id}/pages/{pageId}/console?level=warning&since=42&limit=100
+func (h *Handlers) GetConsoleLog(w http.ResponseWriter, r *http.Request) {
+	sessionID := chi.URLParam(r, "id")
+	pageID := chi.URLParam(r, "pageId")
+	params := r.URL.Query()
+
+	query := session.ConsoleQuery{Level: params.Get("level")}
+	if since := params.Get("since"); since != "" {
+		value, err := strconv.ParseInt(since, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidConsoleQuery, "since must be an entry seq")
+			return
+		}
+		query.Since = value
+	}
+	if limit := params.Get("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidConsoleQuery, "limit must be a number")
+			return
+		}
+		query.Limit = value
+	}
+
+	log, err := h.sessionManager.GetConsoleLog(sessionID, pageID, query)
+	if err != nil {
+		if err.Error() == "failed to get session: session not found: "+sessionID {
+			writeError(w, http.StatusNotFound, ErrCodeSessionNotFound, "Session not found")
+		} else if err.Error() == "page not found in session: "+pageID {
+			writeError(w, http.StatusNotFound, ErrCodePageNotFound, "Page not found in session")
+		} else if errors.Is(err, session.ErrInvalidConsoleQuery) {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidConsoleQuery, err.Error())
+		} else {
+			writeError(w, http.StatusInternalServerError, ErrCodeInternalError, err.Error())
+		}
+		return
+	}
+
+	response := ConsoleResponse{
+		SessionID: sessionID,
+		PageID:    pageID,
+		Entries:   log.Entries,
+		Cursor:    log.Cursor,
+		Dropped:   log.Dropped,
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// GetNetworkLog handles GET /sessions/{id}/pages/{pageId}/network?url=*/api/*&type=xhr,fetch&since=42&limit=100
+func (h *Handlers) GetNetworkLog(w http.ResponseWriter, r *http.Request) {
+	sessionID := chi.URLParam(r, "id")
+	pageID := chi.URLParam(r, "pageId")
+	params := r.URL.Query()
+
+	query := session.NetworkQuery{URL: params.Get("url")}
+	for _, types := range params["type"] {
+		for _, resourceType := range strings.Split(types, ",") {
+			if resourceType = strings.TrimSpace(resourceType); resourceType != "" {
+				query.Types = append(query.Types, resourceType)
+			}
+		}
+	}
+	if since := params.Get("since"); since != "" {
+		value, err := strconv.ParseInt(since, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidNetworkQuery, "since must be an entry seq")
+			return
+		}
+		query.Since = value
+	}
+	if limit := params.Get("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidNetworkQuery, "limit must be a number")
+			return
+		}
+		query.Limit = value
+	}
+
+	log, err := h.sessionManager.GetNetworkLog(sessionID, pageID, query)
+	if err != nil {
+		if err.Error() == "failed to get session: session not found: "+sessionID {
+			writeError(w, http.StatusNotFound, ErrCodeSessionNotFound, "Session not found")
+		} else if err.Error() == "page not found in session: "+pageID {
+			writeError(w, http.StatusNotFound, ErrCodePageNotFound, "Page not found in session")
+		} else if errors.Is(err, session.ErrInvalidNetworkQuery) {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidNetworkQuery, err.Error())
+		} else {
+			writeError(w, http.StatusInternalServerError, ErrCodeInternalError, err.Error())
+		}
+		return
+	}
+
+	response := NetworkResponse{
+		SessionID: sessionID,
+		PageID:    pageID,
+		Entries:   log.Entries,
+		Cursor:    log.Cursor,
+		Dropped:   log.Dropped,
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// GetResponseBody handles GET /sessions/{id}/pages/{pageId}/network/{requestId}/body
+func (h *Handlers) GetResponseBody(w http.ResponseWriter, r *http.Request) {
+	sessionID := chi.URLParam(r, "id")
+	pageID := chi.URLParam(r, "pageId")
+	requestID := chi.URLParam(r, "requestId")
+
+	body, err := h.sessionManager.GetResponseBody(r.Context(), sessionID, pageID, requestID)
+	if err != nil {
+		if err.Error() == "failed to get session: session not found: "+sessionID {
+			writeError(w, http.StatusNotFound, ErrCodeSessionNotFound, "Session not found")
+		} else if err.Error() == "page not found in session: "+pageID {
+			writeError(w, http.StatusNotFound, ErrCodePageNotFound, "Page not found in session")
+		} else if errors.Is(err, session.ErrRequestNotFound) {
+			writeError(w, http.StatusNotFound, ErrCodeRequestNotFound, err.Error())
+		} else if errors.Is(err, session.ErrResponseBodyUnavailable) {
+			writeError(w, http.StatusConflict, ErrCodeBodyUnavailable, err.Error())
+		} else if errors.Is(err, context.DeadlineExceeded) {
+			writeError(w, http.StatusGatewayTimeout, ErrCodeTimeout, err.Error())
+		} else {
+			writeError(w, http.StatusInternalServerError, ErrCodeInternalError, err.Error())
+		}
+		return
+	}
+
+	// Accept naming the response's own type returns the raw body
+	var offers []string
+	if body.MimeType != "" {
+		offers = append(offers, body.MimeType)
+	}
+	mediaType, ok := negotiate(r, offers...)
+	if !ok {
+		writeError(w, http.StatusNotAcceptable, ErrCodeNotAcceptable, "Accept must allow application/json or the response's own type")
+		return
+	}
+	if mediaType != "" {
+		writeBinary(w, mediaType, body.Data)
+		return
+	}
+
+	response := ResponseBodyResponse{
+		SessionID: sessionID,
+		PageID:    pageID,
+		RequestID: requestID,
+		MimeType:  body.MimeType,
+		Size:      len(body.Data),
+	}
+	if utf8.Valid(body.Data) {
+		response.Body = string(body.Data)
+	} else {
+		response.Body = base64.StdEncoding.EncodeToString(body.Data)
+		response.Base64Encoded = true
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// ExportHAR handles GET /sessions/{id}/har
+func (h *Handlers) ExportHAR(w http.ResponseWriter, r *http.Request) {
+	sessionID := chi.URLParam(r, "id")
+
+	har, err := h.sessionManager.ExportHAR(sessionID)
+	if err != nil {
+		if err.Error() == "failed to get session: session not found: "+sessionID {
+			writeError(w, http.StatusNotFound, ErrCodeSessionNotFound, "Session not found")
+		} else {
+			writeError(w, http.StatusInternalServerError, ErrCodeInternalError, err.Error())
+		}
+		return
+	}
+
+	// The archive is returned as is so it can be saved and opened in HAR viewers
+	w.Header().Set("Content-Disposition", `attachment; filename="`+sessionID+`.har"`)
+	writeJSON(w, http.StatusOK, har)
+}
+
+// GetInterception handles GET /sessions/{id}/interception
+func (h *Handlers) GetInterception(w http.ResponseWriter, r *http.Request) {
+	sessionID := chi.URLParam(r, "id")
+
+	stats, err := h.sessionManager.GetInterceptionStats(sessionID)
+	if err != nil {
+		if err.Error() == "failed to get session: session not found: "+sessionID {
+			writeError(w, http.StatusNotFound, ErrCodeSessionNotFound, "Session not found")
+		} else {
+			writeError(w, http.StatusInternalServerError, ErrCodeInternalError, err.Error())
+		}
+		return
+	}
+
+	response := InterceptionResponse{
+		SessionID:   sessionID,
+		Rules:       stats.Rules,
+		Intercepted: stats.Intercepted,
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// SetInterception handles PUT /sessions/{id}/interception
+func (h *Handlers) SetInterception(w http.ResponseWriter, r *http.Request) {
+	sessionID := chi.URLParam(r, "id")
+
+	var req InterceptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "Invalid JSON body")
+		return
+	}
+
+	stats, err := h.sessionManager.SetInterceptionRules(r.Context(), sessionID, req.Rules)
+	if err != nil {
+		if err.Error() == "failed to get session: session not found: "+sessionID {
+			writeError(w, http.StatusNotFound, ErrCodeSessionNotFound, "Session not found")
+		} else if errors.Is(err, session.ErrInvalidInterceptionRule) {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidInterception, err.Error())
+		} else if errors.Is(err, context.DeadlineExceeded) {
+			writeError(w, http.StatusGatewayTimeout, ErrCodeTimeout, err.Error())
+		} else {
+			writeError(w, http.StatusInternalServerError, ErrCodeInternalError, err.Error())
+		}
+		return
+	}
+
+	response := InterceptionResponse{
+		SessionID:   sessionID,
+		Rules:       stats.Rules,
+		Intercepted: stats.Intercepted,
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// SetEmulation handles PUT /sessions/{id}/emulation
+func (h *Handlers) SetEmulation(w http.ResponseWriter, r *http.Request) {
+	sessionID := chi.URLParam(r, "id")
+
+	var opts session.EmulationOptions
+	if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "Invalid JSON body")
+		return
+	}
+
+	if err := h.sessionManager.SetEmulation(r.Context(), sessionID, &opts); err != nil {
+		if err.Error() == "failed to get session: session not found: "+sessionID {
+			writeError(w, http.StatusNotFound, ErrCodeSessionNotFound, "Session not found")
+		} else if errors.Is(err, session.ErrInvalidEmulation) {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidEmulation, err.Error())
+		} else if errors.Is(err, context.DeadlineExceeded) {
+			writeError(w, http.StatusGatewayTimeout, ErrCodeTimeout, err.Error())
+		} else {
+			writeError(w, http.StatusInternalServerError, ErrCodeInternalError, err.Error())
+		}
+		return
+	}
+
+	response := EmulationResponse{
+		SessionID: sessionID,
+	}
+	if !opts.IsZero() {
+		response.Emulation = &opts
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// GetNavigationHistory handles GET /sessions/{id}/pages/{pageId}/history
+func (h *Handlers) GetNavigationHistory(w http.ResponseWriter, r *http.Request) {
+	sessionID := chi.URLParam(r, "id")
+	pageID := chi.URLParam(r, "pageId")
+
+	history, err := h.sessionManager.GetNavigationHistory(r.Context(), sessionID, pageID)
+	if err != nil {
+		if err.Error() == "failed to get session: session not found: "+sessionID {
+			writeError(w, http.StatusNotFound, ErrCodeSessionNotFound, "Session not found")
+		} else if err.Error() == "page not found in session: "+pageID {
+			writeError(w, http.StatusNotFound, ErrCodePageNotFound, "Page not found in session")
+		} else if errors.Is(err, context.DeadlineExceeded) {
+			writeError(w, http.StatusGatewayTimeout, ErrCodeTimeout, err.Error())
+		} else {
+			writeError(w, http.StatusInternalServerError, ErrCodeInternalError, err.Error())
+		}
+		return
+	}
+
+	response := NavigationHistoryResponse{
+		SessionID:    sessionID,
+		PageID:       pageID,
+		CurrentIndex: history.CurrentIndex,
+		Entries:      history.Entries,
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// GoBack handles POST /sessions/{id}/pages/{pageId}/back
+func (h *Handlers) GoBack(w http.ResponseWriter, r *http.Request) {
+	h.historyNavigation(w, r, func(ctx context.Context, sessionID string, pageID string, req HistoryRequest) (*session.NavigationResult, error) {
+		return h.sessionManager.NavigateHistory(ctx, sessionID, pageID, -1)
+	})
+}
+
+// GoForward handles POST /sessions/{id}/pages/{pageId}/forward
+func (h *Handlers) GoForward(w http.ResponseWriter, r *http.Request) {
+	h.historyNavigation(w, r, func(ctx context.Context, sessionID string, pageID string, req HistoryRequest) (*session.NavigationResult, error) {
+		return h.sessionManager.NavigateHistory(ctx, sessionID, pageID, 1)
+	})
+}
+
+// Reload handles POST /sessions/{id}/pages/{pageId}/reload
+func (h *Handlers) Reload(w http.ResponseWriter, r *http.Request) {
+	h.historyNavigation(w, r, func(ctx context.Context, sessionID string, pageID string, req HistoryRequest) (*session.NavigationResult, error) {
+		return h.sessionManager.Reload(ctx, sessionID, pageID, req.IgnoreCache)
+	})
+}
+
+// historyNavigation runs a back/forward/reload action and writes the resulting navigation
+func (h *Handlers) historyNavigation(w http.ResponseWriter, r *http.Request, action func(ctx context.Context, sessionID string, pageID string, req HistoryRequest) (*session.NavigationResult, error)) {
+	sessionID := chi.URLParam(r, "id")
+	pageID := chi.URLParam(r, "pageId")
+
+	// Empty body is acceptable
+	var req HistoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "Invalid JSON body")
+		return
+	}
+
+	if req.TimeoutMs < 0 {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "timeout_ms must not be negative")
+		return
+	}
+
+	ctx, cancel := requestContext(r, req.TimeoutMs)
+	defer cancel()
+
+	navigation, err := action(ctx, sessionID, pageID, req)
+	if err != nil {
+		if err.Error() == "failed to get session: session not found: "+sessionID {
+			writeError(w, http.StatusNotFound, ErrCodeSessionNotFound, "Session not found")
+		} else if err.Error() == "page not found in session: "+pageID {
+			writeError(w, http.StatusNotFound, ErrCodePageNotFound, "Page not found in session")
+		} else if errors.Is(err, session.ErrNoHistoryEntry) {
+			writeError(w, http.StatusConflict, ErrCodeNoHistoryEntry, err.Error())
+		} else if errors.Is(err, context.DeadlineExceeded) {
+			writeError(w, http.StatusGatewayTimeout, ErrCodeTimeout, err.Error())
+		} else {
+			writeError(w, http.StatusInternalServerError, ErrCodeNavigationFailed, err.Error())
+		}
+		return
+	}
+
+	response := NavigateResponse{
+		SessionID: sessionID,
+		PageID:    navigation.PageID,
+		URL:       navigation.URL,
+		Status:    navigation.Status,
+		ErrorText: navigation.ErrorText,
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// Wait handles POST /sessions/{id}/pages/{pageId}/wait
+func (h *Handlers) Wait(w http.ResponseWriter, r *http.Request) {
+	sessionID := chi.URLParam(r, "id")
+	pageID := chi.URLParam(r, "pageId")
+
+	var req WaitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "Invalid JSON body")
+		return
+	}
+
+	// The wait reports its own timeout with diagnostics, only cap it here
+	if req.TimeoutMs > int(MaxRequestTimeout.Milliseconds()) {
+		req.TimeoutMs = int(MaxRequestTimeout.Milliseconds())
+	}
+
+	result, err := h.sessionManager.Wait(r.Context(), sessionID, pageID, req.WaitCondition)
+	if err != nil {
+		var waitErr *session.WaitTimeoutError
+		if err.Error() == "failed to get session: session not found: "+sessionID {
+			writeError(w, http.StatusNotFound, ErrCodeSessionNotFound, "Session not found")
+		} else if err.Error() == "page not found in session: "+pageID {
+			writeError(w, http.StatusNotFound, ErrCodePageNotFound, "Page not found in session")
+		} else if errors.Is(err, session.ErrInvalidWaitCondition) {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidWait, err.Error())
+		} else if errors.As(err, &waitErr) {
+			writeErrorDetails(w, http.StatusGatewayTimeout, ErrCodeWaitTimeout, err.Error(), waitErr.Diagnostics)
+		} else {
+			writeError(w, http.StatusInternalServerError, ErrCodeInternalError, err.Error())
+		}
+		return
+	}
+
+	response := WaitResponse{
+		SessionID: sessionID,
+		PageID:    pageID,
+		URL:       result.URL,
+		ElapsedMs: result.ElapsedMs,
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// PerformActions handles POST /sessions/{id}/pages/{pageId}/actions
+func (h *Handlers) PerformActions(w http.ResponseWriter, r *http.Request) {
+	sessionID := chi.URLParam(r, "id")
+	pageID := chi.URLParam(r, "pageId")
+
+	var req ActionsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "Invalid JSON body")
+		return
+	}
+
+	if len(req.Actions) == 0 {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "actions is required")
+		return
+	}
+
+	if req.TimeoutMs < 0 {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "timeout_ms must not be negative")
+		return
+	}
+
+	ctx, cancel := requestContext(r, req.TimeoutMs)
+	defer cancel()
+
+	results, err := h.sessionManager.PerformActions(ctx, sessionID, pageID, req.Actions)
+	if err != nil {
+		var waitErr *session.WaitTimeoutError
+		if err.Error() == "failed to get session: session not found: "+sessionID {
+			writeError(w, http.StatusNotFound, ErrCodeSessionNotFound, "Session not found")
+		} else if err.Error() == "page not found in session: "+pageID {
+			writeError(w, http.StatusNotFound, ErrCodePageNotFound, "Page not found in session")
+		} else if errors.Is(err, session.ErrInvalidAction) {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidAction, err.Error())
+		} else if errors.Is(err, session.ErrElementNotFound) {
+			writeError(w, http.StatusNotFound, ErrCodeElementNotFound, err.Error())
+		} else if errors.Is(err, session.ErrElementNotVisible) {
+			writeError(w, http.StatusConflict, ErrCodeElementNotVisible, err.Error())
+		} else if errors.Is(err, session.ErrElementDisabled) {
+			writeError(w, http.StatusConflict, ErrCodeElementDisabled, err.Error())
+		} else if errors.Is(err, session.ErrStaleElementRef) {
+			writeError(w, http.StatusConflict, ErrCodeStaleElementRef, err.Error())
+		} else if errors.Is(err, session.ErrInvalidWaitCondition) {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidWait, err.Error())
+		} else if errors.As(err, &waitErr) {
+			writeErrorDetails(w, http.StatusGatewayTimeout, ErrCodeWaitTimeout, err.Error(), waitErr.Diagnostics)
+		} else if errors.Is(err, context.DeadlineExceeded) {
+			writeError(w, http.StatusGatewayTimeout, ErrCodeTimeout, err.Error())
+		} else {
+			writeError(w, http.StatusInternalServerError, ErrCodeActionFailed, err.Error())
+		}
+		return
+	}
+
+	response := ActionsResponse{
+		SessionID: sessionID,
+		PageID:    pageID,
+		Results:   results,
 	}
 
 	writeJSON(w, http.StatusOK, response)
@@ -353,12 +1043,14 @@ func (h *Handlers) AnalyzePage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	analysis, err := h.sessionManager.AnalyzePage(sessionID, req.PageID)
+	analysis, err := h.sessionManager.AnalyzePage(r.Context(), sessionID, req.PageID)
 	if err != nil {
 		if err.Error() == "failed to get session: session not found: "+sessionID {
 			writeError(w, http.StatusNotFound, ErrCodeSessionNotFound, "Session not found")
 		} else if err.Error() == "page not found in session: "+req.PageID {
 			writeError(w, http.StatusNotFound, ErrCodePageNotFound, "Page not found in session")
+		} else if errors.Is(err, context.DeadlineExceeded) {
+			writeError(w, http.StatusGatewayTimeout, ErrCodeTimeout, err.Error())
 		} else {
 			writeError(w, http.StatusInternalServerError, ErrCodeAnalysisFailed, err.Error())
 		}
@@ -389,22 +1081,39 @@ func (h *Handlers) GetAccessibilityTree(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	tree, err := h.sessionManager.GetAccessibilityTree(sessionID, req.PageID)
+	tree, err := h.sessionManager.GetAccessibilityTree(r.Context(), sessionID, req.PageID, req.AXTreeOptions)
 	if err != nil {
 		if err.Error() == "failed to get session: session not found: "+sessionID {
 			writeError(w, http.StatusNotFound, ErrCodeSessionNotFound, "Session not found")
 		} else if err.Error() == "page not found in session: "+req.PageID {
 			writeError(w, http.StatusNotFound, ErrCodePageNotFound, "Page not found in session")
+		} else if errors.Is(err, session.ErrInvalidTreeOptions) || errors.Is(err, session.ErrInvalidAction) {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidTreeOptions, err.Error())
+		} else if errors.Is(err, session.ErrElementNotFound) {
+			writeError(w, http.StatusNotFound, ErrCodeElementNotFound, err.Error())
+		} else if errors.Is(err, session.ErrStaleElementRef) {
+			writeError(w, http.StatusConflict, ErrCodeStaleElementRef, err.Error())
+		} else if errors.Is(err, context.DeadlineExceeded) {
+			writeError(w, http.StatusGatewayTimeout, ErrCodeTimeout, err.Error())
 		} else {
 			writeError(w, http.StatusInternalServerError, ErrCodeAccessibilityFailed, err.Error())
 		}
 		return
 	}
 
+	format := req.Format
+	if format == "" {
+		format = session.AXFormatJSON
+	}
+
 	response := AccessibilityTreeResponse{
 		SessionID: sessionID,
 		PageID:    req.PageID,
+		Format:    format,
 		Nodes:     tree.Nodes,
+		Text:      tree.Text,
+		NodeCount: tree.NodeCount,
+		CharCount: tree.CharCount,
 	}
 
 	writeJSON(w, http.StatusOK, response)
@@ -430,9 +1139,9 @@ func (h *Handlers) ListAgentSessions(w http.ResponseWriter, r *http.Request) {
 	for i, sess := range sessions {
 		summaries[i] = SessionSummary{
 			SessionID:    sess.ID,
-			SessionName:  sess.Name,
+			SessionName:  sess.GetName(),
 			Status:       sess.Status,
-			PageCount:    len(sess.PageIDs),
+			PageCount:    len(sess.Pages()),
 			CreatedAt:    sess.CreatedAt,
 			LastActivity: sess.LastActivity,
 		}
@@ -462,18 +1171,39 @@ func (h *Handlers) ResumeSession(w http.ResponseWriter, r *http.Request) {
 	}
 	
 	// Resume session by name
-	sess, err := h.sessionManager.ResumeSessionByName(req.AgentID, req.SessionName)
+	sess, resurrected, err := h.sessionManager.ResumeSessionByName(req.AgentID, req.SessionName)
 	if err != nil {
 		writeError(w, http.StatusNotFound, ErrCodeSessionNotFound, err.Error())
 		return
 	}
+
+	// A resurrected session is new to its process, one already in memory was counted before
+	if resurrected {
+		processes := h.loadBalancer.GetProcesses()
+		for _, process := range processes {
+			if process.GetPort() == sess.ProcessPort {
+				process.IncrementSessionCount()
+				break
+			}
+		}
+	}
 	
 	response := ResumeSessionResponse{
 		SessionID:   sess.ID,
-		SessionName: sess.Name,
+		SessionName: sess.GetName(),
 		Resumed:     true,
 		CreatedAt:   sess.CreatedAt,
 	}
+
+	// Reopen the pages of the closed session, a page that can't be reopened is left out of the mapping
+	if req.RestorePages {
+		pages, err := h.sessionManager.RestorePages(r.Context(), sess.ID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, ErrCodeInternalError, err.Error())
+			return
+		}
+		response.Pages = pages
+	}
 	
 	writeJSON(w, http.StatusOK, response)
 }
@@ -494,7 +1224,7 @@ func (h *Handlers) ResumeSessionByID(w http.ResponseWriter, r *http.Request) {
 	
 	response := ResumeSessionResponse{
 		SessionID:   sess.ID,
-		SessionName: sess.Name,
+		SessionName: sess.GetName(),
 		Resumed:     true,
 		CreatedAt:   sess.CreatedAt,
 	}
@@ -519,7 +1249,7 @@ func (h *Handlers) RenameSession(w http.ResponseWriter, r *http.Request) {
 	
 	// Rename the session
 	if err := h.sessionManager.RenameSession(sessionID, req.SessionName); err != nil {
-		if err.Error() == fmt.Sprintf("session name '%s' already exists", req.SessionName) {
+		if errors.Is(err, session.ErrSessionNameConflict) {
 			writeError(w, http.StatusConflict, "SESSION_NAME_CONFLICT", err.Error())
 			return
 		}
@@ -533,7 +1263,7 @@ func (h *Handlers) RenameSession(w http.ResponseWriter, r *http.Request) {
 	
 	response := map[string]interface{}{
 		"session_id":   sess.ID,
-		"session_name": sess.Name,
+		"session_name": sess.GetName(),
 		"agent_id":     sess.AgentID,
 	}
 	
@@ -569,10 +1299,27 @@ func (h *Handlers) CloseSession(w http.ResponseWriter, r *http.Request) {
 	// Return success
 	response := map[string]interface{}{
 		"session_id":   sessionID,
-		"session_name": sess.Name,
+		"session_name": sess.GetName(),
 		"status":       "idle",
-		"message":      "Session closed. Pages were disposed and cannot be resumed.",
+		"message":      "Session closed. Pages were disposed, resume with restore_pages to reopen them.",
 	}
 
 	writeJSON(w, http.StatusOK, response)
-}
\ No newline at end of file
+}
+
+// GetReconcileReport handles GET /reconcile
+func (h *Handlers) GetReconcileReport(w http.ResponseWriter, r *http.Request) {
+	report := h.sessionManager.LastReconcileReport()
+	if report == nil {
+		writeError(w, http.StatusNotFound, ErrCodeNoReconcileReport, "No reconciliation has run yet")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
+
+// Reconcile handles POST /reconcile, it runs a reconciliation pass now
+func (h *Handlers) Reconcile(w http.ResponseWriter, r *http.Request) {
+	report := h.sessionManager.Reconcile(h.loadBalancer.HealthyPorts())
+	writeJSON(w, http.StatusOK, report)
+}
@@ -2,10 +2,17 @@ package api
 
 import (
 	"encoding/json"
+	"io"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 )
 
+// streamChunkSize is how much of a streamed string is escaped and written at a time
+const streamChunkSize = 64 * 1024
+
 // writeJSON writes a JSON success response
 func writeJSON(w http.ResponseWriter, statusCode int, data interface{}) error {
 	// Set Content-Type header to tell client it's JSON
@@ -25,6 +32,11 @@ func writeJSON(w http.ResponseWriter, statusCode int, data interface{}) error {
 
 // writeError writes a JSON error response
 func writeError(w http.ResponseWriter, statusCode int, code string, message string) {
+	writeErrorDetails(w, statusCode, code, message, nil)
+}
+
+// writeErrorDetails writes a JSON error response with diagnostics attached
+func writeErrorDetails(w http.ResponseWriter, statusCode int, code string, message string, details interface{}) {
 	// Set Content-Type header
 	w.Header().Set("Content-Type", "application/json")
 	
@@ -36,6 +48,7 @@ func writeError(w http.ResponseWriter, statusCode int, code string, message stri
 		Error: ErrorDetail{
 			Code:    code,
 			Message: message,
+			Details: details,
 		},
 	}
 	
@@ -44,4 +57,109 @@ func writeError(w http.ResponseWriter, statusCode int, code string, message stri
 		// If we can't even write the error response, log it
 		slog.Error("failed to encode error response", "error", err)
 	}
-}
\ No newline at end of file
+}
+
+// writeBinary writes a raw success response, e.g. an image or a PDF
+func writeBinary(w http.ResponseWriter, contentType string, data []byte) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := w.Write(data); err != nil {
+		slog.Error("failed to write binary response", "error", err)
+	}
+}
+
+// writeText writes a raw text success response without copying the content
+func writeText(w http.ResponseWriter, contentType string, text string) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(text)))
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := io.WriteString(w, text); err != nil {
+		slog.Error("failed to write text response", "error", err)
+	}
+}
+
+// negotiate picks the response media type from the Accept header, in the order the client lists them
+// An empty result means JSON: no Accept header, application/json or a full wildcard.
+// ok is false when the client only accepts types that are neither offered nor JSON.
+func negotiate(r *http.Request, offers ...string) (mediaType string, ok bool) {
+	header := r.Header.Get("Accept")
+	if strings.TrimSpace(header) == "" {
+		return "", true
+	}
+
+	for _, accepted := range strings.Split(header, ",") {
+		accepted, params, _ := strings.Cut(accepted, ";")
+		accepted = strings.ToLower(strings.TrimSpace(accepted))
+
+		// q=0 explicitly refuses a type
+		if q := strings.TrimSpace(params); strings.HasPrefix(q, "q=0") && strings.Trim(q[2:], "0.") == "" {
+			continue
+		}
+
+		switch accepted {
+		case "application/json", "application/*", "*/*":
+			return "", true
+		}
+
+		for _, offer := range offers {
+			if accepted == offer {
+				return offer, true
+			}
+			// A type wildcard such as image/* takes the first offer of that type
+			if prefix, found := strings.CutSuffix(accepted, "*"); found && strings.HasPrefix(offer, prefix) {
+				return offer, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// writeJSONStream writes data as a JSON object with one more, potentially huge, string field appended
+// The string is escaped and written in chunks so the whole payload is never encoded in memory at once.
+func writeJSONStream(w http.ResponseWriter, statusCode int, data interface{}, field string, text string) {
+	head, err := json.Marshal(data)
+	if err != nil || len(head) < 2 || head[len(head)-1] != '}' {
+		slog.Error("failed to encode JSON response", "error", err)
+		writeError(w, http.StatusInternalServerError, ErrCodeInternalError, "failed to encode response")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	// Reopen the object and start the string field
+	key, _ := json.Marshal(field)
+	head = head[:len(head)-1]
+	if len(head) > 1 {
+		head = append(head, ',')
+	}
+	head = append(head, key...)
+	head = append(head, ':', '"')
+	if _, err := w.Write(head); err != nil {
+		slog.Error("failed to write JSON response", "error", err)
+		return
+	}
+
+	for len(text) > 0 {
+		// Cut on a rune boundary so multi-byte characters are not split
+		end := min(len(text), streamChunkSize)
+		for end < len(text) && !utf8.RuneStart(text[end]) {
+			end++
+		}
+
+		escaped, _ := json.Marshal(text[:end])
+		if _, err := w.Write(escaped[1 : len(escaped)-1]); err != nil {
+			slog.Error("failed to write JSON response", "error", err)
+			return
+		}
+		text = text[end:]
+	}
+
+	if _, err := io.WriteString(w, "\"}\n"); err != nil {
+		slog.Error("failed to write JSON response", "error", err)
+	}
+}
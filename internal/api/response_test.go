@@ -0,0 +1,69 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestNegotiate tests picking a response media type from the Accept header
+func TestNegotiate(t *testing.T) {
+	offers := []string{"image/png", "image/jpeg"}
+
+	tests := []struct {
+		accept    string
+		mediaType string
+		ok        bool
+	}{
+		{"", "", true},
+		{"application/json", "", true},
+		{"*/*", "", true},
+		{"image/jpeg", "image/jpeg", true},
+		{"IMAGE/PNG; q=0.9", "image/png", true},
+		{"image/*", "image/png", true},
+		{"image/webp, image/jpeg", "image/jpeg", true},
+		{"image/png;q=0, application/json", "", true},
+		{"image/webp", "", false},
+		{"text/html", "", false},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		if tt.accept != "" {
+			r.Header.Set("Accept", tt.accept)
+		}
+
+		mediaType, ok := negotiate(r, offers...)
+		if mediaType != tt.mediaType || ok != tt.ok {
+			t.Errorf("Accept %q: expected %q %v, got %q %v", tt.accept, tt.mediaType, tt.ok, mediaType, ok)
+		}
+	}
+}
+
+// TestWriteJSONStream tests that a streamed string field produces the same JSON as encoding it whole
+func TestWriteJSONStream(t *testing.T) {
+	// Long enough for several chunks, with multi-byte runes and characters that need escaping across boundaries
+	content := strings.Repeat("<p class=\"x\">héllo wörld ✓</p>\n\t", streamChunkSize/8)
+
+	head := pageContentHead{SessionID: "sess_1", PageID: "page_1", Format: "html", Length: len(content)}
+
+	recorder := httptest.NewRecorder()
+	writeJSONStream(recorder, http.StatusOK, head, "content", content)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", recorder.Code)
+	}
+
+	var response GetPageContentResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("streamed response is not valid JSON: %v", err)
+	}
+	if response.Content != content {
+		t.Error("streamed content does not round trip")
+	}
+	if response.SessionID != "sess_1" || response.Length != len(content) {
+		t.Errorf("unexpected head fields %+v", response)
+	}
+}
@@ -54,13 +54,27 @@ func NewServer(port string, manager *session.Manager, loadBalancer *pool.LoadBal
 			r.Post("/navigate", handlers.Navigate)
 			r.Post("/execute", handlers.ExecuteJS)
 			r.Post("/screenshot", handlers.CaptureScreenshot)
+			r.Post("/pdf", handlers.PrintToPDF)
 			r.Post("/analyze", handlers.AnalyzePage)
 			r.Post("/accessibility-tree", handlers.GetAccessibilityTree)
 			r.Post("/resume", handlers.ResumeSessionByID)
 			r.Put("/rename", handlers.RenameSession)
+			r.Get("/har", handlers.ExportHAR)
+			r.Get("/interception", handlers.GetInterception)
+			r.Put("/interception", handlers.SetInterception)
+			r.Put("/emulation", handlers.SetEmulation)
 
 			r.Route("/pages/{pageId}", func(r chi.Router) {
 				r.Get("/content", handlers.GetPageContent)
+				r.Get("/history", handlers.GetNavigationHistory)
+				r.Get("/console", handlers.GetConsoleLog)
+				r.Get("/network", handlers.GetNetworkLog)
+				r.Get("/network/{requestId}/body", handlers.GetResponseBody)
+				r.Post("/back", handlers.GoBack)
+				r.Post("/forward", handlers.GoForward)
+				r.Post("/reload", handlers.Reload)
+				r.Post("/actions", handlers.PerformActions)
+				r.Post("/wait", handlers.Wait)
 				r.Delete("/", handlers.ClosePage)
 			})
 		})
@@ -71,6 +85,10 @@ func NewServer(port string, manager *session.Manager, loadBalancer *pool.LoadBal
 		r.Get("/sessions", handlers.ListAgentSessions)
 	})
 
+	// Reconciliation of the session store with the browser processes
+	router.Get("/reconcile", handlers.GetReconcileReport)
+	router.Post("/reconcile", handlers.Reconcile)
+
 	// Add metrics endpoint
 	router.Get("/metrics", func(w http.ResponseWriter, r *http.Request) {
 		metrics := loadBalancer.GetMetrics()
@@ -81,7 +99,8 @@ func NewServer(port string, manager *session.Manager, loadBalancer *pool.LoadBal
 		Addr:         ":" + port,
 		Handler:      router,
 		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
+		// Leave room for the longest timeout_ms a request may ask for
+		WriteTimeout: MaxRequestTimeout + 15*time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
 
@@ -3,6 +3,7 @@ package api
 import (
 	"time"
 
+	"github.com/dhruvsoni1802/browser-query-ai/internal/extract"
 	"github.com/dhruvsoni1802/browser-query-ai/internal/session"
 )
 
@@ -15,23 +16,58 @@ type CreateSessionRequest struct {
 	// Optional: Allow client to specify port
 	// If not provided, server/load balancer decides
 	BrowserPort int `json:"browser_port,omitempty"`
+	// Optional: Rules applied to every request of the session's pages
+	Interception []session.InterceptionRule `json:"interception,omitempty"`
+	// Optional: Device and environment every page of the session emulates
+	Emulation *session.EmulationOptions `json:"emulation,omitempty"`
 }
 
 // NavigateRequest for POST /sessions/{id}/navigate
 type NavigateRequest struct {
-	URL string `json:"url" validate:"required"`
+	URL       string `json:"url" validate:"required"`
+	PageID    string `json:"page_id,omitempty"`    // Optional, navigate this page instead of opening a new one
+	TimeoutMs int    `json:"timeout_ms,omitempty"` // Optional, overrides the default wait for slow pages
+
+	WaitFor *session.WaitCondition `json:"wait_for,omitempty"` // Optional, condition to wait for once the page is ready
 }
 
 // ExecuteJSRequest for POST /sessions/{id}/execute
 type ExecuteJSRequest struct {
-	PageID string `json:"page_id" validate:"required"`
-	Script string `json:"script" validate:"required"`
+	PageID    string `json:"page_id" validate:"required"`
+	Script    string `json:"script" validate:"required"`
+	TimeoutMs int    `json:"timeout_ms,omitempty"` // Optional, overrides the default command timeout
+}
+
+// HistoryRequest for POST /sessions/{id}/pages/{pageId}/back, /forward and /reload
+// The body is optional
+type HistoryRequest struct {
+	IgnoreCache bool `json:"ignore_cache,omitempty"` // Reload only, bypass the browser cache
+	TimeoutMs   int  `json:"timeout_ms,omitempty"`   // Optional, overrides the default wait for slow pages
+}
+
+// ActionsRequest for POST /sessions/{id}/pages/{pageId}/actions
+// Actions run in order and stop at the first failure
+type ActionsRequest struct {
+	Actions   []session.Action `json:"actions" validate:"required"`
+	TimeoutMs int              `json:"timeout_ms,omitempty"` // Optional, bounds the whole sequence
+}
+
+// WaitRequest for POST /sessions/{id}/pages/{pageId}/wait
+type WaitRequest struct {
+	session.WaitCondition
 }
 
 // ScreenshotRequest for POST /sessions/{id}/screenshot
 type ScreenshotRequest struct {
 	PageID string `json:"page_id" validate:"required"`
-	Format string `json:"format,omitempty"` // "png" or "jpeg", default "png"
+	session.ScreenshotOptions
+}
+
+// PDFRequest for POST /sessions/{id}/pdf
+type PDFRequest struct {
+	PageID string `json:"page_id" validate:"required"`
+	session.PDFOptions
+	TimeoutMs int `json:"timeout_ms,omitempty"` // Optional, long pages can take a while to print
 }
 
 
@@ -50,7 +86,32 @@ type CreateSessionResponse struct {
 type NavigateResponse struct {
 	SessionID string `json:"session_id"`
 	PageID    string `json:"page_id"`
-	URL       string `json:"url"`
+	URL       string `json:"url"`                  // Final URL after redirects
+	Status    int    `json:"status,omitempty"`     // HTTP status of the main document
+	ErrorText string `json:"error_text,omitempty"` // e.g. net::ERR_NAME_NOT_RESOLVED
+}
+
+// NavigationHistoryResponse returned for GET /sessions/{id}/pages/{pageId}/history
+type NavigationHistoryResponse struct {
+	SessionID    string                 `json:"session_id"`
+	PageID       string                 `json:"page_id"`
+	CurrentIndex int                    `json:"current_index"`
+	Entries      []session.HistoryEntry `json:"entries"`
+}
+
+// ActionsResponse returned after performing input actions
+type ActionsResponse struct {
+	SessionID string                  `json:"session_id"`
+	PageID    string                  `json:"page_id"`
+	Results   []*session.ActionResult `json:"results"`
+}
+
+// WaitResponse returned once a wait condition holds
+type WaitResponse struct {
+	SessionID string `json:"session_id"`
+	PageID    string `json:"page_id"`
+	URL       string `json:"url,omitempty"`
+	ElapsedMs int64  `json:"elapsed_ms"`
 }
 
 // ExecuteJSResponse returned after JavaScript execution
@@ -62,21 +123,91 @@ type ExecuteJSResponse struct {
 
 // ScreenshotResponse returned after screenshot capture
 type ScreenshotResponse struct {
-	SessionID  string `json:"session_id"`
-	PageID     string `json:"page_id"`
-	Screenshot string `json:"screenshot"` // base64 encoded PNG/JPEG
-	Format     string `json:"format"`
-	Size       int    `json:"size"` // Size in bytes (before encoding)
+	SessionID  string         `json:"session_id"`
+	PageID     string         `json:"page_id"`
+	Screenshot string         `json:"screenshot"` // base64 encoded PNG/JPEG/WebP
+	Format     string         `json:"format"`
+	Width      int            `json:"width"` // In image pixels, CSS pixels times the scale
+	Height     int            `json:"height"`
+	Size       int            `json:"size"`            // Size in bytes (before encoding)
+	Marks      []session.Mark `json:"marks,omitempty"` // Legend of an annotated screenshot
 }
 
-// GetPageContentResponse returned with page HTML
+// PDFResponse returned after printing a page, unless the client asked for application/pdf
+type PDFResponse struct {
+	SessionID string `json:"session_id"`
+	PageID    string `json:"page_id"`
+	PDF       string `json:"pdf"`  // base64 encoded PDF document
+	Size      int    `json:"size"` // Size in bytes (before encoding)
+}
+
+// GetPageContentResponse returned with page content
 type GetPageContentResponse struct {
 	SessionID string `json:"session_id"`
 	PageID    string `json:"page_id"`
+	URL       string `json:"url"`
+	Format    string `json:"format"` // html, text, markdown or readability
 	Content   string `json:"content"`
 	Length    int    `json:"length"` // Content length in bytes
 }
 
+// pageContentHead is GetPageContentResponse without the content, which is streamed after it
+type pageContentHead struct {
+	SessionID string `json:"session_id"`
+	PageID    string `json:"page_id"`
+	URL       string `json:"url"`
+	Format    string `json:"format"`
+	Content   string `json:"-"`
+	Length    int    `json:"length"`
+}
+
+// ConsoleResponse returned with the console entries of a page
+type ConsoleResponse struct {
+	SessionID string                  `json:"session_id"`
+	PageID    string                  `json:"page_id"`
+	Entries   []*session.ConsoleEntry `json:"entries"`
+	Cursor    int64                   `json:"cursor"`  // Pass as since to get only newer entries
+	Dropped   int64                   `json:"dropped"` // Entries evicted from the buffer since the page opened
+}
+
+// NetworkResponse returned with the requests of a page
+type NetworkResponse struct {
+	SessionID string                  `json:"session_id"`
+	PageID    string                  `json:"page_id"`
+	Entries   []*session.NetworkEntry `json:"entries"`
+	Cursor    int64                   `json:"cursor"`  // Pass as since to get only newer requests
+	Dropped   int64                   `json:"dropped"` // Requests evicted from the buffer since the page opened
+}
+
+// ResponseBodyResponse returned with the body of a captured response
+type ResponseBodyResponse struct {
+	SessionID     string `json:"session_id"`
+	PageID        string `json:"page_id"`
+	RequestID     string `json:"request_id"`
+	MimeType      string `json:"mime_type"`
+	Body          string `json:"body"`
+	Base64Encoded bool   `json:"base64_encoded"` // Set when the body is not valid UTF-8
+	Size          int    `json:"size"`
+}
+
+// InterceptionRequest for PUT /sessions/{id}/interception
+type InterceptionRequest struct {
+	Rules []session.InterceptionRule `json:"rules"` // Replaces all rules, empty turns interception off
+}
+
+// InterceptionResponse returned with the interception rules of a session
+type InterceptionResponse struct {
+	SessionID   string                          `json:"session_id"`
+	Rules       []session.InterceptionRuleStats `json:"rules"`
+	Intercepted int64                           `json:"intercepted"` // Requests evaluated since the rules were set
+}
+
+// EmulationResponse returned with the emulation options of a session
+type EmulationResponse struct {
+	SessionID string                    `json:"session_id"`
+	Emulation *session.EmulationOptions `json:"emulation"` // null when the session uses browser defaults
+}
+
 // GetSessionResponse returned with session details
 type GetSessionResponse struct {
 	SessionID    string                `json:"session_id"`
@@ -88,6 +219,7 @@ type GetSessionResponse struct {
 	CreatedAt    time.Time             `json:"created_at"`
 	LastActivity time.Time             `json:"last_activity"`
 	Status       session.SessionStatus `json:"status"`
+	Emulation    *session.EmulationOptions `json:"emulation,omitempty"`
 }
 
 // ListSessionsResponse returned with all sessions
@@ -123,8 +255,9 @@ type ErrorResponse struct {
 
 // ErrorDetail contains error information
 type ErrorDetail struct {
-	Code    string `json:"code"`    // Machine-readable error code
-	Message string `json:"message"` // Human-readable message
+	Code    string      `json:"code"`              // Machine-readable error code
+	Message string      `json:"message"`           // Human-readable message
+	Details interface{} `json:"details,omitempty"` // Optional diagnostics, e.g. for a timed out wait
 }
 
 // ListAgentSessionsResponse
@@ -146,16 +279,18 @@ type SessionSummary struct {
 
 // ResumeSessionRequest for POST /sessions/resume
 type ResumeSessionRequest struct {
-	AgentID     string `json:"agent_id" validate:"required"`
-	SessionName string `json:"session_name" validate:"required"`
+	AgentID      string `json:"agent_id" validate:"required"`
+	SessionName  string `json:"session_name" validate:"required"`
+	RestorePages bool   `json:"restore_pages,omitempty"` // Reopen the pages that were open when the session was closed
 }
 
 // ResumeSessionResponse for resuming a session
 type ResumeSessionResponse struct {
-	SessionID   string    `json:"session_id"`
-	SessionName string    `json:"session_name"`
-	Resumed     bool      `json:"resumed"`  // true if existed, false if created new
-	CreatedAt   time.Time `json:"created_at"`
+	SessionID   string            `json:"session_id"`
+	SessionName string            `json:"session_name"`
+	Resumed     bool              `json:"resumed"` // true if existed, false if created new
+	CreatedAt   time.Time         `json:"created_at"`
+	Pages       map[string]string `json:"pages,omitempty"` // New page ID of every reopened page, keyed by its old page ID
 }
 
 // RenameSessionRequest for PUT /sessions/{id}/rename
@@ -179,13 +314,18 @@ type AnalyzePageResponse struct {
 // AccessibilityTreeRequest for POST /sessions/{id}/accessibility-tree
 type AccessibilityTreeRequest struct {
 	PageID string `json:"page_id" validate:"required"`
+	session.AXTreeOptions
 }
 
 // AccessibilityTreeResponse returned after retrieving the accessibility tree
 type AccessibilityTreeResponse struct {
-	SessionID string              `json:"session_id"`
-	PageID    string              `json:"page_id"`
-	Nodes     []*session.AXNode   `json:"nodes"`
+	SessionID string            `json:"session_id"`
+	PageID    string            `json:"page_id"`
+	Format    string            `json:"format"`
+	Nodes     []*session.AXNode `json:"nodes,omitempty"`
+	Text      string            `json:"text,omitempty"` // Indented text, format "text" only
+	NodeCount int               `json:"node_count"`
+	CharCount int               `json:"char_count"` // Approximate size of nodes or text
 }
 
 // Common error codes
@@ -200,4 +340,48 @@ const (
 	ErrCodeAnalysisFailed      = "ANALYSIS_FAILED"
 	ErrCodeAccessibilityFailed = "ACCESSIBILITY_FAILED"
 	ErrCodeInternalError       = "INTERNAL_ERROR"
-)
\ No newline at end of file
+	ErrCodeTimeout             = "TIMEOUT"
+	ErrCodeNoHistoryEntry      = "NO_HISTORY_ENTRY"
+	ErrCodeInvalidAction       = "INVALID_ACTION"
+	ErrCodeElementNotFound     = "ELEMENT_NOT_FOUND"
+	ErrCodeElementNotVisible   = "ELEMENT_NOT_VISIBLE"
+	ErrCodeElementDisabled     = "ELEMENT_DISABLED"
+	ErrCodeStaleElementRef     = "STALE_REF"
+	ErrCodeInvalidTreeOptions  = "INVALID_TREE_OPTIONS"
+	ErrCodeInvalidFormat       = "INVALID_FORMAT"
+	ErrCodeInvalidScreenshot   = "INVALID_SCREENSHOT_OPTIONS"
+	ErrCodeInvalidPDF          = "INVALID_PDF_OPTIONS"
+	ErrCodePDFFailed           = "PDF_FAILED"
+	ErrCodeNotAcceptable       = "NOT_ACCEPTABLE"
+	ErrCodeInvalidConsoleQuery = "INVALID_CONSOLE_QUERY"
+	ErrCodeInvalidNetworkQuery = "INVALID_NETWORK_QUERY"
+	ErrCodeRequestNotFound     = "REQUEST_NOT_FOUND"
+	ErrCodeBodyUnavailable     = "RESPONSE_BODY_UNAVAILABLE"
+	ErrCodeInvalidInterception = "INVALID_INTERCEPTION_RULE"
+	ErrCodeInvalidEmulation    = "INVALID_EMULATION"
+	ErrCodeActionFailed        = "ACTION_FAILED"
+	ErrCodeInvalidWait         = "INVALID_WAIT_CONDITION"
+	ErrCodeNoReconcileReport   = "NO_RECONCILE_REPORT"
+	ErrCodeWaitTimeout         = "WAIT_TIMEOUT"
+)
+
+// imageMediaTypes maps screenshot formats to the media types clients can ask for in Accept
+var imageMediaTypes = map[string]string{
+	session.ScreenshotPNG:  "image/png",
+	session.ScreenshotJPEG: "image/jpeg",
+	session.ScreenshotWebP: "image/webp",
+}
+
+// contentMediaTypes maps page content formats to the media types clients can ask for in Accept
+var contentMediaTypes = map[string]string{
+	extract.FormatHTML:        "text/html",
+	extract.FormatText:        "text/plain",
+	extract.FormatMarkdown:    "text/markdown",
+	extract.FormatReadability: "text/markdown",
+}
+
+// StreamContentThreshold is the content size above which JSON responses are streamed
+const StreamContentThreshold = 1 << 20
+
+// MaxRequestTimeout caps the timeout_ms a client can ask for
+const MaxRequestTimeout = 2 * time.Minute
\ No newline at end of file
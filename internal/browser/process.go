@@ -25,6 +25,9 @@ type Process struct {
 	Cmd         *exec.Cmd     // Command to execute the chromium browser
 	StartedAt   time.Time     // Time when the process started
 	Status      ProcessStatus // Status of the process
+
+	exited  chan struct{} // Closed once the process has exited and been reaped
+	exitErr error         // Why the process exited, set before exited is closed
 }
 
 // NewProcess creates a new browser process configuration.
@@ -87,6 +90,14 @@ func (p *Process) Start() error {
 	p.Status = StatusRunning
 	p.StartedAt = time.Now()
 
+	// Reap the process as soon as it exits, a crashed child would otherwise stay a zombie
+	// that still answers signal 0
+	p.exited = make(chan struct{})
+	go func() {
+		p.exitErr = p.Cmd.Wait()
+		close(p.exited)
+	}()
+
 	return nil
 }
 
@@ -97,27 +108,31 @@ func (p *Process) Stop() error {
 		return fmt.Errorf("process was never started")
 	}
 
-	// Send SIGTERM for graceful shutdown
-	if err := p.Cmd.Process.Signal(syscall.SIGTERM); err != nil {
-		return fmt.Errorf("failed to send termination signal: %w", err)
+	// Stopping twice would return the port to the pool twice
+	if p.Status == StatusStopped {
+		return nil
 	}
 
-	// Wait for process to exit with timeout
-	done := make(chan error, 1)
-	go func() {
-		done <- p.Cmd.Wait()
-	}()
-
-	select {
-	case err := <-done:
-		// Process exited gracefully
-		if err != nil && err.Error() != "signal: terminated" {
-			return fmt.Errorf("process exit error: %w", err)
+	// A process that already exited (crashed) only needs its resources cleaned up
+	if p.IsAlive() {
+		// Send SIGTERM for graceful shutdown
+		if err := p.Cmd.Process.Signal(syscall.SIGTERM); err != nil {
+			return fmt.Errorf("failed to send termination signal: %w", err)
 		}
-	case <-time.After(5 * time.Second):
-		// Timeout exceeded - force kill
-		if err := p.Cmd.Process.Kill(); err != nil {
-			return fmt.Errorf("failed to force kill process: %w", err)
+
+		// Wait for process to exit with timeout
+		select {
+		case <-p.exited:
+			// Process exited gracefully
+			if p.exitErr != nil && p.exitErr.Error() != "signal: terminated" {
+				return fmt.Errorf("process exit error: %w", p.exitErr)
+			}
+		case <-time.After(5 * time.Second):
+			// Timeout exceeded - force kill
+			if err := p.Cmd.Process.Kill(); err != nil {
+				return fmt.Errorf("failed to force kill process: %w", err)
+			}
+			<-p.exited
 		}
 	}
 
@@ -142,6 +157,13 @@ func (p *Process) IsAlive() bool {
 		return false
 	}
 
+	// The process exited and was reaped
+	select {
+	case <-p.exited:
+		return false
+	default:
+	}
+
 	// Send signal 0 - checks existence without affecting the process
 	err := p.Cmd.Process.Signal(syscall.Signal(0))
 	return err == nil
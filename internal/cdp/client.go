@@ -3,6 +3,7 @@ package cdp
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"sync"
@@ -21,6 +22,11 @@ type Target struct {
     WebSocketDebuggerURL  string `json:"webSocketDebuggerUrl"`
 }
 
+const (
+	DefaultCommandTimeout       = 10 * time.Second // Timeout for browser-level commands without a deadline
+	DefaultTargetCommandTimeout = 30 * time.Second // Timeout for page commands without a deadline
+)
+
 // Client represents a CDP WebSocket client connection to a browser
 type Client struct {
 	wsURL      string                  // WebSocket URL
@@ -28,10 +34,23 @@ type Client struct {
 	requestID  int                     // Counter for generating unique request IDs
 	pending    map[int]chan *Response  // Pending requests waiting for responses
 	targetSessions map[string]string   // Target ID → Session ID ( CDP Session )
+	attaching  map[string]*pendingAttach // Target ID → attach in flight, concurrent callers wait for it
 	mu         sync.Mutex              // Protects requestID and pending map
 	ctx        context.Context         // Context for cancellation
 	cancel     context.CancelFunc      // Cancel function
 	closeOnce  sync.Once               // Ensures Close() only runs once
+
+	subscribers map[subscriptionKey]map[int]*Subscription // Event subscribers keyed by method and CDP session
+	nextSubID   int                                       // Counter for generating unique subscription IDs
+	eventsMu    sync.RWMutex                              // Protects subscribers and nextSubID
+
+	host      string                         // Debug host used to re-discover the WebSocket URL (optional)
+	port      string                         // Debug port used to re-discover the WebSocket URL (optional)
+	state     ConnectionState                // Current connection state, protected by mu
+	lastErr   error                          // Why the connection dropped, protected by mu
+	listeners []func(ConnectionState)        // Connection state listeners, protected by mu
+	replay    map[string][]replayCommand     // Target ID ("" for browser) → commands to replay after reconnect
+	writeMu   sync.Mutex                     // Serializes writes, the WebSocket allows only one writer
 }
 
 // NewClient creates a new CDP client (doesn't connect yet)
@@ -47,6 +66,10 @@ func NewClient(wsURL string) *Client {
 		requestID: 0,
 		pending: make(map[int]chan *Response),
 		targetSessions: make(map[string]string),
+		attaching: make(map[string]*pendingAttach),
+		subscribers: make(map[subscriptionKey]map[int]*Subscription),
+		state: StateConnecting,
+		replay: make(map[string][]replayCommand),
 		ctx: ctx,
 		cancel: cancel,
 		closeOnce: sync.Once{},
@@ -55,26 +78,37 @@ func NewClient(wsURL string) *Client {
 
 // Connect establishes the WebSocket connection and starts the message reader
 func (c *Client) Connect() error {
-	slog.Info("connecting to CDP WebSocket", "url", c.wsURL)
+	// Discover the URL first if the client was created for a debug port
+	wsURL, err := c.resolveURL()
+	if err != nil {
+		return err
+	}
+
+	slog.Info("connecting to CDP WebSocket", "url", wsURL)
 
 	// Create a new WebSocket connection
-	conn, _, err := websocket.DefaultDialer.Dial(c.wsURL, nil)
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
 	if err != nil {
 		return fmt.Errorf("failed to connect to WebSocket: %w", err)
 	}
 
 	//Set the connection inside the client struct
+	c.mu.Lock()
 	c.conn = conn
+	c.wsURL = wsURL
+	c.state = StateConnected
+	c.mu.Unlock()
 
 	//Start the background reader loop which is a goroutine that reads from the Websocket either responses or events
-	go c.readLoop()
+	go c.readLoop(conn)
 
 	slog.Info("CDP WebSocket connected successfully")
 	return nil
 }
 
 // Function to read from the Websocket either responses or events
-func (c *Client) readLoop() {
+// Each connection gets its own reader, a reconnect starts a new one for the new connection
+func (c *Client) readLoop(conn *websocket.Conn) {
 	// Defer ensures message reader logs when stopped
 	defer func() {
 		slog.Info("message reader stopped")
@@ -88,7 +122,7 @@ func (c *Client) readLoop() {
 			return
 		default:
 			// Read message from WebSocket
-			_, message, err := c.conn.ReadMessage()
+			_, message, err := conn.ReadMessage()
 
 			// If there is an error reading the message
 			if err != nil {
@@ -98,8 +132,8 @@ func (c *Client) readLoop() {
 					// Context cancelled - this is expected during shutdown
 					return
 				default:
-					// Unexpected error - log it
-					slog.Error("error reading WebSocket message", "error", err)
+					// Unexpected error - the connection is gone, fail pending requests and reconnect
+					c.handleDisconnect(conn, err)
 					return
 				}
 			}
@@ -111,7 +145,30 @@ func (c *Client) readLoop() {
 }
 
 // Function to send a command to the browser and wait for the response
+// Waits at most DefaultCommandTimeout, use SendCommandContext to control cancellation
 func (c *Client) SendCommand(method string, params map[string]interface{}) (json.RawMessage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultCommandTimeout)
+	defer cancel()
+
+	return c.SendCommandContext(ctx, method, params)
+}
+
+// SendCommandContext sends a browser-level command and waits until the response arrives or ctx is done
+// If ctx has no deadline, DefaultCommandTimeout is applied so a lost response can't block forever
+func (c *Client) SendCommandContext(ctx context.Context, method string, params map[string]interface{}) (json.RawMessage, error) {
+	ctx, cancel := withDefaultTimeout(ctx, DefaultCommandTimeout)
+	defer cancel()
+
+	return c.send(ctx, "", "", method, params)
+}
+
+// send writes a command (optionally scoped to a CDP session) and waits for its response
+func (c *Client) send(ctx context.Context, targetID, sessionID, method string, params map[string]interface{}) (json.RawMessage, error) {
+	// Don't bother sending if the caller already gave up
+	if err := ctx.Err(); err != nil {
+		return nil, commandContextError(method, err)
+	}
+
 	// Generate unique request ID
 	c.mu.Lock()
 	c.requestID++
@@ -124,42 +181,58 @@ func (c *Client) SendCommand(method string, params map[string]interface{}) (json
 	
 	// Build command
 	command := Command{
-		ID:     id,
-		Method: method,
-		Params: params,
+		ID:        id,
+		Method:    method,
+		Params:    params,
+		SessionID: sessionID,
 	}
 	
 	// Marshal to JSON
 	data, err := json.Marshal(command)
 	if err != nil {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
 		return nil, fmt.Errorf("failed to marshal command: %w", err)
 	}
 	
 	// Send over WebSocket
-	slog.Debug("sending CDP command", "method", method, "id", id)
-	if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+	slog.Debug("sending CDP command", 
+		"method", method, 
+		"target", targetID, 
+		"session", sessionID, 
+		"id", id)
+	if err := c.writeMessage(data); err != nil {
 		// Remove from pending since we failed to send
 		c.mu.Lock()
 		delete(c.pending, id)
 		c.mu.Unlock()
-		return nil, fmt.Errorf("failed to send command: %w", err)
+		return nil, err
 	}
 	
-	// Wait for response with timeout
+	// Wait for response, caller cancellation or client shutdown
 	select {
-	case response := <-responseChan:
+	case response, ok := <-responseChan:
+		// Channel is closed when the connection drops or the client closes
+		if !ok {
+			return nil, c.connectionError()
+		}
+
 		// Check if response has error
 		if response.Error != nil {
 			return nil, fmt.Errorf("CDP error: %s (code %d)", response.Error.Message, response.Error.Code)
 		}
+
+		// Remember session setup commands so they survive a reconnect
+		c.recordReplay(targetID, method, params)
 		return response.Result, nil
 		
-	case <-time.After(10 * time.Second):
-		// Timeout
+	case <-ctx.Done():
+		// Timeout or caller went away - a late response is dropped by handleResponse
 		c.mu.Lock()
 		delete(c.pending, id)
 		c.mu.Unlock()
-		return nil, fmt.Errorf("command timeout after 10 seconds")
+		return nil, commandContextError(method, ctx.Err())
 		
 	case <-c.ctx.Done():
 		// Client is closing
@@ -173,12 +246,16 @@ func (c *Client) Close() error {
 	c.closeOnce.Do(func() {
 		slog.Info("closing CDP client")
 		
-		// Cancel context (stops message reader)
+		// Cancel context (stops message reader and any reconnect in progress)
 		c.cancel()
 		
 		// Close WebSocket connection
-		if c.conn != nil {
-			err = c.conn.Close()
+		c.mu.Lock()
+		c.state = StateClosed
+		conn := c.conn
+		c.mu.Unlock()
+		if conn != nil {
+			err = conn.Close()
 		}
 		
 		// Clean up pending requests
@@ -188,6 +265,11 @@ func (c *Client) Close() error {
 			delete(c.pending, id)
 		}
 		c.mu.Unlock()
+
+		// Release event subscribers
+		c.closeAllSubscriptions()
+
+		c.notifyState(StateClosed)
 	})
 	
 	return err
@@ -195,124 +277,151 @@ func (c *Client) Close() error {
 
 // AttachToTarget attaches to a target and returns CDP sessionId
 func (c *Client) AttachToTarget(targetID string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultCommandTimeout)
+	defer cancel()
+
+	return c.attachToTarget(ctx, targetID)
+}
+
+// pendingAttach is a Target.attachToTarget in flight, done is closed once sessionID or err is set
+type pendingAttach struct {
+	done      chan struct{}
+	sessionID string
+	err       error
+}
+
+// attachToTarget is AttachToTarget bounded by the caller's context
+// Concurrent calls for the same target share one Target.attachToTarget, so a target is attached once.
+func (c *Client) attachToTarget(ctx context.Context, targetID string) (string, error) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
 
 	// Check if already attached
 	if sessionID, exists := c.targetSessions[targetID]; exists {
-			return sessionID, nil
+		c.mu.Unlock()
+		return sessionID, nil
+	}
+
+	// Another caller is attaching, wait for its result
+	if pending, exists := c.attaching[targetID]; exists {
+		c.mu.Unlock()
+		select {
+		case <-pending.done:
+			return pending.sessionID, pending.err
+		case <-ctx.Done():
+			return "", fmt.Errorf("failed to attach to target: %w", ctx.Err())
+		}
 	}
 
-	// Attach to target
+	// Unlock before sending, SendCommand needs the lock as well
+	pending := &pendingAttach{done: make(chan struct{})}
+	c.attaching[targetID] = pending
+	c.mu.Unlock()
+
+	sessionID, err := c.sendAttach(ctx, targetID)
+
+	// Store session mapping
+	c.mu.Lock()
+	if err == nil {
+		c.targetSessions[targetID] = sessionID
+	}
+	delete(c.attaching, targetID)
+	c.mu.Unlock()
+
+	pending.sessionID, pending.err = sessionID, err
+	close(pending.done)
+
+	return sessionID, err
+}
+
+// sendAttach sends Target.attachToTarget and returns the new CDP sessionId
+func (c *Client) sendAttach(ctx context.Context, targetID string) (string, error) {
 	params := map[string]interface{}{
-			"targetId": targetID,
-			"flatten":  true,
+		"targetId": targetID,
+		"flatten":  true,
 	}
 
-	result, err := c.SendCommand("Target.attachToTarget", params)
+	result, err := c.SendCommandContext(ctx, "Target.attachToTarget", params)
 	if err != nil {
-			return "", fmt.Errorf("failed to attach to target: %w", err)
+		return "", fmt.Errorf("failed to attach to target: %w", err)
 	}
 
 	// Parse sessionId
 	var response struct {
-			SessionID string `json:"sessionId"`
+		SessionID string `json:"sessionId"`
 	}
 
 	if err := json.Unmarshal(result, &response); err != nil {
-			return "", fmt.Errorf("failed to parse attach response: %w", err)
+		return "", fmt.Errorf("failed to parse attach response: %w", err)
 	}
 
-	// Store session mapping
-	c.targetSessions[targetID] = response.SessionID
-
 	return response.SessionID, nil
 }
 
 // SendCommandToTarget sends a command to a specific target (page)
+// Waits at most DefaultTargetCommandTimeout, use SendCommandToTargetContext to control cancellation
 func (c *Client) SendCommandToTarget(targetID, method string, params map[string]interface{}) (json.RawMessage, error) {
-	c.mu.Lock()
-	
-	// Check if we already have a session for this target
-	sessionID, exists := c.targetSessions[targetID]
-	
-	if !exists {
-		// Need to attach to target first
-		c.mu.Unlock() // Unlock before recursive call
-		
-		attachParams := map[string]interface{}{
-			"targetId": targetID,
-			"flatten":  true,
-		}
-		
-		result, err := c.SendCommand("Target.attachToTarget", attachParams)
-		if err != nil {
-			return nil, fmt.Errorf("failed to attach to target: %w", err)
-		}
-		
-		var attachResp struct {
-			SessionID string `json:"sessionId"`
-		}
-		if err := json.Unmarshal(result, &attachResp); err != nil {
-			return nil, fmt.Errorf("failed to parse attach response: %w", err)
-		}
-		
-		// Store the session
-		c.mu.Lock()
-		c.targetSessions[targetID] = attachResp.SessionID
-		sessionID = attachResp.SessionID
-	}
-	
-	// Now send command with sessionId
-	c.requestID++
-	id := c.requestID
-	responseChan := make(chan *Response, 1)
-	c.pending[id] = responseChan
-	c.mu.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultTargetCommandTimeout)
+	defer cancel()
 
-	// Build command with sessionId
-	command := Command{
-		ID:        id,
-		Method:    method,
-		Params:    params,
-		SessionID: sessionID,
-	}
+	return c.SendCommandToTargetContext(ctx, targetID, method, params)
+}
 
-	// Marshal to JSON
-	data, err := json.Marshal(command)
+// SendCommandToTargetContext sends a command to a target and waits until the response arrives or ctx is done
+// If ctx has no deadline, DefaultTargetCommandTimeout is applied
+func (c *Client) SendCommandToTargetContext(ctx context.Context, targetID, method string, params map[string]interface{}) (json.RawMessage, error) {
+	ctx, cancel := withDefaultTimeout(ctx, DefaultTargetCommandTimeout)
+	defer cancel()
+
+	// Get the CDP session for this target, attaching first if needed
+	sessionID, err := c.attachToTarget(ctx, targetID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal command: %w", err)
+		return nil, err
 	}
 
-	// Send over WebSocket
-	slog.Debug("sending CDP command to target", 
-		"method", method, 
-		"target", targetID, 
-		"session", sessionID, 
-		"id", id)
-		
-	if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
-		c.mu.Lock()
-		delete(c.pending, id)
-		c.mu.Unlock()
-		return nil, fmt.Errorf("failed to send command: %w", err)
+	return c.send(ctx, targetID, sessionID, method, params)
+}
+
+// writeMessage sends a text frame on the current connection
+// It fails fast with a DisconnectedError while the client is reconnecting.
+func (c *Client) writeMessage(data []byte) error {
+	c.mu.Lock()
+	conn := c.conn
+	state := c.state
+	lastErr := c.lastErr
+	c.mu.Unlock()
+
+	switch state {
+	case StateDisconnected:
+		return lastErr
+	case StateClosed:
+		return fmt.Errorf("client closed")
+	}
+	if conn == nil {
+		return fmt.Errorf("client not connected")
 	}
 
-	// Wait for response with timeout
-	select {
-	case response := <-responseChan:
-		if response.Error != nil {
-			return nil, fmt.Errorf("CDP error: %s (code %d)", response.Error.Message, response.Error.Code)
-		}
-		return response.Result, nil
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
 
-	case <-time.After(30 * time.Second):
-		c.mu.Lock()
-		delete(c.pending, id)
-		c.mu.Unlock()
-		return nil, fmt.Errorf("command timeout after 30 seconds")
+	if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+		return fmt.Errorf("failed to send command: %w", err)
+	}
+	return nil
+}
 
-	case <-c.ctx.Done():
-		return nil, fmt.Errorf("client closed")
+// withDefaultTimeout applies a timeout to ctx unless it already carries a deadline
+func withDefaultTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return context.WithCancel(ctx)
 	}
-}
\ No newline at end of file
+	return context.WithTimeout(ctx, timeout)
+}
+
+// commandContextError describes a command abandoned because its context ended
+// The context error is wrapped so callers can check errors.Is(err, context.DeadlineExceeded)
+func commandContextError(method string, err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("command %s timed out: %w", method, err)
+	}
+	return fmt.Errorf("command %s cancelled: %w", method, err)
+}
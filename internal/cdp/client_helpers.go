@@ -52,7 +52,8 @@ func (c *Client) handleResponse(response *Response) {
 
 // Function to handle the event that was received from the browser
 func (c *Client) handleEvent(event *Event) {
-	// For now, just log events
-	// TODO: Later, we can add event handlers
-	slog.Debug("received CDP event", "method", event.Method)
+	slog.Debug("received CDP event", "method", event.Method, "session", event.SessionID)
+
+	// Fan the event out to subscribers
+	c.dispatchEvent(event)
 }
\ No newline at end of file
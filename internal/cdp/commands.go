@@ -38,6 +38,24 @@ func (c *Client) DisposeBrowserContext(contextID string) error {
 	return nil
 }
 
+// GetBrowserContexts returns the IDs of every browser context except the default one
+func (c *Client) GetBrowserContexts() ([]string, error) {
+	result, err := c.SendCommand("Target.getBrowserContexts", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get browser contexts: %w", err)
+	}
+
+	var response struct {
+		BrowserContextIDs []string `json:"browserContextIds"`
+	}
+
+	if err := json.Unmarshal(result, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse browser contexts response: %w", err)
+	}
+
+	return response.BrowserContextIDs, nil
+}
+
 // CreateTarget creates a new page in the specified browser context
 func (c *Client) CreateTarget(url string, contextID string) (string, error) {
 	params := map[string]interface{}{
@@ -77,6 +95,9 @@ func (c *Client) CloseTarget(targetID string) error {
 		return fmt.Errorf("failed to close target: %w", err)
 	}
 
+	// Nothing to re-attach or replay for a closed target
+	c.forgetTarget(targetID)
+
 	return nil
 }
 
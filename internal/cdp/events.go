@@ -0,0 +1,272 @@
+package cdp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultEventBuffer is the channel size used when Subscribe is called with a non-positive buffer
+const DefaultEventBuffer = 64
+
+// AnySession can be passed as the session ID to receive an event from every target and the browser itself
+const AnySession = "*"
+
+// subscriptionKey identifies the subscribers of one event method on one CDP session
+type subscriptionKey struct {
+	method    string
+	sessionID string
+}
+
+// Subscription delivers the events matching a method and CDP session ID
+//
+// Slow consumer policy: events are not allowed to block the reader loop.
+// If a subscriber's buffer is full the event is dropped for that subscriber
+// and counted in Dropped(), other subscribers are not affected.
+// Fetch events are the exception, a paused request that is dropped is never continued and
+// hangs its page, so they are queued without limit and delivered in order by a goroutine of
+// the subscription until it ends.
+type Subscription struct {
+	C <-chan *Event // Channel the matching events are delivered on, closed on Unsubscribe
+
+	ch      chan *Event     // Writable side of C
+	done    chan struct{}   // Closed when the subscription ends, stops a queued delivery
+	key     subscriptionKey // Method and session this subscription listens to
+	id      int             // Unique ID within the client
+	client  *Client         // Client the subscription belongs to
+	dropped atomic.Uint64   // Number of events dropped because the buffer was full
+	once    sync.Once       // Ensures Unsubscribe only runs once
+
+	lossless bool          // Events are queued instead of dropped, C is closed by deliver
+	queueMu  sync.Mutex    // Protects queue
+	queue    []*Event      // Events waiting for room in the channel
+	wake     chan struct{} // Signals deliver that the queue has events
+}
+
+// Dropped returns how many events were dropped because the subscriber was too slow
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Unsubscribe stops delivery and closes the subscription channel
+func (s *Subscription) Unsubscribe() {
+	s.once.Do(func() {
+		s.client.removeSubscription(s)
+	})
+}
+
+// end stops delivery and closes the channel, the caller must hold eventsMu and have removed the subscription
+func (s *Subscription) end() {
+	close(s.done)
+	if !s.lossless {
+		close(s.ch)
+	}
+}
+
+// enqueue adds an event for deliver without waiting for the subscriber
+func (s *Subscription) enqueue(event *Event) {
+	s.queueMu.Lock()
+	s.queue = append(s.queue, event)
+	s.queueMu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+		// deliver is already woken
+	}
+}
+
+// deliver moves queued events to the channel in order until the subscription ends
+func (s *Subscription) deliver() {
+	defer close(s.ch)
+
+	for {
+		s.queueMu.Lock()
+		if len(s.queue) == 0 {
+			s.queueMu.Unlock()
+			select {
+			case <-s.wake:
+				continue
+			case <-s.done:
+				return
+			}
+		}
+		event := s.queue[0]
+		s.queue[0] = nil
+		s.queue = s.queue[1:]
+		s.queueMu.Unlock()
+
+		select {
+		case s.ch <- event:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// Subscribe registers for events with the given method on the given CDP session
+// An empty sessionID matches browser-level events, AnySession matches all of them.
+// A method of the form "Domain.*" matches every event of that domain, delivered in the order received.
+// The subscription is removed when ctx is done or Unsubscribe is called.
+func (c *Client) Subscribe(ctx context.Context, method string, sessionID string, buffer int) (*Subscription, error) {
+	if method == "" {
+		return nil, fmt.Errorf("event method is required")
+	}
+
+	if buffer <= 0 {
+		buffer = DefaultEventBuffer
+	}
+
+	ch := make(chan *Event, buffer)
+	domain, _, _ := strings.Cut(method, ".")
+	sub := &Subscription{
+		C:        ch,
+		ch:       ch,
+		done:     make(chan struct{}),
+		key:      subscriptionKey{method: method, sessionID: sessionID},
+		client:   c,
+		lossless: domain == "Fetch",
+		wake:     make(chan struct{}, 1),
+	}
+
+	c.eventsMu.Lock()
+	// Refuse new subscriptions once the client is closed, nothing would ever be delivered
+	if c.ctx.Err() != nil {
+		c.eventsMu.Unlock()
+		return nil, fmt.Errorf("client closed")
+	}
+	c.nextSubID++
+	sub.id = c.nextSubID
+	if c.subscribers[sub.key] == nil {
+		c.subscribers[sub.key] = make(map[int]*Subscription)
+	}
+	c.subscribers[sub.key][sub.id] = sub
+	c.eventsMu.Unlock()
+
+	if sub.lossless {
+		go sub.deliver()
+	}
+
+	// Tie the subscription lifetime to the caller's context
+	if ctx.Done() != nil {
+		go func() {
+			select {
+			case <-ctx.Done():
+				sub.Unsubscribe()
+			case <-c.ctx.Done():
+				// Client closed, Close() already released every subscription
+			}
+		}()
+	}
+
+	slog.Debug("subscribed to CDP event", "method", method, "session", sessionID)
+	return sub, nil
+}
+
+// SubscribeTarget registers for events emitted by a specific target (page)
+// It attaches to the target first if we are not attached yet.
+func (c *Client) SubscribeTarget(ctx context.Context, targetID, method string, buffer int) (*Subscription, error) {
+	sessionID, err := c.AttachToTarget(targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Subscribe(ctx, method, sessionID, buffer)
+}
+
+// removeSubscription unregisters a subscription and closes its channel
+func (c *Client) removeSubscription(sub *Subscription) {
+	c.eventsMu.Lock()
+	defer c.eventsMu.Unlock()
+
+	subs, exists := c.subscribers[sub.key]
+	if !exists {
+		return
+	}
+	if _, exists := subs[sub.id]; !exists {
+		return
+	}
+
+	delete(subs, sub.id)
+	if len(subs) == 0 {
+		delete(c.subscribers, sub.key)
+	}
+	sub.end()
+}
+
+// closeAllSubscriptions closes every subscription channel (used when the client is closed)
+func (c *Client) closeAllSubscriptions() {
+	c.eventsMu.Lock()
+	defer c.eventsMu.Unlock()
+
+	for key, subs := range c.subscribers {
+		for id, sub := range subs {
+			sub.end()
+			delete(subs, id)
+		}
+		delete(c.subscribers, key)
+	}
+}
+
+// dispatchEvent fans an event out to every matching subscriber without ever waiting on one
+func (c *Client) dispatchEvent(event *Event) {
+	c.eventsMu.RLock()
+	defer c.eventsMu.RUnlock()
+
+	domain, _, _ := strings.Cut(event.Method, ".")
+	keys := [4]subscriptionKey{
+		{method: event.Method, sessionID: event.SessionID},
+		{method: event.Method, sessionID: AnySession},
+		{method: domain + ".*", sessionID: event.SessionID},
+		{method: domain + ".*", sessionID: AnySession},
+	}
+
+	for _, key := range keys {
+		for _, sub := range c.subscribers[key] {
+			if sub.lossless {
+				sub.enqueue(event)
+				continue
+			}
+
+			select {
+			case sub.ch <- event:
+			default:
+				// Subscriber is not keeping up - drop instead of stalling the reader loop
+				if sub.dropped.Add(1) == 1 {
+					slog.Warn("dropping CDP events for slow subscriber",
+						"method", event.Method,
+						"session", event.SessionID)
+				}
+			}
+		}
+	}
+}
+
+// moveSubscriptions re-keys subscriptions from an old CDP session to a new one after re-attaching
+func (c *Client) moveSubscriptions(oldSessionID, newSessionID string) {
+	if oldSessionID == newSessionID {
+		return
+	}
+
+	c.eventsMu.Lock()
+	defer c.eventsMu.Unlock()
+
+	for key, subs := range c.subscribers {
+		if key.sessionID != oldSessionID {
+			continue
+		}
+
+		newKey := subscriptionKey{method: key.method, sessionID: newSessionID}
+		if c.subscribers[newKey] == nil {
+			c.subscribers[newKey] = make(map[int]*Subscription)
+		}
+		for id, sub := range subs {
+			sub.key = newKey
+			c.subscribers[newKey][id] = sub
+		}
+		delete(c.subscribers, key)
+	}
+}
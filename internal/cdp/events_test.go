@@ -0,0 +1,179 @@
+package cdp
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestSubscribeDispatch tests that events are routed by method and CDP session
+func TestSubscribeDispatch(t *testing.T) {
+	client := NewClient("ws://unused")
+	defer client.Close()
+
+	pageSub, err := client.Subscribe(context.Background(), "Page.loadEventFired", "session-1", 4)
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	anySub, err := client.Subscribe(context.Background(), "Page.loadEventFired", AnySession, 4)
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+
+	client.handleMessage([]byte(`{"method":"Page.loadEventFired","params":{"timestamp":1},"sessionId":"session-1"}`))
+	client.handleMessage([]byte(`{"method":"Page.loadEventFired","params":{"timestamp":2},"sessionId":"session-2"}`))
+	client.handleMessage([]byte(`{"method":"Runtime.consoleAPICalled","params":{},"sessionId":"session-1"}`))
+
+	if got := len(pageSub.C); got != 1 {
+		t.Errorf("expected 1 event for session-1 subscriber, got %d", got)
+	}
+	if got := len(anySub.C); got != 2 {
+		t.Errorf("expected 2 events for wildcard subscriber, got %d", got)
+	}
+
+	event := <-pageSub.C
+	if event.SessionID != "session-1" {
+		t.Errorf("expected event from session-1, got %q", event.SessionID)
+	}
+}
+
+// TestSubscribeDomain tests that a domain subscription receives every event of the domain in order
+func TestSubscribeDomain(t *testing.T) {
+	client := NewClient("ws://unused")
+	defer client.Close()
+
+	sub, err := client.Subscribe(context.Background(), "Network.*", "session-1", 4)
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+
+	client.handleMessage([]byte(`{"method":"Network.requestWillBeSent","params":{},"sessionId":"session-1"}`))
+	client.handleMessage([]byte(`{"method":"Page.loadEventFired","params":{},"sessionId":"session-1"}`))
+	client.handleMessage([]byte(`{"method":"Network.loadingFinished","params":{},"sessionId":"session-1"}`))
+	client.handleMessage([]byte(`{"method":"Network.loadingFinished","params":{},"sessionId":"session-2"}`))
+
+	if got := len(sub.C); got != 2 {
+		t.Fatalf("expected 2 network events for session-1, got %d", got)
+	}
+	if event := <-sub.C; event.Method != "Network.requestWillBeSent" {
+		t.Errorf("expected requestWillBeSent first, got %s", event.Method)
+	}
+	if event := <-sub.C; event.Method != "Network.loadingFinished" {
+		t.Errorf("expected loadingFinished second, got %s", event.Method)
+	}
+}
+
+// TestSubscribeSlowConsumer tests that a full buffer drops events instead of blocking
+func TestSubscribeSlowConsumer(t *testing.T) {
+	client := NewClient("ws://unused")
+	defer client.Close()
+
+	sub, err := client.Subscribe(context.Background(), "Network.requestWillBeSent", "", 2)
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 5; i++ {
+			client.handleMessage([]byte(`{"method":"Network.requestWillBeSent","params":{}}`))
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("dispatch blocked on a slow subscriber")
+	}
+
+	if sub.Dropped() != 3 {
+		t.Errorf("expected 3 dropped events, got %d", sub.Dropped())
+	}
+}
+
+// TestSubscribeContextCancel tests that cancelling the context closes the channel
+func TestSubscribeContextCancel(t *testing.T) {
+	client := NewClient("ws://unused")
+	defer client.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sub, err := client.Subscribe(ctx, "Target.targetCrashed", "", 1)
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+
+	cancel()
+
+	select {
+	case _, ok := <-sub.C:
+		if ok {
+			t.Error("expected channel to be closed, got an event")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("subscription channel was not closed after context cancel")
+	}
+
+	// Unsubscribe after cancellation must be a no-op
+	sub.Unsubscribe()
+}
+
+// TestSubscribeFetchNeverDrops tests that paused requests are queued for a slow subscriber
+// instead of being dropped, without holding up the reader loop
+func TestSubscribeFetchNeverDrops(t *testing.T) {
+	client := NewClient("ws://unused")
+	defer client.Close()
+
+	sub, err := client.Subscribe(context.Background(), "Fetch.requestPaused", "", 1)
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+
+	// Dispatch returns at once although nobody reads
+	dispatched := make(chan struct{})
+	go func() {
+		for i := 0; i < 5; i++ {
+			client.handleMessage([]byte(fmt.Sprintf(`{"method":"Fetch.requestPaused","params":{"requestId":"%d"}}`, i)))
+		}
+		close(dispatched)
+	}()
+
+	select {
+	case <-dispatched:
+	case <-time.After(time.Second):
+		t.Fatal("dispatch blocked on a subscriber that doesn't read")
+	}
+
+	for i := 0; i < 5; i++ {
+		select {
+		case event := <-sub.C:
+			if expected := fmt.Sprintf(`{"requestId":"%d"}`, i); string(event.Params) != expected {
+				t.Errorf("expected event %d in order, got %s", i, event.Params)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected paused request %d to be delivered", i+1)
+		}
+	}
+
+	if sub.Dropped() != 0 {
+		t.Errorf("expected no dropped events, got %d", sub.Dropped())
+	}
+
+	// Events still queued when the subscription ends are discarded and the channel closes
+	client.handleMessage([]byte(`{"method":"Fetch.requestPaused","params":{}}`))
+	client.handleMessage([]byte(`{"method":"Fetch.requestPaused","params":{}}`))
+	sub.Unsubscribe()
+
+	deadline := time.After(time.Second)
+	for {
+		select {
+		case _, ok := <-sub.C:
+			if !ok {
+				return
+			}
+		case <-deadline:
+			t.Fatal("expected the channel to close after Unsubscribe")
+		}
+	}
+}
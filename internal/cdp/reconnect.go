@@ -0,0 +1,292 @@
+package cdp
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	reconnectInitialBackoff = 250 * time.Millisecond // Delay before the first reconnect attempt
+	reconnectMaxBackoff     = 10 * time.Second       // Upper bound for the delay between attempts
+)
+
+// ConnectionState describes the state of the client's WebSocket connection
+type ConnectionState string
+
+const (
+	StateConnecting   ConnectionState = "connecting"   // Connect has not succeeded yet
+	StateConnected    ConnectionState = "connected"    // Commands can be sent
+	StateDisconnected ConnectionState = "disconnected" // Connection dropped, reconnect in progress
+	StateClosed       ConnectionState = "closed"       // Close() was called, the client is unusable
+)
+
+// ErrDisconnected is matched (via errors.Is) by every error returned because the connection dropped
+var ErrDisconnected = errors.New("CDP connection lost")
+
+// DisconnectedError is returned to callers whose command failed because the WebSocket dropped
+type DisconnectedError struct {
+	Err error // The read/write error that broke the connection
+}
+
+func (e *DisconnectedError) Error() string {
+	return fmt.Sprintf("%s: %v", ErrDisconnected, e.Err)
+}
+
+func (e *DisconnectedError) Unwrap() error {
+	return e.Err
+}
+
+// Is lets errors.Is(err, ErrDisconnected) match any DisconnectedError
+func (e *DisconnectedError) Is(target error) bool {
+	return target == ErrDisconnected
+}
+
+// replayCommand is a command re-sent after reconnecting because its effect is tied to the CDP session
+type replayCommand struct {
+	Method string
+	Params map[string]interface{}
+}
+
+// NewClientForPort creates a client that discovers its WebSocket URL from the debug port
+// The URL is resolved again on every reconnect, so a browser restarted on the same port is picked up.
+func NewClientForPort(host string, debugPort string) *Client {
+	client := NewClient("")
+	client.host = host
+	client.port = debugPort
+	return client
+}
+
+// OnStateChange registers a function called whenever the connection state changes
+// Listeners run on the client's reconnect goroutine and must not block for long.
+func (c *Client) OnStateChange(listener func(ConnectionState)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.listeners = append(c.listeners, listener)
+}
+
+// State returns the current connection state
+func (c *Client) State() ConnectionState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state
+}
+
+// notifyState calls every registered listener with the new state
+func (c *Client) notifyState(state ConnectionState) {
+	c.mu.Lock()
+	listeners := make([]func(ConnectionState), len(c.listeners))
+	copy(listeners, c.listeners)
+	c.mu.Unlock()
+
+	for _, listener := range listeners {
+		listener(state)
+	}
+}
+
+// connectionError returns the error handed to callers whose request was cut off
+func (c *Client) connectionError() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.state == StateDisconnected && c.lastErr != nil {
+		return c.lastErr
+	}
+	return fmt.Errorf("client closed")
+}
+
+// handleDisconnect fails pending requests and starts reconnecting after the read loop broke
+func (c *Client) handleDisconnect(conn *websocket.Conn, cause error) {
+	c.mu.Lock()
+
+	// Ignore errors from a connection that was already replaced or closed
+	if c.conn != conn || c.state != StateConnected {
+		c.mu.Unlock()
+		return
+	}
+
+	c.state = StateDisconnected
+	c.lastErr = &DisconnectedError{Err: cause}
+
+	// Fail every waiting caller right away instead of letting them time out
+	for id, ch := range c.pending {
+		close(ch)
+		delete(c.pending, id)
+	}
+
+	// CDP session IDs die with the connection, keep the targets so we can re-attach
+	stale := c.targetSessions
+	c.targetSessions = make(map[string]string)
+	c.mu.Unlock()
+
+	conn.Close()
+
+	slog.Error("CDP connection lost, reconnecting", "url", c.wsURL, "error", cause)
+	c.notifyState(StateDisconnected)
+
+	go c.reconnectLoop(stale)
+}
+
+// reconnectLoop retries the connection with exponential backoff until it succeeds or the client is closed
+func (c *Client) reconnectLoop(stale map[string]string) {
+	backoff := reconnectInitialBackoff
+
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		if err := c.reconnect(); err != nil {
+			slog.Warn("CDP reconnect attempt failed", "attempt", attempt, "error", err)
+			backoff = min(backoff*2, reconnectMaxBackoff)
+			continue
+		}
+
+		slog.Info("CDP connection restored", "url", c.wsURL, "attempts", attempt)
+
+		// Re-attach to the targets we had sessions for and replay their setup commands
+		c.restoreTargets(stale)
+		c.notifyState(StateConnected)
+		return
+	}
+}
+
+// reconnect dials a fresh WebSocket and restarts the read loop
+func (c *Client) reconnect() error {
+	wsURL, err := c.resolveURL()
+	if err != nil {
+		return err
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to connect to WebSocket: %w", err)
+	}
+
+	c.mu.Lock()
+	// Close() may have run while we were dialing
+	if c.ctx.Err() != nil {
+		c.mu.Unlock()
+		conn.Close()
+		return fmt.Errorf("client closed")
+	}
+	c.conn = conn
+	c.wsURL = wsURL
+	c.state = StateConnected
+	c.lastErr = nil
+	c.mu.Unlock()
+
+	go c.readLoop(conn)
+	return nil
+}
+
+// resolveURL returns the WebSocket URL to dial, re-discovering it when we know the debug port
+func (c *Client) resolveURL() (string, error) {
+	if c.port == "" {
+		return c.wsURL, nil
+	}
+
+	wsURL, err := GetWebSocketURL(c.host, c.port)
+	if err != nil {
+		return "", fmt.Errorf("failed to discover WebSocket URL: %w", err)
+	}
+	return wsURL, nil
+}
+
+// restoreTargets re-attaches to targets after a reconnect and replays their session setup
+func (c *Client) restoreTargets(stale map[string]string) {
+	// Browser-level commands first (e.g. target discovery)
+	for _, cmd := range c.replayCommands("") {
+		if _, err := c.SendCommand(cmd.Method, cmd.Params); err != nil {
+			slog.Warn("failed to replay browser command", "method", cmd.Method, "error", err)
+		}
+	}
+
+	for targetID, oldSessionID := range stale {
+		newSessionID, err := c.AttachToTarget(targetID)
+		if err != nil {
+			// Target is gone (page closed or browser restarted), nothing to restore
+			slog.Warn("failed to re-attach to target", "target", targetID, "error", err)
+			c.forgetTarget(targetID)
+			continue
+		}
+
+		// Keep existing subscribers receiving events from the new CDP session
+		c.moveSubscriptions(oldSessionID, newSessionID)
+
+		for _, cmd := range c.replayCommands(targetID) {
+			if _, err := c.SendCommandToTarget(targetID, cmd.Method, cmd.Params); err != nil {
+				slog.Warn("failed to replay target command",
+					"target", targetID,
+					"method", cmd.Method,
+					"error", err)
+			}
+		}
+
+		slog.Debug("re-attached to target", "target", targetID, "session", newSessionID)
+	}
+}
+
+// shouldReplay reports whether a command changes CDP session state that is lost on reconnect
+func shouldReplay(method string) bool {
+	return strings.HasSuffix(method, ".enable") ||
+		strings.HasPrefix(method, "Emulation.set") ||
+		method == "Network.setExtraHTTPHeaders" ||
+		method == "Target.setDiscoverTargets"
+}
+
+// recordReplay remembers (or forgets) a session-state command for a target ("" for the browser)
+func (c *Client) recordReplay(targetID, method string, params map[string]interface{}) {
+	// A disable/clear undoes the matching enable/set
+	undo := ""
+	switch {
+	case strings.HasSuffix(method, ".disable"):
+		undo = strings.TrimSuffix(method, ".disable") + ".enable"
+	case strings.HasPrefix(method, "Emulation.clear"):
+		undo = "Emulation.set" + strings.TrimPrefix(method, "Emulation.clear")
+	}
+
+	if undo == "" && !shouldReplay(method) {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	commands := c.replay[targetID]
+	for i, cmd := range commands {
+		if cmd.Method == method || cmd.Method == undo {
+			commands = append(commands[:i], commands[i+1:]...)
+			break
+		}
+	}
+	if undo == "" {
+		commands = append(commands, replayCommand{Method: method, Params: params})
+	}
+	c.replay[targetID] = commands
+}
+
+// replayCommands returns a copy of the commands to replay for a target
+func (c *Client) replayCommands(targetID string) []replayCommand {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	commands := make([]replayCommand, len(c.replay[targetID]))
+	copy(commands, c.replay[targetID])
+	return commands
+}
+
+// forgetTarget drops all per-target state once the target is closed
+func (c *Client) forgetTarget(targetID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.targetSessions, targetID)
+	delete(c.replay, targetID)
+}
@@ -0,0 +1,169 @@
+package cdp
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// fakeBrowser is a minimal CDP endpoint: it drops the first connection on the
+// first command it receives, and answers every command on later connections
+type fakeBrowser struct {
+	connections atomic.Int32
+	commands    chan Command
+}
+
+func (f *fakeBrowser) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	n := f.connections.Add(1)
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var cmd Command
+		if err := json.Unmarshal(data, &cmd); err != nil {
+			return
+		}
+		f.commands <- cmd
+
+		// Simulate a crash on the first connection
+		if n == 1 {
+			return
+		}
+
+		result := `{}`
+		if cmd.Method == "Target.attachToTarget" {
+			result = `{"sessionId":"new-session"}`
+		}
+		reply := `{"id":` + jsonInt(cmd.ID) + `,"result":` + result + `}`
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(reply)); err != nil {
+			return
+		}
+	}
+}
+
+func jsonInt(i int) string {
+	data, _ := json.Marshal(i)
+	return string(data)
+}
+
+// TestReconnectFailsPendingAndRestores tests that a dropped connection fails
+// the in-flight command immediately, then reconnects and re-attaches targets
+func TestReconnectFailsPendingAndRestores(t *testing.T) {
+	browser := &fakeBrowser{commands: make(chan Command, 16)}
+	server := httptest.NewServer(browser)
+	defer server.Close()
+
+	client := NewClient("ws" + strings.TrimPrefix(server.URL, "http"))
+	defer client.Close()
+
+	states := make(chan ConnectionState, 4)
+	client.OnStateChange(func(state ConnectionState) {
+		states <- state
+	})
+
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+
+	// Pretend we were attached to a page and had the Page domain enabled
+	client.targetSessions["page-1"] = "old-session"
+	client.recordReplay("page-1", "Page.enable", nil)
+
+	sub, err := client.Subscribe(t.Context(), "Page.loadEventFired", "old-session", 1)
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+
+	start := time.Now()
+	_, err = client.SendCommand("Browser.getVersion", nil)
+	if !errors.Is(err, ErrDisconnected) {
+		t.Fatalf("expected ErrDisconnected, got %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("pending command was not failed immediately (took %s)", time.Since(start))
+	}
+
+	waitForState(t, states, StateDisconnected)
+	waitForState(t, states, StateConnected)
+
+	if client.State() != StateConnected {
+		t.Fatalf("expected client to be connected, got %s", client.State())
+	}
+
+	// Re-attach and replay must have happened on the new connection
+	seen := map[string]bool{}
+	for len(browser.commands) > 0 {
+		seen[(<-browser.commands).Method] = true
+	}
+	if !seen["Target.attachToTarget"] || !seen["Page.enable"] {
+		t.Errorf("expected re-attach and replay, saw %v", seen)
+	}
+
+	// Subscribers follow the target to its new CDP session
+	client.handleMessage([]byte(`{"method":"Page.loadEventFired","sessionId":"new-session"}`))
+	select {
+	case <-sub.C:
+	case <-time.After(time.Second):
+		t.Error("subscription was not moved to the new CDP session")
+	}
+}
+
+// TestAttachToTargetConcurrent tests that concurrent attaches to one target send a single Target.attachToTarget
+func TestAttachToTargetConcurrent(t *testing.T) {
+	browser := &fakeBrowser{commands: make(chan Command, 16)}
+	browser.connections.Store(1) // Answer from the first connection on
+	server := httptest.NewServer(browser)
+	defer server.Close()
+
+	client := NewClient("ws" + strings.TrimPrefix(server.URL, "http"))
+	defer client.Close()
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sessionID, err := client.AttachToTarget("page-1")
+			if err != nil || sessionID != "new-session" {
+				t.Errorf("expected new-session, got %q, %v", sessionID, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if attaches := len(browser.commands); attaches != 1 {
+		t.Errorf("expected one Target.attachToTarget, got %d", attaches)
+	}
+}
+
+func waitForState(t *testing.T, states <-chan ConnectionState, want ConnectionState) {
+	t.Helper()
+	select {
+	case got := <-states:
+		if got != want {
+			t.Fatalf("expected state %s, got %s", want, got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for state %s", want)
+	}
+}
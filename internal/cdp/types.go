@@ -25,7 +25,9 @@ type ResponseError struct {
 }
 
 // Event represents an unsolicited CDP event from the browser
+// SessionID is empty for browser-level events and set for events coming from an attached target
 type Event struct {
-	Method string          `json:"method"`
-	Params json.RawMessage `json:"params,omitempty"`
+	Method    string          `json:"method"`
+	Params    json.RawMessage `json:"params,omitempty"`
+	SessionID string          `json:"sessionId,omitempty"`
 }
\ No newline at end of file
@@ -5,6 +5,7 @@ import (
 	"os"
 	"runtime"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -15,11 +16,27 @@ type Config struct {
 	ServerPort   string
 	MaxBrowsers  int
 
+	//Pool scaling, the pool grows from MinBrowsers to MaxBrowsers with the number of sessions
+	MinBrowsers        int
+	SessionsPerBrowser int
+	ScaleDownCooldown  time.Duration
+	ScaleUpWait        time.Duration
+
+	//Session store configuration
+	SessionStore     string // redis, memory or bolt
+	SessionStorePath string // Database file of the bolt store
+
 	//Redis configuration
 	RedisAddr    string
 	RedisPassword string
 	RedisDB      int
 	SessionTTL   time.Duration
+
+	//Reconciliation of the session store with the browser processes
+	ReconcileInterval time.Duration
+
+	//Health checks of the browser processes, crashed or hung ones are restarted
+	HealthCheckInterval time.Duration
 }
 
 func Load() (*Config, error) {
@@ -28,16 +45,37 @@ func Load() (*Config, error) {
 		return nil, err
 	}
 
+	sessionStore := strings.ToLower(getEnv("SESSION_STORE", "redis"))
+	switch sessionStore {
+	case "redis", "memory", "bolt":
+	default:
+		return nil, fmt.Errorf("unknown SESSION_STORE %q, expected redis, memory or bolt", sessionStore)
+	}
+
 	return &Config{
 		ChromiumPath:  chromiumPath,
 		ServerPort:    getEnv("SERVER_PORT", "8080"),
 		MaxBrowsers:   getEnvAsInt("MAX_BROWSERS", 5),
+
+		// Pool scaling defaults
+		MinBrowsers:        getEnvAsInt("MIN_BROWSERS", 1),
+		SessionsPerBrowser: getEnvAsInt("SESSIONS_PER_BROWSER", 10),
+		ScaleDownCooldown:  getEnvAsDuration("SCALE_DOWN_COOLDOWN", 5*time.Minute),
+		ScaleUpWait:        getEnvAsDuration("SCALE_UP_WAIT", 5*time.Second),
+
+		// Session store defaults
+		SessionStore:     sessionStore,
+		SessionStorePath: getEnv("SESSION_STORE_PATH", "sessions.db"),
 		
 		// Redis defaults
 		RedisAddr:     getEnv("REDIS_ADDR", "localhost:6379"),
 		RedisPassword: getEnv("REDIS_PASSWORD", ""),
 		RedisDB:       getEnvAsInt("REDIS_DB", 0),
 		SessionTTL:    getEnvAsDuration("SESSION_TTL", 1*time.Hour),
+
+		ReconcileInterval: getEnvAsDuration("RECONCILE_INTERVAL", 10*time.Minute),
+
+		HealthCheckInterval: getEnvAsDuration("HEALTH_CHECK_INTERVAL", 5*time.Second),
 	}, nil
 }
 
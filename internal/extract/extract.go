@@ -0,0 +1,514 @@
+// Package extract converts page HTML into text an AI agent can read
+package extract
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Output formats accepted by Convert
+const (
+	FormatHTML        = "html"        // The HTML as is
+	FormatText        = "text"        // Plain text, keeping block structure and list bullets
+	FormatMarkdown    = "markdown"    // Markdown with headings, lists, links, images and tables
+	FormatReadability = "readability" // Markdown of the main article only
+)
+
+// Placeholders for whitespace that must survive the final cleanup
+const (
+	hardSpace   = "\x00" // Indentation of nested list items and preformatted text
+	hardNewline = "\x01" // Line breaks inside preformatted text
+)
+
+var blankLines = regexp.MustCompile(`\n{3,}`)
+
+// ValidFormat reports whether format is one of the supported output formats
+func ValidFormat(format string) bool {
+	switch format {
+	case FormatHTML, FormatText, FormatMarkdown, FormatReadability:
+		return true
+	}
+	return false
+}
+
+// Convert turns an HTML document into the requested format
+// pageURL is used to make links and images absolute.
+func Convert(content string, pageURL string, format string) (string, error) {
+	if !ValidFormat(format) {
+		return "", fmt.Errorf("unknown format %q", format)
+	}
+	if format == FormatHTML {
+		return content, nil
+	}
+
+	doc, err := html.Parse(strings.NewReader(content))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	base, _ := url.Parse(pageURL)
+	base = documentBase(doc, base)
+
+	r := &renderer{
+		markdown: format != FormatText,
+		base:     base,
+	}
+
+	root := findElement(doc, atom.Body)
+	if root == nil {
+		root = doc
+	}
+
+	prefix := ""
+	if format == FormatReadability {
+		root = MainContent(doc)
+
+		// The article heading often lives outside the article element
+		if title := documentTitle(doc); title != "" && findElement(root, atom.H1) == nil {
+			prefix = "# " + r.escape(title) + "\n\n"
+		}
+	}
+
+	return cleanup(prefix + r.render(root)), nil
+}
+
+// renderer converts a node tree into text or markdown
+type renderer struct {
+	markdown bool
+	base     *url.URL
+}
+
+// render converts a node and its children
+func (r *renderer) render(n *html.Node) string {
+	switch n.Type {
+	case html.TextNode:
+		return r.escape(collapseSpace(n.Data))
+	case html.DocumentNode:
+		return r.children(n)
+	case html.ElementNode:
+	default:
+		return ""
+	}
+
+	if skipped(n) {
+		return ""
+	}
+
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		text := flatten(r.children(n))
+		if text == "" {
+			return ""
+		}
+		if r.markdown {
+			level := int(n.Data[1] - '0')
+			text = strings.Repeat("#", level) + " " + text
+		}
+		return block(text)
+	case atom.P, atom.Div, atom.Section, atom.Article, atom.Main, atom.Header, atom.Footer,
+		atom.Nav, atom.Aside, atom.Figure, atom.Figcaption, atom.Details, atom.Summary,
+		atom.Form, atom.Fieldset, atom.Address, atom.Dl, atom.Body:
+		return block(r.children(n))
+	case atom.Dt, atom.Dd:
+		return "\n" + strings.TrimSpace(r.children(n)) + "\n"
+	case atom.Br:
+		return "\n"
+	case atom.Hr:
+		if r.markdown {
+			return block("---")
+		}
+		return block("")
+	case atom.Ul, atom.Ol:
+		return block(r.list(n))
+	case atom.Li:
+		// A list item outside a list
+		return block(r.children(n))
+	case atom.Blockquote:
+		content := strings.TrimSpace(r.children(n))
+		if r.markdown {
+			content = "> " + strings.ReplaceAll(content, "\n", "\n> ")
+		}
+		return block(content)
+	case atom.Pre:
+		return block(r.pre(n))
+	case atom.Table:
+		return block(r.table(n))
+	case atom.A:
+		return r.link(n)
+	case atom.Img:
+		return r.image(n)
+	case atom.Strong, atom.B:
+		return r.emphasis(n, "**")
+	case atom.Em, atom.I:
+		return r.emphasis(n, "*")
+	case atom.Code, atom.Kbd, atom.Samp:
+		return r.code(n)
+	}
+
+	return r.children(n)
+}
+
+// children renders the children of a node in order
+func (r *renderer) children(n *html.Node) string {
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(r.render(c))
+	}
+	return b.String()
+}
+
+// list renders the items of a ul or ol, nested lists are indented under their item
+func (r *renderer) list(n *html.Node) string {
+	ordered := n.DataAtom == atom.Ol
+	number := 1
+	if start, err := strconv.Atoi(attr(n, "start")); ordered && err == nil {
+		number = start
+	}
+
+	var items []string
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode || c.DataAtom != atom.Li || skipped(c) {
+			continue
+		}
+
+		marker := "- "
+		if ordered {
+			marker = strconv.Itoa(number) + ". "
+			number++
+		}
+
+		// Nested lists follow their item directly, other blocks keep their blank lines
+		var item strings.Builder
+		for cc := c.FirstChild; cc != nil; cc = cc.NextSibling {
+			if cc.Type == html.ElementNode && (cc.DataAtom == atom.Ul || cc.DataAtom == atom.Ol) && !skipped(cc) {
+				item.WriteString("\n" + r.list(cc) + "\n")
+				continue
+			}
+			item.WriteString(r.render(cc))
+		}
+
+		lines := strings.Split(strings.TrimSpace(blankLines.ReplaceAllString(item.String(), "\n\n")), "\n")
+		indent := strings.Repeat(hardSpace, len(marker))
+		for i := 1; i < len(lines); i++ {
+			if strings.TrimSpace(lines[i]) != "" {
+				lines[i] = indent + strings.TrimLeft(lines[i], " ")
+			}
+		}
+		items = append(items, marker+strings.Join(lines, "\n"))
+	}
+
+	return strings.Join(items, "\n")
+}
+
+// pre renders preformatted text as is, fenced in markdown
+func (r *renderer) pre(n *html.Node) string {
+	text := strings.Trim(textContent(n), "\n")
+	text = strings.ReplaceAll(text, "\t", "    ")
+	text = strings.ReplaceAll(text, " ", hardSpace)
+	text = strings.ReplaceAll(text, "\n", hardNewline)
+
+	if r.markdown {
+		return "```" + hardNewline + text + hardNewline + "```"
+	}
+	return text
+}
+
+// table renders a table with the first row as the header
+func (r *renderer) table(n *html.Node) string {
+	var rows [][]string
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode || skipped(c) {
+				continue
+			}
+			switch c.DataAtom {
+			case atom.Tr:
+				var cells []string
+				for cell := c.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.Type == html.ElementNode && (cell.DataAtom == atom.Td || cell.DataAtom == atom.Th) {
+						text := flatten(r.children(cell))
+						if r.markdown {
+							text = strings.ReplaceAll(text, "|", `\|`)
+						}
+						cells = append(cells, text)
+					}
+				}
+				if len(cells) > 0 {
+					rows = append(rows, cells)
+				}
+			case atom.Thead, atom.Tbody, atom.Tfoot:
+				walk(c)
+			}
+		}
+	}
+	walk(n)
+
+	if len(rows) == 0 {
+		return ""
+	}
+
+	if !r.markdown {
+		lines := make([]string, len(rows))
+		for i, row := range rows {
+			lines[i] = strings.Join(row, " | ")
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	columns := 0
+	for _, row := range rows {
+		columns = max(columns, len(row))
+	}
+
+	lines := make([]string, 0, len(rows)+1)
+	for i, row := range rows {
+		for len(row) < columns {
+			row = append(row, "")
+		}
+		lines = append(lines, "| "+strings.Join(row, " | ")+" |")
+		if i == 0 {
+			lines = append(lines, "|"+strings.Repeat(" --- |", columns))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// link renders an anchor with an absolute URL
+func (r *renderer) link(n *html.Node) string {
+	text := r.children(n)
+	href := r.absolute(attr(n, "href"))
+	if !r.markdown || href == "" || strings.TrimSpace(text) == "" {
+		return text
+	}
+
+	// Keep the surrounding spaces outside the brackets
+	trimmed := strings.TrimSpace(text)
+	leading, trailing := leadingSpace(text), trailingSpace(text)
+	return leading + "[" + flatten(trimmed) + "](" + href + ")" + trailing
+}
+
+// image renders an image with an absolute URL, markdown only
+func (r *renderer) image(n *html.Node) string {
+	src := r.absolute(attr(n, "src"))
+	if !r.markdown || src == "" || strings.HasPrefix(src, "data:") {
+		return ""
+	}
+	return "![" + r.escape(collapseSpace(attr(n, "alt"))) + "](" + src + ")"
+}
+
+// emphasis wraps inline content in a markdown marker such as ** or `
+func (r *renderer) emphasis(n *html.Node, marker string) string {
+	text := r.children(n)
+	trimmed := strings.TrimSpace(text)
+	if !r.markdown || trimmed == "" || strings.Contains(trimmed, "\n") {
+		return text
+	}
+	return leadingSpace(text) + marker + trimmed + marker + trailingSpace(text)
+}
+
+// code renders inline code, its content is literal and not escaped
+func (r *renderer) code(n *html.Node) string {
+	text := collapseSpace(textContent(n))
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return text
+	}
+	if !r.markdown {
+		return text
+	}
+
+	// A code span containing backticks needs a longer fence
+	fence := "`"
+	for strings.Contains(trimmed, fence) {
+		fence += "`"
+	}
+	if strings.HasPrefix(trimmed, "`") || strings.HasSuffix(trimmed, "`") {
+		trimmed = " " + trimmed + " "
+	}
+	return leadingSpace(text) + fence + trimmed + fence + trailingSpace(text)
+}
+
+// absolute resolves a link against the page URL, dropping links that go nowhere
+func (r *renderer) absolute(link string) string {
+	link = strings.TrimSpace(link)
+	if link == "" || strings.HasPrefix(strings.ToLower(link), "javascript:") {
+		return ""
+	}
+
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	if r.base != nil {
+		parsed = r.base.ResolveReference(parsed)
+	}
+
+	// Spaces and parentheses would end the markdown link early
+	return strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29").Replace(parsed.String())
+}
+
+// escape protects characters that would otherwise turn text into markdown syntax
+func (r *renderer) escape(text string) string {
+	if !r.markdown {
+		return text
+	}
+	return markdownEscaper.Replace(text)
+}
+
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`)
+
+// skipped reports whether an element never contributes readable text
+func skipped(n *html.Node) bool {
+	switch n.DataAtom {
+	case atom.Script, atom.Style, atom.Noscript, atom.Template, atom.Svg, atom.Canvas,
+		atom.Iframe, atom.Object, atom.Embed, atom.Head, atom.Select, atom.Input,
+		atom.Textarea, atom.Button, atom.Link, atom.Meta:
+		return true
+	}
+
+	if hasAttr(n, "hidden") || attr(n, "aria-hidden") == "true" {
+		return true
+	}
+
+	style := strings.ReplaceAll(strings.ToLower(attr(n, "style")), " ", "")
+	return strings.Contains(style, "display:none") || strings.Contains(style, "visibility:hidden")
+}
+
+// block separates content from its surroundings by blank lines
+func block(content string) string {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return ""
+	}
+	return "\n\n" + content + "\n\n"
+}
+
+// flatten joins multi-line content into a single line, e.g. for table cells and headings
+func flatten(content string) string {
+	return strings.Join(strings.Fields(content), " ")
+}
+
+// collapseSpace collapses runs of whitespace into single spaces like a browser does
+func collapseSpace(text string) string {
+	if strings.TrimSpace(text) == "" {
+		if text == "" {
+			return ""
+		}
+		return " "
+	}
+	return leadingSpace(text) + strings.Join(strings.Fields(text), " ") + trailingSpace(text)
+}
+
+func leadingSpace(text string) string {
+	if text != "" && strings.TrimLeft(text, " \t\r\n") != text {
+		return " "
+	}
+	return ""
+}
+
+func trailingSpace(text string) string {
+	if text != "" && strings.TrimRight(text, " \t\r\n") != text {
+		return " "
+	}
+	return ""
+}
+
+// cleanup trims every line, collapses blank lines and restores protected whitespace
+func cleanup(content string) string {
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		lines[i] = strings.Trim(line, " \t\r")
+	}
+	content = strings.Join(lines, "\n")
+	content = blankLines.ReplaceAllString(content, "\n\n")
+	content = strings.TrimSpace(content)
+
+	content = strings.ReplaceAll(content, hardSpace, " ")
+	content = strings.ReplaceAll(content, hardNewline, "\n")
+	return content + "\n"
+}
+
+// textContent returns all text below a node without any processing
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && (c.DataAtom == atom.Script || c.DataAtom == atom.Style) {
+			continue
+		}
+		if c.Type == html.ElementNode && c.DataAtom == atom.Br {
+			b.WriteString("\n")
+			continue
+		}
+		b.WriteString(textContent(c))
+	}
+	return b.String()
+}
+
+// documentBase applies a <base href> to the page URL
+func documentBase(doc *html.Node, pageURL *url.URL) *url.URL {
+	base := findElement(doc, atom.Base)
+	if base == nil || attr(base, "href") == "" {
+		return pageURL
+	}
+
+	href, err := url.Parse(attr(base, "href"))
+	if err != nil {
+		return pageURL
+	}
+	if pageURL == nil {
+		return href
+	}
+	return pageURL.ResolveReference(href)
+}
+
+// documentTitle returns the text of the <title> element
+func documentTitle(doc *html.Node) string {
+	if title := findElement(doc, atom.Title); title != nil {
+		return flatten(textContent(title))
+	}
+	return ""
+}
+
+// findElement returns the first element of a kind in document order
+func findElement(n *html.Node, kind atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == kind {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findElement(c, kind); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// attr returns the value of an attribute, or "" if it is not set
+func attr(n *html.Node, name string) string {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// hasAttr reports whether an attribute is set, e.g. a boolean attribute such as hidden
+func hasAttr(n *html.Node, name string) bool {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,162 @@
+package extract
+
+import (
+	"strings"
+	"testing"
+)
+
+const samplePage = `<!DOCTYPE html>
+<html>
+<head><title>Release notes</title><style>body { color: red }</style></head>
+<body>
+  <nav><a href="/">Home</a> <a href="/docs">Docs</a></nav>
+  <header class="site-header">Acme Inc.</header>
+  <article>
+    <h1>Version 2.0</h1>
+    <p>This release brings <strong>faster</strong> builds, see the <a href="guide/upgrade.html">upgrade guide</a>.</p>
+    <h2>Changes</h2>
+    <ul>
+      <li>New <code>--fast</code> flag</li>
+      <li>Plugins
+        <ol><li>Loader</li><li>Cache</li></ol>
+      </li>
+    </ul>
+    <table>
+      <tr><th>Target</th><th>Time</th></tr>
+      <tr><td>linux</td><td>12s</td></tr>
+    </table>
+    <pre>go build
+  ./...</pre>
+    <script>track()</script>
+    <div hidden>Secret</div>
+  </article>
+  <footer>Copyright Acme</footer>
+</body>
+</html>`
+
+// TestConvertMarkdown tests headings, lists, links, tables and code in markdown output
+func TestConvertMarkdown(t *testing.T) {
+	markdown, err := Convert(samplePage, "https://example.com/releases/2.0", FormatMarkdown)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	expected := []string{
+		"# Version 2.0",
+		"This release brings **faster** builds, see the [upgrade guide](https://example.com/releases/guide/upgrade.html).",
+		"## Changes",
+		"- New `--fast` flag",
+		"- Plugins\n  1. Loader\n  2. Cache",
+		"| Target | Time |\n| --- | --- |\n| linux | 12s |",
+		"```\ngo build\n  ./...\n```",
+		"[Home](https://example.com/)",
+		"Copyright Acme",
+	}
+	for _, want := range expected {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("expected markdown to contain %q, got:\n%s", want, markdown)
+		}
+	}
+
+	for _, unwanted := range []string{"track()", "color: red", "Secret"} {
+		if strings.Contains(markdown, unwanted) {
+			t.Errorf("expected markdown not to contain %q, got:\n%s", unwanted, markdown)
+		}
+	}
+}
+
+// TestConvertText tests that plain text keeps the structure without markdown syntax
+func TestConvertText(t *testing.T) {
+	text, err := Convert(samplePage, "https://example.com/", FormatText)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	if !strings.Contains(text, "Version 2.0\n\nThis release brings faster builds, see the upgrade guide.") {
+		t.Errorf("unexpected text output:\n%s", text)
+	}
+	if strings.Contains(text, "**") || strings.Contains(text, "](") {
+		t.Errorf("expected no markdown syntax in text output:\n%s", text)
+	}
+}
+
+// TestConvertReadability tests that only the article is kept
+func TestConvertReadability(t *testing.T) {
+	article, err := Convert(samplePage, "https://example.com/", FormatReadability)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	if !strings.HasPrefix(article, "# Version 2.0") {
+		t.Errorf("expected the article to start with its heading, got:\n%s", article)
+	}
+	for _, boilerplate := range []string{"Home", "Acme Inc.", "Copyright"} {
+		if strings.Contains(article, boilerplate) {
+			t.Errorf("expected %q to be stripped, got:\n%s", boilerplate, article)
+		}
+	}
+}
+
+// TestMainContentScoring tests picking the main content without article or main elements
+func TestMainContentScoring(t *testing.T) {
+	page := `<html><head><title>Story</title></head><body>
+		<div class="menu"><a href="/a">A</a><a href="/b">B</a></div>
+		<div class="links"><p><a href="/1">A long list of links that looks like text</a></p></div>
+		<div id="story">
+			<p>The first paragraph of the story, with enough text to count, and a few commas.</p>
+			<p>The second paragraph continues, adding more words, so the story scores highest.</p>
+		</div>
+	</body></html>`
+
+	article, err := Convert(page, "https://example.com/", FormatReadability)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	if !strings.HasPrefix(article, "# Story\n\nThe first paragraph") {
+		t.Errorf("expected the title and the story, got:\n%s", article)
+	}
+	if strings.Contains(article, "list of links") {
+		t.Errorf("expected the link block to be left out, got:\n%s", article)
+	}
+}
+
+// TestMainContentInBoilerplateWrapper tests that wrappers named like boilerplate keep the content they hold
+func TestMainContentInBoilerplateWrapper(t *testing.T) {
+	page := `<html><head><title>Guide</title></head><body>
+		<div id="page-header-wrapper">
+			<div class="site-nav"><a href="/">Home</a></div>
+			<div class="layout-with-sidebar">
+				<div class="sidebar"><p><a href="/x">Other guides you might like to read next</a></p></div>
+				<div class="text">
+					<p>The guide starts here, with enough text to count, and a few commas.</p>
+					<p>It goes on for another paragraph, adding words, so it scores highest.</p>
+					<form action="/search"><label>Search the guide</label><input name="q"></form>
+				</div>
+			</div>
+		</div>
+	</body></html>`
+
+	article, err := Convert(page, "https://example.com/", FormatReadability)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	for _, want := range []string{"The guide starts here", "another paragraph", "Search the guide"} {
+		if !strings.Contains(article, want) {
+			t.Errorf("expected the article to contain %q, got:\n%s", want, article)
+		}
+	}
+	for _, unwanted := range []string{"Home", "Other guides"} {
+		if strings.Contains(article, unwanted) {
+			t.Errorf("expected %q to be stripped, got:\n%s", unwanted, article)
+		}
+	}
+}
+
+// TestConvertUnknownFormat tests that unknown formats are rejected
+func TestConvertUnknownFormat(t *testing.T) {
+	if _, err := Convert("<p>x</p>", "", "pdf"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
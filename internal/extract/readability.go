@@ -0,0 +1,231 @@
+package extract
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// boilerplatePattern matches class and id names of navigation, footers and other page chrome
+var boilerplatePattern = regexp.MustCompile(`(?i)(^|[-_\s])(nav|navbar|navigation|menu|header|footer|sidebar|breadcrumbs?|cookies?|consent|banner|share|sharing|social|related|comments?|advert|ads?|promo|newsletter|subscribe|popup|modal|skip)($|[-_\s])`)
+
+// contentPattern matches class and id names that suggest the element holds the content
+var contentPattern = regexp.MustCompile(`(?i)article|body|column|main|content|post|entry|story`)
+
+// boilerplateRoles are ARIA landmarks that never hold the main content
+var boilerplateRoles = map[string]bool{
+	"navigation":    true,
+	"banner":        true,
+	"contentinfo":   true,
+	"complementary": true,
+	"search":        true,
+	"menu":          true,
+	"menubar":       true,
+	"dialog":        true,
+}
+
+// MainContent finds the element holding the main article of a document
+// Navigation, headers, footers and other boilerplate that don't hold the article are removed from the document.
+func MainContent(doc *html.Node) *html.Node {
+	// Content outside boilerplate comes first, but a wrapper whose class merely mentions
+	// a sidebar or header may hold the whole page
+	content := findContent(doc, true)
+	if content == nil {
+		content = findContent(doc, false)
+	}
+	if content == nil {
+		content = findElement(doc, atom.Body)
+	}
+	if content == nil {
+		content = doc
+	}
+
+	removeBoilerplate(doc, content)
+	return content
+}
+
+// findContent returns the article, the main landmark or the best scored element of a document
+// With skipBoilerplate, elements in boilerplate are left out.
+func findContent(doc *html.Node, skipBoilerplate bool) *html.Node {
+	usable := func(n *html.Node) bool {
+		return !skipBoilerplate || !inBoilerplate(n)
+	}
+
+	// An explicit article is the best hint, pick the one with the most text
+	var best *html.Node
+	bestLength := 0
+	walkElements(doc, func(n *html.Node) {
+		if n.DataAtom == atom.Article && usable(n) {
+			if length := len(strings.TrimSpace(textContent(n))); length > bestLength {
+				best, bestLength = n, length
+			}
+		}
+	})
+	if best != nil {
+		return best
+	}
+
+	if main := findMain(doc, usable); main != nil {
+		return main
+	}
+
+	return bestCandidate(doc, usable)
+}
+
+// removeBoilerplate detaches elements that are page chrome rather than content
+// Elements holding the content are kept, whatever their class names say.
+func removeBoilerplate(doc, content *html.Node) {
+	var remove []*html.Node
+	walkElements(doc, func(n *html.Node) {
+		if isBoilerplate(n) && !contains(n, content) {
+			remove = append(remove, n)
+		}
+	})
+
+	for _, n := range remove {
+		if n.Parent != nil {
+			n.Parent.RemoveChild(n)
+		}
+	}
+}
+
+// isBoilerplate reports whether an element is navigation, a footer or similar
+func isBoilerplate(n *html.Node) bool {
+	switch n.DataAtom {
+	case atom.Html, atom.Body, atom.Main, atom.Article:
+		return false
+	case atom.Nav, atom.Footer, atom.Aside:
+		return true
+	case atom.Header:
+		// An article's own header holds its title
+		return !insideArticle(n)
+	}
+
+	role := attr(n, "role")
+	if role == "main" || role == "article" {
+		return false
+	}
+	if boilerplateRoles[role] {
+		return true
+	}
+
+	names := attr(n, "class") + " " + attr(n, "id")
+	return boilerplatePattern.MatchString(names) && !contentPattern.MatchString(names)
+}
+
+// inBoilerplate reports whether an element is boilerplate or nested in it
+func inBoilerplate(n *html.Node) bool {
+	for ; n != nil; n = n.Parent {
+		if n.Type == html.ElementNode && isBoilerplate(n) {
+			return true
+		}
+	}
+	return false
+}
+
+// contains reports whether descendant is n or nested in it
+func contains(n, descendant *html.Node) bool {
+	for ; descendant != nil; descendant = descendant.Parent {
+		if descendant == n {
+			return true
+		}
+	}
+	return false
+}
+
+// insideArticle reports whether an element is nested in an article or main element
+func insideArticle(n *html.Node) bool {
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.Type == html.ElementNode && (p.DataAtom == atom.Article || p.DataAtom == atom.Main) {
+			return true
+		}
+	}
+	return false
+}
+
+// findMain returns the first usable main landmark of the page
+func findMain(doc *html.Node, usable func(*html.Node) bool) *html.Node {
+	var main *html.Node
+	walkElements(doc, func(n *html.Node) {
+		if main == nil && (n.DataAtom == atom.Main || attr(n, "role") == "main") && usable(n) {
+			main = n
+		}
+	})
+	return main
+}
+
+// bestCandidate scores the parents of paragraphs by the text they hold and picks the best one
+// Paragraph text counts fully for its parent and half for its grandparent, links count against it.
+// Only usable paragraphs are scored.
+func bestCandidate(doc *html.Node, usable func(*html.Node) bool) *html.Node {
+	scores := make(map[*html.Node]float64)
+	var order []*html.Node
+
+	addScore := func(n *html.Node, score float64) {
+		if n == nil || n.Type != html.ElementNode {
+			return
+		}
+		if _, ok := scores[n]; !ok {
+			order = append(order, n)
+		}
+		scores[n] += score
+	}
+
+	walkElements(doc, func(n *html.Node) {
+		if n.DataAtom != atom.P && n.DataAtom != atom.Pre && n.DataAtom != atom.Td {
+			return
+		}
+		if !usable(n) {
+			return
+		}
+
+		text := strings.TrimSpace(textContent(n))
+		if len(text) < 25 {
+			return
+		}
+
+		score := 1 + float64(strings.Count(text, ",")) + min(float64(len(text))/100, 3)
+		addScore(n.Parent, score)
+		if n.Parent != nil {
+			addScore(n.Parent.Parent, score/2)
+		}
+	})
+
+	var best *html.Node
+	bestScore := 0.0
+	for _, n := range order {
+		score := scores[n] * (1 - linkDensity(n))
+		if score > bestScore {
+			best, bestScore = n, score
+		}
+	}
+	return best
+}
+
+// linkDensity is the share of an element's text that is link text
+func linkDensity(n *html.Node) float64 {
+	total := len(strings.TrimSpace(textContent(n)))
+	if total == 0 {
+		return 0
+	}
+
+	links := 0
+	walkElements(n, func(c *html.Node) {
+		if c.DataAtom == atom.A {
+			links += len(strings.TrimSpace(textContent(c)))
+		}
+	})
+	return float64(links) / float64(total)
+}
+
+// walkElements calls fn for every element below n in document order
+func walkElements(n *html.Node, fn func(*html.Node)) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode {
+			fn(c)
+		}
+		walkElements(c, fn)
+	}
+}
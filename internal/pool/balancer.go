@@ -3,6 +3,7 @@ package pool
 import (
 	"fmt"
 	"log/slog"
+	"slices"
 )
 
 //The load Balancer struct is responsible for balancing the load between the browser processes
@@ -40,6 +41,11 @@ func (lb *LoadBalancer) SelectProcess() (*ManagedProcess, error) {
 			continue
 		}
 
+		//Draining processes are about to be stopped and take no new sessions
+		if process.IsDraining() {
+			continue
+		}
+
 		//Then we check if the process has the least number of sessions
 		sessionCount := process.GetSessionCount()
 		if minSessions == -1 || sessionCount < minSessions {
@@ -48,6 +54,17 @@ func (lb *LoadBalancer) SelectProcess() (*ManagedProcess, error) {
 		}
 	}
 
+	//If every process is full, we try to add one within the scale-up wait
+	if selected == nil || selected.GetSessionCount() >= lb.pool.config.TargetSessionsPerProcess {
+		reason := "no healthy process in service"
+		if selected != nil {
+			reason = fmt.Sprintf("every process has at least %d sessions", lb.pool.config.TargetSessionsPerProcess)
+		}
+		if process := lb.pool.scaleUp(reason); process != nil {
+			selected = process
+		}
+	}
+
 	//If we didn't find any healthy process, we return an error
 	if selected == nil {
 		return nil, fmt.Errorf("no healthy processes in the pool")
@@ -72,6 +89,27 @@ func (lb *LoadBalancer) GetPort() (int, error) {
 	return process.GetPort(), nil
 }
 
+// ResumePort returns the port a stored session on the stored port is resumed on
+// The stored port is kept while its process takes new sessions, otherwise a process is selected
+// like for a new session.
+func (lb *LoadBalancer) ResumePort(stored int) (int, error) {
+	if slices.Contains(lb.HealthyPorts(), stored) {
+		return stored, nil
+	}
+	return lb.GetPort()
+}
+
+// HealthyPorts returns the ports of the healthy processes in the pool that take new sessions
+func (lb *LoadBalancer) HealthyPorts() []int {
+	ports := make([]int, 0)
+	for _, process := range lb.pool.GetProcesses() {
+		if process.IsHealthy() && !process.IsDraining() {
+			ports = append(ports, process.GetPort())
+		}
+	}
+	return ports
+}
+
 // GetProcesses returns all processes from the pool
 func (lb *LoadBalancer) GetProcesses() []*ManagedProcess {
 	return lb.pool.GetProcesses()
@@ -1,17 +1,37 @@
 package pool
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
 	"sync"
+	"sync/atomic"
 )
 
+// errProcessRemoved is returned when a process left the pool while it was being replaced
+var errProcessRemoved = errors.New("process is no longer in the pool")
+
 // ProcessPool manages a pool of browser processes
 type ProcessPool struct {
 	processes    []*ManagedProcess // Pool of browser processes
 	chromiumPath string            // Path to chromium binary
-	maxProcesses int               // Maximum number of processes
-	mu           sync.RWMutex      // Protects processes slice
+	config       ScalingConfig     // Pool size limits and when to grow or shrink
+	mu           sync.RWMutex      // Protects processes slice, spawning and closed
+
+	spawning *spawn // Process being started by a scale-up, nil when none is
+	closed   bool   // Shutdown was called, no process may be added
+
+	decisionsMu sync.Mutex        // Protects decisions and removed
+	decisions   []ScalingDecision // Most recent scaling decisions, oldest first
+	removed     []func(port int)  // Called for every process stopped by a scale-down
+
+	ctx    context.Context // Cancelled by Shutdown to stop the autoscaler
+	cancel context.CancelFunc
+
+	// Replaced in tests
+	startProcess func() (*ManagedProcess, error)
 }
 
 // PoolMetrics contains metrics about the entire pool
@@ -19,25 +39,31 @@ type PoolMetrics struct {
 	TotalProcesses int              `json:"total_processes"`
 	TotalSessions  int64            `json:"total_sessions"`
 	Processes      []ProcessMetrics `json:"processes"`
+	Scaling        ScalingMetrics   `json:"scaling"`
 }
 
-// NewProcessPool creates a new process pool
-func NewProcessPool(chromiumPath string, poolSize int) (*ProcessPool, error) {
-	// Validate pool size
-	if poolSize < 1 || poolSize > 10 {
-		return nil, fmt.Errorf("pool size must be between 1 and 10, got %d", poolSize)
+// NewProcessPool creates a new process pool with config.MinProcesses processes
+func NewProcessPool(chromiumPath string, config ScalingConfig) (*ProcessPool, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
 	}
 
 	// Create process pool
+	ctx, cancel := context.WithCancel(context.Background())
 	pool := &ProcessPool{
-		processes:    make([]*ManagedProcess, 0, poolSize),
+		processes:    make([]*ManagedProcess, 0, config.MaxProcesses),
 		chromiumPath: chromiumPath,
-		maxProcesses: poolSize,
+		config:       config,
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+	pool.startProcess = func() (*ManagedProcess, error) {
+		return NewManagedProcess(chromiumPath)
 	}
 
 	// Start managed processes
-	for i := 0; i < poolSize; i++ {
-		process, err := NewManagedProcess(chromiumPath)
+	for i := 0; i < config.MinProcesses; i++ {
+		process, err := pool.startProcess()
 		if err != nil {
 			// Cleanup on failure - stop all processes started so far
 			slog.Error("failed to start process, cleaning up", "index", i, "error", err)
@@ -48,7 +74,10 @@ func NewProcessPool(chromiumPath string, poolSize int) (*ProcessPool, error) {
 		slog.Info("started browser process", "index", i, "port", process.GetPort())
 	}
 
-	slog.Info("process pool initialized", "size", poolSize)
+	slog.Info("process pool initialized",
+		"size", config.MinProcesses,
+		"max_size", config.MaxProcesses,
+		"target_sessions_per_process", config.TargetSessionsPerProcess)
 	return pool, nil
 }
 
@@ -70,10 +99,52 @@ func (p *ProcessPool) GetProcessCount() int {
 	return len(p.processes)
 }
 
+// replaceProcess stops a failed process and puts the one returned by start in its place
+// The session count carries over, the sessions are moved onto the new process.
+func (p *ProcessPool) replaceProcess(old *ManagedProcess, start func() (*ManagedProcess, error)) (*ManagedProcess, error) {
+	p.mu.RLock()
+	inPool := slices.Contains(p.processes, old)
+	p.mu.RUnlock()
+	if !inPool {
+		return nil, errProcessRemoved
+	}
+
+	// Best effort, a crashed process only has its port and profile directory left to clean up
+	if err := old.Stop(); err != nil {
+		slog.Warn("failed to stop failed process", "port", old.GetPort(), "error", err)
+	}
+
+	process, err := start()
+	if err != nil {
+		return nil, err
+	}
+	atomic.StoreInt64(&process.sessionCount, old.GetSessionCount())
+
+	p.mu.Lock()
+	index := slices.Index(p.processes, old)
+	if index >= 0 {
+		p.processes[index] = process
+	}
+	p.mu.Unlock()
+
+	// The pool was shut down while the new process started
+	if index < 0 {
+		process.Stop()
+		return nil, errProcessRemoved
+	}
+
+	return process, nil
+}
+
 // Shutdown stops all processes in the pool (best effort)
 func (p *ProcessPool) Shutdown() error {
+	if p.cancel != nil {
+		p.cancel()
+	}
+
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	p.closed = true
 
 	var errors []error
 
@@ -119,5 +190,6 @@ func (p *ProcessPool) GetMetrics() PoolMetrics {
 		TotalProcesses: len(p.processes),
 		TotalSessions:  totalSessions,
 		Processes:      processMetrics,
+		Scaling:        p.scalingMetrics(),
 	}
 }
\ No newline at end of file
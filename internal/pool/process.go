@@ -12,7 +12,10 @@ type ManagedProcess struct {
 	Process      *browser.Process // The actual browser process
 	sessionCount int64            // Active session count
 	startedAt    time.Time        // When process was started
-	lastHealthy  time.Time        // Last successful health check
+	lastHealthy  atomic.Int64     // Last successful health check, in Unix nanoseconds
+	unresponsive atomic.Bool      // Set by the supervisor while the process fails its CDP health checks
+	draining     atomic.Bool      // Set by the autoscaler, no new sessions are placed on the process
+	idleSince    atomic.Int64     // When the session count last dropped to zero, in Unix nanoseconds
 }
 
 // ProcessMetrics contains metrics about a managed process
@@ -21,6 +24,7 @@ type ProcessMetrics struct {
 	SessionCount     int64         `json:"session_count"`
 	Uptime           time.Duration `json:"uptime"`
 	LastHealthyCheck time.Time     `json:"last_healthy_check"`
+	Draining         bool          `json:"draining"`
 }
 
 // NewManagedProcess creates a new managed process
@@ -39,12 +43,14 @@ func NewManagedProcess(chromiumPath string) (*ManagedProcess, error) {
 	// Wait for the browser process to be ready
 	time.Sleep(2 * time.Second)
 
-	return &ManagedProcess{
+	managed := &ManagedProcess{
 		Process:      process,
 		sessionCount: 0,
 		startedAt:    time.Now(),
-		lastHealthy:  time.Now(),
-	}, nil
+	}
+	managed.lastHealthy.Store(time.Now().UnixNano())
+	managed.idleSince.Store(time.Now().UnixNano())
+	return managed, nil
 }
 
 // GetSessionCount returns the current session count using atomic operations
@@ -59,7 +65,9 @@ func (mp *ManagedProcess) IncrementSessionCount() {
 
 // DecrementSessionCount decrements the session count using atomic operations
 func (mp *ManagedProcess) DecrementSessionCount() {
-	atomic.AddInt64(&mp.sessionCount, -1)
+	if atomic.AddInt64(&mp.sessionCount, -1) <= 0 {
+		mp.idleSince.Store(time.Now().UnixNano())
+	}
 }
 
 // GetPort returns the browser process port
@@ -67,13 +75,28 @@ func (mp *ManagedProcess) GetPort() int {
 	return mp.Process.DebugPort
 }
 
-// IsHealthy checks if the browser process is still alive
+// IsHealthy checks if the browser process is still alive and answering the supervisor's health checks
 func (mp *ManagedProcess) IsHealthy() bool {
-	if mp.Process.IsAlive() {
-		mp.lastHealthy = time.Now()
-		return true
+	return mp.Process.IsAlive() && !mp.unresponsive.Load()
+}
+
+// IsDraining reports whether the process is being drained before it is stopped
+func (mp *ManagedProcess) IsDraining() bool {
+	return mp.draining.Load()
+}
+
+// idleFor returns how long the process has had no sessions, zero while it has some
+func (mp *ManagedProcess) idleFor(now time.Time) time.Duration {
+	if mp.GetSessionCount() > 0 {
+		return 0
 	}
-	return false
+	return now.Sub(time.Unix(0, mp.idleSince.Load()))
+}
+
+// markHealthy records a successful health check
+func (mp *ManagedProcess) markHealthy() {
+	mp.unresponsive.Store(false)
+	mp.lastHealthy.Store(time.Now().UnixNano())
 }
 
 // Stop stops the browser process
@@ -87,6 +110,7 @@ func (mp *ManagedProcess) GetMetrics() ProcessMetrics {
 		Port:             mp.GetPort(),
 		SessionCount:     atomic.LoadInt64(&mp.sessionCount),
 		Uptime:           time.Since(mp.startedAt),
-		LastHealthyCheck: mp.lastHealthy,
+		LastHealthyCheck: time.Unix(0, mp.lastHealthy.Load()),
+		Draining:         mp.draining.Load(),
 	}
 }
\ No newline at end of file
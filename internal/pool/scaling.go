@@ -0,0 +1,303 @@
+package pool
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+const (
+	// DefaultScaleCheckInterval is how often idle processes are looked for when the config doesn't say
+	DefaultScaleCheckInterval = 15 * time.Second
+
+	// maxScalingDecisions is how many decisions are kept for the metrics, older ones are dropped
+	maxScalingDecisions = 50
+)
+
+// Scaling actions recorded in the decisions
+const (
+	ScaleSpawn       = "spawn"        // A process was started because every process was full
+	ScaleSpawnFailed = "spawn_failed" // Starting a process failed
+	ScaleUndrain     = "undrain"      // A draining process was put back into service instead of spawning one
+	ScaleDrain       = "drain"        // An idle process stopped taking new sessions
+	ScaleReap        = "reap"         // A drained process was stopped
+)
+
+// ScalingConfig sets how the pool grows and shrinks with the number of sessions
+type ScalingConfig struct {
+	MinProcesses             int           // Started at boot and always kept in service
+	MaxProcesses             int           // Upper bound the pool grows to
+	TargetSessionsPerProcess int64         // A process is spawned when every process has this many sessions
+	IdleCooldown             time.Duration // How long a process goes without sessions before it is drained and stopped
+	ScaleUpWait              time.Duration // How long session creation waits for a new process before using a full one
+	CheckInterval            time.Duration // How often idle processes are looked for, DefaultScaleCheckInterval when zero
+}
+
+// validate checks the limits and fills in defaults
+func (c *ScalingConfig) validate() error {
+	if c.MaxProcesses < 1 || c.MaxProcesses > 10 {
+		return fmt.Errorf("pool size must be between 1 and 10, got %d", c.MaxProcesses)
+	}
+	if c.MinProcesses < 1 || c.MinProcesses > c.MaxProcesses {
+		return fmt.Errorf("min pool size must be between 1 and %d, got %d", c.MaxProcesses, c.MinProcesses)
+	}
+	if c.TargetSessionsPerProcess < 1 {
+		return fmt.Errorf("target sessions per process must be at least 1, got %d", c.TargetSessionsPerProcess)
+	}
+	if c.IdleCooldown < 0 || c.ScaleUpWait < 0 {
+		return fmt.Errorf("idle cool-down and scale-up wait can't be negative")
+	}
+	if c.CheckInterval <= 0 {
+		c.CheckInterval = DefaultScaleCheckInterval
+	}
+	return nil
+}
+
+// ScalingDecision records a change the autoscaler made to the pool
+type ScalingDecision struct {
+	Time      time.Time `json:"time"`
+	Action    string    `json:"action"`
+	Port      int       `json:"port,omitempty"`
+	Reason    string    `json:"reason"`
+	Processes int       `json:"processes"` // Pool size after the decision
+}
+
+// ScalingMetrics describes the autoscaler's settings and recent decisions
+type ScalingMetrics struct {
+	MinProcesses             int               `json:"min_processes"`
+	MaxProcesses             int               `json:"max_processes"`
+	TargetSessionsPerProcess int64             `json:"target_sessions_per_process"`
+	Spawning                 bool              `json:"spawning"`
+	DrainingProcesses        int               `json:"draining_processes"`
+	Decisions                []ScalingDecision `json:"decisions"`
+}
+
+// spawn is a process being started by a scale-up, done is closed once it is ready or failed
+type spawn struct {
+	done    chan struct{}
+	process *ManagedProcess
+	err     error
+}
+
+// OnProcessRemoved registers a function called with the port of every process stopped by a scale-down
+func (p *ProcessPool) OnProcessRemoved(listener func(port int)) {
+	p.decisionsMu.Lock()
+	defer p.decisionsMu.Unlock()
+	p.removed = append(p.removed, listener)
+}
+
+// StartAutoscaler drains and stops idle processes every CheckInterval until Shutdown
+// inUse may be nil, otherwise a process is only considered idle when it returns false for its port.
+// It covers sessions the session counts miss.
+func (p *ProcessPool) StartAutoscaler(inUse func(port int) bool) {
+	go func() {
+		ticker := time.NewTicker(p.config.CheckInterval)
+		defer ticker.Stop()
+
+		slog.Info("pool autoscaler started",
+			"check_interval", p.config.CheckInterval,
+			"idle_cooldown", p.config.IdleCooldown)
+
+		for {
+			select {
+			case <-p.ctx.Done():
+				slog.Info("pool autoscaler stopping")
+				return
+
+			case <-ticker.C:
+				p.scaleDown(inUse)
+			}
+		}
+	}()
+}
+
+// scaleUp adds a process for a new session when every process is full
+// A draining process is put back into service first, otherwise one is spawned unless the pool is at
+// its maximum. Returns nil when no process became available within ScaleUpWait, the spawn then
+// carries on in the background.
+func (p *ProcessPool) scaleUp(reason string) *ManagedProcess {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+
+	// A draining process is ready at once
+	for _, process := range p.processes {
+		if process.IsDraining() && process.IsHealthy() {
+			process.draining.Store(false)
+			p.mu.Unlock()
+			p.recordDecision(ScaleUndrain, process.GetPort(), reason)
+			return process
+		}
+	}
+
+	// Concurrent requests share one spawn
+	pending := p.spawning
+	if pending == nil {
+		if len(p.processes) >= p.config.MaxProcesses {
+			p.mu.Unlock()
+			return nil
+		}
+		pending = &spawn{done: make(chan struct{})}
+		p.spawning = pending
+		go p.runSpawn(pending, reason)
+	}
+	p.mu.Unlock()
+
+	timer := time.NewTimer(p.config.ScaleUpWait)
+	defer timer.Stop()
+
+	select {
+	case <-pending.done:
+		if pending.err != nil {
+			return nil
+		}
+		return pending.process
+	case <-timer.C:
+		slog.Debug("scale-up still in progress, using a full process", "wait", p.config.ScaleUpWait)
+		return nil
+	}
+}
+
+// runSpawn starts a process and adds it to the pool
+func (p *ProcessPool) runSpawn(pending *spawn, reason string) {
+	process, err := p.startProcess()
+
+	p.mu.Lock()
+	p.spawning = nil
+	stale := err == nil && p.closed
+	if err == nil && !stale {
+		p.processes = append(p.processes, process)
+	}
+	p.mu.Unlock()
+
+	// The pool was shut down while the process started
+	if stale {
+		process.Stop()
+		err = errors.New("pool was shut down")
+	}
+
+	pending.process, pending.err = process, err
+	close(pending.done)
+
+	if err != nil {
+		p.recordDecision(ScaleSpawnFailed, 0, fmt.Sprintf("%s: %v", reason, err))
+		return
+	}
+	p.recordDecision(ScaleSpawn, process.GetPort(), reason)
+}
+
+// scaleDown stops drained processes and starts draining the ones idle for longer than the cool-down
+// Draining and stopping happen on separate passes, so a session placed on a process just before
+// it started draining is seen before the process is stopped.
+func (p *ProcessPool) scaleDown(inUse func(port int) bool) {
+	now := time.Now()
+	busy := func(process *ManagedProcess) bool {
+		return process.GetSessionCount() > 0 || (inUse != nil && inUse(process.GetPort()))
+	}
+
+	p.mu.Lock()
+
+	// Step 1: Take drained processes without sessions out of the pool
+	var reaped []*ManagedProcess
+	kept := make([]*ManagedProcess, 0, len(p.processes))
+	for _, process := range p.processes {
+		if process.IsDraining() && !busy(process) {
+			reaped = append(reaped, process)
+			continue
+		}
+		kept = append(kept, process)
+	}
+	p.processes = kept
+
+	// Step 2: Drain idle processes, keeping MinProcesses in service
+	inService := 0
+	for _, process := range p.processes {
+		if !process.IsDraining() {
+			inService++
+		}
+	}
+
+	var drained []*ManagedProcess
+	for _, process := range p.processes {
+		if inService <= p.config.MinProcesses {
+			break
+		}
+		if process.IsDraining() || busy(process) || process.idleFor(now) < p.config.IdleCooldown {
+			continue
+		}
+		process.draining.Store(true)
+		drained = append(drained, process)
+		inService--
+	}
+	p.mu.Unlock()
+
+	for _, process := range drained {
+		p.recordDecision(ScaleDrain, process.GetPort(),
+			fmt.Sprintf("no sessions for %s", process.idleFor(now).Round(time.Second)))
+	}
+
+	for _, process := range reaped {
+		if err := process.Stop(); err != nil {
+			slog.Warn("failed to stop drained process", "port", process.GetPort(), "error", err)
+		}
+		p.recordDecision(ScaleReap, process.GetPort(), "drained")
+
+		p.decisionsMu.Lock()
+		listeners := append([]func(port int){}, p.removed...)
+		p.decisionsMu.Unlock()
+		for _, listener := range listeners {
+			listener(process.GetPort())
+		}
+	}
+}
+
+// recordDecision logs a scaling decision and keeps it for the metrics
+func (p *ProcessPool) recordDecision(action string, port int, reason string) {
+	decision := ScalingDecision{
+		Time:      time.Now(),
+		Action:    action,
+		Port:      port,
+		Reason:    reason,
+		Processes: p.GetProcessCount(),
+	}
+
+	slog.Info("pool scaling decision",
+		"action", action,
+		"port", port,
+		"reason", reason,
+		"processes", decision.Processes)
+
+	p.decisionsMu.Lock()
+	defer p.decisionsMu.Unlock()
+
+	p.decisions = append(p.decisions, decision)
+	if len(p.decisions) > maxScalingDecisions {
+		p.decisions = p.decisions[len(p.decisions)-maxScalingDecisions:]
+	}
+}
+
+// scalingMetrics returns the autoscaler's settings and recent decisions, the caller must hold p.mu
+func (p *ProcessPool) scalingMetrics() ScalingMetrics {
+	draining := 0
+	for _, process := range p.processes {
+		if process.IsDraining() {
+			draining++
+		}
+	}
+
+	p.decisionsMu.Lock()
+	decisions := append([]ScalingDecision{}, p.decisions...)
+	p.decisionsMu.Unlock()
+
+	return ScalingMetrics{
+		MinProcesses:             p.config.MinProcesses,
+		MaxProcesses:             p.config.MaxProcesses,
+		TargetSessionsPerProcess: p.config.TargetSessionsPerProcess,
+		Spawning:                 p.spawning != nil,
+		DrainingProcesses:        draining,
+		Decisions:                decisions,
+	}
+}
@@ -0,0 +1,265 @@
+package pool
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testScalingConfig allows two processes with two sessions each and drains without a cool-down
+func testScalingConfig() ScalingConfig {
+	return ScalingConfig{
+		MinProcesses:             1,
+		MaxProcesses:             2,
+		TargetSessionsPerProcess: 2,
+		ScaleUpWait:              time.Second,
+		CheckInterval:            time.Hour,
+	}
+}
+
+// newTestPool returns a pool of the given processes that starts next when it scales up
+func newTestPool(config ScalingConfig, next func() (*ManagedProcess, error), processes ...*ManagedProcess) *ProcessPool {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &ProcessPool{
+		processes:    processes,
+		config:       config,
+		ctx:          ctx,
+		cancel:       cancel,
+		startProcess: next,
+	}
+}
+
+// withSessions sets the session count of a process
+func withSessions(process *ManagedProcess, count int) *ManagedProcess {
+	for range count {
+		process.IncrementSessionCount()
+	}
+	return process
+}
+
+// idleProcess returns a running process that just became idle
+func idleProcess(t *testing.T, port int) *ManagedProcess {
+	process := runningProcess(t, port)
+	process.idleSince.Store(time.Now().UnixNano())
+	return process
+}
+
+// hasDecision reports whether the pool recorded a decision with the action
+func hasDecision(pool *ProcessPool, action string) bool {
+	return slices.ContainsFunc(pool.GetMetrics().Scaling.Decisions, func(d ScalingDecision) bool {
+		return d.Action == action
+	})
+}
+
+// TestSelectProcessScalesUp tests that a process is spawned once every process reached the target
+func TestSelectProcessScalesUp(t *testing.T) {
+	spawned := runningProcess(t, 9301)
+	pool := newTestPool(testScalingConfig(), func() (*ManagedProcess, error) { return spawned, nil },
+		withSessions(runningProcess(t, 9300), 1))
+	balancer := NewLoadBalancer(pool)
+
+	// Below the target the existing process is used
+	process, err := balancer.SelectProcess()
+	if err != nil || process.GetPort() != 9300 {
+		t.Fatalf("expected port 9300, got %v, %v", process, err)
+	}
+	process.IncrementSessionCount()
+
+	process, err = balancer.SelectProcess()
+	if err != nil || process != spawned {
+		t.Fatalf("expected the spawned process, got %v, %v", process, err)
+	}
+	if count := pool.GetProcessCount(); count != 2 {
+		t.Errorf("expected 2 processes, got %d", count)
+	}
+	if !hasDecision(pool, ScaleSpawn) {
+		t.Error("expected a spawn decision in the metrics")
+	}
+
+	// At the maximum the least loaded process is used even when full
+	spawned.IncrementSessionCount()
+	spawned.IncrementSessionCount()
+	process, err = balancer.SelectProcess()
+	if err != nil || process == nil {
+		t.Fatalf("expected a full process at the maximum, got %v", err)
+	}
+	if count := pool.GetProcessCount(); count != 2 {
+		t.Errorf("expected the pool to stay at 2 processes, got %d", count)
+	}
+}
+
+// TestSelectProcessScaleUpWait tests that a slow spawn doesn't hold up the session past ScaleUpWait
+func TestSelectProcessScaleUpWait(t *testing.T) {
+	release := make(chan struct{})
+	spawned := runningProcess(t, 9301)
+	config := testScalingConfig()
+	config.ScaleUpWait = 10 * time.Millisecond
+
+	full := withSessions(runningProcess(t, 9300), 2)
+	pool := newTestPool(config, func() (*ManagedProcess, error) {
+		<-release
+		return spawned, nil
+	}, full)
+	balancer := NewLoadBalancer(pool)
+
+	started := time.Now()
+	process, err := balancer.SelectProcess()
+	if err != nil || process != full {
+		t.Fatalf("expected the full process while the spawn is slow, got %v, %v", process, err)
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("expected SelectProcess to give up after the wait, took %v", elapsed)
+	}
+	if !pool.GetMetrics().Scaling.Spawning {
+		t.Error("expected the spawn to be reported in progress")
+	}
+
+	// The spawn finishes in the background and joins the pool
+	close(release)
+	deadline := time.Now().Add(time.Second)
+	for pool.GetProcessCount() != 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if count := pool.GetProcessCount(); count != 2 {
+		t.Errorf("expected the spawned process to join the pool, got %d processes", count)
+	}
+}
+
+// TestSelectProcessSharesSpawn tests that concurrent sessions wait for the same spawn
+func TestSelectProcessSharesSpawn(t *testing.T) {
+	config := testScalingConfig()
+	config.MaxProcesses = 5
+
+	var mu sync.Mutex
+	starts := 0
+	spawned := runningProcess(t, 9301)
+	pool := newTestPool(config, func() (*ManagedProcess, error) {
+		mu.Lock()
+		starts++
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		return spawned, nil
+	}, withSessions(runningProcess(t, 9300), 2))
+	balancer := NewLoadBalancer(pool)
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := balancer.SelectProcess(); err != nil {
+				t.Errorf("SelectProcess failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if starts != 1 {
+		t.Errorf("expected one spawn, got %d", starts)
+	}
+}
+
+// TestScaleUpUndrains tests that a draining process is put back into service instead of spawning
+func TestScaleUpUndrains(t *testing.T) {
+	draining := runningProcess(t, 9301)
+	draining.draining.Store(true)
+	pool := newTestPool(testScalingConfig(), func() (*ManagedProcess, error) {
+		t.Error("expected no spawn while a draining process is available")
+		return nil, nil
+	}, withSessions(runningProcess(t, 9300), 2), draining)
+	balancer := NewLoadBalancer(pool)
+
+	process, err := balancer.SelectProcess()
+	if err != nil || process != draining {
+		t.Fatalf("expected the draining process, got %v, %v", process, err)
+	}
+	if draining.IsDraining() {
+		t.Error("expected the process to be back in service")
+	}
+	if !hasDecision(pool, ScaleUndrain) {
+		t.Error("expected an undrain decision in the metrics")
+	}
+}
+
+// TestScaleDown tests that idle processes are drained, then stopped, down to the minimum
+func TestScaleDown(t *testing.T) {
+	first := runningProcess(t, 9300)
+	second := runningProcess(t, 9301)
+	resumed := runningProcess(t, 9302)
+	busy := withSessions(runningProcess(t, 9303), 1)
+	pool := newTestPool(testScalingConfig(), nil, first, second, resumed, busy)
+
+	var removed []int
+	pool.OnProcessRemoved(func(port int) { removed = append(removed, port) })
+
+	// A session the counts miss keeps its process
+	inUse := func(port int) bool { return port == 9302 }
+
+	pool.scaleDown(inUse)
+	if !first.IsDraining() || !second.IsDraining() {
+		t.Fatal("expected the idle processes to be draining")
+	}
+	if resumed.IsDraining() || busy.IsDraining() {
+		t.Error("expected processes with sessions to stay in service")
+	}
+	if len(removed) != 0 {
+		t.Fatalf("expected nothing stopped on the draining pass, got %v", removed)
+	}
+
+	// A session placed just before draining keeps the process until it ends
+	second.IncrementSessionCount()
+	pool.scaleDown(inUse)
+	if !slices.Equal(removed, []int{9300}) {
+		t.Fatalf("expected port 9300 removed, got %v", removed)
+	}
+	if first.Process.IsAlive() {
+		t.Error("expected the drained process to be stopped")
+	}
+
+	second.DecrementSessionCount()
+	pool.scaleDown(inUse)
+	if !slices.Equal(removed, []int{9300, 9301}) {
+		t.Fatalf("expected port 9301 removed once idle, got %v", removed)
+	}
+	if count := pool.GetProcessCount(); count != 2 {
+		t.Errorf("expected 2 processes left, got %d", count)
+	}
+}
+
+// TestScaleDownKeepsMinimum tests that the cool-down and MinProcesses hold back draining
+func TestScaleDownKeepsMinimum(t *testing.T) {
+	config := testScalingConfig()
+	config.IdleCooldown = time.Hour
+	pool := newTestPool(config, nil, idleProcess(t, 9300), idleProcess(t, 9301))
+
+	pool.scaleDown(nil)
+	if draining := pool.GetMetrics().Scaling.DrainingProcesses; draining != 0 {
+		t.Errorf("expected processes idle for less than the cool-down to stay in service, got %d draining", draining)
+	}
+
+	pool.config.IdleCooldown = 0
+	pool.scaleDown(nil)
+	pool.scaleDown(nil)
+
+	processes := pool.GetProcesses()
+	if len(processes) != 1 || processes[0].IsDraining() {
+		t.Fatalf("expected one process kept in service, got %d", len(processes))
+	}
+}
+
+// TestResumePort tests that a resumed session keeps its process while it takes sessions and moves otherwise
+func TestResumePort(t *testing.T) {
+	draining := runningProcess(t, 9301)
+	draining.draining.Store(true)
+	pool := newTestPool(testScalingConfig(), nil, runningProcess(t, 9300), draining)
+	balancer := NewLoadBalancer(pool)
+
+	for stored, expected := range map[int]int{9300: 9300, 9301: 9300, 9302: 9300} {
+		port, err := balancer.ResumePort(stored)
+		if err != nil || port != expected {
+			t.Errorf("expected a session stored on %d to resume on %d, got %d, %v", stored, expected, port, err)
+		}
+	}
+}
@@ -0,0 +1,360 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dhruvsoni1802/browser-query-ai/internal/cdp"
+)
+
+// ProcessEventType tells what happened to a browser process
+type ProcessEventType string
+
+const (
+	ProcessDown      ProcessEventType = "down"      // The process died or stopped answering health checks
+	ProcessRestarted ProcessEventType = "restarted" // A replacement process took its place in the pool
+)
+
+// ProcessEvent is emitted by the supervisor when a process fails or is replaced
+type ProcessEvent struct {
+	Type    ProcessEventType
+	Port    int    // Port of the failed process
+	NewPort int    // Port of the replacement, set for ProcessRestarted
+	Reason  string // Why the process was considered down
+}
+
+// SupervisorConfig tunes health checks and restarts
+type SupervisorConfig struct {
+	Interval       time.Duration // Time between health checks of a process
+	ProbeTimeout   time.Duration // How long Browser.getVersion may take before a check fails
+	MaxFailures    int           // Failed checks in a row before a running process counts as hung
+	InitialBackoff time.Duration // Delay before retrying a failed restart, doubled on every failure
+	MaxBackoff     time.Duration // Upper bound for the delay between restarts
+	StableAfter    time.Duration // Uptime after which a process that fails is restarted without delay
+}
+
+// DefaultSupervisorConfig returns the settings used by the server
+func DefaultSupervisorConfig() SupervisorConfig {
+	return SupervisorConfig{
+		Interval:       5 * time.Second,
+		ProbeTimeout:   5 * time.Second,
+		MaxFailures:    3,
+		InitialBackoff: 1 * time.Second,
+		MaxBackoff:     1 * time.Minute,
+		StableAfter:    2 * time.Minute,
+	}
+}
+
+// nextBackoff doubles a restart delay, starting from InitialBackoff
+func (c SupervisorConfig) nextBackoff(delay time.Duration) time.Duration {
+	if delay <= 0 {
+		return c.InitialBackoff
+	}
+	return min(delay*2, c.MaxBackoff)
+}
+
+// processHealth is what the supervisor tracks about one process
+type processHealth struct {
+	client     *cdp.Client   // Connection used for health checks, nil until connected
+	failures   int           // Failed checks in a row
+	restarting bool          // A restart is in progress, checks are skipped
+	backoff    time.Duration // Delay before restarting, grows while replacements keep failing soon after starting
+}
+
+// Supervisor health-checks the processes of a pool and restarts the ones that die or hang
+type Supervisor struct {
+	pool   *ProcessPool
+	config SupervisorConfig
+
+	mu        sync.Mutex
+	health    map[*ManagedProcess]*processHealth
+	listeners []func(ProcessEvent)
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	// Replaced in tests
+	probe        func(ctx context.Context, process *ManagedProcess) error
+	startProcess func() (*ManagedProcess, error)
+}
+
+// NewSupervisor creates a supervisor for the pool, Start begins the health checks
+func NewSupervisor(pool *ProcessPool, config SupervisorConfig) *Supervisor {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s := &Supervisor{
+		pool:   pool,
+		config: config,
+		health: make(map[*ManagedProcess]*processHealth),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	s.probe = s.probeCDP
+	s.startProcess = pool.startProcess
+	if s.startProcess == nil {
+		s.startProcess = func() (*ManagedProcess, error) {
+			return NewManagedProcess(pool.chromiumPath)
+		}
+	}
+	return s
+}
+
+// OnEvent registers a function called for every process event
+// Listeners run on the supervisor's goroutines, a ProcessDown event always comes before the
+// ProcessRestarted event of the same process.
+func (s *Supervisor) OnEvent(listener func(ProcessEvent)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, listener)
+}
+
+// Start runs the health checks in the background until Stop is called
+func (s *Supervisor) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.config.Interval)
+		defer ticker.Stop()
+
+		slog.Info("process supervisor started", "check_interval", s.config.Interval)
+
+		for {
+			select {
+			case <-s.ctx.Done():
+				slog.Info("process supervisor stopping")
+				return
+
+			case <-ticker.C:
+				s.checkAll()
+			}
+		}
+	}()
+}
+
+// Stop ends the health checks and waits for restarts in progress
+func (s *Supervisor) Stop() {
+	s.cancel()
+	s.wg.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for process, health := range s.health {
+		if health.client != nil {
+			health.client.Close()
+		}
+		delete(s.health, process)
+	}
+}
+
+// checkAll checks every process of the pool once
+func (s *Supervisor) checkAll() {
+	processes := s.pool.GetProcesses()
+	for _, process := range processes {
+		if s.ctx.Err() != nil {
+			return
+		}
+		s.check(process)
+	}
+
+	// Processes stopped by a scale-down keep no health check connection open
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for process, health := range s.health {
+		if health.restarting || slices.Contains(processes, process) {
+			continue
+		}
+		if health.client != nil {
+			health.client.Close()
+		}
+		delete(s.health, process)
+	}
+}
+
+// check health-checks a process and starts a restart when it is dead or hung
+func (s *Supervisor) check(process *ManagedProcess) {
+	s.mu.Lock()
+	health, exists := s.health[process]
+	if !exists {
+		health = &processHealth{}
+		s.health[process] = health
+	}
+	if health.restarting {
+		s.mu.Unlock()
+		return
+	}
+	s.mu.Unlock()
+
+	reason := ""
+	if !process.Process.IsAlive() {
+		reason = "process exited"
+	} else {
+		ctx, cancel := context.WithTimeout(s.ctx, s.config.ProbeTimeout)
+		err := s.probe(ctx, process)
+		cancel()
+
+		if err == nil {
+			process.markHealthy()
+			s.mu.Lock()
+			health.failures = 0
+			s.mu.Unlock()
+			return
+		}
+
+		// Keep new sessions away while the process doesn't answer
+		process.unresponsive.Store(true)
+
+		s.mu.Lock()
+		health.failures++
+		failures := health.failures
+		s.mu.Unlock()
+
+		slog.Warn("browser health check failed", "port", process.GetPort(), "failures", failures, "error", err)
+		if failures < s.config.MaxFailures {
+			return
+		}
+		reason = fmt.Sprintf("no answer to %d health checks: %v", failures, err)
+	}
+
+	s.mu.Lock()
+	health.restarting = true
+	s.mu.Unlock()
+	process.unresponsive.Store(true)
+
+	slog.Error("browser process down, restarting", "port", process.GetPort(), "reason", reason)
+	s.emit(ProcessEvent{Type: ProcessDown, Port: process.GetPort(), Reason: reason})
+
+	s.wg.Add(1)
+	go s.restart(process, health)
+}
+
+// restart replaces a failed process, retrying with backoff until it succeeds or the supervisor stops
+func (s *Supervisor) restart(old *ManagedProcess, health *processHealth) {
+	defer s.wg.Done()
+
+	// A process that fails soon after it was started waits longer every time
+	delay := time.Duration(0)
+	if time.Since(old.startedAt) < s.config.StableAfter {
+		delay = health.backoff
+	}
+
+	var replacement *ManagedProcess
+	for attempt := 1; ; attempt++ {
+		if delay > 0 {
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+		}
+
+		var err error
+		replacement, err = s.pool.replaceProcess(old, s.startProcess)
+		if err == nil {
+			break
+		}
+		if errors.Is(err, errProcessRemoved) {
+			s.forget(old)
+			return
+		}
+
+		delay = s.config.nextBackoff(delay)
+		slog.Warn("browser restart failed", "port", old.GetPort(), "attempt", attempt, "retry_in", delay, "error", err)
+	}
+
+	s.forget(old)
+	s.mu.Lock()
+	s.health[replacement] = &processHealth{backoff: s.config.nextBackoff(delay)}
+	s.mu.Unlock()
+
+	slog.Info("browser process restarted", "old_port", old.GetPort(), "new_port", replacement.GetPort())
+	s.emit(ProcessEvent{Type: ProcessRestarted, Port: old.GetPort(), NewPort: replacement.GetPort()})
+}
+
+// forget drops what the supervisor tracked about a process that left the pool
+func (s *Supervisor) forget(process *ManagedProcess) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if health, exists := s.health[process]; exists {
+		if health.client != nil {
+			health.client.Close()
+		}
+		delete(s.health, process)
+	}
+}
+
+// emit calls every listener with the event
+func (s *Supervisor) emit(event ProcessEvent) {
+	s.mu.Lock()
+	listeners := make([]func(ProcessEvent), len(s.listeners))
+	copy(listeners, s.listeners)
+	s.mu.Unlock()
+
+	for _, listener := range listeners {
+		listener(event)
+	}
+}
+
+// probeCDP sends Browser.getVersion, which goes through the browser's main thread and fails when it hangs
+func (s *Supervisor) probeCDP(ctx context.Context, process *ManagedProcess) error {
+	client, err := s.healthClient(ctx, process)
+	if err != nil {
+		return err
+	}
+
+	if _, err := client.SendCommandContext(ctx, "Browser.getVersion", nil); err != nil {
+		return fmt.Errorf("Browser.getVersion failed: %w", err)
+	}
+	return nil
+}
+
+// healthClient returns the health check connection of a process, connecting it the first time
+// Discovery has no timeout of its own, so a hung browser is given up on when ctx ends.
+func (s *Supervisor) healthClient(ctx context.Context, process *ManagedProcess) (*cdp.Client, error) {
+	s.mu.Lock()
+	health := s.health[process]
+	if health != nil && health.client != nil {
+		client := health.client
+		s.mu.Unlock()
+		return client, nil
+	}
+	s.mu.Unlock()
+
+	client := cdp.NewClientForPort("localhost", strconv.Itoa(process.GetPort()))
+	connected := make(chan error, 1)
+	go func() {
+		connected <- client.Connect()
+	}()
+
+	select {
+	case err := <-connected:
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect for health check: %w", err)
+		}
+	case <-ctx.Done():
+		// Close the client once the connect attempt gives up or succeeds
+		go func() {
+			<-connected
+			client.Close()
+		}()
+		return nil, fmt.Errorf("failed to connect for health check: %w", ctx.Err())
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if health == nil || s.health[process] != health {
+		// The process was forgotten while connecting
+		client.Close()
+		return nil, errProcessRemoved
+	}
+	health.client = client
+	return client, nil
+}
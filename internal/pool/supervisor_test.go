@@ -0,0 +1,184 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dhruvsoni1802/browser-query-ai/internal/browser"
+)
+
+// testSupervisorConfig checks quickly and barely waits between restarts
+func testSupervisorConfig() SupervisorConfig {
+	return SupervisorConfig{
+		Interval:       10 * time.Millisecond,
+		ProbeTimeout:   10 * time.Millisecond,
+		MaxFailures:    2,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     4 * time.Millisecond,
+		StableAfter:    time.Minute,
+	}
+}
+
+// fakeProcess returns a managed process that was never started, so it counts as dead
+func fakeProcess(port int) *ManagedProcess {
+	return &ManagedProcess{Process: &browser.Process{DebugPort: port}, startedAt: time.Now()}
+}
+
+// runningProcess starts a stand-in for chromium that ignores its flags and sleeps
+func runningProcess(t *testing.T, port int) *ManagedProcess {
+	t.Helper()
+
+	script := filepath.Join(t.TempDir(), "chromium")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\nexec sleep 60\n"), 0755); err != nil {
+		t.Fatalf("failed to write script: %v", err)
+	}
+
+	process := &browser.Process{BinaryPath: script, DebugPort: port, UserDataDir: t.TempDir()}
+	if err := process.Start(); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+	t.Cleanup(func() { process.Stop() })
+
+	return &ManagedProcess{Process: process, startedAt: time.Now()}
+}
+
+// eventRecorder collects the events a supervisor emits
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []ProcessEvent
+}
+
+func (r *eventRecorder) record(event ProcessEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *eventRecorder) list() []ProcessEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]ProcessEvent(nil), r.events...)
+}
+
+// TestSupervisorRestartsDeadProcess tests that a dead process is replaced and its sessions carried over
+func TestSupervisorRestartsDeadProcess(t *testing.T) {
+	dead := fakeProcess(9300)
+	dead.IncrementSessionCount()
+	dead.IncrementSessionCount()
+	pool := &ProcessPool{processes: []*ManagedProcess{dead}}
+
+	supervisor := NewSupervisor(pool, testSupervisorConfig())
+	supervisor.startProcess = func() (*ManagedProcess, error) { return fakeProcess(9301), nil }
+	recorder := &eventRecorder{}
+	supervisor.OnEvent(recorder.record)
+
+	supervisor.check(dead)
+	supervisor.wg.Wait()
+
+	events := recorder.list()
+	if len(events) != 2 || events[0].Type != ProcessDown || events[1].Type != ProcessRestarted {
+		t.Fatalf("expected a down and a restarted event, got %+v", events)
+	}
+	if events[0].Port != 9300 || events[1].Port != 9300 || events[1].NewPort != 9301 {
+		t.Errorf("expected the restart to move port 9300 to 9301, got %+v", events)
+	}
+
+	processes := pool.GetProcesses()
+	if len(processes) != 1 || processes[0].GetPort() != 9301 {
+		t.Fatalf("expected the replacement in the pool, got %d processes", len(processes))
+	}
+	if count := processes[0].GetSessionCount(); count != 2 {
+		t.Errorf("expected the session count to carry over, got %d", count)
+	}
+}
+
+// TestSupervisorRestartsHungProcess tests that a running process is restarted only after MaxFailures failed checks
+func TestSupervisorRestartsHungProcess(t *testing.T) {
+	hung := runningProcess(t, 9300)
+	pool := &ProcessPool{processes: []*ManagedProcess{hung}}
+
+	supervisor := NewSupervisor(pool, testSupervisorConfig())
+	supervisor.probe = func(ctx context.Context, process *ManagedProcess) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	supervisor.startProcess = func() (*ManagedProcess, error) { return fakeProcess(9301), nil }
+	recorder := &eventRecorder{}
+	supervisor.OnEvent(recorder.record)
+
+	// The first failure only takes the process out of balancing
+	supervisor.check(hung)
+	if len(recorder.list()) != 0 {
+		t.Fatalf("expected no restart after one failed check, got %+v", recorder.list())
+	}
+	if hung.IsHealthy() {
+		t.Error("expected a process failing its checks to be unhealthy")
+	}
+
+	supervisor.check(hung)
+	supervisor.wg.Wait()
+
+	events := recorder.list()
+	if len(events) != 2 || events[0].Type != ProcessDown || events[1].Type != ProcessRestarted {
+		t.Fatalf("expected a down and a restarted event, got %+v", events)
+	}
+	if hung.Process.IsAlive() {
+		t.Error("expected the hung process to be stopped")
+	}
+}
+
+// TestSupervisorRestartBackoff tests that failed restarts are retried with growing delays
+func TestSupervisorRestartBackoff(t *testing.T) {
+	dead := fakeProcess(9300)
+	pool := &ProcessPool{processes: []*ManagedProcess{dead}}
+
+	supervisor := NewSupervisor(pool, testSupervisorConfig())
+	attempts := 0
+	supervisor.startProcess = func() (*ManagedProcess, error) {
+		attempts++
+		if attempts < 3 {
+			return nil, errors.New("failed to start")
+		}
+		return fakeProcess(9301), nil
+	}
+	recorder := &eventRecorder{}
+	supervisor.OnEvent(recorder.record)
+
+	supervisor.check(dead)
+	supervisor.wg.Wait()
+
+	if attempts != 3 {
+		t.Errorf("expected 3 start attempts, got %d", attempts)
+	}
+	if events := recorder.list(); len(events) != 2 || events[1].Type != ProcessRestarted {
+		t.Fatalf("expected the restart to succeed eventually, got %+v", events)
+	}
+
+	// The replacement failed within StableAfter, so its own restart would wait
+	replacement := pool.GetProcesses()[0]
+	supervisor.mu.Lock()
+	backoff := supervisor.health[replacement].backoff
+	supervisor.mu.Unlock()
+	if backoff != 4*time.Millisecond {
+		t.Errorf("expected the backoff to carry over doubled, got %v", backoff)
+	}
+}
+
+// TestNextBackoff tests that the restart delay doubles up to the maximum
+func TestNextBackoff(t *testing.T) {
+	config := testSupervisorConfig()
+
+	delay := time.Duration(0)
+	expected := []time.Duration{time.Millisecond, 2 * time.Millisecond, 4 * time.Millisecond, 4 * time.Millisecond}
+	for i, want := range expected {
+		delay = config.nextBackoff(delay)
+		if delay != want {
+			t.Errorf("step %d: expected %v, got %v", i, want, delay)
+		}
+	}
+}
@@ -1,35 +1,42 @@
 package session
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 )
 
 // AXNode represents a node in the accessibility tree
 type AXNode struct {
-	Role      string    `json:"role"`
-	Name      string    `json:"name,omitempty"`
-	Level     int       `json:"level,omitempty"`
-	Value     string    `json:"value,omitempty"`
-	Focusable bool      `json:"focusable,omitempty"`
-	Children  []*AXNode `json:"children"`
+	Role          string    `json:"role"`
+	Ref           string    `json:"ref,omitempty"` // Interactive nodes only, usable as an action target until the page navigates
+	Name          string    `json:"name,omitempty"`
+	Level         int       `json:"level,omitempty"`
+	Value         string    `json:"value,omitempty"`
+	Focusable     bool      `json:"focusable,omitempty"`
+	BackendNodeID int       `json:"backend_node_id,omitempty"` // DOM node, usable as an action target
+	Children      []*AXNode `json:"children"`
 }
 
 // AccessibilityTree represents the full accessibility tree for a page
 type AccessibilityTree struct {
-	PageID string    `json:"page_id"`
-	Nodes  []*AXNode `json:"nodes"`
+	PageID    string    `json:"page_id"`
+	Nodes     []*AXNode `json:"nodes,omitempty"`
+	Text      string    `json:"text,omitempty"` // Set instead of Nodes for the text format
+	NodeCount int       `json:"node_count"`
+	CharCount int       `json:"char_count"` // Approximate size of the serialized tree
 }
 
 // cdpAXNode represents a raw CDP accessibility node from Accessibility.getFullAXTree
 type cdpAXNode struct {
-	NodeID     string        `json:"nodeId"`
-	Role       cdpAXValue    `json:"role"`
-	Name       *cdpAXValue   `json:"name,omitempty"`
-	Value      *cdpAXValue   `json:"value,omitempty"`
-	Properties []cdpAXProp   `json:"properties,omitempty"`
-	ChildIDs   []string      `json:"childIds,omitempty"`
-	Ignored    bool          `json:"ignored"`
+	NodeID           string      `json:"nodeId"`
+	Role             cdpAXValue  `json:"role"`
+	Name             *cdpAXValue `json:"name,omitempty"`
+	Value            *cdpAXValue `json:"value,omitempty"`
+	Properties       []cdpAXProp `json:"properties,omitempty"`
+	ChildIDs         []string    `json:"childIds,omitempty"`
+	BackendDOMNodeID int         `json:"backendDOMNodeId,omitempty"`
+	Ignored          bool        `json:"ignored"`
 }
 
 // cdpAXValue represents a CDP accessibility value
@@ -45,9 +52,20 @@ type cdpAXProp struct {
 }
 
 // GetAccessibilityTree retrieves the accessibility tree for a page using CDP
-func (s *Session) GetAccessibilityTree(targetID string) (*AccessibilityTree, error) {
+// The options trim and serialize the tree, the zero value returns the whole tree as JSON.
+func (s *Session) GetAccessibilityTree(ctx context.Context, targetID string, opts AXTreeOptions) (*AccessibilityTree, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	// Refs are tied to the document, look it up before the tree so a navigation in between makes them stale
+	loaderID, err := s.documentLoaderID(ctx, targetID)
+	if err != nil {
+		return nil, err
+	}
+
 	// Call CDP Accessibility.getFullAXTree
-	result, err := s.CDPClient.SendCommandToTarget(targetID, "Accessibility.getFullAXTree", nil)
+	result, err := s.CDPClient.SendCommandToTargetContext(ctx, targetID, "Accessibility.getFullAXTree", nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get accessibility tree: %w", err)
 	}
@@ -92,17 +110,26 @@ func (s *Session) GetAccessibilityTree(targetID string) (*AccessibilityTree, err
 		}
 	}
 
-	return &AccessibilityTree{
+	// Give interactive nodes refs that actions can target
+	s.assignElementRefs(targetID, loaderID, roots)
+
+	tree := &AccessibilityTree{
 		PageID: targetID,
 		Nodes:  roots,
-	}, nil
+	}
+	if err := s.shapeAXTree(ctx, tree, opts); err != nil {
+		return nil, err
+	}
+
+	return tree, nil
 }
 
 // buildAXTree recursively converts a CDP AX node into our clean AXNode format
 func buildAXTree(cdpNode *cdpAXNode, nodeMap map[string]*cdpAXNode) *AXNode {
 	node := &AXNode{
-		Role:     stringValue(cdpNode.Role),
-		Children: make([]*AXNode, 0),
+		Role:          stringValue(cdpNode.Role),
+		BackendNodeID: cdpNode.BackendDOMNodeID,
+		Children:      make([]*AXNode, 0),
 	}
 
 	// Extract name
@@ -0,0 +1,259 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Accessibility tree output formats
+const (
+	AXFormatJSON = "json"
+	AXFormatText = "text"
+)
+
+// AXTreeOptions trims the accessibility tree to fit an LLM context budget
+type AXTreeOptions struct {
+	InteractiveOnly bool   `json:"interactive_only,omitempty"` // Keep only nodes that have a ref
+	MaxDepth        int    `json:"max_depth,omitempty"`        // Levels below the roots to keep, 0 is unlimited
+	RootSelector    string `json:"root_selector,omitempty"`    // Only the subtree of the element matching this CSS selector
+	RootRef         string `json:"root_ref,omitempty"`         // Only the subtree of this ref
+	Compact         bool   `json:"compact,omitempty"`          // Drop nameless generic/none nodes, text boxes and text repeating its parent
+	Format          string `json:"format,omitempty"`           // json (default) or text
+}
+
+// prunableRoles are structural roles that carry no meaning without a name
+var prunableRoles = map[string]bool{
+	"generic":      true,
+	"none":         true,
+	"presentation": true,
+}
+
+// validate checks that the options are well formed
+func (o *AXTreeOptions) validate() error {
+	switch o.Format {
+	case "", AXFormatJSON, AXFormatText:
+	default:
+		return fmt.Errorf("%w: unknown format %q", ErrInvalidTreeOptions, o.Format)
+	}
+
+	if o.MaxDepth < 0 {
+		return fmt.Errorf("%w: max_depth must not be negative", ErrInvalidTreeOptions)
+	}
+
+	if o.RootSelector != "" && o.RootRef != "" {
+		return fmt.Errorf("%w: set root_selector or root_ref, not both", ErrInvalidTreeOptions)
+	}
+
+	return nil
+}
+
+// shapeAXTree applies the options to a tree and fills in its serialization and counts
+func (s *Session) shapeAXTree(ctx context.Context, tree *AccessibilityTree, opts AXTreeOptions) error {
+	nodes := tree.Nodes
+
+	// Cut down to the requested subtree first, refs are assigned on the full tree
+	if opts.RootSelector != "" || opts.RootRef != "" {
+		root, err := s.findAXRoot(ctx, tree.PageID, nodes, opts)
+		if err != nil {
+			return err
+		}
+		nodes = []*AXNode{root}
+	}
+
+	if opts.Compact {
+		nodes = compactAXNodes(nodes, "")
+	}
+	if opts.InteractiveOnly {
+		nodes = interactiveAXNodes(nodes)
+	}
+	if opts.MaxDepth > 0 {
+		nodes = limitAXDepth(nodes, opts.MaxDepth)
+	}
+
+	tree.Nodes = nodes
+	tree.NodeCount = countAXNodes(nodes)
+
+	if opts.Format == AXFormatText {
+		tree.Text = FormatAXTree(nodes)
+		tree.Nodes = nil
+		tree.CharCount = len(tree.Text)
+		return nil
+	}
+
+	encoded, err := json.Marshal(nodes)
+	if err != nil {
+		return fmt.Errorf("failed to encode accessibility tree: %w", err)
+	}
+	tree.CharCount = len(encoded)
+
+	return nil
+}
+
+// findAXRoot finds the node the subtree should start at
+func (s *Session) findAXRoot(ctx context.Context, targetID string, nodes []*AXNode, opts AXTreeOptions) (*AXNode, error) {
+	var backendNodeID int
+	var description string
+
+	if opts.RootRef != "" {
+		var err error
+		if backendNodeID, err = s.resolveElementRef(ctx, targetID, opts.RootRef); err != nil {
+			return nil, err
+		}
+		description = opts.RootRef
+	} else {
+		selector, _ := json.Marshal(opts.RootSelector)
+		description = "selector " + opts.RootSelector
+
+		objectID, err := s.evaluateElement(ctx, targetID, "document.querySelector("+string(selector)+")", description)
+		if err != nil {
+			return nil, err
+		}
+		defer s.releaseObject(targetID, objectID)
+
+		if backendNodeID, err = s.describeBackendNode(ctx, targetID, objectID); err != nil {
+			return nil, err
+		}
+	}
+
+	if node := findAXNode(nodes, backendNodeID); node != nil {
+		return node, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s has no accessibility node, try an ancestor", ErrElementNotFound, description)
+}
+
+// describeBackendNode returns the backend node ID of a remote object
+func (s *Session) describeBackendNode(ctx context.Context, targetID string, objectID string) (int, error) {
+	params := map[string]interface{}{
+		"objectId": objectID,
+	}
+
+	result, err := s.CDPClient.SendCommandToTargetContext(ctx, targetID, "DOM.describeNode", params)
+	if err != nil {
+		return 0, fmt.Errorf("failed to describe node: %w", err)
+	}
+
+	var response struct {
+		Node struct {
+			BackendNodeID int `json:"backendNodeId"`
+		} `json:"node"`
+	}
+	if err := json.Unmarshal(result, &response); err != nil {
+		return 0, fmt.Errorf("failed to parse describe node response: %w", err)
+	}
+
+	return response.Node.BackendNodeID, nil
+}
+
+// findAXNode searches the tree for the node of a backend DOM node
+func findAXNode(nodes []*AXNode, backendNodeID int) *AXNode {
+	for _, node := range nodes {
+		if node.BackendNodeID == backendNodeID {
+			return node
+		}
+		if found := findAXNode(node.Children, backendNodeID); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// compactAXNodes drops nodes that add no information, keeping their children in their place
+func compactAXNodes(nodes []*AXNode, parentName string) []*AXNode {
+	kept := make([]*AXNode, 0, len(nodes))
+	for _, node := range nodes {
+		children := compactAXNodes(node.Children, node.Name)
+
+		prunable := node.Ref == "" && ((prunableRoles[node.Role] && node.Name == "") ||
+			node.Role == "InlineTextBox" ||
+			(node.Role == "StaticText" && node.Name == parentName))
+		if prunable {
+			kept = append(kept, children...)
+			continue
+		}
+
+		copied := *node
+		copied.Children = children
+		kept = append(kept, &copied)
+	}
+	return kept
+}
+
+// interactiveAXNodes keeps only interactive nodes, nested under their closest interactive ancestor
+func interactiveAXNodes(nodes []*AXNode) []*AXNode {
+	kept := make([]*AXNode, 0)
+	for _, node := range nodes {
+		children := interactiveAXNodes(node.Children)
+		if node.Ref == "" {
+			kept = append(kept, children...)
+			continue
+		}
+
+		copied := *node
+		copied.Children = children
+		kept = append(kept, &copied)
+	}
+	return kept
+}
+
+// limitAXDepth cuts the tree below depth levels (1 keeps only the roots)
+func limitAXDepth(nodes []*AXNode, depth int) []*AXNode {
+	kept := make([]*AXNode, len(nodes))
+	for i, node := range nodes {
+		copied := *node
+		if depth <= 1 {
+			copied.Children = make([]*AXNode, 0)
+		} else {
+			copied.Children = limitAXDepth(node.Children, depth-1)
+		}
+		kept[i] = &copied
+	}
+	return kept
+}
+
+// countAXNodes counts the nodes in the tree
+func countAXNodes(nodes []*AXNode) int {
+	count := len(nodes)
+	for _, node := range nodes {
+		count += countAXNodes(node.Children)
+	}
+	return count
+}
+
+// FormatAXTree serializes the tree as indented YAML-like text, one node per line:
+//
+//	- heading "Example Domain" [level=1]
+//	- link "More information..." [ref=e1]
+func FormatAXTree(nodes []*AXNode) string {
+	var b strings.Builder
+	writeAXNodes(&b, nodes, 0)
+	return b.String()
+}
+
+func writeAXNodes(b *strings.Builder, nodes []*AXNode, depth int) {
+	for _, node := range nodes {
+		b.WriteString(strings.Repeat("  ", depth))
+		b.WriteString("- ")
+		b.WriteString(node.Role)
+		if node.Name != "" {
+			b.WriteString(" ")
+			b.WriteString(strconv.Quote(node.Name))
+		}
+		if node.Ref != "" {
+			b.WriteString(" [ref=" + node.Ref + "]")
+		}
+		if node.Level > 0 {
+			b.WriteString(" [level=" + strconv.Itoa(node.Level) + "]")
+		}
+		if node.Value != "" {
+			b.WriteString(": ")
+			b.WriteString(strconv.Quote(node.Value))
+		}
+		b.WriteString("\n")
+
+		writeAXNodes(b, node.Children, depth+1)
+	}
+}
@@ -0,0 +1,91 @@
+package session
+
+import (
+	"errors"
+	"testing"
+)
+
+// sampleAXTree returns a small tree shaped like Chrome's output
+func sampleAXTree() []*AXNode {
+	return []*AXNode{{
+		Role: "RootWebArea",
+		Name: "Shop",
+		Children: []*AXNode{
+			{Role: "generic", Children: []*AXNode{
+				{Role: "heading", Name: "Products", Level: 1, Children: []*AXNode{
+					{Role: "StaticText", Name: "Products", Children: []*AXNode{
+						{Role: "InlineTextBox", Name: "Products", Children: []*AXNode{}},
+					}},
+				}},
+				{Role: "link", Name: "Cart", Ref: "e1", Children: []*AXNode{
+					{Role: "StaticText", Name: "Cart", Children: []*AXNode{}},
+				}},
+			}},
+			{Role: "textbox", Name: "Search", Ref: "e2", Value: "shoes", Children: []*AXNode{}},
+		},
+	}}
+}
+
+// TestCompactAXNodes tests pruning of nodes that add no information
+func TestCompactAXNodes(t *testing.T) {
+	original := sampleAXTree()
+	nodes := compactAXNodes(original, "")
+
+	expected := "- RootWebArea \"Shop\"\n" +
+		"  - heading \"Products\" [level=1]\n" +
+		"  - link \"Cart\" [ref=e1]\n" +
+		"  - textbox \"Search\" [ref=e2]: \"shoes\"\n"
+	if got := FormatAXTree(nodes); got != expected {
+		t.Errorf("unexpected compact tree:\n%s\nexpected:\n%s", got, expected)
+	}
+	if count := countAXNodes(nodes); count != 4 {
+		t.Errorf("expected 4 nodes, got %d", count)
+	}
+
+	// The input tree is left alone
+	if countAXNodes(original) != 8 {
+		t.Error("expected compaction not to modify the original tree")
+	}
+}
+
+// TestInteractiveAXNodes tests keeping only nodes with refs
+func TestInteractiveAXNodes(t *testing.T) {
+	nodes := interactiveAXNodes(sampleAXTree())
+
+	expected := "- link \"Cart\" [ref=e1]\n" +
+		"- textbox \"Search\" [ref=e2]: \"shoes\"\n"
+	if got := FormatAXTree(nodes); got != expected {
+		t.Errorf("unexpected interactive tree:\n%s\nexpected:\n%s", got, expected)
+	}
+}
+
+// TestLimitAXDepth tests cutting the tree at a depth
+func TestLimitAXDepth(t *testing.T) {
+	nodes := limitAXDepth(sampleAXTree(), 2)
+
+	if count := countAXNodes(nodes); count != 3 {
+		t.Errorf("expected 3 nodes within depth 2, got %d", count)
+	}
+	if len(nodes[0].Children[0].Children) != 0 {
+		t.Error("expected nodes below depth 2 to be cut")
+	}
+}
+
+// TestAXTreeOptionsValidate tests that conflicting options are rejected
+func TestAXTreeOptionsValidate(t *testing.T) {
+	invalid := []AXTreeOptions{
+		{Format: "yaml"},
+		{MaxDepth: -1},
+		{RootSelector: "main", RootRef: "e1"},
+	}
+	for _, opts := range invalid {
+		if err := opts.validate(); !errors.Is(err, ErrInvalidTreeOptions) {
+			t.Errorf("expected %+v to be invalid, got %v", opts, err)
+		}
+	}
+
+	valid := AXTreeOptions{InteractiveOnly: true, MaxDepth: 3, RootRef: "e1", Compact: true, Format: AXFormatText}
+	if err := valid.validate(); err != nil {
+		t.Errorf("expected %+v to be valid, got %v", valid, err)
+	}
+}
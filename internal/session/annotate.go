@@ -0,0 +1,235 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// Mark is a numbered element outlined on an annotated screenshot
+type Mark struct {
+	ID   int      `json:"id"`  // Number drawn on the screenshot
+	Ref  string   `json:"ref"` // Accessibility tree ref, usable as an action target
+	Role string   `json:"role"`
+	Name string   `json:"name,omitempty"`
+	Box  ClipRect `json:"box"` // In screenshot image pixels, clipped to the screenshot
+}
+
+// markOverlayID is the id of the element holding the overlay, removed again after the capture
+const markOverlayID = "__browser_query_marks"
+
+// markOverlayJS draws an outline and a numbered label for every mark
+// Boxes are in viewport coordinates, the overlay lives in a shadow root so page CSS cannot restyle it.
+const markOverlayJS = `(function(marks) {
+  var old = document.getElementById('` + markOverlayID + `');
+  if (old) old.remove();
+
+  var host = document.createElement('div');
+  host.id = '` + markOverlayID + `';
+  host.style.cssText = 'all:initial;position:absolute;left:0;top:0;width:0;height:0;overflow:visible;z-index:2147483647;pointer-events:none;';
+  var root = host.attachShadow({ mode: 'closed' });
+
+  var colors = ['#e6194b', '#3cb44b', '#4363d8', '#f58231', '#911eb4', '#008080', '#f032e6', '#9a6324'];
+  marks.forEach(function(m, i) {
+    var color = colors[i % colors.length];
+    var left = m.x + scrollX, top = m.y + scrollY;
+
+    var box = document.createElement('div');
+    box.style.cssText = 'position:absolute;box-sizing:border-box;border:2px solid ' + color +
+      ';left:' + left + 'px;top:' + top + 'px;width:' + m.width + 'px;height:' + m.height + 'px;';
+
+    // Labels sit above the box, or inside it when there is no room above
+    var label = document.createElement('div');
+    label.textContent = String(m.id);
+    label.style.cssText = 'position:absolute;left:-2px;' + (top >= 16 ? 'top:-16px;' : 'top:0;') +
+      'background:' + color + ';color:#fff;font:bold 12px/14px monospace;padding:1px 3px;white-space:nowrap;';
+
+    box.appendChild(label);
+    root.appendChild(box);
+  });
+
+  document.documentElement.appendChild(host);
+})`
+
+// captureAnnotatedScreenshot takes a screenshot with every interactive element outlined and numbered
+// The legend in Marks maps the numbers to accessibility refs. The overlay is removed before returning.
+func (s *Session) captureAnnotatedScreenshot(ctx context.Context, targetID string, opts ScreenshotOptions) (*Screenshot, error) {
+	format := opts.Format
+	if format == "" {
+		format = ScreenshotPNG
+	}
+
+	// The tree hands out the refs the legend points to
+	tree, err := s.GetAccessibilityTree(ctx, targetID, AXTreeOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	metrics, err := s.layoutMetrics(ctx, targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The captured region in document coordinates
+	viewport := metrics.CSSVisualViewport
+	region := ClipRect{X: viewport.PageX, Y: viewport.PageY, Width: viewport.ClientWidth, Height: viewport.ClientHeight}
+	var clip *ClipRect
+	if opts.FullPage {
+		region = ClipRect{Width: metrics.CSSContentSize.Width, Height: metrics.CSSContentSize.Height}
+		clip = &region
+	} else if opts.Scale != 0 {
+		clip = &region
+	}
+
+	marks, boxes := s.collectMarks(ctx, targetID, tree.Nodes, viewport.PageX, viewport.PageY, region)
+
+	if len(marks) > 0 {
+		if err := s.drawMarks(ctx, targetID, boxes); err != nil {
+			return nil, err
+		}
+		defer s.removeMarks(targetID)
+	}
+
+	screenshot, err := s.captureScreenshot(ctx, targetID, format, opts.Quality, clip, opts.Scale)
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert the legend from CSS pixels to image pixels
+	factor := 1.0
+	if screenshot.Width > 0 && region.Width > 0 {
+		factor = float64(screenshot.Width) / region.Width
+	} else if opts.Scale != 0 {
+		factor = opts.Scale
+	}
+	for i := range marks {
+		marks[i].Box = scaleRect(marks[i].Box, factor)
+	}
+	screenshot.Marks = marks
+
+	return screenshot, nil
+}
+
+// markBox is a mark's box in viewport coordinates, as the overlay script expects it
+type markBox struct {
+	ID     int     `json:"id"`
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// collectMarks measures every node with a ref and keeps the ones inside the region
+// Legend boxes are relative to the region, overlay boxes to the viewport.
+func (s *Session) collectMarks(ctx context.Context, targetID string, nodes []*AXNode, scrollX, scrollY float64, region ClipRect) ([]Mark, []markBox) {
+	marks := make([]Mark, 0)
+	boxes := make([]markBox, 0)
+
+	var walk func(nodes []*AXNode)
+	walk = func(nodes []*AXNode) {
+		for _, node := range nodes {
+			if node.Ref != "" {
+				// Nodes without a box (display: none, detached) are not drawn
+				if box, ok := s.nodeBox(ctx, targetID, node.BackendNodeID); ok {
+					document := ClipRect{X: box.X + scrollX, Y: box.Y + scrollY, Width: box.Width, Height: box.Height}
+					if visible, ok := intersectRect(document, region); ok {
+						id := len(marks) + 1
+						marks = append(marks, Mark{
+							ID:   id,
+							Ref:  node.Ref,
+							Role: node.Role,
+							Name: node.Name,
+							Box:  ClipRect{X: visible.X - region.X, Y: visible.Y - region.Y, Width: visible.Width, Height: visible.Height},
+						})
+						boxes = append(boxes, markBox{ID: id, X: box.X, Y: box.Y, Width: box.Width, Height: box.Height})
+					}
+				}
+			}
+			walk(node.Children)
+		}
+	}
+	walk(nodes)
+
+	return marks, boxes
+}
+
+// nodeBox returns the border box of a DOM node in viewport coordinates
+func (s *Session) nodeBox(ctx context.Context, targetID string, backendNodeID int) (ClipRect, bool) {
+	params := map[string]interface{}{
+		"backendNodeId": backendNodeID,
+	}
+
+	result, err := s.CDPClient.SendCommandToTargetContext(ctx, targetID, "DOM.getBoxModel", params)
+	if err != nil {
+		return ClipRect{}, false
+	}
+
+	var response struct {
+		Model struct {
+			Border []float64 `json:"border"`
+		} `json:"model"`
+	}
+	if err := json.Unmarshal(result, &response); err != nil || len(response.Model.Border) != 8 {
+		return ClipRect{}, false
+	}
+
+	return quadBounds(response.Model.Border)
+}
+
+// quadBounds returns the bounding rectangle of a CDP quad (four x, y corner pairs)
+func quadBounds(quad []float64) (ClipRect, bool) {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for i := 0; i < len(quad); i += 2 {
+		minX, maxX = math.Min(minX, quad[i]), math.Max(maxX, quad[i])
+		minY, maxY = math.Min(minY, quad[i+1]), math.Max(maxY, quad[i+1])
+	}
+
+	if maxX-minX <= 0 || maxY-minY <= 0 {
+		return ClipRect{}, false
+	}
+	return ClipRect{X: minX, Y: minY, Width: maxX - minX, Height: maxY - minY}, true
+}
+
+// intersectRect returns the overlap of two rectangles, false if they do not overlap
+func intersectRect(a, b ClipRect) (ClipRect, bool) {
+	left, top := math.Max(a.X, b.X), math.Max(a.Y, b.Y)
+	right, bottom := math.Min(a.X+a.Width, b.X+b.Width), math.Min(a.Y+a.Height, b.Y+b.Height)
+
+	if right <= left || bottom <= top {
+		return ClipRect{}, false
+	}
+	return ClipRect{X: left, Y: top, Width: right - left, Height: bottom - top}, true
+}
+
+// scaleRect multiplies a rectangle by a factor, rounded to whole pixels
+func scaleRect(r ClipRect, factor float64) ClipRect {
+	return ClipRect{
+		X:      math.Round(r.X * factor),
+		Y:      math.Round(r.Y * factor),
+		Width:  math.Round(r.Width * factor),
+		Height: math.Round(r.Height * factor),
+	}
+}
+
+// drawMarks adds the overlay to the page
+func (s *Session) drawMarks(ctx context.Context, targetID string, boxes []markBox) error {
+	encoded, err := json.Marshal(boxes)
+	if err != nil {
+		return fmt.Errorf("failed to encode marks: %w", err)
+	}
+
+	if _, err := s.ExecuteJavascript(ctx, targetID, markOverlayJS+"("+string(encoded)+")"); err != nil {
+		return fmt.Errorf("failed to draw marks: %w", err)
+	}
+	return nil
+}
+
+// removeMarks takes the overlay off the page, even when the request context is already done
+func (s *Session) removeMarks(targetID string) {
+	params := map[string]interface{}{
+		"expression": "(function() { var el = document.getElementById('" + markOverlayID + "'); if (el) el.remove(); })()",
+	}
+	s.CDPClient.SendCommandToTarget(targetID, "Runtime.evaluate", params)
+}
@@ -0,0 +1,445 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dhruvsoni1802/browser-query-ai/internal/cdp"
+)
+
+// Console entry sources
+const (
+	ConsoleSourceConsole   = "console"   // console.log and friends
+	ConsoleSourceException = "exception" // Uncaught exceptions and unhandled rejections
+	ConsoleSourceLog       = "log"       // Browser log: network errors, CSP violations, deprecations
+)
+
+// Console levels, from least to most severe
+const (
+	ConsoleLevelDebug   = "debug"
+	ConsoleLevelInfo    = "info"
+	ConsoleLevelWarning = "warning"
+	ConsoleLevelError   = "error"
+)
+
+// consoleSeverity orders the levels for the minimum level filter
+var consoleSeverity = map[string]int{
+	ConsoleLevelDebug:   0,
+	ConsoleLevelInfo:    1,
+	ConsoleLevelWarning: 2,
+	ConsoleLevelError:   3,
+}
+
+// ConsoleEntry is one message the page logged
+type ConsoleEntry struct {
+	Seq    int64     `json:"seq"` // Increases by one per entry, use as the since cursor
+	Time   time.Time `json:"time"`
+	Source string    `json:"source"`
+	Level  string    `json:"level"`
+	Type   string    `json:"type,omitempty"` // Console API call such as log, table or assert
+	Text   string    `json:"text"`
+	URL    string    `json:"url,omitempty"`
+	Line   int       `json:"line,omitempty"` // 1-based
+	Column int       `json:"column,omitempty"`
+	Stack  []string  `json:"stack,omitempty"` // Top frames as "function (url:line:column)"
+}
+
+// ConsoleQuery filters the captured entries
+type ConsoleQuery struct {
+	Level string // Minimum level, empty returns all
+	Since int64  // Only entries with a higher seq
+	Limit int    // Most recent entries to return, 0 returns all
+}
+
+// ConsoleLog is the result of a console query
+type ConsoleLog struct {
+	PageID  string          `json:"page_id"`
+	Entries []*ConsoleEntry `json:"entries"`
+	Cursor  int64           `json:"cursor"`  // Seq of the newest entry captured, pass as since to get only newer ones
+	Dropped int64           `json:"dropped"` // Entries evicted from the buffer since the page opened
+}
+
+// consoleBuffer holds the most recent console entries of a page
+type consoleBuffer struct {
+	mu      sync.Mutex
+	entries *ring[*ConsoleEntry]
+	seq     int64
+	cancel  context.CancelFunc // Stops the capture goroutine
+}
+
+// newConsoleBuffer creates a buffer keeping up to capacity entries
+func newConsoleBuffer(capacity int, cancel context.CancelFunc) *consoleBuffer {
+	return &consoleBuffer{
+		entries: newRing[*ConsoleEntry](capacity),
+		cancel:  cancel,
+	}
+}
+
+// add appends an entry, evicting the oldest one when the buffer is full
+func (b *consoleBuffer) add(entry *ConsoleEntry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	entry.Seq = b.seq
+	b.entries.push(entry)
+}
+
+// query returns the matching entries oldest first, the newest seq and how many entries were evicted
+func (b *consoleBuffer) query(q ConsoleQuery) ([]*ConsoleEntry, int64, int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	minSeverity := consoleSeverity[q.Level]
+	entries := make([]*ConsoleEntry, 0)
+	b.entries.each(func(entry *ConsoleEntry) {
+		if entry.Seq > q.Since && consoleSeverity[entry.Level] >= minSeverity {
+			entries = append(entries, entry)
+		}
+	})
+
+	if q.Limit > 0 && len(entries) > q.Limit {
+		entries = entries[len(entries)-q.Limit:]
+	}
+
+	return entries, b.seq, b.seq - int64(b.entries.len())
+}
+
+// validate checks that the query is well formed
+func (q *ConsoleQuery) validate() error {
+	if _, ok := consoleSeverity[q.Level]; q.Level != "" && !ok {
+		return fmt.Errorf("%w: unknown level %q", ErrInvalidConsoleQuery, q.Level)
+	}
+	if q.Since < 0 || q.Limit < 0 {
+		return fmt.Errorf("%w: since and limit must not be negative", ErrInvalidConsoleQuery)
+	}
+	return nil
+}
+
+// startConsoleCapture begins recording the console of a page until stopConsoleCapture
+func (s *Session) startConsoleCapture(targetID string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var subscriptions []*cdp.Subscription
+	for _, method := range []string{"Runtime.consoleAPICalled", "Runtime.exceptionThrown", "Log.entryAdded"} {
+		sub, err := s.CDPClient.SubscribeTarget(ctx, targetID, method, 256)
+		if err != nil {
+			cancel()
+			return fmt.Errorf("failed to subscribe to console events: %w", err)
+		}
+		subscriptions = append(subscriptions, sub)
+	}
+
+	// Both domains are replayed after a reconnect, so capture survives it
+	for _, method := range []string{"Runtime.enable", "Log.enable"} {
+		if _, err := s.CDPClient.SendCommandToTargetContext(ctx, targetID, method, nil); err != nil {
+			cancel()
+			return fmt.Errorf("failed to enable console events: %w", err)
+		}
+	}
+
+	buffer := newConsoleBuffer(ConsoleBufferSize, cancel)
+
+	s.consoleMu.Lock()
+	if s.consoles == nil {
+		s.consoles = make(map[string]*consoleBuffer)
+	}
+	if old := s.consoles[targetID]; old != nil {
+		old.cancel()
+	}
+	s.consoles[targetID] = buffer
+	s.consoleMu.Unlock()
+
+	go buffer.capture(ctx, subscriptions[0].C, subscriptions[1].C, subscriptions[2].C)
+
+	return nil
+}
+
+// capture reads console events into the buffer until ctx is done or the client closes
+func (b *consoleBuffer) capture(ctx context.Context, console, exceptions, logs <-chan *cdp.Event) {
+	for console != nil || exceptions != nil || logs != nil {
+		select {
+		case event, ok := <-console:
+			if !ok {
+				console = nil
+				continue
+			}
+			if entry := parseConsoleAPICalled(event.Params); entry != nil {
+				b.add(entry)
+			}
+		case event, ok := <-exceptions:
+			if !ok {
+				exceptions = nil
+				continue
+			}
+			if entry := parseExceptionThrown(event.Params); entry != nil {
+				b.add(entry)
+			}
+		case event, ok := <-logs:
+			if !ok {
+				logs = nil
+				continue
+			}
+			if entry := parseLogEntry(event.Params); entry != nil {
+				b.add(entry)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// stopConsoleCapture stops recording a page and drops its entries
+func (s *Session) stopConsoleCapture(targetID string) {
+	s.consoleMu.Lock()
+	defer s.consoleMu.Unlock()
+
+	if buffer := s.consoles[targetID]; buffer != nil {
+		buffer.cancel()
+		delete(s.consoles, targetID)
+	}
+}
+
+// stopAllConsoleCaptures stops recording every page of the session
+func (s *Session) stopAllConsoleCaptures() {
+	s.consoleMu.Lock()
+	defer s.consoleMu.Unlock()
+
+	for targetID, buffer := range s.consoles {
+		buffer.cancel()
+		delete(s.consoles, targetID)
+	}
+}
+
+// GetConsoleLog returns the console entries captured for a page
+func (s *Session) GetConsoleLog(targetID string, query ConsoleQuery) (*ConsoleLog, error) {
+	if err := query.validate(); err != nil {
+		return nil, err
+	}
+
+	s.consoleMu.Lock()
+	buffer := s.consoles[targetID]
+	s.consoleMu.Unlock()
+
+	log := &ConsoleLog{
+		PageID:  targetID,
+		Entries: make([]*ConsoleEntry, 0),
+	}
+	if buffer == nil {
+		return log, nil
+	}
+
+	log.Entries, log.Cursor, log.Dropped = buffer.query(query)
+	return log, nil
+}
+
+// cdpRemoteObject is the part of a Runtime.RemoteObject needed to print it
+type cdpRemoteObject struct {
+	Type                string          `json:"type"`
+	Subtype             string          `json:"subtype,omitempty"`
+	Value               json.RawMessage `json:"value,omitempty"`
+	UnserializableValue string          `json:"unserializableValue,omitempty"`
+	Description         string          `json:"description,omitempty"`
+}
+
+// String prints the object the way DevTools would in a single line
+func (o cdpRemoteObject) String() string {
+	switch {
+	case o.Type == "string":
+		var text string
+		if json.Unmarshal(o.Value, &text) == nil {
+			return text
+		}
+	case o.UnserializableValue != "":
+		return o.UnserializableValue
+	case o.Type == "undefined":
+		return "undefined"
+	case o.Subtype == "null":
+		return "null"
+	case len(o.Value) > 0 && o.Type != "object":
+		return string(o.Value)
+	}
+	if o.Description != "" {
+		return o.Description
+	}
+	return o.Type
+}
+
+// cdpStackTrace is a Runtime.StackTrace
+type cdpStackTrace struct {
+	CallFrames []struct {
+		FunctionName string `json:"functionName"`
+		URL          string `json:"url"`
+		LineNumber   int    `json:"lineNumber"` // 0-based
+		ColumnNumber int    `json:"columnNumber"`
+	} `json:"callFrames"`
+}
+
+// maxStackFrames caps the frames kept per entry
+const maxStackFrames = 10
+
+// frames formats the top of the stack, one frame per line
+func (t *cdpStackTrace) frames() []string {
+	if t == nil {
+		return nil
+	}
+
+	frames := make([]string, 0, min(len(t.CallFrames), maxStackFrames))
+	for _, frame := range t.CallFrames[:min(len(t.CallFrames), maxStackFrames)] {
+		name := frame.FunctionName
+		if name == "" {
+			name = "(anonymous)"
+		}
+		frames = append(frames, fmt.Sprintf("%s (%s:%d:%d)", name, frame.URL, frame.LineNumber+1, frame.ColumnNumber+1))
+	}
+	return frames
+}
+
+// location returns the URL and 1-based position of the top frame
+func (t *cdpStackTrace) location() (string, int, int) {
+	if t == nil || len(t.CallFrames) == 0 {
+		return "", 0, 0
+	}
+	top := t.CallFrames[0]
+	return top.URL, top.LineNumber + 1, top.ColumnNumber + 1
+}
+
+// cdpTimestamp converts a Runtime.Timestamp (milliseconds since the epoch) to a time
+func cdpTimestamp(ms float64) time.Time {
+	if ms == 0 {
+		return time.Now()
+	}
+	return time.UnixMicro(int64(ms * 1000))
+}
+
+// consoleTypeLevels maps console API calls to levels, everything else is info
+var consoleTypeLevels = map[string]string{
+	"debug":   ConsoleLevelDebug,
+	"warning": ConsoleLevelWarning,
+	"error":   ConsoleLevelError,
+	"assert":  ConsoleLevelError,
+}
+
+// parseConsoleAPICalled converts a Runtime.consoleAPICalled event
+func parseConsoleAPICalled(params json.RawMessage) *ConsoleEntry {
+	var event struct {
+		Type       string            `json:"type"`
+		Args       []cdpRemoteObject `json:"args"`
+		Timestamp  float64           `json:"timestamp"`
+		StackTrace *cdpStackTrace    `json:"stackTrace,omitempty"`
+	}
+	if err := json.Unmarshal(params, &event); err != nil {
+		slog.Debug("failed to parse console event", "error", err)
+		return nil
+	}
+
+	args := make([]string, len(event.Args))
+	for i, arg := range event.Args {
+		args[i] = arg.String()
+	}
+
+	level, ok := consoleTypeLevels[event.Type]
+	if !ok {
+		level = ConsoleLevelInfo
+	}
+
+	entry := &ConsoleEntry{
+		Time:   cdpTimestamp(event.Timestamp),
+		Source: ConsoleSourceConsole,
+		Level:  level,
+		Type:   event.Type,
+		Text:   strings.Join(args, " "),
+	}
+	entry.URL, entry.Line, entry.Column = event.StackTrace.location()
+	// Only errors carry a stack, a trace on every log line is noise
+	if level == ConsoleLevelError || event.Type == "trace" {
+		entry.Stack = event.StackTrace.frames()
+	}
+
+	return entry
+}
+
+// parseExceptionThrown converts a Runtime.exceptionThrown event
+func parseExceptionThrown(params json.RawMessage) *ConsoleEntry {
+	var event struct {
+		Timestamp        float64 `json:"timestamp"`
+		ExceptionDetails struct {
+			Text         string           `json:"text"`
+			URL          string           `json:"url"`
+			LineNumber   int              `json:"lineNumber"`
+			ColumnNumber int              `json:"columnNumber"`
+			StackTrace   *cdpStackTrace   `json:"stackTrace,omitempty"`
+			Exception    *cdpRemoteObject `json:"exception,omitempty"`
+		} `json:"exceptionDetails"`
+	}
+	if err := json.Unmarshal(params, &event); err != nil {
+		slog.Debug("failed to parse exception event", "error", err)
+		return nil
+	}
+
+	// Text is just "Uncaught" or "Uncaught (in promise)", the message is the first line of the description
+	details := event.ExceptionDetails
+	text := details.Text
+	if details.Exception != nil {
+		message, _, _ := strings.Cut(details.Exception.String(), "\n")
+		text = strings.TrimSpace(text + " " + message)
+	}
+
+	return &ConsoleEntry{
+		Time:   cdpTimestamp(event.Timestamp),
+		Source: ConsoleSourceException,
+		Level:  ConsoleLevelError,
+		Text:   text,
+		URL:    details.URL,
+		Line:   details.LineNumber + 1,
+		Column: details.ColumnNumber + 1,
+		Stack:  details.StackTrace.frames(),
+	}
+}
+
+// parseLogEntry converts a Log.entryAdded event
+func parseLogEntry(params json.RawMessage) *ConsoleEntry {
+	var event struct {
+		Entry struct {
+			Source     string         `json:"source"`
+			Level      string         `json:"level"`
+			Text       string         `json:"text"`
+			Timestamp  float64        `json:"timestamp"`
+			URL        string         `json:"url,omitempty"`
+			LineNumber *int           `json:"lineNumber,omitempty"`
+			StackTrace *cdpStackTrace `json:"stackTrace,omitempty"`
+		} `json:"entry"`
+	}
+	if err := json.Unmarshal(params, &event); err != nil {
+		slog.Debug("failed to parse log event", "error", err)
+		return nil
+	}
+
+	level := event.Entry.Level
+	if level == "verbose" {
+		level = ConsoleLevelDebug
+	}
+	if _, ok := consoleSeverity[level]; !ok {
+		level = ConsoleLevelInfo
+	}
+
+	entry := &ConsoleEntry{
+		Time:   cdpTimestamp(event.Entry.Timestamp),
+		Source: ConsoleSourceLog,
+		Level:  level,
+		Type:   event.Entry.Source, // network, security, deprecation, ...
+		Text:   event.Entry.Text,
+		URL:    event.Entry.URL,
+		Stack:  event.Entry.StackTrace.frames(),
+	}
+	if event.Entry.LineNumber != nil {
+		entry.Line = *event.Entry.LineNumber + 1
+	}
+
+	return entry
+}
@@ -0,0 +1,110 @@
+package session
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestConsoleBuffer tests ring buffer eviction and the query filters
+func TestConsoleBuffer(t *testing.T) {
+	buffer := newConsoleBuffer(3, func() {})
+	levels := []string{ConsoleLevelInfo, ConsoleLevelError, ConsoleLevelDebug, ConsoleLevelWarning, ConsoleLevelInfo}
+	for _, level := range levels {
+		buffer.add(&ConsoleEntry{Level: level})
+	}
+
+	// Only the last three entries are kept
+	entries, cursor, dropped := buffer.query(ConsoleQuery{})
+	if len(entries) != 3 || entries[0].Seq != 3 || entries[2].Seq != 5 {
+		t.Fatalf("expected seqs 3-5, got %d entries starting at %d", len(entries), entries[0].Seq)
+	}
+	if cursor != 5 || dropped != 2 {
+		t.Errorf("expected cursor 5 and 2 dropped, got %d and %d", cursor, dropped)
+	}
+
+	entries, _, _ = buffer.query(ConsoleQuery{Level: ConsoleLevelWarning})
+	if len(entries) != 1 || entries[0].Seq != 4 {
+		t.Errorf("expected only the warning, got %d entries", len(entries))
+	}
+
+	entries, _, _ = buffer.query(ConsoleQuery{Since: 4})
+	if len(entries) != 1 || entries[0].Seq != 5 {
+		t.Errorf("expected only entries after seq 4, got %d entries", len(entries))
+	}
+
+	entries, _, _ = buffer.query(ConsoleQuery{Limit: 2})
+	if len(entries) != 2 || entries[0].Seq != 4 {
+		t.Errorf("expected the two newest entries, got %d entries", len(entries))
+	}
+
+	for _, query := range []ConsoleQuery{{Level: "fatal"}, {Since: -1}, {Limit: -1}} {
+		if err := query.validate(); !errors.Is(err, ErrInvalidConsoleQuery) {
+			t.Errorf("expected %+v to be invalid, got %v", query, err)
+		}
+	}
+}
+
+// TestParseConsoleEvents tests converting CDP console, exception and log events
+func TestParseConsoleEvents(t *testing.T) {
+	console := parseConsoleAPICalled([]byte(`{
+		"type": "warning",
+		"args": [
+			{"type": "string", "value": "retrying"},
+			{"type": "number", "value": 3, "description": "3"},
+			{"type": "object", "subtype": "null", "value": null},
+			{"type": "object", "className": "Object", "description": "Object"},
+			{"type": "number", "unserializableValue": "NaN", "description": "NaN"}
+		],
+		"timestamp": 1700000000000.5,
+		"stackTrace": {"callFrames": [{"functionName": "load", "url": "https://example.com/app.js", "lineNumber": 9, "columnNumber": 4}]}
+	}`))
+	if console == nil {
+		t.Fatal("failed to parse console event")
+	}
+	if console.Text != "retrying 3 null Object NaN" {
+		t.Errorf("unexpected text %q", console.Text)
+	}
+	if console.Level != ConsoleLevelWarning || console.Source != ConsoleSourceConsole || console.Type != "warning" {
+		t.Errorf("unexpected level/source/type %s/%s/%s", console.Level, console.Source, console.Type)
+	}
+	if console.URL != "https://example.com/app.js" || console.Line != 10 || console.Column != 5 {
+		t.Errorf("unexpected location %s:%d:%d", console.URL, console.Line, console.Column)
+	}
+	if console.Time.UnixMilli() != 1700000000000 {
+		t.Errorf("unexpected time %v", console.Time)
+	}
+
+	exception := parseExceptionThrown([]byte(`{
+		"timestamp": 1700000000000,
+		"exceptionDetails": {
+			"text": "Uncaught",
+			"url": "https://example.com/app.js",
+			"lineNumber": 0,
+			"columnNumber": 12,
+			"exception": {"type": "object", "subtype": "error", "description": "TypeError: x is undefined\n    at load (https://example.com/app.js:1:13)"},
+			"stackTrace": {"callFrames": [{"functionName": "", "url": "https://example.com/app.js", "lineNumber": 0, "columnNumber": 12}]}
+		}
+	}`))
+	if exception == nil {
+		t.Fatal("failed to parse exception event")
+	}
+	if exception.Text != "Uncaught TypeError: x is undefined" || exception.Level != ConsoleLevelError {
+		t.Errorf("unexpected exception %q at %s", exception.Text, exception.Level)
+	}
+	if len(exception.Stack) != 1 || exception.Stack[0] != "(anonymous) (https://example.com/app.js:1:13)" {
+		t.Errorf("unexpected stack %v", exception.Stack)
+	}
+
+	log := parseLogEntry([]byte(`{"entry": {"source": "network", "level": "error", "text": "Failed to load resource: 404", "timestamp": 1700000000000, "url": "https://example.com/missing.png"}}`))
+	if log == nil {
+		t.Fatal("failed to parse log event")
+	}
+	if log.Source != ConsoleSourceLog || log.Type != "network" || log.Level != ConsoleLevelError || log.URL != "https://example.com/missing.png" {
+		t.Errorf("unexpected log entry %+v", log)
+	}
+
+	verbose := parseLogEntry([]byte(`{"entry": {"source": "violation", "level": "verbose", "text": "Forced reflow", "timestamp": 1}}`))
+	if verbose == nil || verbose.Level != ConsoleLevelDebug {
+		t.Errorf("expected verbose to map to debug, got %+v", verbose)
+	}
+}
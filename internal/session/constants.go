@@ -2,7 +2,10 @@
 
 package session
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 const (
 	// MaxSessionsPerAgent is the maximum number of active sessions per agent
@@ -13,12 +16,48 @@ const (
 
 	// DefaultSessionNamePrefix for auto-generated names
 	DefaultSessionNamePrefix = "session"
+
+	// DefaultReadyTimeout is how long navigation waits for the page to become ready
+	DefaultReadyTimeout = 10 * time.Second
+
+	// DefaultWaitTimeout is how long a wait condition is given when it has no timeout of its own
+	DefaultWaitTimeout = 10 * time.Second
+
+	// ConsoleBufferSize is how many console entries are kept per page, older ones are dropped
+	ConsoleBufferSize = 1000
+
+	// NetworkBufferSize is how many requests are kept per page, older ones are dropped
+	NetworkBufferSize = 1000
+
+	// MaxInterceptionRules is the maximum number of interception rules per session
+	MaxInterceptionRules = 100
+
+	// BrowserStateTimeout is how long saving or restoring cookies and localStorage may take on close or resume
+	BrowserStateTimeout = 30 * time.Second
 )
 
 // Error definitions
 var (
-	ErrSessionLimitReached   = fmt.Errorf("agent session limit reached")
-	ErrSessionNameConflict   = fmt.Errorf("session name already exists")
-	ErrInvalidSessionName    = fmt.Errorf("invalid session name")
-	ErrSessionNotFound       = fmt.Errorf("session not found")
-)
\ No newline at end of file
+	ErrSessionLimitReached      = fmt.Errorf("agent session limit reached")
+	ErrSessionNameConflict      = fmt.Errorf("session name already exists")
+	ErrInvalidSessionName       = fmt.Errorf("invalid session name")
+	ErrSessionNotFound          = fmt.Errorf("session not found")
+	ErrNoHistoryEntry           = fmt.Errorf("no history entry to navigate to")
+	ErrInvalidAction            = fmt.Errorf("invalid action")
+	ErrElementNotFound          = fmt.Errorf("element not found")
+	ErrElementNotVisible        = fmt.Errorf("element not visible")
+	ErrElementDisabled          = fmt.Errorf("element is disabled")
+	ErrStaleElementRef          = fmt.Errorf("stale element ref")
+	ErrInvalidWaitCondition     = fmt.Errorf("invalid wait condition")
+	ErrInvalidTreeOptions       = fmt.Errorf("invalid accessibility tree options")
+	ErrInvalidContentFormat     = fmt.Errorf("invalid content format")
+	ErrInvalidScreenshotOptions = fmt.Errorf("invalid screenshot options")
+	ErrInvalidPDFOptions        = fmt.Errorf("invalid PDF options")
+	ErrInvalidConsoleQuery      = fmt.Errorf("invalid console query")
+	ErrInvalidNetworkQuery      = fmt.Errorf("invalid network query")
+	ErrRequestNotFound          = fmt.Errorf("request not found")
+	ErrResponseBodyUnavailable  = fmt.Errorf("response body unavailable")
+	ErrInvalidInterceptionRule  = fmt.Errorf("invalid interception rule")
+	ErrInvalidEmulation         = fmt.Errorf("invalid emulation options")
+	ErrWaitTimeout              = fmt.Errorf("wait condition not met")
+)
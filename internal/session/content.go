@@ -0,0 +1,50 @@
+package session
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dhruvsoni1802/browser-query-ai/internal/extract"
+)
+
+// PageContent is the content of a page in a readable format
+type PageContent struct {
+	PageID  string `json:"page_id"`
+	URL     string `json:"url"`
+	Format  string `json:"format"`
+	Content string `json:"content"`
+}
+
+// ExtractPageContent gets the content of a page as html, text, markdown or readability (main article only)
+func (s *Session) ExtractPageContent(ctx context.Context, targetID string, format string) (*PageContent, error) {
+	if format == "" {
+		format = extract.FormatHTML
+	}
+	if !extract.ValidFormat(format) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidContentFormat, format)
+	}
+
+	html, err := s.GetPageContent(ctx, targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Links are made absolute against the page URL
+	href, err := s.ExecuteJavascript(ctx, targetID, "location.href")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get page URL: %w", err)
+	}
+	pageURL, _ := href.(string)
+
+	content, err := extract.Convert(html, pageURL, format)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert page content: %w", err)
+	}
+
+	return &PageContent{
+		PageID:  targetID,
+		URL:     pageURL,
+		Format:  format,
+		Content: content,
+	}, nil
+}
@@ -0,0 +1,451 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Emulated media feature values
+const (
+	ColorSchemeLight    = "light"
+	ColorSchemeDark     = "dark"
+	MediaNoPreference   = "no-preference"
+	ReducedMotionReduce = "reduce"
+)
+
+// maxEmulatedViewportSide is the largest width or height a viewport can have
+const maxEmulatedViewportSide = 10000
+
+// localePattern matches BCP 47 tags such as en, en-US or zh-Hant-TW
+var localePattern = regexp.MustCompile(`^[A-Za-z]{2,3}([-_][A-Za-z0-9]{2,8})*$`)
+
+// emulationPermissions are the permission names Browser.grantPermissions accepts
+var emulationPermissions = map[string]bool{
+	"accessibilityEvents": true, "audioCapture": true, "backgroundSync": true, "backgroundFetch": true,
+	"clipboardReadWrite": true, "clipboardSanitizedWrite": true, "displayCapture": true, "durableStorage": true,
+	"geolocation": true, "idleDetection": true, "localFonts": true, "midi": true, "midiSysex": true, "nfc": true,
+	"notifications": true, "paymentHandler": true, "periodicBackgroundSync": true, "protectedMediaIdentifier": true,
+	"sensors": true, "storageAccess": true, "topLevelStorageAccess": true, "videoCapture": true,
+	"videoCapturePanTiltZoom": true, "wakeLockScreen": true, "wakeLockSystem": true, "windowManagement": true,
+}
+
+// EmulationOptions change how the pages of a session present themselves to sites
+// Fields left empty keep the device preset's value, or the browser default without a preset.
+type EmulationOptions struct {
+	Device        string             `json:"device,omitempty"` // Preset name such as iphone-15 or pixel-7
+	Viewport      *Viewport          `json:"viewport,omitempty"`
+	UserAgent     string             `json:"user_agent,omitempty"`
+	ClientHints   *UserAgentMetadata `json:"client_hints,omitempty"` // Sec-CH-UA headers and navigator.userAgentData
+	Locale        string             `json:"locale,omitempty"`       // Also sets Accept-Language
+	Timezone      string             `json:"timezone,omitempty"`     // IANA name such as Europe/Berlin
+	Geolocation   *Geolocation       `json:"geolocation,omitempty"`  // Grants the geolocation permission
+	Permissions   []string           `json:"permissions,omitempty"`
+	ColorScheme   string             `json:"color_scheme,omitempty"`   // light, dark or no-preference
+	ReducedMotion string             `json:"reduced_motion,omitempty"` // reduce or no-preference
+}
+
+// Viewport is the emulated screen
+type Viewport struct {
+	Width             int     `json:"width"`
+	Height            int     `json:"height"`
+	DeviceScaleFactor float64 `json:"device_scale_factor,omitempty"` // Defaults to 1
+	Mobile            bool    `json:"mobile,omitempty"`              // Mobile layout: meta viewport, overlay scrollbars
+	Touch             bool    `json:"touch,omitempty"`
+}
+
+// UserAgentMetadata is what the page sees through User-Agent Client Hints
+type UserAgentMetadata struct {
+	Brands          []UserAgentBrand `json:"brands,omitempty"`
+	Platform        string           `json:"platform"`
+	PlatformVersion string           `json:"platform_version,omitempty"`
+	Architecture    string           `json:"architecture,omitempty"`
+	Model           string           `json:"model,omitempty"`
+	Mobile          bool             `json:"mobile,omitempty"`
+}
+
+// UserAgentBrand is one entry of the Sec-CH-UA brand list
+type UserAgentBrand struct {
+	Brand   string `json:"brand"`
+	Version string `json:"version"`
+}
+
+// Geolocation is the emulated position
+type Geolocation struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Accuracy  float64 `json:"accuracy,omitempty"` // Meters, defaults to 1
+}
+
+// devicePreset is a named device the options can start from
+type devicePreset struct {
+	viewport    Viewport
+	userAgent   string
+	clientHints *UserAgentMetadata
+}
+
+// devicePresets are the devices available by name
+var devicePresets = map[string]devicePreset{
+	"iphone-15": {
+		viewport:  Viewport{Width: 393, Height: 852, DeviceScaleFactor: 3, Mobile: true, Touch: true},
+		userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1",
+	},
+	"iphone-se": {
+		viewport:  Viewport{Width: 375, Height: 667, DeviceScaleFactor: 2, Mobile: true, Touch: true},
+		userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1",
+	},
+	"ipad-pro-11": {
+		viewport:  Viewport{Width: 834, Height: 1194, DeviceScaleFactor: 2, Mobile: true, Touch: true},
+		userAgent: "Mozilla/5.0 (iPad; CPU OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1",
+	},
+	"pixel-7": {
+		viewport:    Viewport{Width: 412, Height: 915, DeviceScaleFactor: 2.625, Mobile: true, Touch: true},
+		userAgent:   "Mozilla/5.0 (Linux; Android 14; Pixel 7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Mobile Safari/537.36",
+		clientHints: &UserAgentMetadata{Platform: "Android", PlatformVersion: "14.0.0", Model: "Pixel 7", Mobile: true},
+	},
+	"galaxy-s23": {
+		viewport:    Viewport{Width: 360, Height: 780, DeviceScaleFactor: 3, Mobile: true, Touch: true},
+		userAgent:   "Mozilla/5.0 (Linux; Android 14; SM-S911B) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Mobile Safari/537.36",
+		clientHints: &UserAgentMetadata{Platform: "Android", PlatformVersion: "14.0.0", Model: "SM-S911B", Mobile: true},
+	},
+	"desktop-hd": {
+		viewport:    Viewport{Width: 1366, Height: 768, DeviceScaleFactor: 1},
+		userAgent:   "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36",
+		clientHints: &UserAgentMetadata{Platform: "Windows", PlatformVersion: "15.0.0", Architecture: "x86"},
+	},
+	"desktop-fhd": {
+		viewport:    Viewport{Width: 1920, Height: 1080, DeviceScaleFactor: 1},
+		userAgent:   "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36",
+		clientHints: &UserAgentMetadata{Platform: "Windows", PlatformVersion: "15.0.0", Architecture: "x86"},
+	},
+	"macbook-pro-14": {
+		viewport:    Viewport{Width: 1512, Height: 982, DeviceScaleFactor: 2},
+		userAgent:   "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36",
+		clientHints: &UserAgentMetadata{Platform: "macOS", PlatformVersion: "14.5.0", Architecture: "arm"},
+	},
+}
+
+// DevicePresets returns the names of the device presets
+func DevicePresets() []string {
+	names := make([]string, 0, len(devicePresets))
+	for name := range devicePresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsZero reports whether the options change nothing
+func (o *EmulationOptions) IsZero() bool {
+	return o == nil || (o.Device == "" && o.Viewport == nil && o.UserAgent == "" && o.ClientHints == nil &&
+		o.Locale == "" && o.Timezone == "" && o.Geolocation == nil && len(o.Permissions) == 0 &&
+		o.ColorScheme == "" && o.ReducedMotion == "")
+}
+
+// Validate checks that the options can be applied
+func (o *EmulationOptions) Validate() error {
+	if o == nil {
+		return nil
+	}
+
+	if o.Device != "" {
+		if _, ok := devicePresets[strings.ToLower(o.Device)]; !ok {
+			return fmt.Errorf("%w: unknown device %q, expected one of %s", ErrInvalidEmulation, o.Device, strings.Join(DevicePresets(), ", "))
+		}
+	}
+
+	if v := o.Viewport; v != nil {
+		if v.Width < 1 || v.Height < 1 || v.Width > maxEmulatedViewportSide || v.Height > maxEmulatedViewportSide {
+			return fmt.Errorf("%w: viewport must be between 1 and %d pixels on each side", ErrInvalidEmulation, maxEmulatedViewportSide)
+		}
+		if v.DeviceScaleFactor < 0 || v.DeviceScaleFactor > 10 {
+			return fmt.Errorf("%w: device_scale_factor must be between 0 and 10", ErrInvalidEmulation)
+		}
+	}
+
+	if o.ClientHints != nil && o.ClientHints.Platform == "" {
+		return fmt.Errorf("%w: client_hints requires a platform", ErrInvalidEmulation)
+	}
+
+	if o.Locale != "" && !localePattern.MatchString(o.Locale) {
+		return fmt.Errorf("%w: locale %q is not a language tag", ErrInvalidEmulation, o.Locale)
+	}
+
+	if strings.ContainsAny(o.Timezone, " \t") {
+		return fmt.Errorf("%w: timezone %q is not an IANA name", ErrInvalidEmulation, o.Timezone)
+	}
+
+	if g := o.Geolocation; g != nil {
+		if g.Latitude < -90 || g.Latitude > 90 || g.Longitude < -180 || g.Longitude > 180 {
+			return fmt.Errorf("%w: geolocation is out of range", ErrInvalidEmulation)
+		}
+		if g.Accuracy < 0 {
+			return fmt.Errorf("%w: geolocation accuracy cannot be negative", ErrInvalidEmulation)
+		}
+	}
+
+	for _, permission := range o.Permissions {
+		if !emulationPermissions[permission] {
+			return fmt.Errorf("%w: unknown permission %q", ErrInvalidEmulation, permission)
+		}
+	}
+
+	switch o.ColorScheme {
+	case "", ColorSchemeLight, ColorSchemeDark, MediaNoPreference:
+	default:
+		return fmt.Errorf("%w: color_scheme must be light, dark or no-preference", ErrInvalidEmulation)
+	}
+
+	switch o.ReducedMotion {
+	case "", ReducedMotionReduce, MediaNoPreference:
+	default:
+		return fmt.Errorf("%w: reduced_motion must be reduce or no-preference", ErrInvalidEmulation)
+	}
+
+	return nil
+}
+
+// resolve fills the fields left empty from the device preset
+func (o *EmulationOptions) resolve() EmulationOptions {
+	resolved := *o
+	preset, ok := devicePresets[strings.ToLower(o.Device)]
+	if !ok {
+		return resolved
+	}
+
+	if resolved.Viewport == nil {
+		viewport := preset.viewport
+		resolved.Viewport = &viewport
+	}
+	if resolved.UserAgent == "" {
+		resolved.UserAgent = preset.userAgent
+		// Hints only make sense with the user agent they were written for
+		if resolved.ClientHints == nil {
+			resolved.ClientHints = preset.clientHints
+		}
+	}
+	return resolved
+}
+
+// permissions returns the permissions to grant, including geolocation when a position is set
+func (o *EmulationOptions) permissions() []string {
+	permissions := append([]string{}, o.Permissions...)
+	if o.Geolocation != nil && !slices.Contains(permissions, "geolocation") {
+		permissions = append(permissions, "geolocation")
+	}
+	return permissions
+}
+
+// emulationCommand is one CDP call applying part of the options to a page
+type emulationCommand struct {
+	method string
+	params map[string]interface{}
+}
+
+// pageCommands builds the commands that bring a page in line with the options
+// Every setting is sent, cleared ones included, so switching options never leaves stale overrides behind.
+func (o *EmulationOptions) pageCommands(defaultUserAgent string) []emulationCommand {
+	var commands []emulationCommand
+	add := func(method string, params map[string]interface{}) {
+		commands = append(commands, emulationCommand{method: method, params: params})
+	}
+
+	if v := o.Viewport; v != nil {
+		scale := v.DeviceScaleFactor
+		if scale == 0 {
+			scale = 1
+		}
+		add("Emulation.setDeviceMetricsOverride", map[string]interface{}{
+			"width":             v.Width,
+			"height":            v.Height,
+			"deviceScaleFactor": scale,
+			"mobile":            v.Mobile,
+		})
+		add("Emulation.setTouchEmulationEnabled", map[string]interface{}{"enabled": v.Touch, "maxTouchPoints": 5})
+	} else {
+		add("Emulation.clearDeviceMetricsOverride", nil)
+		add("Emulation.setTouchEmulationEnabled", map[string]interface{}{"enabled": false})
+	}
+
+	userAgent := map[string]interface{}{"userAgent": o.UserAgent}
+	if o.UserAgent == "" {
+		// No headless marker for sites that check it
+		userAgent["userAgent"] = strings.Replace(defaultUserAgent, "HeadlessChrome", "Chrome", 1)
+	}
+	if o.Locale != "" {
+		userAgent["acceptLanguage"] = o.Locale
+	}
+	if h := o.ClientHints; h != nil {
+		brands := make([]map[string]string, 0, len(h.Brands))
+		for _, brand := range h.Brands {
+			brands = append(brands, map[string]string{"brand": brand.Brand, "version": brand.Version})
+		}
+		userAgent["userAgentMetadata"] = map[string]interface{}{
+			"brands":          brands,
+			"platform":        h.Platform,
+			"platformVersion": h.PlatformVersion,
+			"architecture":    h.Architecture,
+			"model":           h.Model,
+			"mobile":          h.Mobile,
+		}
+	}
+	add("Emulation.setUserAgentOverride", userAgent)
+
+	// An empty locale or timezone removes the override
+	add("Emulation.setLocaleOverride", map[string]interface{}{"locale": o.Locale})
+	add("Emulation.setTimezoneOverride", map[string]interface{}{"timezoneId": o.Timezone})
+
+	if g := o.Geolocation; g != nil {
+		accuracy := g.Accuracy
+		if accuracy == 0 {
+			accuracy = 1
+		}
+		add("Emulation.setGeolocationOverride", map[string]interface{}{
+			"latitude":  g.Latitude,
+			"longitude": g.Longitude,
+			"accuracy":  accuracy,
+		})
+	} else {
+		add("Emulation.clearGeolocationOverride", nil)
+	}
+
+	add("Emulation.setEmulatedMedia", map[string]interface{}{
+		"features": []map[string]string{
+			{"name": "prefers-color-scheme", "value": o.ColorScheme},
+			{"name": "prefers-reduced-motion", "value": o.ReducedMotion},
+		},
+	})
+
+	return commands
+}
+
+// Emulation returns the emulation options of the session, nil when it uses browser defaults
+func (s *Session) Emulation() *EmulationOptions {
+	s.emulationMu.Lock()
+	defer s.emulationMu.Unlock()
+
+	return s.emulation
+}
+
+// SetEmulation replaces the emulation options of the session and applies them to its open pages
+// The user agent of an open page changes with its next navigation.
+func (s *Session) SetEmulation(ctx context.Context, opts *EmulationOptions) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	if opts.IsZero() {
+		opts = nil
+	}
+
+	s.emulationMu.Lock()
+	previous := s.emulation
+	s.emulation = opts
+	s.emulationMu.Unlock()
+
+	// Nothing was overridden and nothing will be
+	if previous == nil && opts == nil {
+		return nil
+	}
+
+	if err := s.applyEmulationToSession(ctx, opts); err != nil {
+		// Options the browser rejects must not stick, every later page would fail with them
+		if errors.Is(err, ErrInvalidEmulation) {
+			s.emulationMu.Lock()
+			s.emulation = previous
+			s.emulationMu.Unlock()
+			if restoreErr := s.applyEmulationToSession(ctx, previous); restoreErr != nil {
+				slog.Warn("failed to restore previous emulation", "session_id", s.ID, "error", restoreErr)
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// applyEmulationToSession applies options to the browser context and every open page
+func (s *Session) applyEmulationToSession(ctx context.Context, opts *EmulationOptions) error {
+	if err := s.applyPermissions(ctx, opts); err != nil {
+		return err
+	}
+
+	for _, pageID := range s.Pages() {
+		if err := s.applyEmulation(ctx, pageID, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyPermissions grants the permissions of the options to the session's browser context
+func (s *Session) applyPermissions(ctx context.Context, opts *EmulationOptions) error {
+	var permissions []string
+	if opts != nil {
+		permissions = opts.permissions()
+	}
+
+	// Start from a clean slate so revoked permissions do not linger
+	params := map[string]interface{}{"browserContextId": s.ContextID}
+	if _, err := s.CDPClient.SendCommandContext(ctx, "Browser.resetPermissions", params); err != nil {
+		return fmt.Errorf("failed to reset permissions: %w", err)
+	}
+	if len(permissions) == 0 {
+		return nil
+	}
+
+	params["permissions"] = permissions
+	if _, err := s.CDPClient.SendCommandContext(ctx, "Browser.grantPermissions", params); err != nil {
+		return fmt.Errorf("failed to grant permissions: %w", err)
+	}
+	return nil
+}
+
+// applyEmulation brings one page in line with the options, nil restores the browser defaults
+func (s *Session) applyEmulation(ctx context.Context, targetID string, opts *EmulationOptions) error {
+	resolved := EmulationOptions{}
+	if opts != nil {
+		resolved = opts.resolve()
+	}
+
+	defaultUserAgent, err := s.defaultUserAgent()
+	if err != nil {
+		return err
+	}
+	if opts == nil {
+		// Restoring defaults means the browser's own user agent, headless marker included
+		resolved.UserAgent = defaultUserAgent
+	}
+
+	for _, command := range resolved.pageCommands(defaultUserAgent) {
+		if _, err := s.CDPClient.SendCommandToTargetContext(ctx, targetID, command.method, command.params); err != nil {
+			// The browser is the authority on locale and timezone names
+			if strings.Contains(err.Error(), "Invalid") {
+				return fmt.Errorf("%w: %s: %v", ErrInvalidEmulation, command.method, err)
+			}
+			return fmt.Errorf("failed to apply emulation: %w", err)
+		}
+	}
+	return nil
+}
+
+// emulatePage applies the session's options to a new page, it does nothing when there are none
+func (s *Session) emulatePage(ctx context.Context, targetID string) error {
+	opts := s.Emulation()
+	if opts == nil {
+		return nil
+	}
+	return s.applyEmulation(ctx, targetID, opts)
+}
+
+// defaultUserAgent returns the browser's own user agent
+func (s *Session) defaultUserAgent() (string, error) {
+	version, err := s.CDPClient.GetBrowserVersion()
+	if err != nil {
+		return "", err
+	}
+	return version["userAgent"], nil
+}
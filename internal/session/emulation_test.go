@@ -0,0 +1,134 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dhruvsoni1802/browser-query-ai/internal/storage"
+)
+
+// TestEmulationOptionsValidate tests rejecting options that cannot be applied
+func TestEmulationOptionsValidate(t *testing.T) {
+	valid := []*EmulationOptions{
+		nil,
+		{},
+		{Device: "iPhone-15"},
+		{Viewport: &Viewport{Width: 800, Height: 600, DeviceScaleFactor: 2}},
+		{Locale: "de-DE", Timezone: "Europe/Berlin", ColorScheme: ColorSchemeDark, ReducedMotion: ReducedMotionReduce},
+		{Geolocation: &Geolocation{Latitude: 52.52, Longitude: 13.405}, Permissions: []string{"notifications"}},
+		{UserAgent: "bot", ClientHints: &UserAgentMetadata{Platform: "Linux"}},
+	}
+	for _, opts := range valid {
+		if err := opts.Validate(); err != nil {
+			t.Errorf("expected %+v to be valid, got %v", opts, err)
+		}
+	}
+
+	invalid := []*EmulationOptions{
+		{Device: "nokia-3310"},
+		{Viewport: &Viewport{Width: 0, Height: 600}},
+		{Viewport: &Viewport{Width: 800, Height: 20000}},
+		{Viewport: &Viewport{Width: 800, Height: 600, DeviceScaleFactor: -1}},
+		{ClientHints: &UserAgentMetadata{Model: "Pixel"}},
+		{Locale: "german please"},
+		{Timezone: "Central European Time"},
+		{Geolocation: &Geolocation{Latitude: 91}},
+		{Geolocation: &Geolocation{Accuracy: -1}},
+		{Permissions: []string{"superpowers"}},
+		{ColorScheme: "sepia"},
+		{ReducedMotion: "yes"},
+	}
+	for _, opts := range invalid {
+		if err := opts.Validate(); !errors.Is(err, ErrInvalidEmulation) {
+			t.Errorf("expected %+v to be invalid, got %v", opts, err)
+		}
+	}
+}
+
+// TestEmulationResolve tests that explicit fields win over the device preset
+func TestEmulationResolve(t *testing.T) {
+	resolved := (&EmulationOptions{Device: "Pixel-7", Locale: "fr-FR"}).resolve()
+	if resolved.Viewport == nil || resolved.Viewport.Width != 412 || !resolved.Viewport.Mobile {
+		t.Errorf("expected the preset viewport, got %+v", resolved.Viewport)
+	}
+	if resolved.ClientHints == nil || resolved.ClientHints.Platform != "Android" || resolved.Locale != "fr-FR" {
+		t.Errorf("expected preset hints and the explicit locale, got %+v", resolved)
+	}
+
+	custom := (&EmulationOptions{Device: "pixel-7", UserAgent: "custom", Viewport: &Viewport{Width: 100, Height: 100}}).resolve()
+	if custom.Viewport.Width != 100 || custom.UserAgent != "custom" || custom.ClientHints != nil {
+		t.Errorf("expected explicit fields to win without preset hints, got %+v", custom)
+	}
+}
+
+// TestEmulationPageCommands tests the CDP commands sent for set and cleared options
+func TestEmulationPageCommands(t *testing.T) {
+	headless := "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) HeadlessChrome/126.0.0.0 Safari/537.36"
+
+	opts := (&EmulationOptions{Device: "iphone-15", Locale: "de-DE", Geolocation: &Geolocation{Latitude: 1, Longitude: 2}, ColorScheme: ColorSchemeDark}).resolve()
+	commands := map[string]map[string]interface{}{}
+	for _, command := range opts.pageCommands(headless) {
+		commands[command.method] = command.params
+	}
+
+	if metrics := commands["Emulation.setDeviceMetricsOverride"]; metrics == nil || metrics["width"] != 393 || metrics["mobile"] != true {
+		t.Errorf("unexpected device metrics %v", metrics)
+	}
+	if touch := commands["Emulation.setTouchEmulationEnabled"]; touch["enabled"] != true {
+		t.Errorf("expected touch to be enabled, got %v", touch)
+	}
+	if userAgent := commands["Emulation.setUserAgentOverride"]; userAgent["acceptLanguage"] != "de-DE" || userAgent["userAgent"] == headless {
+		t.Errorf("unexpected user agent override %v", userAgent)
+	}
+	if geolocation := commands["Emulation.setGeolocationOverride"]; geolocation["accuracy"] != 1.0 {
+		t.Errorf("expected the default accuracy, got %v", geolocation)
+	}
+	if _, ok := commands["Emulation.clearGeolocationOverride"]; ok {
+		t.Error("did not expect the geolocation to be cleared")
+	}
+
+	// Empty options clear every override and drop the headless marker
+	cleared := map[string]map[string]interface{}{}
+	for _, command := range (&EmulationOptions{}).pageCommands(headless) {
+		cleared[command.method] = command.params
+	}
+	for _, method := range []string{"Emulation.clearDeviceMetricsOverride", "Emulation.clearGeolocationOverride", "Emulation.setEmulatedMedia"} {
+		if _, ok := cleared[method]; !ok {
+			t.Errorf("expected %s when clearing", method)
+		}
+	}
+	if userAgent := cleared["Emulation.setUserAgentOverride"]["userAgent"]; userAgent != "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36" {
+		t.Errorf("expected the headless marker to be dropped, got %v", userAgent)
+	}
+	if locale := cleared["Emulation.setLocaleOverride"]["locale"]; locale != "" {
+		t.Errorf("expected an empty locale to clear the override, got %v", locale)
+	}
+
+	if permissions := (&EmulationOptions{Geolocation: &Geolocation{}, Permissions: []string{"notifications"}}).permissions(); len(permissions) != 2 || permissions[1] != "geolocation" {
+		t.Errorf("expected geolocation to be granted with a position, got %v", permissions)
+	}
+}
+
+// TestOpenPageEmulationFails tests that a page whose emulation can't be applied is closed and forgotten
+func TestOpenPageEmulationFails(t *testing.T) {
+	browser, client := newFakeBrowser(t)
+	browser.failing = map[string]bool{"Browser.getVersion": true}
+
+	manager := NewManager(storage.NewMemoryStore(time.Hour))
+	session := &Session{ID: "sess_emulated", ContextID: "ctx-1", CDPClient: client, Status: SessionActive,
+		emulation: &EmulationOptions{Locale: "de-DE"}, pageAnalysisCache: make(map[string]*PageStructure)}
+	manager.sessions[session.ID] = session
+
+	if _, err := manager.openPage(context.Background(), session); err == nil {
+		t.Fatal("expected openPage to fail when emulation can't be applied")
+	}
+
+	if pages := session.Pages(); len(pages) != 0 {
+		t.Errorf("expected no pages listed, got %v", pages)
+	}
+	if targets := browser.targetIDs(); len(targets) != 0 {
+		t.Errorf("expected the page to be closed, got %v", targets)
+	}
+}
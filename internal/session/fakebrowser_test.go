@@ -0,0 +1,118 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/dhruvsoni1802/browser-query-ai/internal/cdp"
+)
+
+// fakeBrowser answers the browser context and target commands of CDP, for tests that don't need chromium
+type fakeBrowser struct {
+	mu          sync.Mutex
+	contexts    []string
+	targets     []string
+	created     int
+	createDelay time.Duration   // How long Target.createBrowserContext takes
+	failing     map[string]bool // Methods answered with an error
+}
+
+func (f *fakeBrowser) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	for {
+		var cmd cdp.Command
+		if err := conn.ReadJSON(&cmd); err != nil {
+			return
+		}
+
+		if cmd.Method == "Target.createBrowserContext" {
+			time.Sleep(f.createDelay)
+		}
+
+		var result any = struct{}{}
+		f.mu.Lock()
+		if f.failing[cmd.Method] {
+			f.mu.Unlock()
+			response := cdp.Response{ID: cmd.ID, Error: &cdp.ResponseError{Code: -32000, Message: cmd.Method + " failed"}}
+			if err := conn.WriteJSON(response); err != nil {
+				return
+			}
+			continue
+		}
+		switch cmd.Method {
+		case "Target.getBrowserContexts":
+			result = map[string][]string{"browserContextIds": slices.Clone(f.contexts)}
+		case "Target.createBrowserContext":
+			f.created++
+			id := fmt.Sprintf("ctx-new-%d", f.created)
+			f.contexts = append(f.contexts, id)
+			result = map[string]string{"browserContextId": id}
+		case "Target.disposeBrowserContext":
+			id, _ := cmd.Params["browserContextId"].(string)
+			f.contexts = slices.DeleteFunc(f.contexts, func(c string) bool { return c == id })
+		case "Target.createTarget":
+			f.created++
+			id := fmt.Sprintf("page-%d", f.created)
+			f.targets = append(f.targets, id)
+			result = map[string]string{"targetId": id}
+		case "Target.closeTarget":
+			id, _ := cmd.Params["targetId"].(string)
+			f.targets = slices.DeleteFunc(f.targets, func(t string) bool { return t == id })
+		case "Target.attachToTarget":
+			id, _ := cmd.Params["targetId"].(string)
+			result = map[string]string{"sessionId": "session-" + id}
+		}
+		f.mu.Unlock()
+
+		data, _ := json.Marshal(result)
+		if err := conn.WriteJSON(cdp.Response{ID: cmd.ID, Result: data}); err != nil {
+			return
+		}
+	}
+}
+
+// newFakeBrowser returns a connected client for a fake browser holding the contexts
+func newFakeBrowser(t *testing.T, contexts ...string) (*fakeBrowser, *cdp.Client) {
+	t.Helper()
+
+	browser := &fakeBrowser{contexts: contexts}
+	server := httptest.NewServer(browser)
+	t.Cleanup(server.Close)
+
+	client := cdp.NewClient("ws" + strings.TrimPrefix(server.URL, "http"))
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	return browser, client
+}
+
+// targetIDs returns the pages the fake browser holds
+func (f *fakeBrowser) targetIDs() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.targets)
+}
+
+// contextIDs returns the browser contexts the fake browser holds
+func (f *fakeBrowser) contextIDs() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.contexts)
+}
@@ -0,0 +1,268 @@
+package session
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// HAR is an HTTP Archive 1.2 document
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+// HARLog is the root of a HAR document
+type HARLog struct {
+	Version string     `json:"version"`
+	Creator HARCreator `json:"creator"`
+	Pages   []HARPage  `json:"pages"`
+	Entries []HAREntry `json:"entries"`
+}
+
+// HARCreator names the application that produced the archive
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// HARPage is one page of the session, entries refer to it by ID
+type HARPage struct {
+	StartedDateTime time.Time      `json:"startedDateTime"`
+	ID              string         `json:"id"`
+	Title           string         `json:"title"`
+	PageTimings     HARPageTimings `json:"pageTimings"`
+}
+
+// HARPageTimings are the page load milestones, -1 when not recorded
+type HARPageTimings struct {
+	OnContentLoad float64 `json:"onContentLoad"`
+	OnLoad        float64 `json:"onLoad"`
+}
+
+// HAREntry is one request and its response
+type HAREntry struct {
+	Pageref         string         `json:"pageref"`
+	StartedDateTime time.Time      `json:"startedDateTime"`
+	Time            float64        `json:"time"`
+	Request         HARRequest     `json:"request"`
+	Response        HARResponse    `json:"response"`
+	Cache           struct{}       `json:"cache"`
+	Timings         NetworkTimings `json:"timings"`
+	ServerIPAddress string         `json:"serverIPAddress,omitempty"`
+	ResourceType    string         `json:"_resourceType,omitempty"`
+	Error           string         `json:"_error,omitempty"`
+}
+
+// HARRequest is the request half of an entry
+type HARRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	QueryString []HARNameValue `json:"queryString"`
+	PostData    *HARPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+// HARResponse is the response half of an entry
+type HARResponse struct {
+	Status       int            `json:"status"`
+	StatusText   string         `json:"statusText"`
+	HTTPVersion  string         `json:"httpVersion"`
+	Cookies      []HARNameValue `json:"cookies"`
+	Headers      []HARNameValue `json:"headers"`
+	Content      HARContent     `json:"content"`
+	RedirectURL  string         `json:"redirectURL"`
+	HeadersSize  int            `json:"headersSize"`
+	BodySize     int            `json:"bodySize"`
+	TransferSize int64          `json:"_transferSize"`
+}
+
+// HARNameValue is a header, query parameter or cookie
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// HARPostData is the body of a request
+type HARPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+// HARContent describes a response body, the body itself is fetched separately
+type HARContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+}
+
+// ExportHAR builds a HAR of the finished requests of every page in the session
+func (s *Session) ExportHAR() *HAR {
+	har := &HAR{Log: HARLog{
+		Version: "1.2",
+		Creator: HARCreator{Name: "browser-query-ai", Version: "1.0"},
+		Pages:   make([]HARPage, 0),
+		Entries: make([]HAREntry, 0),
+	}}
+
+	for _, pageID := range s.Pages() {
+		buffer := s.networkBufferFor(pageID)
+		if buffer == nil {
+			continue
+		}
+
+		entries, _, _ := buffer.query(NetworkQuery{})
+		page, harEntries := buildHARPage(pageID, entries)
+		if len(harEntries) == 0 {
+			continue
+		}
+		har.Log.Pages = append(har.Log.Pages, page)
+		har.Log.Entries = append(har.Log.Entries, harEntries...)
+	}
+
+	sort.SliceStable(har.Log.Entries, func(i, j int) bool {
+		return har.Log.Entries[i].StartedDateTime.Before(har.Log.Entries[j].StartedDateTime)
+	})
+
+	return har
+}
+
+// buildHARPage converts the entries of one page, skipping requests still in flight
+func buildHARPage(pageID string, entries []*NetworkEntry) (HARPage, []HAREntry) {
+	page := HARPage{
+		ID:          pageID,
+		Title:       pageID,
+		PageTimings: HARPageTimings{OnContentLoad: -1, OnLoad: -1},
+	}
+
+	harEntries := make([]HAREntry, 0, len(entries))
+	for _, entry := range entries {
+		if entry.State == NetworkPending {
+			continue
+		}
+
+		if len(harEntries) == 0 {
+			page.StartedDateTime = entry.StartedAt
+		}
+		if page.Title == pageID && entry.ResourceType == "Document" {
+			page.Title = entry.URL
+		}
+
+		harEntries = append(harEntries, harEntry(pageID, entry))
+	}
+
+	return page, harEntries
+}
+
+// harEntry converts one finished network entry
+func harEntry(pageID string, entry *NetworkEntry) HAREntry {
+	version := httpVersion(entry.Protocol)
+
+	request := HARRequest{
+		Method:      entry.Method,
+		URL:         entry.URL,
+		HTTPVersion: version,
+		Cookies:     make([]HARNameValue, 0),
+		Headers:     harHeaders(entry.RequestHeaders),
+		QueryString: harQueryString(entry.URL),
+		HeadersSize: -1,
+		BodySize:    len(entry.PostData),
+	}
+	if entry.PostData != "" {
+		request.PostData = &HARPostData{
+			MimeType: headerValue(entry.RequestHeaders, "Content-Type"),
+			Text:     entry.PostData,
+		}
+	}
+
+	response := HARResponse{
+		Status:       entry.Status,
+		StatusText:   entry.StatusText,
+		HTTPVersion:  version,
+		Cookies:      make([]HARNameValue, 0),
+		Headers:      harHeaders(entry.ResponseHeaders),
+		Content:      HARContent{Size: entry.BodySize, MimeType: entry.MimeType},
+		RedirectURL:  entry.RedirectURL,
+		HeadersSize:  -1,
+		BodySize:     -1,
+		TransferSize: entry.TransferSize,
+	}
+
+	converted := HAREntry{
+		Pageref:         pageID,
+		StartedDateTime: entry.StartedAt,
+		Time:            entry.DurationMs,
+		Request:         request,
+		Response:        response,
+		ServerIPAddress: strings.Trim(entry.RemoteIP, "[]"),
+		ResourceType:    strings.ToLower(entry.ResourceType),
+		Error:           entry.Error,
+	}
+	if entry.Timings != nil {
+		converted.Timings = *entry.Timings
+	}
+
+	return converted
+}
+
+// harHeaders lists headers sorted by name so archives diff cleanly
+func harHeaders(headers map[string]string) []HARNameValue {
+	list := make([]HARNameValue, 0, len(headers))
+	for name, value := range headers {
+		list = append(list, HARNameValue{Name: name, Value: value})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// harQueryString lists the query parameters of a URL in order
+func harQueryString(rawURL string) []HARNameValue {
+	list := make([]HARNameValue, 0)
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.RawQuery == "" {
+		return list
+	}
+
+	for _, pair := range strings.Split(parsed.RawQuery, "&") {
+		name, value, _ := strings.Cut(pair, "=")
+		if name == "" {
+			continue
+		}
+		if unescaped, err := url.QueryUnescape(name); err == nil {
+			name = unescaped
+		}
+		if unescaped, err := url.QueryUnescape(value); err == nil {
+			value = unescaped
+		}
+		list = append(list, HARNameValue{Name: name, Value: value})
+	}
+	return list
+}
+
+// headerValue looks up a header case-insensitively
+func headerValue(headers map[string]string, name string) string {
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
+
+// httpVersion converts a CDP protocol name such as h2 into the HAR form
+func httpVersion(protocol string) string {
+	switch strings.ToLower(protocol) {
+	case "":
+		return ""
+	case "h2":
+		return "HTTP/2"
+	case "h3", "h3-29":
+		return "HTTP/3"
+	default:
+		return strings.ToUpper(protocol)
+	}
+}
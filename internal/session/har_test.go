@@ -0,0 +1,93 @@
+package session
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// TestBuildHARPage tests converting network entries into HAR entries
+func TestBuildHARPage(t *testing.T) {
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	entries := []*NetworkEntry{
+		{
+			RequestID:       "1",
+			URL:             "https://example.com/search?q=go+lang&page=2",
+			Method:          "GET",
+			ResourceType:    "Document",
+			State:           NetworkComplete,
+			StartedAt:       start,
+			DurationMs:      120,
+			Status:          200,
+			StatusText:      "OK",
+			MimeType:        "text/html",
+			Protocol:        "h2",
+			RemoteIP:        "[2606:2800::1]",
+			TransferSize:    900,
+			BodySize:        1256,
+			RequestHeaders:  map[string]string{"User-Agent": "test", "Accept": "text/html"},
+			ResponseHeaders: map[string]string{"Content-Type": "text/html"},
+			Timings:         &NetworkTimings{Blocked: 1, DNS: -1, Connect: -1, SSL: -1, Send: 1, Wait: 100, Receive: 18},
+		},
+		{
+			RequestID:      "2",
+			URL:            "https://example.com/api",
+			Method:         "POST",
+			ResourceType:   "Fetch",
+			State:          NetworkFailed,
+			StartedAt:      start.Add(time.Second),
+			Error:          "net::ERR_FAILED",
+			RequestHeaders: map[string]string{"content-type": "application/json"},
+			PostData:       `{"a":1}`,
+			Timings:        &NetworkTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1},
+		},
+		{RequestID: "3", URL: "https://example.com/slow", State: NetworkPending, StartedAt: start.Add(2 * time.Second)},
+	}
+
+	page, harEntries := buildHARPage("page_1", entries)
+	if page.Title != "https://example.com/search?q=go+lang&page=2" || !page.StartedDateTime.Equal(start) {
+		t.Errorf("unexpected page %+v", page)
+	}
+	if len(harEntries) != 2 {
+		t.Fatalf("expected the pending request to be skipped, got %d entries", len(harEntries))
+	}
+
+	document := harEntries[0]
+	if document.Pageref != "page_1" || document.Request.HTTPVersion != "HTTP/2" || document.ServerIPAddress != "2606:2800::1" {
+		t.Errorf("unexpected document entry %+v", document)
+	}
+	if len(document.Request.Headers) != 2 || document.Request.Headers[0].Name != "Accept" {
+		t.Errorf("expected sorted request headers, got %v", document.Request.Headers)
+	}
+	query := document.Request.QueryString
+	if len(query) != 2 || query[0] != (HARNameValue{Name: "q", Value: "go lang"}) || query[1] != (HARNameValue{Name: "page", Value: "2"}) {
+		t.Errorf("unexpected query string %v", query)
+	}
+	if document.Response.Content.Size != 1256 || document.Response.TransferSize != 900 || document.ResourceType != "document" {
+		t.Errorf("unexpected response %+v", document.Response)
+	}
+
+	failed := harEntries[1]
+	if failed.Error != "net::ERR_FAILED" || failed.Request.PostData == nil || failed.Request.PostData.MimeType != "application/json" {
+		t.Errorf("unexpected failed entry %+v", failed)
+	}
+
+	// Required HAR fields are present even when empty
+	data, err := json.Marshal(HAR{Log: HARLog{Version: "1.2", Pages: []HARPage{page}, Entries: harEntries}})
+	if err != nil {
+		t.Fatalf("failed to marshal HAR: %v", err)
+	}
+	var decoded struct {
+		Log struct {
+			Entries []map[string]json.RawMessage `json:"entries"`
+		} `json:"log"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("failed to decode HAR: %v", err)
+	}
+	for _, field := range []string{"startedDateTime", "time", "request", "response", "cache", "timings"} {
+		if _, ok := decoded.Log.Entries[1][field]; !ok {
+			t.Errorf("expected %s in HAR entry", field)
+		}
+	}
+}
@@ -0,0 +1,607 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Action types accepted by PerformAction
+const (
+	ActionClick  = "click"
+	ActionType   = "type"
+	ActionPress  = "press"
+	ActionHover  = "hover"
+	ActionScroll = "scroll"
+)
+
+// ActionTarget identifies what an action applies to
+// Set one of Selector, XPath, Ref or BackendNodeID to target an element, or X and Y for a viewport point.
+type ActionTarget struct {
+	Selector      string   `json:"selector,omitempty"`
+	XPath         string   `json:"xpath,omitempty"`
+	Ref           string   `json:"ref,omitempty"`             // From the accessibility tree, e.g. "e12"
+	BackendNodeID int      `json:"backend_node_id,omitempty"` // From the accessibility tree
+	X             *float64 `json:"x,omitempty"`
+	Y             *float64 `json:"y,omitempty"`
+}
+
+// Action is a single trusted input action on a page
+type Action struct {
+	Type string `json:"type"`
+	ActionTarget
+
+	Text       string  `json:"text,omitempty"`        // type: text to insert
+	Clear      bool    `json:"clear,omitempty"`       // type: replace the current value instead of appending
+	Key        string  `json:"key,omitempty"`         // press: key or combination such as "Enter" or "Control+A"
+	Button     string  `json:"button,omitempty"`      // click: left (default), right or middle
+	ClickCount int     `json:"click_count,omitempty"` // click: 2 for a double click
+	DeltaX     float64 `json:"delta_x,omitempty"`     // scroll: horizontal pixels
+	DeltaY     float64 `json:"delta_y,omitempty"`     // scroll: vertical pixels
+
+	WaitFor *WaitCondition `json:"wait_for,omitempty"` // Optional, condition to wait for after the action
+}
+
+// ActionResult describes where an action was performed
+type ActionResult struct {
+	PageID string  `json:"page_id"`
+	Type   string  `json:"type"`
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+}
+
+// elementCheckJS scrolls the element into view and reports whether it can receive input.
+// It runs with the element as `this` and returns the viewport point to act on.
+const elementCheckJS = `function(requireEnabled) {
+  var el = this.nodeType === Node.ELEMENT_NODE ? this : this.parentElement;
+  if (!el || !el.isConnected) return { state: 'detached' };
+
+  el.scrollIntoView({ block: 'center', inline: 'center', behavior: 'instant' });
+
+  var rect = el.getBoundingClientRect();
+  var style = getComputedStyle(el);
+  if (rect.width === 0 || rect.height === 0 || style.visibility === 'hidden' || style.display === 'none') {
+    return { state: 'hidden' };
+  }
+
+  var x = rect.left + rect.width / 2;
+  var y = rect.top + rect.height / 2;
+  if (x < 0 || y < 0 || x > innerWidth || y > innerHeight) return { state: 'hidden' };
+
+  if (requireEnabled && (el.disabled || el.getAttribute('aria-disabled') === 'true' || el.closest('fieldset:disabled'))) {
+    return { state: 'disabled' };
+  }
+
+  var hit = document.elementFromPoint(x, y);
+  if (hit && hit !== el && !el.contains(hit)) {
+    var by = hit.tagName.toLowerCase();
+    if (hit.id) by += '#' + hit.id;
+    else if (hit.classList.length) by += '.' + hit.classList[0];
+    return { state: 'covered', by: by };
+  }
+
+  return { state: 'ok', x: x, y: y };
+}`
+
+// clearValueJS selects the current value of an input so typed text replaces it
+const clearValueJS = `function() {
+  var el = this;
+  if (typeof el.select === 'function') {
+    el.select();
+  } else if (el.isContentEditable) {
+    var range = document.createRange();
+    range.selectNodeContents(el);
+    var selection = getSelection();
+    selection.removeAllRanges();
+    selection.addRange(range);
+  }
+}`
+
+// PerformAction dispatches a trusted input action on a page
+// Element targets are scrolled into view and checked for visibility (and enabled state for click/type/press) first.
+func (s *Session) PerformAction(ctx context.Context, targetID string, action Action) (*ActionResult, error) {
+	switch action.Type {
+	case ActionClick, ActionType, ActionPress, ActionHover, ActionScroll:
+	default:
+		return nil, fmt.Errorf("%w: unknown action type %q", ErrInvalidAction, action.Type)
+	}
+
+	// Validate action specific fields before touching the page
+	var keys []keyDefinition
+	switch action.Type {
+	case ActionPress:
+		var err error
+		if keys, err = parseKeyCombo(action.Key); err != nil {
+			return nil, err
+		}
+	case ActionClick:
+		if _, ok := mouseButtons[buttonOrDefault(action.Button)]; !ok {
+			return nil, fmt.Errorf("%w: unknown mouse button %q", ErrInvalidAction, action.Button)
+		}
+	case ActionType:
+		if action.Text == "" && !action.Clear {
+			return nil, fmt.Errorf("%w: text is required", ErrInvalidAction)
+		}
+	}
+
+	// Start tracking before acting so network idle sees the requests the action causes
+	var waiter *waiter
+	if action.WaitFor != nil {
+		var err error
+		if waiter, err = s.newWaiter(ctx, targetID, *action.WaitFor); err != nil {
+			return nil, err
+		}
+		defer waiter.stop()
+	}
+
+	result := &ActionResult{
+		PageID: targetID,
+		Type:   action.Type,
+	}
+
+	objectID, err := s.resolveElement(ctx, targetID, action.ActionTarget)
+	if err != nil {
+		return nil, err
+	}
+
+	if objectID != "" {
+		defer s.releaseObject(targetID, objectID)
+
+		requireEnabled := action.Type == ActionClick || action.Type == ActionType || action.Type == ActionPress
+		result.X, result.Y, err = s.prepareElement(ctx, targetID, objectID, requireEnabled)
+		if err != nil {
+			return nil, err
+		}
+	} else if action.X != nil && action.Y != nil {
+		result.X, result.Y = *action.X, *action.Y
+	} else if action.Type == ActionClick || action.Type == ActionHover {
+		return nil, fmt.Errorf("%w: %s needs a selector, xpath, ref, backend_node_id or x/y", ErrInvalidAction, action.Type)
+	}
+
+	switch action.Type {
+	case ActionHover:
+		err = s.dispatchMouse(ctx, targetID, "mouseMoved", result.X, result.Y, "none", 0)
+	case ActionClick:
+		err = s.click(ctx, targetID, result.X, result.Y, buttonOrDefault(action.Button), max(action.ClickCount, 1))
+	case ActionScroll:
+		err = s.scroll(ctx, targetID, result.X, result.Y, action.DeltaX, action.DeltaY)
+	case ActionType:
+		err = s.typeText(ctx, targetID, objectID, result.X, result.Y, action)
+	case ActionPress:
+		if objectID != "" {
+			if _, err := s.callFunctionOn(ctx, targetID, objectID, "function() { this.focus(); }"); err != nil {
+				return nil, fmt.Errorf("failed to focus element: %w", err)
+			}
+		}
+		err = s.pressKeys(ctx, targetID, keys)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to %s: %w", action.Type, err)
+	}
+
+	// Anything but hovering and scrolling may change the page
+	if action.Type != ActionHover && action.Type != ActionScroll {
+		s.InvalidatePageAnalysis(targetID)
+	}
+
+	if waiter != nil {
+		if _, err := waiter.wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// resolveElement returns a remote object ID for the element the target points to
+// Returns an empty ID when the target is a plain viewport point (or empty).
+func (s *Session) resolveElement(ctx context.Context, targetID string, target ActionTarget) (string, error) {
+	switch {
+	case target.Selector != "":
+		selector, _ := json.Marshal(target.Selector)
+		return s.evaluateElement(ctx, targetID, "document.querySelector("+string(selector)+")", "selector "+target.Selector)
+	case target.XPath != "":
+		xpath, _ := json.Marshal(target.XPath)
+		expression := "document.evaluate(" + string(xpath) + ", document, null, XPathResult.FIRST_ORDERED_NODE_TYPE, null).singleNodeValue"
+		return s.evaluateElement(ctx, targetID, expression, "xpath "+target.XPath)
+	case target.Ref != "":
+		backendNodeID, err := s.resolveElementRef(ctx, targetID, target.Ref)
+		if err != nil {
+			return "", err
+		}
+
+		objectID, err := s.resolveBackendNode(ctx, targetID, backendNodeID)
+		if errors.Is(err, ErrElementNotFound) {
+			// The element was removed from the document after the ref was issued
+			return "", fmt.Errorf("%w: %s is no longer in the document", ErrStaleElementRef, target.Ref)
+		}
+		return objectID, err
+	case target.BackendNodeID != 0:
+		return s.resolveBackendNode(ctx, targetID, target.BackendNodeID)
+	}
+
+	return "", nil
+}
+
+// resolveBackendNode returns a remote object ID for a backend DOM node
+func (s *Session) resolveBackendNode(ctx context.Context, targetID string, backendNodeID int) (string, error) {
+	params := map[string]interface{}{
+		"backendNodeId": backendNodeID,
+	}
+
+	result, err := s.CDPClient.SendCommandToTargetContext(ctx, targetID, "DOM.resolveNode", params)
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("failed to resolve node: %w", err)
+		}
+		// The node no longer exists in this document
+		return "", fmt.Errorf("%w: backend node %d", ErrElementNotFound, backendNodeID)
+	}
+
+	var response struct {
+		Object struct {
+			ObjectID string `json:"objectId"`
+		} `json:"object"`
+	}
+	if err := json.Unmarshal(result, &response); err != nil {
+		return "", fmt.Errorf("failed to parse resolve node response: %w", err)
+	}
+
+	return response.Object.ObjectID, nil
+}
+
+// evaluateElement evaluates an expression that yields an element or null
+func (s *Session) evaluateElement(ctx context.Context, targetID string, expression string, description string) (string, error) {
+	params := map[string]interface{}{
+		"expression":    expression,
+		"returnByValue": false,
+	}
+
+	result, err := s.CDPClient.SendCommandToTargetContext(ctx, targetID, "Runtime.evaluate", params)
+	if err != nil {
+		return "", fmt.Errorf("failed to find element: %w", err)
+	}
+
+	var response struct {
+		Result struct {
+			Subtype  string `json:"subtype"`
+			ObjectID string `json:"objectId"`
+		} `json:"result"`
+		ExceptionDetails *struct {
+			Exception struct {
+				Description string `json:"description"`
+			} `json:"exception"`
+		} `json:"exceptionDetails,omitempty"`
+	}
+
+	if err := json.Unmarshal(result, &response); err != nil {
+		return "", fmt.Errorf("failed to parse element lookup: %w", err)
+	}
+
+	// A malformed selector or xpath throws
+	if response.ExceptionDetails != nil {
+		return "", fmt.Errorf("%w: invalid %s: %s", ErrInvalidAction, description, response.ExceptionDetails.Exception.Description)
+	}
+
+	if response.Result.ObjectID == "" || response.Result.Subtype == "null" {
+		return "", fmt.Errorf("%w: %s", ErrElementNotFound, description)
+	}
+
+	return response.Result.ObjectID, nil
+}
+
+// prepareElement scrolls the element into view and checks that it can receive input
+// Returns the viewport coordinates of the element's center.
+func (s *Session) prepareElement(ctx context.Context, targetID string, objectID string, requireEnabled bool) (float64, float64, error) {
+	value, err := s.callFunctionOn(ctx, targetID, objectID, elementCheckJS, requireEnabled)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to check element: %w", err)
+	}
+
+	var check struct {
+		State string  `json:"state"`
+		By    string  `json:"by"`
+		X     float64 `json:"x"`
+		Y     float64 `json:"y"`
+	}
+	if err := json.Unmarshal(value, &check); err != nil {
+		return 0, 0, fmt.Errorf("failed to parse element check: %w", err)
+	}
+
+	switch check.State {
+	case "ok":
+		return check.X, check.Y, nil
+	case "detached":
+		return 0, 0, fmt.Errorf("%w: element is no longer attached to the document", ErrElementNotFound)
+	case "hidden":
+		return 0, 0, fmt.Errorf("%w: element has no visible box", ErrElementNotVisible)
+	case "covered":
+		return 0, 0, fmt.Errorf("%w: element is covered by %s", ErrElementNotVisible, check.By)
+	case "disabled":
+		return 0, 0, ErrElementDisabled
+	}
+
+	return 0, 0, fmt.Errorf("unexpected element state %q", check.State)
+}
+
+// callFunctionOn calls a JavaScript function with the remote object as `this` and returns its JSON value
+func (s *Session) callFunctionOn(ctx context.Context, targetID string, objectID string, function string, args ...interface{}) (json.RawMessage, error) {
+	arguments := make([]map[string]interface{}, len(args))
+	for i, arg := range args {
+		arguments[i] = map[string]interface{}{"value": arg}
+	}
+
+	params := map[string]interface{}{
+		"objectId":            objectID,
+		"functionDeclaration": function,
+		"arguments":           arguments,
+		"returnByValue":       true,
+		"awaitPromise":        true,
+	}
+
+	result, err := s.CDPClient.SendCommandToTargetContext(ctx, targetID, "Runtime.callFunctionOn", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		Result struct {
+			Value json.RawMessage `json:"value"`
+		} `json:"result"`
+		ExceptionDetails interface{} `json:"exceptionDetails,omitempty"`
+	}
+
+	if err := json.Unmarshal(result, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse function result: %w", err)
+	}
+
+	if response.ExceptionDetails != nil {
+		return nil, fmt.Errorf("javascript execution error: %v", response.ExceptionDetails)
+	}
+
+	return response.Result.Value, nil
+}
+
+// releaseObject frees a remote object, errors are irrelevant because objects die with the document anyway
+func (s *Session) releaseObject(targetID string, objectID string) {
+	params := map[string]interface{}{
+		"objectId": objectID,
+	}
+	s.CDPClient.SendCommandToTarget(targetID, "Runtime.releaseObject", params)
+}
+
+// mouseButtons maps button names to the CDP "buttons" bitmask
+var mouseButtons = map[string]int{
+	"left":   1,
+	"right":  2,
+	"middle": 4,
+}
+
+func buttonOrDefault(button string) string {
+	if button == "" {
+		return "left"
+	}
+	return button
+}
+
+// dispatchMouse sends a single Input.dispatchMouseEvent
+func (s *Session) dispatchMouse(ctx context.Context, targetID string, eventType string, x, y float64, button string, clickCount int) error {
+	params := map[string]interface{}{
+		"type":       eventType,
+		"x":          x,
+		"y":          y,
+		"button":     button,
+		"buttons":    mouseButtons[button],
+		"clickCount": clickCount,
+	}
+
+	_, err := s.CDPClient.SendCommandToTargetContext(ctx, targetID, "Input.dispatchMouseEvent", params)
+	return err
+}
+
+// click moves the mouse to the point and presses and releases the button
+func (s *Session) click(ctx context.Context, targetID string, x, y float64, button string, clickCount int) error {
+	if err := s.dispatchMouse(ctx, targetID, "mouseMoved", x, y, "none", 0); err != nil {
+		return err
+	}
+
+	// Each press/release pair carries its click number so double clicks register
+	for i := 1; i <= clickCount; i++ {
+		if err := s.dispatchMouse(ctx, targetID, "mousePressed", x, y, button, i); err != nil {
+			return err
+		}
+		if err := s.dispatchMouse(ctx, targetID, "mouseReleased", x, y, button, i); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// scroll sends a mouse wheel event at the point
+func (s *Session) scroll(ctx context.Context, targetID string, x, y float64, deltaX, deltaY float64) error {
+	params := map[string]interface{}{
+		"type":   "mouseWheel",
+		"x":      x,
+		"y":      y,
+		"deltaX": deltaX,
+		"deltaY": deltaY,
+	}
+
+	_, err := s.CDPClient.SendCommandToTargetContext(ctx, targetID, "Input.dispatchMouseEvent", params)
+	return err
+}
+
+// typeText focuses the element (by clicking it) and inserts text like an IME would
+// Without an element target the text goes to whatever has focus.
+func (s *Session) typeText(ctx context.Context, targetID string, objectID string, x, y float64, action Action) error {
+	if objectID != "" {
+		if err := s.click(ctx, targetID, x, y, "left", 1); err != nil {
+			return err
+		}
+
+		if action.Clear {
+			if _, err := s.callFunctionOn(ctx, targetID, objectID, clearValueJS); err != nil {
+				return fmt.Errorf("failed to clear element: %w", err)
+			}
+		}
+	}
+
+	// Inserting nothing over a selection would leave the old value in place
+	if action.Clear && action.Text == "" {
+		return s.pressKeys(ctx, targetID, []keyDefinition{namedKeys["Backspace"]})
+	}
+
+	params := map[string]interface{}{
+		"text": action.Text,
+	}
+
+	_, err := s.CDPClient.SendCommandToTargetContext(ctx, targetID, "Input.insertText", params)
+	return err
+}
+
+// keyDefinition describes a key for Input.dispatchKeyEvent
+type keyDefinition struct {
+	Key      string
+	Code     string
+	KeyCode  int
+	Text     string
+	Modifier int // Non-zero for modifier keys
+}
+
+// Modifier bits used by Input.dispatchKeyEvent
+const (
+	modifierAlt   = 1
+	modifierCtrl  = 2
+	modifierMeta  = 4
+	modifierShift = 8
+)
+
+// namedKeys are the non-printable keys understood by press
+var namedKeys = map[string]keyDefinition{
+	"Enter":      {Key: "Enter", Code: "Enter", KeyCode: 13, Text: "\r"},
+	"Tab":        {Key: "Tab", Code: "Tab", KeyCode: 9},
+	"Backspace":  {Key: "Backspace", Code: "Backspace", KeyCode: 8},
+	"Delete":     {Key: "Delete", Code: "Delete", KeyCode: 46},
+	"Escape":     {Key: "Escape", Code: "Escape", KeyCode: 27},
+	"Space":      {Key: " ", Code: "Space", KeyCode: 32, Text: " "},
+	"ArrowUp":    {Key: "ArrowUp", Code: "ArrowUp", KeyCode: 38},
+	"ArrowDown":  {Key: "ArrowDown", Code: "ArrowDown", KeyCode: 40},
+	"ArrowLeft":  {Key: "ArrowLeft", Code: "ArrowLeft", KeyCode: 37},
+	"ArrowRight": {Key: "ArrowRight", Code: "ArrowRight", KeyCode: 39},
+	"Home":       {Key: "Home", Code: "Home", KeyCode: 36},
+	"End":        {Key: "End", Code: "End", KeyCode: 35},
+	"PageUp":     {Key: "PageUp", Code: "PageUp", KeyCode: 33},
+	"PageDown":   {Key: "PageDown", Code: "PageDown", KeyCode: 34},
+	"Shift":      {Key: "Shift", Code: "ShiftLeft", KeyCode: 16, Modifier: modifierShift},
+	"Control":    {Key: "Control", Code: "ControlLeft", KeyCode: 17, Modifier: modifierCtrl},
+	"Alt":        {Key: "Alt", Code: "AltLeft", KeyCode: 18, Modifier: modifierAlt},
+	"Meta":       {Key: "Meta", Code: "MetaLeft", KeyCode: 91, Modifier: modifierMeta},
+}
+
+// parseKeyCombo parses "Enter", "a" or "Control+Shift+A" into the keys to hold, in order
+func parseKeyCombo(combo string) ([]keyDefinition, error) {
+	if combo == "" {
+		return nil, fmt.Errorf("%w: key is required", ErrInvalidAction)
+	}
+
+	parts := strings.Split(combo, "+")
+
+	// "+" on its own or after modifiers ("Shift++") is the plus key
+	if rest, ok := strings.CutSuffix(combo, "+"); ok && (rest == "" || strings.HasSuffix(rest, "+")) {
+		parts = []string{"+"}
+		if modifiers := strings.TrimSuffix(rest, "+"); modifiers != "" {
+			parts = append(strings.Split(modifiers, "+"), "+")
+		}
+	}
+
+	keys := make([]keyDefinition, 0, len(parts))
+	for i, part := range parts {
+		key, ok := namedKeys[part]
+		if !ok {
+			runes := []rune(part)
+			if len(runes) != 1 {
+				return nil, fmt.Errorf("%w: unknown key %q", ErrInvalidAction, part)
+			}
+			key = printableKey(runes[0])
+		}
+
+		// Only the last key may be a regular key, the rest must be modifiers
+		if i < len(parts)-1 && key.Modifier == 0 {
+			return nil, fmt.Errorf("%w: %q is not a modifier in %q", ErrInvalidAction, part, combo)
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+// printableKey builds the definition of a single character key
+func printableKey(r rune) keyDefinition {
+	key := keyDefinition{
+		Key:  string(r),
+		Text: string(r),
+	}
+
+	switch {
+	case r >= 'a' && r <= 'z':
+		key.Code = "Key" + strings.ToUpper(string(r))
+		key.KeyCode = int(r - 'a' + 'A')
+	case r >= 'A' && r <= 'Z':
+		key.Code = "Key" + string(r)
+		key.KeyCode = int(r)
+	case r >= '0' && r <= '9':
+		key.Code = "Digit" + string(r)
+		key.KeyCode = int(r)
+	}
+
+	return key
+}
+
+// pressKeys holds the keys down in order and releases them in reverse
+func (s *Session) pressKeys(ctx context.Context, targetID string, keys []keyDefinition) error {
+	modifiers := 0
+	for _, key := range keys {
+		modifiers |= key.Modifier
+	}
+
+	// Shortcuts such as Control+A must not insert text
+	insertsText := modifiers&(modifierCtrl|modifierAlt|modifierMeta) == 0
+
+	for _, key := range keys {
+		eventType := "rawKeyDown"
+		params := map[string]interface{}{
+			"key":                   key.Key,
+			"code":                  key.Code,
+			"windowsVirtualKeyCode": key.KeyCode,
+			"modifiers":             modifiers,
+		}
+		if key.Text != "" && insertsText {
+			eventType = "keyDown"
+			params["text"] = key.Text
+		}
+		params["type"] = eventType
+
+		if _, err := s.CDPClient.SendCommandToTargetContext(ctx, targetID, "Input.dispatchKeyEvent", params); err != nil {
+			return err
+		}
+	}
+
+	for i := len(keys) - 1; i >= 0; i-- {
+		params := map[string]interface{}{
+			"type":                  "keyUp",
+			"key":                   keys[i].Key,
+			"code":                  keys[i].Code,
+			"windowsVirtualKeyCode": keys[i].KeyCode,
+			"modifiers":             modifiers,
+		}
+
+		if _, err := s.CDPClient.SendCommandToTargetContext(ctx, targetID, "Input.dispatchKeyEvent", params); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
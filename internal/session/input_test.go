@@ -0,0 +1,52 @@
+package session
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestParseKeyCombo tests parsing of keys and modifier combinations for press actions
+func TestParseKeyCombo(t *testing.T) {
+	tests := []struct {
+		combo string
+		keys  []string
+	}{
+		{"Enter", []string{"Enter"}},
+		{"a", []string{"a"}},
+		{"Control+A", []string{"Control", "A"}},
+		{"Control+Shift+ArrowLeft", []string{"Control", "Shift", "ArrowLeft"}},
+		{"+", []string{"+"}},
+		{"Shift++", []string{"Shift", "+"}},
+	}
+
+	for _, tt := range tests {
+		keys, err := parseKeyCombo(tt.combo)
+		if err != nil {
+			t.Errorf("parseKeyCombo(%q) failed: %v", tt.combo, err)
+			continue
+		}
+
+		if len(keys) != len(tt.keys) {
+			t.Errorf("parseKeyCombo(%q) returned %d keys, expected %d", tt.combo, len(keys), len(tt.keys))
+			continue
+		}
+
+		for i, key := range keys {
+			if key.Key != tt.keys[i] {
+				t.Errorf("parseKeyCombo(%q) key %d is %q, expected %q", tt.combo, i, key.Key, tt.keys[i])
+			}
+		}
+	}
+
+	// Letters carry the virtual key code of their uppercase form
+	keys, _ := parseKeyCombo("a")
+	if keys[0].Code != "KeyA" || keys[0].KeyCode != 65 {
+		t.Errorf("expected KeyA/65, got %s/%d", keys[0].Code, keys[0].KeyCode)
+	}
+
+	for _, combo := range []string{"", "Enterr", "a+Control", "Control+"} {
+		if _, err := parseKeyCombo(combo); !errors.Is(err, ErrInvalidAction) {
+			t.Errorf("parseKeyCombo(%q) expected ErrInvalidAction, got %v", combo, err)
+		}
+	}
+}
@@ -0,0 +1,456 @@
+package session
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/dhruvsoni1802/browser-query-ai/internal/cdp"
+)
+
+// Interception rule actions
+const (
+	InterceptBlock   = "block"   // Fail the request as blocked by the client
+	InterceptHeaders = "headers" // Add or override request headers and let it continue
+	InterceptFulfill = "fulfill" // Answer with a canned response without hitting the network
+)
+
+// interceptResourceTypes are the CDP resource types a rule can match, keyed by lower case name
+var interceptResourceTypes = map[string]bool{
+	"document": true, "stylesheet": true, "image": true, "media": true, "font": true,
+	"script": true, "texttrack": true, "xhr": true, "fetch": true, "prefetch": true,
+	"eventsource": true, "websocket": true, "manifest": true, "signedexchange": true,
+	"ping": true, "cspviolationreport": true, "preflight": true, "other": true,
+}
+
+// InterceptionRule decides what happens to the requests it matches
+// A rule matches when the URL glob (if any) and one of the resource types (if any) match.
+// Header rules all apply, the first matching block or fulfill rule ends the evaluation.
+type InterceptionRule struct {
+	ID            string            `json:"id,omitempty"` // Generated from the position when empty
+	Action        string            `json:"action"`
+	URL           string            `json:"url,omitempty"`            // Glob, * stops at / and ** does not
+	ResourceTypes []string          `json:"resource_types,omitempty"` // Such as image, font or media
+	Headers       map[string]string `json:"headers,omitempty"`        // For headers
+	Response      *MockResponse     `json:"response,omitempty"`       // For fulfill
+}
+
+// MockResponse is the canned response of a fulfill rule
+type MockResponse struct {
+	Status      int               `json:"status,omitempty"` // Defaults to 200
+	Headers     map[string]string `json:"headers,omitempty"`
+	ContentType string            `json:"content_type,omitempty"`
+	Body        string            `json:"body,omitempty"`
+	BodyBase64  string            `json:"body_base64,omitempty"` // For binary bodies, instead of body
+}
+
+// InterceptionRuleStats is a rule and how many requests it matched
+type InterceptionRuleStats struct {
+	InterceptionRule
+	Matched int64 `json:"matched"`
+}
+
+// InterceptionStats describes the interception rules of a session
+type InterceptionStats struct {
+	Rules       []InterceptionRuleStats `json:"rules"`
+	Intercepted int64                   `json:"intercepted"` // Requests evaluated since the rules were set
+}
+
+// interceptRule is a validated rule ready for matching
+type interceptRule struct {
+	InterceptionRule
+	pattern *regexp.Regexp
+	types   map[string]bool
+	body    []byte
+	matched atomic.Int64
+}
+
+// interceptor holds the compiled rules of a session
+type interceptor struct {
+	rules       []*interceptRule
+	intercepted atomic.Int64
+}
+
+// ValidateInterceptionRules checks that rules can be applied
+func ValidateInterceptionRules(rules []InterceptionRule) error {
+	_, err := compileInterceptionRules(rules)
+	return err
+}
+
+// compileInterceptionRules validates rules and prepares them for matching
+func compileInterceptionRules(rules []InterceptionRule) (*interceptor, error) {
+	if len(rules) > MaxInterceptionRules {
+		return nil, fmt.Errorf("%w: at most %d rules are allowed", ErrInvalidInterceptionRule, MaxInterceptionRules)
+	}
+
+	compiled := &interceptor{rules: make([]*interceptRule, 0, len(rules))}
+	seen := make(map[string]bool)
+	for i, rule := range rules {
+		if rule.ID == "" {
+			rule.ID = fmt.Sprintf("rule_%d", i+1)
+		}
+		if seen[rule.ID] {
+			return nil, fmt.Errorf("%w: duplicate id %q", ErrInvalidInterceptionRule, rule.ID)
+		}
+		seen[rule.ID] = true
+
+		c, err := compileInterceptionRule(rule)
+		if err != nil {
+			return nil, fmt.Errorf("%w: rule %s: %v", ErrInvalidInterceptionRule, rule.ID, err)
+		}
+		compiled.rules = append(compiled.rules, c)
+	}
+
+	return compiled, nil
+}
+
+// compileInterceptionRule validates one rule
+func compileInterceptionRule(rule InterceptionRule) (*interceptRule, error) {
+	c := &interceptRule{InterceptionRule: rule}
+
+	if rule.URL != "" {
+		c.pattern = globToRegexp(rule.URL)
+	}
+	if len(rule.ResourceTypes) > 0 {
+		c.types = make(map[string]bool)
+		for _, resourceType := range rule.ResourceTypes {
+			resourceType = strings.ToLower(resourceType)
+			if !interceptResourceTypes[resourceType] {
+				return nil, fmt.Errorf("unknown resource type %q", resourceType)
+			}
+			c.types[resourceType] = true
+		}
+	}
+
+	switch rule.Action {
+	case InterceptBlock:
+		if len(rule.Headers) > 0 || rule.Response != nil {
+			return nil, fmt.Errorf("block takes no headers or response")
+		}
+	case InterceptHeaders:
+		if len(rule.Headers) == 0 {
+			return nil, fmt.Errorf("headers requires at least one header")
+		}
+		if rule.Response != nil {
+			return nil, fmt.Errorf("headers takes no response")
+		}
+	case InterceptFulfill:
+		response := rule.Response
+		if response == nil {
+			return nil, fmt.Errorf("fulfill requires a response")
+		}
+		if len(rule.Headers) > 0 {
+			return nil, fmt.Errorf("fulfill takes response headers, not request headers")
+		}
+		if response.Status != 0 && (response.Status < 100 || response.Status > 599) {
+			return nil, fmt.Errorf("status must be between 100 and 599")
+		}
+		if response.Body != "" && response.BodyBase64 != "" {
+			return nil, fmt.Errorf("body and body_base64 are mutually exclusive")
+		}
+		c.body = []byte(response.Body)
+		if response.BodyBase64 != "" {
+			body, err := base64.StdEncoding.DecodeString(response.BodyBase64)
+			if err != nil {
+				return nil, fmt.Errorf("body_base64 is not valid base64")
+			}
+			c.body = body
+		}
+	case "":
+		return nil, fmt.Errorf("action is required")
+	default:
+		return nil, fmt.Errorf("unknown action %q", rule.Action)
+	}
+
+	return c, nil
+}
+
+// matches reports whether the rule applies to a request
+func (r *interceptRule) matches(url string, resourceType string) bool {
+	if r.pattern != nil && !r.pattern.MatchString(url) {
+		return false
+	}
+	if r.types != nil && !r.types[strings.ToLower(resourceType)] {
+		return false
+	}
+	return true
+}
+
+// evaluate returns the block or fulfill rule ending the evaluation, if any, and the matching header rules
+func (i *interceptor) evaluate(url string, resourceType string) (*interceptRule, []*interceptRule) {
+	i.intercepted.Add(1)
+
+	var headers []*interceptRule
+	for _, rule := range i.rules {
+		if !rule.matches(url, resourceType) {
+			continue
+		}
+		rule.matched.Add(1)
+		if rule.Action != InterceptHeaders {
+			return rule, headers
+		}
+		headers = append(headers, rule)
+	}
+	return nil, headers
+}
+
+// stats reports the rules and their counters
+func (i *interceptor) stats() *InterceptionStats {
+	stats := &InterceptionStats{Rules: make([]InterceptionRuleStats, 0)}
+	if i == nil {
+		return stats
+	}
+
+	stats.Intercepted = i.intercepted.Load()
+	for _, rule := range i.rules {
+		stats.Rules = append(stats.Rules, InterceptionRuleStats{
+			InterceptionRule: rule.InterceptionRule,
+			Matched:          rule.matched.Load(),
+		})
+	}
+	return stats
+}
+
+// SetInterceptionRules replaces the interception rules of the session and resets their counters
+// Pages start or stop pausing requests as needed, an empty list turns interception off.
+func (s *Session) SetInterceptionRules(ctx context.Context, rules []InterceptionRule) (*InterceptionStats, error) {
+	compiled, err := compileInterceptionRules(rules)
+	if err != nil {
+		return nil, err
+	}
+	if len(compiled.rules) == 0 {
+		compiled = nil
+	}
+
+	s.interceptMu.Lock()
+	s.interceptor = compiled
+	s.interceptMu.Unlock()
+
+	for _, pageID := range s.Pages() {
+		if compiled == nil {
+			s.disableInterception(ctx, pageID)
+			continue
+		}
+		if err := s.startInterception(pageID); err != nil {
+			return nil, err
+		}
+	}
+
+	return compiled.stats(), nil
+}
+
+// InterceptionStats returns the interception rules of the session and how often they matched
+func (s *Session) InterceptionStats() *InterceptionStats {
+	s.interceptMu.Lock()
+	defer s.interceptMu.Unlock()
+
+	return s.interceptor.stats()
+}
+
+// startInterception makes a page pause its requests for the session's rules
+// It does nothing when the session has no rules or the page is already intercepted.
+func (s *Session) startInterception(targetID string) error {
+	s.interceptMu.Lock()
+	active := s.interceptor != nil && s.intercepts[targetID] == nil
+	s.interceptMu.Unlock()
+	if !active {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	sub, err := s.CDPClient.SubscribeTarget(ctx, targetID, "Fetch.requestPaused", 256)
+	if err != nil {
+		cancel()
+		return fmt.Errorf("failed to subscribe to paused requests: %w", err)
+	}
+
+	// Pause every request before it is sent, the rules decide what happens next
+	params := map[string]interface{}{
+		"patterns": []map[string]interface{}{{"urlPattern": "*", "requestStage": "Request"}},
+	}
+	if _, err := s.CDPClient.SendCommandToTargetContext(ctx, targetID, "Fetch.enable", params); err != nil {
+		cancel()
+		return fmt.Errorf("failed to enable request interception: %w", err)
+	}
+
+	s.interceptMu.Lock()
+	if s.intercepts == nil {
+		s.intercepts = make(map[string]context.CancelFunc)
+	}
+	if old := s.intercepts[targetID]; old != nil {
+		old()
+	}
+	s.intercepts[targetID] = cancel
+	s.interceptMu.Unlock()
+
+	go s.intercept(ctx, targetID, sub.C)
+
+	return nil
+}
+
+// intercept resolves paused requests until ctx is done or the client closes
+func (s *Session) intercept(ctx context.Context, targetID string, paused <-chan *cdp.Event) {
+	for {
+		select {
+		case event, ok := <-paused:
+			if !ok {
+				return
+			}
+			// Requests are resolved concurrently, one slow round trip must not hold up the page
+			go s.resolvePausedRequest(ctx, targetID, event.Params)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// resolvePausedRequest applies the rules to one paused request
+func (s *Session) resolvePausedRequest(ctx context.Context, targetID string, raw json.RawMessage) {
+	var params cdpRequestPaused
+	if err := json.Unmarshal(raw, &params); err != nil {
+		slog.Warn("failed to parse paused request", "page_id", targetID, "error", err)
+		return
+	}
+
+	s.interceptMu.Lock()
+	rules := s.interceptor
+	s.interceptMu.Unlock()
+
+	method := "Fetch.continueRequest"
+	command := map[string]interface{}{"requestId": params.RequestID}
+
+	if rules != nil {
+		final, headerRules := rules.evaluate(params.Request.URL, params.ResourceType)
+
+		switch {
+		case final != nil && final.Action == InterceptBlock:
+			method = "Fetch.failRequest"
+			command["errorReason"] = "BlockedByClient"
+		case final != nil && final.Action == InterceptFulfill:
+			method = "Fetch.fulfillRequest"
+			command["responseCode"] = final.Response.status()
+			command["responseHeaders"] = cdpHeaderEntries(final.Response.headers())
+			command["body"] = base64.StdEncoding.EncodeToString(final.body)
+		case len(headerRules) > 0:
+			headers := params.Request.Headers
+			for _, rule := range headerRules {
+				headers = overrideHeaders(headers, rule.Headers)
+			}
+			command["headers"] = cdpHeaderEntries(headers)
+		}
+	}
+
+	if _, err := s.CDPClient.SendCommandToTargetContext(ctx, targetID, method, command); err != nil {
+		// The page may have navigated away or closed, the request is gone either way
+		slog.Debug("failed to resolve paused request", "page_id", targetID, "method", method, "error", err)
+	}
+}
+
+// disableInterception turns request pausing off on a page that stays open
+func (s *Session) disableInterception(ctx context.Context, targetID string) {
+	s.interceptMu.Lock()
+	active := s.intercepts[targetID] != nil
+	s.interceptMu.Unlock()
+	if !active {
+		return
+	}
+
+	// Disable before the listener stops so no request is left paused without one
+	if _, err := s.CDPClient.SendCommandToTargetContext(ctx, targetID, "Fetch.disable", nil); err != nil {
+		slog.Warn("failed to disable request interception", "page_id", targetID, "error", err)
+	}
+	s.stopInterception(targetID)
+}
+
+// stopInterception stops listening for the paused requests of a page
+func (s *Session) stopInterception(targetID string) {
+	s.interceptMu.Lock()
+	defer s.interceptMu.Unlock()
+
+	if cancel := s.intercepts[targetID]; cancel != nil {
+		cancel()
+		delete(s.intercepts, targetID)
+	}
+}
+
+// stopAllInterceptions stops listening for paused requests on every page of the session
+func (s *Session) stopAllInterceptions() {
+	s.interceptMu.Lock()
+	defer s.interceptMu.Unlock()
+
+	for targetID, cancel := range s.intercepts {
+		cancel()
+		delete(s.intercepts, targetID)
+	}
+}
+
+// status returns the response status, 200 when unset
+func (m *MockResponse) status() int {
+	if m.Status == 0 {
+		return http.StatusOK
+	}
+	return m.Status
+}
+
+// headers returns the response headers with the content type added
+func (m *MockResponse) headers() map[string]string {
+	headers := make(map[string]string, len(m.Headers)+1)
+	for name, value := range m.Headers {
+		headers[name] = value
+	}
+	if m.ContentType != "" {
+		headers = overrideHeaders(headers, map[string]string{"Content-Type": m.ContentType})
+	}
+	return headers
+}
+
+// overrideHeaders returns headers with the overrides set, replacing existing ones case-insensitively
+func overrideHeaders(headers map[string]string, overrides map[string]string) map[string]string {
+	merged := make(map[string]string, len(headers)+len(overrides))
+	for name, value := range headers {
+		merged[name] = value
+	}
+	for name, value := range overrides {
+		for existing := range merged {
+			if strings.EqualFold(existing, name) {
+				delete(merged, existing)
+			}
+		}
+		merged[name] = value
+	}
+	return merged
+}
+
+// cdpHeaderEntries converts headers into the Fetch domain's list form, sorted by name
+func cdpHeaderEntries(headers map[string]string) []map[string]string {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	entries := make([]map[string]string, 0, len(names))
+	for _, name := range names {
+		entries = append(entries, map[string]string{"name": name, "value": headers[name]})
+	}
+	return entries
+}
+
+// cdpRequestPaused is the Fetch.requestPaused event
+type cdpRequestPaused struct {
+	RequestID    string `json:"requestId"`
+	ResourceType string `json:"resourceType"`
+	Request      struct {
+		URL     string            `json:"url"`
+		Method  string            `json:"method"`
+		Headers map[string]string `json:"headers"`
+	} `json:"request"`
+}
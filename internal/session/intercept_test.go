@@ -0,0 +1,109 @@
+package session
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestCompileInterceptionRules tests rule validation
+func TestCompileInterceptionRules(t *testing.T) {
+	valid := []InterceptionRule{
+		{Action: InterceptBlock, ResourceTypes: []string{"Image", "font"}},
+		{Action: InterceptBlock, URL: "**doubleclick.net**"},
+		{Action: InterceptHeaders, Headers: map[string]string{"Authorization": "Bearer x"}},
+		{Action: InterceptFulfill, URL: "**/api/**", Response: &MockResponse{Status: 204}},
+		{Action: InterceptFulfill, Response: &MockResponse{BodyBase64: "aGVsbG8="}},
+	}
+	compiled, err := compileInterceptionRules(valid)
+	if err != nil {
+		t.Fatalf("expected valid rules, got %v", err)
+	}
+	if compiled.rules[0].ID != "rule_1" || compiled.rules[4].ID != "rule_5" {
+		t.Errorf("expected generated IDs, got %s and %s", compiled.rules[0].ID, compiled.rules[4].ID)
+	}
+	if string(compiled.rules[4].body) != "hello" {
+		t.Errorf("expected the decoded body, got %q", compiled.rules[4].body)
+	}
+
+	invalid := [][]InterceptionRule{
+		{{}},
+		{{Action: "redirect"}},
+		{{Action: InterceptBlock, ResourceTypes: []string{"video"}}},
+		{{Action: InterceptBlock, Headers: map[string]string{"A": "b"}}},
+		{{Action: InterceptHeaders}},
+		{{Action: InterceptFulfill}},
+		{{Action: InterceptFulfill, Response: &MockResponse{Status: 42}}},
+		{{Action: InterceptFulfill, Response: &MockResponse{Body: "a", BodyBase64: "YQ=="}}},
+		{{Action: InterceptFulfill, Response: &MockResponse{BodyBase64: "not base64!"}}},
+		{{ID: "same", Action: InterceptBlock}, {ID: "same", Action: InterceptBlock}},
+	}
+	for _, rules := range invalid {
+		if err := ValidateInterceptionRules(rules); !errors.Is(err, ErrInvalidInterceptionRule) {
+			t.Errorf("expected %+v to be invalid, got %v", rules, err)
+		}
+	}
+}
+
+// TestInterceptorEvaluate tests rule order, header accumulation and match counters
+func TestInterceptorEvaluate(t *testing.T) {
+	compiled, err := compileInterceptionRules([]InterceptionRule{
+		{ID: "auth", Action: InterceptHeaders, URL: "https://api.example.com/**", Headers: map[string]string{"Authorization": "Bearer x"}},
+		{ID: "images", Action: InterceptBlock, ResourceTypes: []string{"image"}},
+		{ID: "mock", Action: InterceptFulfill, URL: "https://api.example.com/items", Response: &MockResponse{Body: "[]"}},
+		{ID: "trace", Action: InterceptHeaders, Headers: map[string]string{"X-Trace": "1"}},
+	})
+	if err != nil {
+		t.Fatalf("compile failed: %v", err)
+	}
+
+	tests := []struct {
+		url, resourceType string
+		final             string
+		headers           []string
+	}{
+		{"https://example.com/logo.png", "Image", "images", nil},
+		{"https://api.example.com/items", "Fetch", "mock", []string{"auth"}},
+		{"https://api.example.com/users", "XHR", "", []string{"auth", "trace"}},
+		{"https://example.com/", "Document", "", []string{"trace"}},
+	}
+	for _, tt := range tests {
+		final, headers := compiled.evaluate(tt.url, tt.resourceType)
+		finalID := ""
+		if final != nil {
+			finalID = final.ID
+		}
+		var headerIDs []string
+		for _, rule := range headers {
+			headerIDs = append(headerIDs, rule.ID)
+		}
+		if finalID != tt.final || len(headerIDs) != len(tt.headers) {
+			t.Errorf("%s: expected %q with headers %v, got %q with %v", tt.url, tt.final, tt.headers, finalID, headerIDs)
+		}
+	}
+
+	stats := compiled.stats()
+	matched := map[string]int64{}
+	for _, rule := range stats.Rules {
+		matched[rule.ID] = rule.Matched
+	}
+	if stats.Intercepted != 4 || matched["auth"] != 2 || matched["images"] != 1 || matched["mock"] != 1 || matched["trace"] != 2 {
+		t.Errorf("unexpected counters %d %v", stats.Intercepted, matched)
+	}
+}
+
+// TestOverrideHeaders tests that overrides replace existing headers case-insensitively
+func TestOverrideHeaders(t *testing.T) {
+	headers := overrideHeaders(map[string]string{"user-agent": "chrome", "Accept": "*/*"}, map[string]string{"User-Agent": "bot", "X-Extra": "1"})
+	if len(headers) != 3 || headers["User-Agent"] != "bot" || headers["Accept"] != "*/*" || headers["X-Extra"] != "1" {
+		t.Errorf("unexpected headers %v", headers)
+	}
+
+	response := &MockResponse{Headers: map[string]string{"content-type": "text/plain"}, ContentType: "application/json"}
+	entries := cdpHeaderEntries(response.headers())
+	if len(entries) != 1 || entries[0]["name"] != "Content-Type" || entries[0]["value"] != "application/json" {
+		t.Errorf("expected the content type to override the header, got %v", entries)
+	}
+	if response.status() != 200 {
+		t.Errorf("expected the default status 200, got %d", response.status())
+	}
+}
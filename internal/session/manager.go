@@ -4,10 +4,14 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/dhruvsoni1802/browser-query-ai/internal/cdp"
@@ -19,22 +23,34 @@ type Manager struct {
 	sessions   map[string]*Session
 	cdpClients map[int]*cdp.Client
 	mu         sync.RWMutex
+
+	// Port → browser contexts being created outside mu for sessions that don't own them yet, protected by mu
+	contextSetups map[int]int
 	ctx        context.Context
 	cancel     context.CancelFunc
-	repo       *storage.SessionRepository
+	repo       storage.SessionStore
 
 	// Session limits
 	maxSessionsPerAgent int 
 	maxTotalSessions    int
+
+	// Reconciliation, one pass runs at a time
+	reconcileMu   sync.Mutex
+	lastReconcile atomic.Pointer[ReconcileReport]
+
+	// Picks the port a stored session is resumed on, nil keeps the stored port
+	resumePort func(stored int) (int, error)
 }
 
 // NewManager creates a new session manager
-func NewManager(repo *storage.SessionRepository) *Manager {
+// repo may be nil, sessions are then kept in memory only and can't be closed and resumed
+func NewManager(repo storage.SessionStore) *Manager {
 	ctx, cancel := context.WithCancel(context.Background())
 	
 	return &Manager{
 		sessions:   make(map[string]*Session),
 		cdpClients: make(map[int]*cdp.Client),
+		contextSetups: make(map[int]int),
 		ctx:        ctx,
 		cancel:     cancel,
 		repo:        repo,
@@ -43,6 +59,12 @@ func NewManager(repo *storage.SessionRepository) *Manager {
 	}
 }
 
+// SetResumePort sets how the port of a resumed session is picked from the port it was stored with
+// It must be called before sessions are resumed, the browser process of a stored port may have been stopped.
+func (m *Manager) SetResumePort(resumePort func(stored int) (int, error)) {
+	m.resumePort = resumePort
+}
+
 // generateSessionID creates a unique session identifier
 func generateSessionID() (string, error) {
 	// Generate 16 random bytes
@@ -64,29 +86,106 @@ func generateSessionID() (string, error) {
 // GetOrCreateCDPClient gets existing client or creates new one for a port
 func (m *Manager) GetOrCreateCDPClient(port int) (*cdp.Client, error) {
 	// Check if the client already exists for this port
+	// A closed client can't reconnect on its own anymore, so replace it
 	client, exists := m.cdpClients[port]
-	if exists {
+	if exists && client.State() != cdp.StateClosed {
 		return client, nil
 	}
 
-	// If the client does not exist, discover the WebSocket URL
+	// If the client does not exist, create one that discovers the WebSocket URL from the port
+	// The URL is discovered again on every reconnect
 	// TODO: Change this later so that we can use something other than localhost such as actual IP address of the machine
-	wsURL, err := cdp.GetWebSocketURL("localhost", strconv.Itoa(port))
-	if err != nil {
-		return nil, fmt.Errorf("failed to discover WebSocket URL: %w", err)
-	}
-
-	// Create a new CDP client and connect to it
-	client = cdp.NewClient(wsURL)
+	client = cdp.NewClientForPort("localhost", strconv.Itoa(port))
 	if err := client.Connect(); err != nil {
 		return nil, fmt.Errorf("failed to connect to CDP client: %w", err)
 	}
 
+	// Mark sessions on this port degraded while the connection is down
+	client.OnStateChange(func(state cdp.ConnectionState) {
+		m.handleConnectionState(client, port, state)
+	})
+
 	// Add the client to the manager
 	m.cdpClients[port] = client
 	return client, nil
 }
 
+// beginContextSetup marks a browser context about to be created on port without holding m.mu
+// Reconciliation leaves the port's contexts alone until endContextSetup. The caller must hold m.mu.
+func (m *Manager) beginContextSetup(port int) {
+	m.contextSetups[port]++
+}
+
+// endContextSetup is called once the context created after beginContextSetup is owned by a session or gone
+// The caller must hold m.mu.
+func (m *Manager) endContextSetup(port int) {
+	m.contextSetups[port]--
+	if m.contextSetups[port] <= 0 {
+		delete(m.contextSetups, port)
+	}
+}
+
+// handleConnectionState updates the sessions of a browser process when its CDP connection changes state
+// After a reconnect a session only becomes active again if its browser context survived, a browser
+// that restarted on the same port lost them and the session is interrupted instead.
+func (m *Manager) handleConnectionState(client *cdp.Client, port int, state cdp.ConnectionState) {
+	var status SessionStatus
+	var contexts []string
+	switch state {
+	case cdp.StateDisconnected:
+		status = SessionDegraded
+	case cdp.StateConnected:
+		status = SessionActive
+
+		// Ask the browser before taking the lock, the command may take a while
+		var err error
+		contexts, err = client.GetBrowserContexts()
+		if err != nil {
+			slog.Warn("failed to list browser contexts after reconnect, sessions stay degraded", "port", port, "error", err)
+			return
+		}
+	default:
+		return
+	}
+
+	m.mu.Lock()
+	affected := make(map[SessionStatus][]*Session)
+	for _, session := range m.sessions {
+		if session.ProcessPort != port {
+			continue
+		}
+		// Only recover sessions we degraded ourselves
+		if status == SessionActive && session.Status != SessionDegraded {
+			continue
+		}
+
+		newStatus := status
+		if status == SessionActive && !slices.Contains(contexts, session.ContextID) {
+			newStatus = SessionInterrupted
+		}
+		session.Status = newStatus
+		affected[newStatus] = append(affected[newStatus], session)
+	}
+	m.mu.Unlock()
+
+	for status, sessions := range affected {
+		slog.Warn("CDP connection state changed",
+			"port", port,
+			"state", state,
+			"status", status,
+			"sessions", len(sessions))
+
+		if m.repo == nil {
+			continue
+		}
+		for _, session := range sessions {
+			if err := m.repo.UpdateSessionStatus(session.ID, string(status)); err != nil {
+				slog.Warn("failed to update session status", "session_id", session.ID, "error", err)
+			}
+		}
+	}
+}
+
 // CreateSession creates a new isolated browsing session
 func (m *Manager) CreateSession(port int) (*Session, error) {
 	// Acquire write lock to prevent concurrent access
@@ -156,12 +255,17 @@ func (m *Manager) DestroySession(sessionID string) error {
 	// If session is in memory, clean up browser resources
 	if exists {
 		// Close all pages
-		for _, pageID := range session.PageIDs {
+		for _, pageID := range session.Pages() {
 			if err := session.CDPClient.CloseTarget(pageID); err != nil {
 				slog.Warn("failed to close page", "page_id", pageID, "error", err)
 			}
 		}
 
+		session.stopAllConsoleCaptures()
+		session.stopAllNetworkCaptures()
+		session.stopAllInterceptions()
+		session.stopAllPageTracking()
+
 		// Dispose browser context
 		if err := session.CDPClient.DisposeBrowserContext(session.ContextID); err != nil {
 			slog.Warn("failed to dispose browser context", "error", err)
@@ -303,51 +407,18 @@ func (m *Manager) CreateSessionWithName(agentID, sessionName string, port int) (
 	if agentID == "" {
 		return nil, fmt.Errorf("agent_id is required")
 	}
-	
-	// Check session limits
-	if err := m.checkSessionLimits(agentID); err != nil {
-		return nil, err
-	}
-	
-	// If name provided, check for conflicts
-	if sessionName != "" && m.repo != nil {
-		exists, err := m.repo.CheckSessionNameExists(agentID, sessionName)
-		if err != nil {
-			return nil, fmt.Errorf("failed to check session name: %w", err)
-		}
-		if exists {
-			return nil, ErrSessionNameConflict
-		}
-	}
-	
-	// Create the session (existing logic)
-	m.mu.Lock()
-	defer m.mu.Unlock()
 
 	sessionID, err := generateSessionID()
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate session ID: %w", err)
 	}
 
-	client, err := m.GetOrCreateCDPClient(port)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get or create CDP client: %w", err)
-	}
-
-	contextID, err := client.CreateBrowserContext()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create browser context: %w", err)
-	}
-
-	// Create session with name
 	session := &Session{
 		ID:                sessionID,
-		Name:              sessionName,  // ← ADD (will be auto-generated if empty)
-		AgentID:           agentID,      // ← ADD
+		Name:              sessionName,
+		AgentID:           agentID,
 		ProcessPort:       port,
-		ContextID:         contextID,
 		PageIDs:           []string{},
-		CDPClient:         client,
 		CreatedAt:         time.Now(),
 		LastActivity:      time.Now(),
 		Status:            SessionActive,
@@ -359,6 +430,35 @@ func (m *Manager) CreateSessionWithName(agentID, sessionName string, port int) (
 		session.Name = m.generateSessionName(session)
 	}
 
+	// Claim the name and a slot in the agent's limit in one step, so concurrent creates can't both pass
+	if err := m.reserveSession(session); err != nil {
+		return nil, err
+	}
+
+	// Create the browser context
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.sessions) >= m.maxTotalSessions {
+		m.releaseReservation(session)
+		return nil, fmt.Errorf("global session limit reached (%d)", m.maxTotalSessions)
+	}
+
+	client, err := m.GetOrCreateCDPClient(port)
+	if err != nil {
+		m.releaseReservation(session)
+		return nil, fmt.Errorf("failed to get or create CDP client: %w", err)
+	}
+
+	contextID, err := client.CreateBrowserContext()
+	if err != nil {
+		m.releaseReservation(session)
+		return nil, fmt.Errorf("failed to create browser context: %w", err)
+	}
+
+	session.ContextID = contextID
+	session.CDPClient = client
+
 	// Add to manager
 	m.sessions[sessionID] = session
 
@@ -372,40 +472,44 @@ func (m *Manager) CreateSessionWithName(agentID, sessionName string, port int) (
 
 	slog.Info("session created", 
 		"session_id", session.ID,
-		"session_name", session.Name,
+		"session_name", session.GetName(),
 		"agent_id", agentID,
 		"port", port)
 
 	return session, nil
 }
 
-// Helper: Check if agent is within session limits
-func (m *Manager) checkSessionLimits(agentID string) error {
-	// Check total sessions
-	m.mu.RLock()
-	totalSessions := len(m.sessions)
-	m.mu.RUnlock()
-	
-	if totalSessions >= m.maxTotalSessions {
-		return fmt.Errorf("global session limit reached (%d)", m.maxTotalSessions)
+// reserveSession claims the session's name and counts it against the agent's limit
+func (m *Manager) reserveSession(session *Session) error {
+	if m.repo == nil {
+		return nil
 	}
-	
-	// Check per-agent limit (from Redis)
-	if m.repo != nil {
-		count, err := m.repo.CountAgentSessions(agentID)
-		if err != nil {
-			slog.Warn("failed to count agent sessions", "error", err)
-			// Don't block on Redis error
-			return nil
-		}
-		
-		if count >= m.maxSessionsPerAgent {
-			return fmt.Errorf("%w: agent has %d sessions (max %d)", 
-				ErrSessionLimitReached, count, m.maxSessionsPerAgent)
-		}
+
+	err := m.repo.ReserveSession(session.AgentID, session.Name, session.ID, m.maxSessionsPerAgent)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, storage.ErrNameTaken):
+		return ErrSessionNameConflict
+	case errors.Is(err, storage.ErrAgentLimitReached):
+		return fmt.Errorf("%w: agent has reached the max of %d sessions",
+			ErrSessionLimitReached, m.maxSessionsPerAgent)
+	default:
+		return fmt.Errorf("failed to reserve session name: %w", err)
+	}
+}
+
+// releaseReservation gives back the reservation of a session that could not be created
+func (m *Manager) releaseReservation(session *Session) {
+	if m.repo == nil {
+		return
+	}
+
+	if err := m.repo.ReleaseReservation(session.AgentID, session.Name, session.ID); err != nil {
+		slog.Warn("failed to release session reservation",
+			"session_id", session.ID,
+			"error", err)
 	}
-	
-	return nil
 }
 
 // Helper: Auto-generate session name
@@ -417,18 +521,12 @@ func (m *Manager) generateSessionName(session *Session) string {
 
 // Helper: Convert Session to SessionState for Redis
 func (m *Manager) sessionToState(s *Session) *storage.SessionState {
-	// Collect page states
-	pages := make([]storage.PageState, len(s.PageIDs))
-	for i, pageID := range s.PageIDs {
-		pages[i] = storage.PageState{
-			PageID: pageID,
-			// URL and Title could be fetched if needed
-		}
-	}
-	
-	return &storage.SessionState{
+	// Collect page states, URL and title as last seen by the page trackers
+	pages := s.PageStates()
+
+	state := &storage.SessionState{
 		SessionID:    s.ID,
-		SessionName:  s.Name,
+		SessionName:  s.GetName(),
 		AgentID:      s.AgentID,
 		ProcessPort:  s.ProcessPort,
 		ContextID:    s.ContextID,
@@ -437,22 +535,51 @@ func (m *Manager) sessionToState(s *Session) *storage.SessionState {
 		Status:       string(s.Status),
 		Pages:        pages,
 	}
+
+	// Emulation survives a close so the resumed session looks the same to sites
+	if emulation := s.Emulation(); emulation != nil {
+		if data, err := json.Marshal(emulation); err == nil {
+			state.Emulation = data
+		}
+	}
+
+	return state
+}
+
+// savePages writes the open pages of a session to the repository
+// Sessions no longer in the manager are skipped, their pages were saved when they were closed.
+func (m *Manager) savePages(session *Session) {
+	if m.repo == nil {
+		return
+	}
+
+	m.mu.RLock()
+	_, active := m.sessions[session.ID]
+	m.mu.RUnlock()
+	if !active {
+		return
+	}
+
+	if err := m.repo.SavePages(session.ID, session.PageStates()); err != nil {
+		slog.Warn("failed to save pages", "session_id", session.ID, "error", err)
+	}
 }
 
 // ResumeSessionByName resumes a session by agent ID and session name
-func (m *Manager) ResumeSessionByName(agentID, sessionName string) (*Session, error) {
+// resurrected is true when the session was brought back from the store rather than found in memory.
+func (m *Manager) ResumeSessionByName(agentID, sessionName string) (session *Session, resurrected bool, err error) {
 	if agentID == "" || sessionName == "" {
-		return nil, fmt.Errorf("agent_id and session_name are required")
+		return nil, false, fmt.Errorf("agent_id and session_name are required")
 	}
 	
 	// Look up session ID by name
 	if m.repo == nil {
-		return nil, fmt.Errorf("Redis not configured")
+		return nil, false, fmt.Errorf("Redis not configured")
 	}
 	
 	sessionID, err := m.repo.GetSessionByName(agentID, sessionName)
 	if err != nil {
-		return nil, fmt.Errorf("session not found: %w", err)
+		return nil, false, fmt.Errorf("session not found: %w", err)
 	}
 	
 	// Try to get from memory first
@@ -472,51 +599,69 @@ func (m *Manager) ResumeSessionByName(agentID, sessionName string) (*Session, er
 			"session_name", sessionName,
 			"agent_id", agentID)
 		
-		return session, nil
+		return session, false, nil
 	}
 	
 	// Session not in memory - resurrect from Redis
 	state, err := m.repo.GetSession(sessionID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load session from Redis: %w", err)
+		return nil, false, fmt.Errorf("failed to load session from Redis: %w", err)
 	}
 	
 	// Resurrect the session
-	session, err = m.resurrectSession(state)
+	session, resurrected, err = m.resurrectSession(state)
 	if err != nil {
-		return nil, fmt.Errorf("failed to resurrect session: %w", err)
+		return nil, false, fmt.Errorf("failed to resurrect session: %w", err)
 	}
 	
 	slog.Info("resurrected session from Redis", 
 		"session_id", sessionID,
 		"session_name", sessionName,
-		"agent_id", agentID)
+		"agent_id", agentID,
+		"port", session.ProcessPort)
 	
-	return session, nil
+	return session, resurrected, nil
 }
 
-func (m *Manager) resurrectSession(state *storage.SessionState) (*Session, error) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	
+// resurrectSession recreates a stored session in a new browser context and adds it to the manager
+// The browser work happens without holding m.mu, restoring cookies and localStorage loads a page per origin.
+// resurrected is false when a concurrent resume added the session first, that session is returned.
+func (m *Manager) resurrectSession(state *storage.SessionState) (session *Session, resurrected bool, err error) {
+	// Pick the port, the stored one may belong to a process that was stopped
+	port := state.ProcessPort
+	if m.resumePort != nil {
+		port, err = m.resumePort(state.ProcessPort)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to select browser: %w", err)
+		}
+	}
+
 	// Get or create CDP client for the port
-	client, err := m.GetOrCreateCDPClient(state.ProcessPort)
+	m.mu.Lock()
+	client, err := m.GetOrCreateCDPClient(port)
+	if err == nil {
+		m.beginContextSetup(port)
+	}
+	m.mu.Unlock()
 	if err != nil {
-		return nil, fmt.Errorf("failed to reconnect to browser: %w", err)
+		return nil, false, fmt.Errorf("failed to reconnect to browser: %w", err)
 	}
 	
 	// Create a new browser context (old one was disposed when session was closed)
 	contextID, err := client.CreateBrowserContext()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create browser context: %w", err)
+		m.mu.Lock()
+		m.endContextSetup(port)
+		m.mu.Unlock()
+		return nil, false, fmt.Errorf("failed to create browser context: %w", err)
 	}
 	
 	// Recreate session object
-	session := &Session{
+	session = &Session{
 		ID:                state.SessionID,
 		Name:              state.SessionName,  // Should not be empty!
 		AgentID:           state.AgentID,
-		ProcessPort:       state.ProcessPort,
+		ProcessPort:       port,
 		ContextID:         contextID,  // Use new context ID
 		PageIDs:           []string{},
 		CDPClient:         client,
@@ -526,10 +671,41 @@ func (m *Manager) resurrectSession(state *storage.SessionState) (*Session, error
 		pageAnalysisCache: make(map[string]*PageStructure),
 	}
 	
-	// Don't restore pages - they were closed when session was closed
+	// Pages were closed with the old context, RestorePages reopens them on request
+	session.restorablePages = state.Pages
+
+	// Restore cookies and localStorage before any page can load without them
+	if len(state.Cookies) > 0 || len(state.LocalStorage) > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), BrowserStateTimeout)
+		browser := &browserState{cookies: state.Cookies, localStorage: state.LocalStorage}
+		if err := session.restoreBrowserState(ctx, browser); err != nil {
+			slog.Warn("failed to restore browser state", "session_id", session.ID, "error", err)
+		}
+		cancel()
+	}
+
+	// Restore emulation, pages opened from now on pick it up
+	if len(state.Emulation) > 0 {
+		var emulation EmulationOptions
+		if err := json.Unmarshal(state.Emulation, &emulation); err != nil {
+			slog.Warn("failed to restore emulation", "session_id", session.ID, "error", err)
+		} else if err := session.SetEmulation(context.Background(), &emulation); err != nil {
+			slog.Warn("failed to restore emulation", "session_id", session.ID, "error", err)
+		}
+	}
 	
-	// Add to manager
+	// Add to manager, unless a concurrent resume got there first
+	m.mu.Lock()
+	m.endContextSetup(port)
+	if existing, exists := m.sessions[session.ID]; exists {
+		m.mu.Unlock()
+		if err := client.DisposeBrowserContext(contextID); err != nil {
+			slog.Warn("failed to dispose browser context", "error", err)
+		}
+		return existing, false, nil
+	}
 	m.sessions[session.ID] = session
+	m.mu.Unlock()
 	
 	// Update status to ACTIVE in Redis and save new context ID
 	if m.repo != nil {
@@ -549,7 +725,49 @@ func (m *Manager) resurrectSession(state *storage.SessionState) (*Session, error
 		}
 	}
 	
-	return session, nil
+	return session, true, nil
+}
+
+// RestorePages reopens the pages a resurrected session had open when it was closed
+// Returns the new page ID of every reopened page, keyed by its old page ID.
+func (m *Manager) RestorePages(ctx context.Context, sessionID string) (map[string]string, error) {
+	// Get the session from the manager
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	restored := make(map[string]string)
+	for _, page := range session.takeRestorablePages() {
+		if err := ctx.Err(); err != nil {
+			return restored, err
+		}
+
+		pageID, err := m.openPage(ctx, session)
+		if err != nil {
+			slog.Warn("failed to reopen page", "session_id", sessionID, "page_id", page.PageID, "error", err)
+			continue
+		}
+		restored[page.PageID] = pageID
+
+		// A blank page is reopened as is
+		if page.URL == "" || page.URL == "about:blank" {
+			continue
+		}
+
+		// A page that fails to load stays open, like one that failed to navigate
+		if _, err := session.NavigatePage(ctx, pageID, page.URL, nil); err != nil {
+			slog.Warn("failed to reload reopened page", "page_id", pageID, "url", page.URL, "error", err)
+		}
+	}
+
+	// Update the last activity time of the session
+	session.UpdateActivity()
+
+	// The repository now lists the new page IDs
+	m.savePages(session)
+
+	return restored, nil
 }
 
 // ListAgentSessions returns all sessions for an agent
@@ -628,17 +846,24 @@ func (m *Manager) RenameSession(sessionID, newName string) error {
 		return fmt.Errorf("cannot rename session without agent_id")
 	}
 	
-	oldName := session.Name
+	// One rename of the session at a time, the store releases the name the session holds
+	session.renameMu.Lock()
+	defer session.renameMu.Unlock()
+
+	oldName := session.GetName()
 	
 	// Update in Redis
 	if m.repo != nil {
 		if err := m.repo.RenameSession(sessionID, session.AgentID, oldName, newName); err != nil {
+			if errors.Is(err, storage.ErrNameTaken) {
+				return fmt.Errorf("%w: '%s'", ErrSessionNameConflict, newName)
+			}
 			return fmt.Errorf("failed to rename session in Redis: %w", err)
 		}
 	}
 	
 	// Update in memory
-	session.Name = newName
+	session.setName(newName)
 	
 	slog.Info("session renamed", 
 		"session_id", sessionID,
@@ -649,51 +874,89 @@ func (m *Manager) RenameSession(sessionID, newName string) error {
 }
 
 // CloseSession disconnects from browser but keeps in Redis
+// The browser work happens without holding m.mu, m.mu is only taken to remove the session.
 func (m *Manager) CloseSession(sessionID string) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
+	m.mu.RLock()
 	session, exists := m.sessions[sessionID]
+	m.mu.RUnlock()
 	if !exists {
 		return fmt.Errorf("session not found: %s", sessionID)
 	}
 
+	// Remember what was open, pages never reopened since the last resume stay restorable
+	pages := session.PageStates()
+	if len(pages) == 0 {
+		pages = session.takeRestorablePages()
+	}
+	session.stopAllPageTracking()
+
+	// Snapshot cookies and localStorage while the pages and context still exist
+	var browser *browserState
+	if m.repo != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), BrowserStateTimeout)
+		var err error
+		if browser, err = session.captureBrowserState(ctx); err != nil {
+			// The previous snapshot is kept, it is better than none
+			slog.Warn("failed to capture browser state", "session_id", sessionID, "error", err)
+		}
+		cancel()
+	}
+
 	// Close all pages
-	for _, pageID := range session.PageIDs {
+	for _, pageID := range session.Pages() {
 		if err := session.CDPClient.CloseTarget(pageID); err != nil {
 			slog.Warn("failed to close page", "page_id", pageID, "error", err)
 		}
 	}
 
+	session.stopAllConsoleCaptures()
+	session.stopAllNetworkCaptures()
+	session.stopAllInterceptions()
+
 	// Dispose browser context
 	if err := session.CDPClient.DisposeBrowserContext(session.ContextID); err != nil {
 		slog.Warn("failed to dispose browser context", "error", err)
 	}
 
-	// Update status to IDLE in Redis
+	// Remove from memory only, a concurrent close or delete that got there first has the last word
+	m.mu.Lock()
+	if m.sessions[sessionID] != session {
+		m.mu.Unlock()
+		return fmt.Errorf("session not found: %s", sessionID)
+	}
+	delete(m.sessions, sessionID)
 	session.Status = SessionIdle
-	// Clear pages - they're destroyed with the context and can't be restored
-	session.PageIDs = []string{}
+	m.mu.Unlock()
+
+	// Clear pages - they're destroyed with the context, resume reopens them from their URLs
+	session.clearPages()
 	
 	if m.repo != nil {
 		state := m.sessionToState(session)
 		state.Status = string(SessionIdle)
 
 		if state.SessionName == "" {
-			state.SessionName = session.Name
+			state.SessionName = session.GetName()
 		}
 		
 		if err := m.repo.SaveSession(state); err != nil {
 			slog.Warn("failed to update session status in Redis", "error", err)
 		}
-	}
 
-	// Remove from memory only
-	delete(m.sessions, sessionID)
+		if err := m.repo.SavePages(sessionID, pages); err != nil {
+			slog.Warn("failed to save pages in Redis", "error", err)
+		}
+
+		if browser != nil {
+			if err := m.repo.ReplaceBrowserState(sessionID, browser.cookies, browser.localStorage); err != nil {
+				slog.Warn("failed to save browser state in Redis", "error", err)
+			}
+		}
+	}
 
 	slog.Info("session closed (kept in Redis)", 
 		"session_id", sessionID,
-		"session_name", session.Name,
+		"session_name", session.GetName(),
 		"agent_id", session.AgentID)
 
 	return nil
@@ -701,5 +964,6 @@ func (m *Manager) CloseSession(sessionID string) error {
 
 // GetSessionByName is a convenience wrapper
 func (m *Manager) GetSessionByName(agentID, sessionName string) (*Session, error) {
-	return m.ResumeSessionByName(agentID, sessionName)
+	session, _, err := m.ResumeSessionByName(agentID, sessionName)
+	return session, err
 }
\ No newline at end of file
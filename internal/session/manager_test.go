@@ -1,11 +1,13 @@
 package session
 
 import (
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/dhruvsoni1802/browser-query-ai/internal/browser"
 	"github.com/dhruvsoni1802/browser-query-ai/internal/config"
+	"github.com/dhruvsoni1802/browser-query-ai/internal/storage"
 )
 
 // Test helper: Setup browser process for tests
@@ -390,4 +392,118 @@ func TestSessionActivityTracking(t *testing.T) {
 	if session.IsExpired(2 * time.Hour) {
 		t.Error("session should not be expired but is")
 	}
-}
\ No newline at end of file
+}
+
+// TestResurrectSessionUnlocked tests that resuming a session doesn't hold up other sessions
+// and that concurrent resumes of one session share a single browser context
+func TestResurrectSessionUnlocked(t *testing.T) {
+	browser, client := newFakeBrowser(t)
+	browser.createDelay = 200 * time.Millisecond
+
+	manager := NewManager(storage.NewMemoryStore(time.Hour))
+	manager.cdpClients[9300] = client
+	manager.sessions["sess_other"] = &Session{ID: "sess_other", ProcessPort: 9300, Status: SessionActive}
+
+	state := &storage.SessionState{SessionID: "sess_idle", SessionName: "idle", AgentID: "agent", ProcessPort: 9300, Status: string(SessionIdle)}
+
+	var wg sync.WaitGroup
+	resumed := make([]*Session, 2)
+	resurrected := make([]bool, 2)
+	for i := range resumed {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			session, created, err := manager.resurrectSession(state)
+			if err != nil {
+				t.Errorf("resurrectSession failed: %v", err)
+			}
+			resumed[i], resurrected[i] = session, created
+		}()
+	}
+
+	// Other sessions stay usable while the browser works
+	time.Sleep(50 * time.Millisecond)
+	started := time.Now()
+	if _, err := manager.GetSession("sess_other"); err != nil {
+		t.Fatalf("GetSession failed: %v", err)
+	}
+	if elapsed := time.Since(started); elapsed > 100*time.Millisecond {
+		t.Errorf("expected GetSession not to wait for the resume, took %v", elapsed)
+	}
+
+	wg.Wait()
+	if resumed[0] == nil || resumed[0] != resumed[1] {
+		t.Fatalf("expected both resumes to return the same session, got %p and %p", resumed[0], resumed[1])
+	}
+	if resurrected[0] == resurrected[1] {
+		t.Errorf("expected exactly one resume to report the session resurrected, got %v", resurrected)
+	}
+	if contexts := browser.contextIDs(); len(contexts) != 1 || contexts[0] != resumed[0].ContextID {
+		t.Errorf("expected only the context of the resumed session to be left, got %v", contexts)
+	}
+}
+
+// TestResurrectSessionResumePort tests that a session stored on a stopped process is resumed on the selected one
+func TestResurrectSessionResumePort(t *testing.T) {
+	_, client := newFakeBrowser(t)
+
+	store := storage.NewMemoryStore(time.Hour)
+	manager := NewManager(store)
+	manager.cdpClients[9300] = client
+	manager.SetResumePort(func(stored int) (int, error) {
+		if stored != 9222 {
+			t.Errorf("expected the stored port 9222, got %d", stored)
+		}
+		return 9300, nil
+	})
+
+	state := &storage.SessionState{SessionID: "sess_idle", SessionName: "idle", AgentID: "agent", ProcessPort: 9222, Status: string(SessionIdle), CreatedAt: time.Now()}
+	session, resurrected, err := manager.resurrectSession(state)
+	if err != nil {
+		t.Fatalf("resurrectSession failed: %v", err)
+	}
+	if !resurrected || session.ProcessPort != 9300 || session.CDPClient != client {
+		t.Errorf("expected the session resurrected on port 9300, got %v on %d", resurrected, session.ProcessPort)
+	}
+	if stored, err := store.GetSession("sess_idle"); err != nil || stored.ProcessPort != 9300 {
+		t.Errorf("expected the new port to be stored, got %+v, %v", stored, err)
+	}
+}
+
+// TestRenameSessionConcurrent tests that concurrent renames leave the session with the name the store kept
+func TestRenameSessionConcurrent(t *testing.T) {
+	store := storage.NewMemoryStore(time.Hour)
+	manager := NewManager(store)
+
+	session := &Session{ID: "sess_renamed", Name: "one", AgentID: "agent", Status: SessionActive, CreatedAt: time.Now()}
+	manager.sessions[session.ID] = session
+	if err := store.SaveSession(manager.sessionToState(session)); err != nil {
+		t.Fatalf("SaveSession failed: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for _, name := range []string{"two", "three"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := manager.RenameSession(session.ID, name); err != nil {
+				t.Errorf("RenameSession failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	state, err := store.GetSession(session.ID)
+	if err != nil {
+		t.Fatalf("GetSession failed: %v", err)
+	}
+	if name := session.GetName(); name != state.SessionName {
+		t.Errorf("expected the session to carry the stored name %q, got %q", state.SessionName, name)
+	}
+	for _, name := range []string{"one", "two", "three"} {
+		id, _ := store.GetSessionByName("agent", name)
+		if held := id == session.ID; held != (name == state.SessionName) {
+			t.Errorf("expected only %q to map to the session, %q maps to %q", state.SessionName, name, id)
+		}
+	}
+}
@@ -0,0 +1,315 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/dhruvsoni1802/browser-query-ai/internal/cdp"
+)
+
+// NavigationResult describes where a navigation ended up
+type NavigationResult struct {
+	PageID    string `json:"page_id"`
+	URL       string `json:"url"`                  // Final URL after redirects
+	Status    int    `json:"status,omitempty"`     // HTTP status of the main document, 0 if unknown
+	ErrorText string `json:"error_text,omitempty"` // Navigation error such as net::ERR_NAME_NOT_RESOLVED
+}
+
+// documentResponseEvent is the part of Network.responseReceived used to find the main document response
+type documentResponseEvent struct {
+	LoaderID string `json:"loaderId"`
+	Type     string `json:"type"`
+	Response struct {
+		URL    string `json:"url"`
+		Status int    `json:"status"`
+	} `json:"response"`
+}
+
+// NavigatePage navigates an existing page to a URL with Page.navigate
+// It waits for readiness (and the wait condition, if any) and reports the final URL, HTTP status and any navigation error.
+func (s *Session) NavigatePage(ctx context.Context, targetID string, url string, wait *WaitCondition) (*NavigationResult, error) {
+	// Start tracking before navigating so network idle sees the document's own requests
+	var waiter *waiter
+	if wait != nil {
+		var err error
+		if waiter, err = s.newWaiter(ctx, targetID, *wait); err != nil {
+			return nil, err
+		}
+		defer waiter.stop()
+	}
+
+	// The Network domain tells us the status code of the main document
+	if _, err := s.CDPClient.SendCommandToTargetContext(ctx, targetID, "Network.enable", nil); err != nil {
+		return nil, fmt.Errorf("failed to enable network events: %w", err)
+	}
+
+	// Subscribe before navigating, the response arrives before Page.navigate returns
+	responses, err := s.CDPClient.SubscribeTarget(ctx, targetID, "Network.responseReceived", 256)
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to network events: %w", err)
+	}
+	defer responses.Unsubscribe()
+
+	params := map[string]interface{}{
+		"url": url,
+	}
+
+	result, err := s.CDPClient.SendCommandToTargetContext(ctx, targetID, "Page.navigate", params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to navigate: %w", err)
+	}
+
+	var response struct {
+		FrameID   string `json:"frameId"`
+		LoaderID  string `json:"loaderId"`
+		ErrorText string `json:"errorText,omitempty"`
+	}
+
+	if err := json.Unmarshal(result, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse navigate response: %w", err)
+	}
+
+	// Whatever was analyzed before belongs to the previous document
+	s.InvalidatePageAnalysis(targetID)
+
+	navigation := &NavigationResult{
+		PageID:    targetID,
+		URL:       url,
+		ErrorText: response.ErrorText,
+	}
+
+	// Failed navigations (DNS, connection refused, ...) don't produce a document to wait for
+	if response.ErrorText != "" {
+		return navigation, nil
+	}
+
+	// Best-effort wait for page readiness
+	if err := s.WaitForReady(ctx, targetID, readyTimeout(ctx)); err != nil {
+		slog.Warn("page did not reach ready state before timeout", "page_id", targetID, "error", err)
+	}
+
+	// Find the main document response among the buffered events
+	// Same-document navigations (e.g. #fragment) have no loader and no response
+	if response.LoaderID != "" {
+		navigation.Status, navigation.URL = findDocumentResponse(responses, response.LoaderID, navigation.URL)
+	}
+
+	if waiter != nil {
+		if _, err := waiter.wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	// location.href is authoritative for the final URL (client-side redirects included)
+	if href, err := s.ExecuteJavascript(ctx, targetID, "location.href"); err == nil {
+		if finalURL, ok := href.(string); ok && finalURL != "" {
+			navigation.URL = finalURL
+		}
+	}
+
+	return navigation, nil
+}
+
+// findDocumentResponse drains buffered Network.responseReceived events looking for the document of a loader
+// Returns the status and URL of that response, or 0 and fallbackURL if it was not seen
+func findDocumentResponse(responses *cdp.Subscription, loaderID string, fallbackURL string) (int, string) {
+	for {
+		select {
+		case event, ok := <-responses.C:
+			if !ok {
+				return 0, fallbackURL
+			}
+
+			var received documentResponseEvent
+			if err := json.Unmarshal(event.Params, &received); err != nil {
+				continue
+			}
+
+			if received.LoaderID == loaderID && received.Type == "Document" {
+				return received.Response.Status, received.Response.URL
+			}
+		default:
+			return 0, fallbackURL
+		}
+	}
+}
+
+// HistoryEntry is one entry in a page's back/forward list
+type HistoryEntry struct {
+	ID      int    `json:"id"`
+	URL     string `json:"url"`
+	Title   string `json:"title"`
+	Current bool   `json:"current"`
+}
+
+// NavigationHistory is the back/forward list of a page
+type NavigationHistory struct {
+	PageID       string         `json:"page_id"`
+	CurrentIndex int            `json:"current_index"`
+	Entries      []HistoryEntry `json:"entries"`
+}
+
+// GetNavigationHistory returns the back/forward list of a page
+func (s *Session) GetNavigationHistory(ctx context.Context, targetID string) (*NavigationHistory, error) {
+	result, err := s.CDPClient.SendCommandToTargetContext(ctx, targetID, "Page.getNavigationHistory", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get navigation history: %w", err)
+	}
+
+	var response struct {
+		CurrentIndex int `json:"currentIndex"`
+		Entries      []struct {
+			ID    int    `json:"id"`
+			URL   string `json:"url"`
+			Title string `json:"title"`
+		} `json:"entries"`
+	}
+
+	if err := json.Unmarshal(result, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse navigation history: %w", err)
+	}
+
+	history := &NavigationHistory{
+		PageID:       targetID,
+		CurrentIndex: response.CurrentIndex,
+		Entries:      make([]HistoryEntry, len(response.Entries)),
+	}
+	for i, entry := range response.Entries {
+		history.Entries[i] = HistoryEntry{
+			ID:      entry.ID,
+			URL:     entry.URL,
+			Title:   entry.Title,
+			Current: i == response.CurrentIndex,
+		}
+	}
+
+	return history, nil
+}
+
+// NavigateHistory moves delta entries through the page history (-1 is back, 1 is forward)
+func (s *Session) NavigateHistory(ctx context.Context, targetID string, delta int) (*NavigationResult, error) {
+	history, err := s.GetNavigationHistory(ctx, targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	index := history.CurrentIndex + delta
+	if index < 0 || index >= len(history.Entries) {
+		return nil, ErrNoHistoryEntry
+	}
+
+	params := map[string]interface{}{
+		"entryId": history.Entries[index].ID,
+	}
+
+	err = s.runNavigation(ctx, targetID, func() error {
+		_, err := s.CDPClient.SendCommandToTargetContext(ctx, targetID, "Page.navigateToHistoryEntry", params)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to navigate history: %w", err)
+	}
+
+	return s.currentNavigation(ctx, targetID, history.Entries[index].URL), nil
+}
+
+// Reload reloads the page, optionally bypassing the cache
+func (s *Session) Reload(ctx context.Context, targetID string, ignoreCache bool) (*NavigationResult, error) {
+	params := map[string]interface{}{
+		"ignoreCache": ignoreCache,
+	}
+
+	err := s.runNavigation(ctx, targetID, func() error {
+		_, err := s.CDPClient.SendCommandToTargetContext(ctx, targetID, "Page.reload", params)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to reload page: %w", err)
+	}
+
+	return s.currentNavigation(ctx, targetID, ""), nil
+}
+
+// runNavigation triggers a navigation and waits for the page to commit and become ready
+// Waiting for the commit first matters: right after the trigger the old document still reports readyState "complete".
+func (s *Session) runNavigation(ctx context.Context, targetID string, trigger func() error) error {
+	// Page domain events tell us when the main frame has moved on
+	if _, err := s.CDPClient.SendCommandToTargetContext(ctx, targetID, "Page.enable", nil); err != nil {
+		return fmt.Errorf("failed to enable page events: %w", err)
+	}
+
+	committed, err := s.CDPClient.SubscribeTarget(ctx, targetID, "Page.frameNavigated", 16)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to page events: %w", err)
+	}
+	defer committed.Unsubscribe()
+
+	// Fragment and pushState history entries never commit a new document
+	withinDocument, err := s.CDPClient.SubscribeTarget(ctx, targetID, "Page.navigatedWithinDocument", 16)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to page events: %w", err)
+	}
+	defer withinDocument.Unsubscribe()
+
+	if err := trigger(); err != nil {
+		return err
+	}
+
+	// Whatever was analyzed before belongs to the previous document
+	defer s.InvalidatePageAnalysis(targetID)
+
+	timeout := time.NewTimer(readyTimeout(ctx))
+	defer timeout.Stop()
+
+	for waiting := true; waiting; {
+		select {
+		case event, ok := <-committed.C:
+			if !ok {
+				waiting = false
+				break
+			}
+			var navigated struct {
+				Frame struct {
+					ParentID string `json:"parentId"`
+				} `json:"frame"`
+			}
+			// Only the main frame counts, iframes navigate on their own
+			if err := json.Unmarshal(event.Params, &navigated); err == nil && navigated.Frame.ParentID == "" {
+				waiting = false
+			}
+		case <-withinDocument.C:
+			waiting = false
+		case <-timeout.C:
+			slog.Warn("page did not commit navigation before timeout", "page_id", targetID)
+			waiting = false
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	// Best-effort wait for page readiness
+	if err := s.WaitForReady(ctx, targetID, readyTimeout(ctx)); err != nil {
+		slog.Warn("page did not reach ready state before timeout", "page_id", targetID, "error", err)
+	}
+
+	return nil
+}
+
+// currentNavigation reports the page's current URL after a history navigation or reload
+func (s *Session) currentNavigation(ctx context.Context, targetID string, fallbackURL string) *NavigationResult {
+	navigation := &NavigationResult{
+		PageID: targetID,
+		URL:    fallbackURL,
+	}
+
+	if href, err := s.ExecuteJavascript(ctx, targetID, "location.href"); err == nil {
+		if currentURL, ok := href.(string); ok && currentURL != "" {
+			navigation.URL = currentURL
+		}
+	}
+
+	return navigation
+}
@@ -0,0 +1,561 @@
+package session
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dhruvsoni1802/browser-query-ai/internal/cdp"
+)
+
+// Network request states
+const (
+	NetworkPending  = "pending"
+	NetworkComplete = "complete"
+	NetworkFailed   = "failed"
+)
+
+// NetworkEntry is one request the page made and its response
+type NetworkEntry struct {
+	Seq             int64             `json:"seq"` // Increases by one per request, use as the since cursor
+	RequestID       string            `json:"request_id"`
+	URL             string            `json:"url"`
+	Method          string            `json:"method"`
+	ResourceType    string            `json:"resource_type"` // Document, XHR, Fetch, Script, Image...
+	State           string            `json:"state"`
+	StartedAt       time.Time         `json:"started_at"`
+	DurationMs      float64           `json:"duration_ms,omitempty"` // Until the body finished loading or the request failed
+	Status          int               `json:"status,omitempty"`
+	StatusText      string            `json:"status_text,omitempty"`
+	MimeType        string            `json:"mime_type,omitempty"`
+	Protocol        string            `json:"protocol,omitempty"`
+	RemoteIP        string            `json:"remote_ip,omitempty"`
+	FromCache       bool              `json:"from_cache,omitempty"`
+	TransferSize    int64             `json:"transfer_size"` // Bytes on the wire, headers included
+	BodySize        int64             `json:"body_size"`     // Decoded body bytes
+	Error           string            `json:"error,omitempty"`
+	RedirectURL     string            `json:"redirect_url,omitempty"`
+	RequestHeaders  map[string]string `json:"request_headers,omitempty"`
+	PostData        string            `json:"post_data,omitempty"`
+	ResponseHeaders map[string]string `json:"response_headers,omitempty"`
+	Timings         *NetworkTimings   `json:"timings,omitempty"`
+
+	startTimestamp float64            // Monotonic seconds when the request was issued
+	endTimestamp   float64            // Monotonic seconds when it finished or failed
+	timing         *cdpResourceTiming // Connection phases reported with the response
+}
+
+// NetworkTimings splits the duration of a request into HAR phases, in milliseconds
+// Phases that did not happen, such as DNS on a reused connection, are -1.
+type NetworkTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"` // Includes SSL
+	SSL     float64 `json:"ssl"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"` // Time to first byte
+	Receive float64 `json:"receive"`
+}
+
+// NetworkQuery filters the captured requests
+type NetworkQuery struct {
+	URL   string   // Substring of the URL, or a glob if it contains * or ?
+	Types []string // Resource types such as xhr or fetch, empty returns all
+	Since int64    // Only requests with a higher seq
+	Limit int      // Most recent requests to return, 0 returns all
+}
+
+// NetworkLog is the result of a network query
+type NetworkLog struct {
+	PageID  string          `json:"page_id"`
+	Entries []*NetworkEntry `json:"entries"`
+	Cursor  int64           `json:"cursor"`  // Seq of the newest request captured, pass as since to get only newer ones
+	Dropped int64           `json:"dropped"` // Requests evicted from the buffer since the page opened
+}
+
+// ResponseBody is the body of a captured response
+type ResponseBody struct {
+	Data     []byte
+	MimeType string
+}
+
+// networkBuffer holds the most recent requests of a page
+type networkBuffer struct {
+	mu      sync.Mutex
+	entries *ring[*NetworkEntry]
+	byID    map[string]*NetworkEntry // Latest entry for each CDP request ID, redirects reuse the ID
+	seq     int64
+	cancel  context.CancelFunc // Stops the capture goroutine
+}
+
+// newNetworkBuffer creates a buffer keeping up to capacity requests
+func newNetworkBuffer(capacity int, cancel context.CancelFunc) *networkBuffer {
+	return &networkBuffer{
+		entries: newRing[*NetworkEntry](capacity),
+		byID:    make(map[string]*NetworkEntry),
+		cancel:  cancel,
+	}
+}
+
+// validate checks that the query is well formed
+func (q *NetworkQuery) validate() error {
+	if q.Since < 0 {
+		return fmt.Errorf("%w: since cannot be negative", ErrInvalidNetworkQuery)
+	}
+	if q.Limit < 0 {
+		return fmt.Errorf("%w: limit cannot be negative", ErrInvalidNetworkQuery)
+	}
+	return nil
+}
+
+// matcher returns a function reporting whether an entry matches the URL and type filters
+func (q *NetworkQuery) matcher() func(*NetworkEntry) bool {
+	var pattern *regexp.Regexp
+	if strings.ContainsAny(q.URL, "*?") {
+		pattern = globToRegexp(q.URL)
+	}
+
+	return func(entry *NetworkEntry) bool {
+		if pattern != nil && !pattern.MatchString(entry.URL) {
+			return false
+		}
+		if pattern == nil && !strings.Contains(entry.URL, q.URL) {
+			return false
+		}
+		if len(q.Types) == 0 {
+			return true
+		}
+		for _, resourceType := range q.Types {
+			if strings.EqualFold(resourceType, entry.ResourceType) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// query returns copies of the matching entries oldest first, the newest seq and how many entries were evicted
+func (b *networkBuffer) query(q NetworkQuery) ([]*NetworkEntry, int64, int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	match := q.matcher()
+	entries := make([]*NetworkEntry, 0)
+	b.entries.each(func(entry *NetworkEntry) {
+		if entry.Seq > q.Since && match(entry) {
+			entries = append(entries, entry.snapshot())
+		}
+	})
+
+	if q.Limit > 0 && len(entries) > q.Limit {
+		entries = entries[len(entries)-q.Limit:]
+	}
+
+	return entries, b.seq, b.seq - int64(b.entries.len())
+}
+
+// lookup returns a copy of the latest entry for a CDP request ID
+func (b *networkBuffer) lookup(requestID string) *NetworkEntry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if entry := b.byID[requestID]; entry != nil {
+		return entry.snapshot()
+	}
+	return nil
+}
+
+// snapshot copies the entry with its timings filled in, so it can be read without the buffer lock
+func (e *NetworkEntry) snapshot() *NetworkEntry {
+	entry := *e
+	entry.Timings = e.timings()
+	return &entry
+}
+
+// timings converts the CDP resource timing into HAR phases
+func (e *NetworkEntry) timings() *NetworkTimings {
+	if e.State == NetworkPending {
+		return nil
+	}
+
+	total := e.DurationMs
+	t := e.timing
+	if t == nil {
+		// Served from cache or a data URL, nothing went over the wire
+		return &NetworkTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1, Receive: total}
+	}
+
+	phase := func(start, end float64) float64 {
+		if start < 0 || end < 0 {
+			return -1
+		}
+		return end - start
+	}
+
+	timings := &NetworkTimings{
+		DNS:     phase(t.DNSStart, t.DNSEnd),
+		Connect: phase(t.ConnectStart, t.ConnectEnd),
+		SSL:     phase(t.SSLStart, t.SSLEnd),
+		Send:    math.Max(phase(t.SendStart, t.SendEnd), 0),
+		Wait:    math.Max(phase(t.SendEnd, t.ReceiveHeadersEnd), 0),
+	}
+
+	// Blocked is everything before the first connection phase, including time queued before requestTime
+	queued := math.Max((t.RequestTime-e.startTimestamp)*1000, 0)
+	timings.Blocked = queued
+	for _, start := range []float64{t.DNSStart, t.ConnectStart, t.SendStart} {
+		if start >= 0 {
+			timings.Blocked += start
+			break
+		}
+	}
+
+	if e.endTimestamp > 0 {
+		timings.Receive = math.Max((e.endTimestamp-t.RequestTime)*1000-t.ReceiveHeadersEnd, 0)
+	}
+
+	return timings
+}
+
+// add starts a new entry for a request
+func (b *networkBuffer) add(entry *NetworkEntry) {
+	b.seq++
+	entry.Seq = b.seq
+	if evicted, ok := b.entries.push(entry); ok && b.byID[evicted.RequestID] == evicted {
+		delete(b.byID, evicted.RequestID)
+	}
+	b.byID[entry.RequestID] = entry
+}
+
+// handle applies one Network domain event to the buffer
+func (b *networkBuffer) handle(event *cdp.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch event.Method {
+	case "Network.requestWillBeSent":
+		var params cdpRequestWillBeSent
+		if err := json.Unmarshal(event.Params, &params); err != nil {
+			return
+		}
+
+		// A redirect reuses the request ID, finish the hop that was redirected first
+		if previous := b.byID[params.RequestID]; previous != nil && params.RedirectResponse != nil {
+			previous.applyResponse(params.RedirectResponse)
+			previous.RedirectURL = params.Request.URL
+			previous.finish(params.Timestamp, NetworkComplete)
+		}
+
+		b.add(&NetworkEntry{
+			RequestID:      params.RequestID,
+			URL:            params.Request.URL + params.Request.URLFragment,
+			Method:         params.Request.Method,
+			ResourceType:   params.Type,
+			State:          NetworkPending,
+			StartedAt:      time.UnixMicro(int64(params.WallTime * 1e6)),
+			RequestHeaders: params.Request.Headers,
+			PostData:       params.Request.PostData,
+			startTimestamp: params.Timestamp,
+		})
+
+	case "Network.responseReceived":
+		var params cdpResponseReceived
+		if err := json.Unmarshal(event.Params, &params); err != nil {
+			return
+		}
+		if entry := b.byID[params.RequestID]; entry != nil {
+			entry.applyResponse(&params.Response)
+			if params.Type != "" {
+				entry.ResourceType = params.Type
+			}
+		}
+
+	case "Network.dataReceived":
+		var params cdpDataReceived
+		if err := json.Unmarshal(event.Params, &params); err != nil {
+			return
+		}
+		if entry := b.byID[params.RequestID]; entry != nil {
+			entry.BodySize += params.DataLength
+		}
+
+	case "Network.requestServedFromCache":
+		var params cdpRequestEvent
+		if err := json.Unmarshal(event.Params, &params); err != nil {
+			return
+		}
+		if entry := b.byID[params.RequestID]; entry != nil {
+			entry.FromCache = true
+		}
+
+	case "Network.loadingFinished":
+		var params cdpLoadingFinished
+		if err := json.Unmarshal(event.Params, &params); err != nil {
+			return
+		}
+		if entry := b.byID[params.RequestID]; entry != nil {
+			entry.TransferSize = params.EncodedDataLength
+			entry.finish(params.Timestamp, NetworkComplete)
+		}
+
+	case "Network.loadingFailed":
+		var params cdpLoadingFailed
+		if err := json.Unmarshal(event.Params, &params); err != nil {
+			return
+		}
+		if entry := b.byID[params.RequestID]; entry != nil {
+			entry.Error = params.ErrorText
+			if params.BlockedReason != "" {
+				entry.Error += " (blocked: " + params.BlockedReason + ")"
+			}
+			if params.Type != "" {
+				entry.ResourceType = params.Type
+			}
+			entry.finish(params.Timestamp, NetworkFailed)
+		}
+	}
+}
+
+// applyResponse records the response fields of a CDP response
+func (e *NetworkEntry) applyResponse(response *cdpResponse) {
+	e.Status = response.Status
+	e.StatusText = response.StatusText
+	e.MimeType = response.MimeType
+	e.Protocol = response.Protocol
+	e.RemoteIP = response.RemoteIPAddress
+	e.ResponseHeaders = response.Headers
+	e.FromCache = e.FromCache || response.FromDiskCache || response.FromPrefetchCache
+	e.TransferSize = response.EncodedDataLength
+	e.timing = response.Timing
+}
+
+// finish marks the entry done at a monotonic timestamp
+func (e *NetworkEntry) finish(timestamp float64, state string) {
+	e.State = state
+	e.endTimestamp = timestamp
+	e.DurationMs = math.Max((timestamp-e.startTimestamp)*1000, 0)
+}
+
+// startNetworkCapture begins recording the requests of a page into a ring buffer
+func (s *Session) startNetworkCapture(targetID string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// One subscription for the whole domain keeps a request's events in order
+	sub, err := s.CDPClient.SubscribeTarget(ctx, targetID, "Network.*", 1024)
+	if err != nil {
+		cancel()
+		return fmt.Errorf("failed to subscribe to network events: %w", err)
+	}
+
+	// The domain is replayed after a reconnect, so capture survives it
+	if _, err := s.CDPClient.SendCommandToTargetContext(ctx, targetID, "Network.enable", nil); err != nil {
+		cancel()
+		return fmt.Errorf("failed to enable network events: %w", err)
+	}
+
+	buffer := newNetworkBuffer(NetworkBufferSize, cancel)
+
+	s.networkMu.Lock()
+	if s.networks == nil {
+		s.networks = make(map[string]*networkBuffer)
+	}
+	if old := s.networks[targetID]; old != nil {
+		old.cancel()
+	}
+	s.networks[targetID] = buffer
+	s.networkMu.Unlock()
+
+	go buffer.capture(ctx, sub.C)
+
+	return nil
+}
+
+// capture reads network events into the buffer until ctx is done or the client closes
+func (b *networkBuffer) capture(ctx context.Context, events <-chan *cdp.Event) {
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			b.handle(event)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// stopNetworkCapture stops recording a page and drops its requests
+func (s *Session) stopNetworkCapture(targetID string) {
+	s.networkMu.Lock()
+	defer s.networkMu.Unlock()
+
+	if buffer := s.networks[targetID]; buffer != nil {
+		buffer.cancel()
+		delete(s.networks, targetID)
+	}
+}
+
+// stopAllNetworkCaptures stops recording every page of the session
+func (s *Session) stopAllNetworkCaptures() {
+	s.networkMu.Lock()
+	defer s.networkMu.Unlock()
+
+	for targetID, buffer := range s.networks {
+		buffer.cancel()
+		delete(s.networks, targetID)
+	}
+}
+
+// networkBufferFor returns the buffer of a page, nil if it is not being captured
+func (s *Session) networkBufferFor(targetID string) *networkBuffer {
+	s.networkMu.Lock()
+	defer s.networkMu.Unlock()
+
+	return s.networks[targetID]
+}
+
+// GetNetworkLog returns the requests captured for a page
+func (s *Session) GetNetworkLog(targetID string, query NetworkQuery) (*NetworkLog, error) {
+	if err := query.validate(); err != nil {
+		return nil, err
+	}
+
+	log := &NetworkLog{
+		PageID:  targetID,
+		Entries: make([]*NetworkEntry, 0),
+	}
+
+	buffer := s.networkBufferFor(targetID)
+	if buffer == nil {
+		return log, nil
+	}
+
+	log.Entries, log.Cursor, log.Dropped = buffer.query(query)
+	return log, nil
+}
+
+// GetResponseBody fetches the body of a captured response from the browser
+// Only the final hop of a redirect chain has a body, and the browser may evict it after a while.
+func (s *Session) GetResponseBody(ctx context.Context, targetID string, requestID string) (*ResponseBody, error) {
+	var entry *NetworkEntry
+	if buffer := s.networkBufferFor(targetID); buffer != nil {
+		entry = buffer.lookup(requestID)
+	}
+	if entry == nil {
+		return nil, fmt.Errorf("%w: %s", ErrRequestNotFound, requestID)
+	}
+	if entry.State != NetworkComplete {
+		return nil, fmt.Errorf("%w: request is %s", ErrResponseBodyUnavailable, entry.State)
+	}
+
+	params := map[string]interface{}{"requestId": requestID}
+	result, err := s.CDPClient.SendCommandToTargetContext(ctx, targetID, "Network.getResponseBody", params)
+	if err != nil {
+		if strings.Contains(err.Error(), "No resource with given identifier") || strings.Contains(err.Error(), "No data found") {
+			return nil, fmt.Errorf("%w: %v", ErrResponseBodyUnavailable, err)
+		}
+		return nil, fmt.Errorf("failed to get response body: %w", err)
+	}
+
+	var body struct {
+		Body          string `json:"body"`
+		Base64Encoded bool   `json:"base64Encoded"`
+	}
+	if err := json.Unmarshal(result, &body); err != nil {
+		return nil, fmt.Errorf("failed to parse response body: %w", err)
+	}
+
+	data := []byte(body.Body)
+	if body.Base64Encoded {
+		data, err = base64.StdEncoding.DecodeString(body.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode response body: %w", err)
+		}
+	}
+
+	return &ResponseBody{Data: data, MimeType: entry.MimeType}, nil
+}
+
+// cdpRequestEvent is the part every Network event shares
+type cdpRequestEvent struct {
+	RequestID string  `json:"requestId"`
+	Timestamp float64 `json:"timestamp"`
+}
+
+// cdpRequestWillBeSent is the Network.requestWillBeSent event
+type cdpRequestWillBeSent struct {
+	cdpRequestEvent
+	WallTime float64 `json:"wallTime"`
+	Type     string  `json:"type"`
+	Request  struct {
+		URL         string            `json:"url"`
+		URLFragment string            `json:"urlFragment"`
+		Method      string            `json:"method"`
+		Headers     map[string]string `json:"headers"`
+		PostData    string            `json:"postData"`
+	} `json:"request"`
+	RedirectResponse *cdpResponse `json:"redirectResponse"`
+}
+
+// cdpResponse is a Network.Response
+type cdpResponse struct {
+	URL               string             `json:"url"`
+	Status            int                `json:"status"`
+	StatusText        string             `json:"statusText"`
+	Headers           map[string]string  `json:"headers"`
+	MimeType          string             `json:"mimeType"`
+	Protocol          string             `json:"protocol"`
+	RemoteIPAddress   string             `json:"remoteIPAddress"`
+	FromDiskCache     bool               `json:"fromDiskCache"`
+	FromPrefetchCache bool               `json:"fromPrefetchCache"`
+	EncodedDataLength int64              `json:"encodedDataLength"`
+	Timing            *cdpResourceTiming `json:"timing"`
+}
+
+// cdpResourceTiming holds connection phases in milliseconds relative to RequestTime, -1 when skipped
+type cdpResourceTiming struct {
+	RequestTime       float64 `json:"requestTime"` // Monotonic seconds
+	DNSStart          float64 `json:"dnsStart"`
+	DNSEnd            float64 `json:"dnsEnd"`
+	ConnectStart      float64 `json:"connectStart"`
+	ConnectEnd        float64 `json:"connectEnd"`
+	SSLStart          float64 `json:"sslStart"`
+	SSLEnd            float64 `json:"sslEnd"`
+	SendStart         float64 `json:"sendStart"`
+	SendEnd           float64 `json:"sendEnd"`
+	ReceiveHeadersEnd float64 `json:"receiveHeadersEnd"`
+}
+
+// cdpResponseReceived is the Network.responseReceived event
+type cdpResponseReceived struct {
+	cdpRequestEvent
+	Type     string      `json:"type"`
+	Response cdpResponse `json:"response"`
+}
+
+// cdpDataReceived is the Network.dataReceived event
+type cdpDataReceived struct {
+	cdpRequestEvent
+	DataLength int64 `json:"dataLength"`
+}
+
+// cdpLoadingFinished is the Network.loadingFinished event
+type cdpLoadingFinished struct {
+	cdpRequestEvent
+	EncodedDataLength int64 `json:"encodedDataLength"`
+}
+
+// cdpLoadingFailed is the Network.loadingFailed event
+type cdpLoadingFailed struct {
+	cdpRequestEvent
+	Type          string `json:"type"`
+	ErrorText     string `json:"errorText"`
+	BlockedReason string `json:"blockedReason"`
+}
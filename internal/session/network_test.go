@@ -0,0 +1,155 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/dhruvsoni1802/browser-query-ai/internal/cdp"
+)
+
+// networkEvent builds a CDP event for feeding a network buffer
+func networkEvent(method string, params string) *cdp.Event {
+	return &cdp.Event{Method: "Network." + method, Params: json.RawMessage(params)}
+}
+
+// TestNetworkBufferEvents tests building entries from a request, a redirect and a failure
+func TestNetworkBufferEvents(t *testing.T) {
+	buffer := newNetworkBuffer(10, func() {})
+
+	events := []*cdp.Event{
+		networkEvent("requestWillBeSent", `{"requestId": "1", "timestamp": 100, "wallTime": 1700000000, "type": "Document",
+			"request": {"url": "http://example.com/", "method": "GET", "headers": {"Accept": "text/html"}}}`),
+		networkEvent("requestWillBeSent", `{"requestId": "1", "timestamp": 100.05, "wallTime": 1700000000.05, "type": "Document",
+			"request": {"url": "https://example.com/", "method": "GET", "headers": {}},
+			"redirectResponse": {"url": "http://example.com/", "status": 301, "statusText": "Moved Permanently", "headers": {"Location": "https://example.com/"}, "mimeType": "text/html", "encodedDataLength": 120}}`),
+		networkEvent("responseReceived", `{"requestId": "1", "timestamp": 100.2, "type": "Document",
+			"response": {"url": "https://example.com/", "status": 200, "statusText": "OK", "mimeType": "text/html", "protocol": "h2", "remoteIPAddress": "93.184.216.34", "encodedDataLength": 300,
+				"timing": {"requestTime": 100.06, "dnsStart": 1, "dnsEnd": 11, "connectStart": 11, "connectEnd": 51, "sslStart": 21, "sslEnd": 51, "sendStart": 52, "sendEnd": 53, "receiveHeadersEnd": 133}}}`),
+		networkEvent("dataReceived", `{"requestId": "1", "timestamp": 100.25, "dataLength": 1000, "encodedDataLength": 500}`),
+		networkEvent("dataReceived", `{"requestId": "1", "timestamp": 100.28, "dataLength": 256, "encodedDataLength": 100}`),
+		networkEvent("loadingFinished", `{"requestId": "1", "timestamp": 100.3, "encodedDataLength": 900}`),
+		networkEvent("requestWillBeSent", `{"requestId": "2", "timestamp": 100.4, "wallTime": 1700000000.4, "type": "Fetch",
+			"request": {"url": "https://example.com/api/items?page=2", "method": "POST", "headers": {"Content-Type": "application/json"}, "postData": "{}"}}`),
+		networkEvent("loadingFailed", `{"requestId": "2", "timestamp": 100.5, "type": "Fetch", "errorText": "net::ERR_BLOCKED_BY_CLIENT", "blockedReason": "inspector"}`),
+		networkEvent("requestWillBeSent", `{"requestId": "3", "timestamp": 100.6, "wallTime": 1700000000.6, "type": "Image",
+			"request": {"url": "https://example.com/logo.png", "method": "GET", "headers": {}}}`),
+	}
+	for _, event := range events {
+		buffer.handle(event)
+	}
+
+	entries, cursor, dropped := buffer.query(NetworkQuery{})
+	if len(entries) != 4 || cursor != 4 || dropped != 0 {
+		t.Fatalf("expected 4 entries, cursor 4 and none dropped, got %d, %d and %d", len(entries), cursor, dropped)
+	}
+
+	redirect := entries[0]
+	if redirect.Status != 301 || redirect.State != NetworkComplete || redirect.RedirectURL != "https://example.com/" {
+		t.Errorf("unexpected redirect entry %+v", redirect)
+	}
+	if redirect.DurationMs < 49 || redirect.DurationMs > 51 {
+		t.Errorf("expected the redirect to take about 50ms, got %f", redirect.DurationMs)
+	}
+
+	document := entries[1]
+	if document.RequestID != "1" || document.Status != 200 || document.State != NetworkComplete {
+		t.Errorf("unexpected document entry %+v", document)
+	}
+	if document.TransferSize != 900 || document.BodySize != 1256 || document.Protocol != "h2" {
+		t.Errorf("unexpected sizes %d/%d or protocol %s", document.TransferSize, document.BodySize, document.Protocol)
+	}
+	if document.StartedAt.UnixMilli() != 1700000000050 {
+		t.Errorf("unexpected start time %v", document.StartedAt)
+	}
+
+	timings := document.Timings
+	if timings == nil {
+		t.Fatal("expected timings for the document")
+	}
+	expected := NetworkTimings{Blocked: 11, DNS: 10, Connect: 40, SSL: 30, Send: 1, Wait: 80, Receive: 107}
+	for name, pair := range map[string][2]float64{
+		"blocked": {timings.Blocked, expected.Blocked}, "dns": {timings.DNS, expected.DNS},
+		"connect": {timings.Connect, expected.Connect}, "ssl": {timings.SSL, expected.SSL},
+		"send": {timings.Send, expected.Send}, "wait": {timings.Wait, expected.Wait},
+		"receive": {timings.Receive, expected.Receive},
+	} {
+		if pair[0] < pair[1]-0.5 || pair[0] > pair[1]+0.5 {
+			t.Errorf("expected %s timing %f, got %f", name, pair[1], pair[0])
+		}
+	}
+
+	failed := entries[2]
+	if failed.State != NetworkFailed || failed.Error != "net::ERR_BLOCKED_BY_CLIENT (blocked: inspector)" || failed.PostData != "{}" {
+		t.Errorf("unexpected failed entry %+v", failed)
+	}
+
+	if pending := entries[3]; pending.State != NetworkPending || pending.Timings != nil {
+		t.Errorf("expected a pending entry without timings, got %+v", pending)
+	}
+
+	// Entries are copies, changing them does not touch the buffer
+	document.Status = 500
+	if buffer.lookup("1").Status != 200 {
+		t.Error("query returned the buffer's own entry")
+	}
+}
+
+// TestNetworkQuery tests the URL, type, since and limit filters
+func TestNetworkQuery(t *testing.T) {
+	buffer := newNetworkBuffer(3, func() {})
+	requests := []struct{ id, url, resourceType string }{
+		{"1", "https://example.com/", "Document"},
+		{"2", "https://example.com/app.js", "Script"},
+		{"3", "https://example.com/api/items", "XHR"},
+		{"4", "https://cdn.example.com/api/v2/users", "Fetch"},
+	}
+	for _, request := range requests {
+		buffer.handle(networkEvent("requestWillBeSent", `{"requestId": "`+request.id+`", "type": "`+request.resourceType+`",
+			"request": {"url": "`+request.url+`", "method": "GET"}}`))
+	}
+
+	tests := []struct {
+		query NetworkQuery
+		ids   []string
+	}{
+		{NetworkQuery{}, []string{"2", "3", "4"}},
+		{NetworkQuery{URL: "/api/"}, []string{"3", "4"}},
+		{NetworkQuery{URL: "https://example.com/*"}, []string{"2"}},
+		{NetworkQuery{URL: "**/api/**"}, []string{"3", "4"}},
+		{NetworkQuery{Types: []string{"xhr", "FETCH"}}, []string{"3", "4"}},
+		{NetworkQuery{Since: 3}, []string{"4"}},
+		{NetworkQuery{Limit: 1}, []string{"4"}},
+	}
+	for _, tt := range tests {
+		entries, _, dropped := buffer.query(tt.query)
+		var ids []string
+		for _, entry := range entries {
+			ids = append(ids, entry.RequestID)
+		}
+		if len(ids) != len(tt.ids) {
+			t.Errorf("%+v: expected %v, got %v", tt.query, tt.ids, ids)
+			continue
+		}
+		for i := range ids {
+			if ids[i] != tt.ids[i] {
+				t.Errorf("%+v: expected %v, got %v", tt.query, tt.ids, ids)
+				break
+			}
+		}
+		if dropped != 1 {
+			t.Errorf("expected 1 dropped entry, got %d", dropped)
+		}
+	}
+
+	// The evicted request can no longer be looked up
+	if buffer.lookup("1") != nil {
+		t.Error("expected the evicted request to be forgotten")
+	}
+
+	for _, query := range []NetworkQuery{{Since: -1}, {Limit: -1}} {
+		if err := query.validate(); !errors.Is(err, ErrInvalidNetworkQuery) {
+			t.Errorf("expected %+v to be invalid, got %v", query, err)
+		}
+	}
+}
@@ -1,22 +1,49 @@
 package session
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
-	"slices"
 	"time"
 )
 
-// Navigate navigates to a URL and creates a new page in the session
-func (m *Manager) Navigate(sessionID string, url string) (string, error) {
+// Navigate navigates to a URL
+// If pageID is empty a new page is created in the session, otherwise the existing page is navigated
+// If wait is set, navigation also waits for that condition once the page is ready
+func (m *Manager) Navigate(ctx context.Context, sessionID string, url string, pageID string, wait *WaitCondition) (*NavigationResult, error) {
 	// Get the session from the manager
 	session, err := m.GetSession(sessionID)
 	if err != nil {
-		return "", fmt.Errorf("failed to get session: %w", err)
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	if pageID == "" {
+		// Open a blank page, then navigate it so new and existing pages report status and errors the same way
+		if pageID, err = m.openPage(ctx, session); err != nil {
+			return nil, err
+		}
+	} else if !session.HasPage(pageID) {
+		// Verify that the page ID is in the session
+		return nil, fmt.Errorf("page not found in session: %s", pageID)
 	}
 
-	// Create a new target/page in this session's context
-	pageID, err := session.CDPClient.CreateTarget(url, session.ContextID)
+	// Navigate the page and wait for it to be ready
+	result, err := session.NavigatePage(ctx, pageID, url, wait)
+	if err != nil {
+		return nil, fmt.Errorf("failed to navigate page: %w", err)
+	}
+
+	// Update the last activity time of the session
+	session.UpdateActivity()
+
+	// Return the navigation result
+	return result, nil
+}
+
+// openPage creates a blank page in the session's context with every per-page monitor running
+func (m *Manager) openPage(ctx context.Context, session *Session) (string, error) {
+	// Create a blank target/page in this session's context
+	pageID, err := session.CDPClient.CreateTarget("about:blank", session.ContextID)
 	if err != nil {
 		return "", fmt.Errorf("failed to create target: %w", err)
 	}
@@ -24,17 +51,176 @@ func (m *Manager) Navigate(sessionID string, url string) (string, error) {
 	// Add the page ID to the session
 	session.AddPage(pageID)
 
-	// Best-effort wait for page readiness
-	if err := session.WaitForReady(pageID, 10*time.Second); err != nil {
-		slog.Warn("page did not reach ready state before timeout", "page_id", pageID, "error", err)
+	// Record what the page logs from the start, a page without a console log is still usable
+	if err := session.startConsoleCapture(pageID); err != nil {
+		slog.Warn("failed to start console capture", "page_id", pageID, "error", err)
+	}
+
+	// Likewise for the requests it makes, so the document request itself is in the log
+	if err := session.startNetworkCapture(pageID); err != nil {
+		slog.Warn("failed to start network capture", "page_id", pageID, "error", err)
+	}
+
+	// Apply the session's interception rules before the first request goes out
+	if err := session.startInterception(pageID); err != nil {
+		slog.Warn("failed to start request interception", "page_id", pageID, "error", err)
+	}
+
+	// Keep the page's URL and title in the repository so it can be reopened on resume
+	if err := session.startPageTracking(pageID, func() { m.savePages(session) }); err != nil {
+		slog.Warn("failed to start page tracking", "page_id", pageID, "error", err)
+	}
+
+	// The page must look like the emulated device before it loads anything, one that can't is closed again
+	if err := session.emulatePage(ctx, pageID); err != nil {
+		session.RemovePage(pageID)
+		if closeErr := session.CDPClient.CloseTarget(pageID); closeErr != nil {
+			slog.Warn("failed to close page", "page_id", pageID, "error", closeErr)
+		}
+		return "", err
 	}
 
-	// Return the page ID
 	return pageID, nil
 }
 
+// NavigateHistory moves a page delta entries through its history (-1 is back, 1 is forward)
+func (m *Manager) NavigateHistory(ctx context.Context, sessionID string, pageID string, delta int) (*NavigationResult, error) {
+	// Get the session from the manager
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	// Verify that the page ID is in the session
+	if !session.HasPage(pageID) {
+		return nil, fmt.Errorf("page not found in session: %s", pageID)
+	}
+
+	// Move through the history and wait for the page to be ready
+	result, err := session.NavigateHistory(ctx, pageID, delta)
+	if err != nil {
+		return nil, err
+	}
+
+	// Update the last activity time of the session
+	session.UpdateActivity()
+
+	// Return the navigation result
+	return result, nil
+}
+
+// Reload reloads a page, optionally bypassing the cache
+func (m *Manager) Reload(ctx context.Context, sessionID string, pageID string, ignoreCache bool) (*NavigationResult, error) {
+	// Get the session from the manager
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	// Verify that the page ID is in the session
+	if !session.HasPage(pageID) {
+		return nil, fmt.Errorf("page not found in session: %s", pageID)
+	}
+
+	// Reload the page and wait for it to be ready
+	result, err := session.Reload(ctx, pageID, ignoreCache)
+	if err != nil {
+		return nil, err
+	}
+
+	// Update the last activity time of the session
+	session.UpdateActivity()
+
+	// Return the navigation result
+	return result, nil
+}
+
+// GetNavigationHistory returns the back/forward list of a page
+func (m *Manager) GetNavigationHistory(ctx context.Context, sessionID string, pageID string) (*NavigationHistory, error) {
+	// Get the session from the manager
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	// Verify that the page ID is in the session
+	if !session.HasPage(pageID) {
+		return nil, fmt.Errorf("page not found in session: %s", pageID)
+	}
+
+	// Get the history of the page
+	history, err := session.GetNavigationHistory(ctx, pageID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Update the last activity time of the session
+	session.UpdateActivity()
+
+	// Return the history
+	return history, nil
+}
+
+// PerformActions runs input actions on a page in order, stopping at the first failure
+// The results of the actions that completed are returned along with the error
+func (m *Manager) PerformActions(ctx context.Context, sessionID string, pageID string, actions []Action) ([]*ActionResult, error) {
+	// Get the session from the manager
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	// Verify that the page ID is in the session
+	if !session.HasPage(pageID) {
+		return nil, fmt.Errorf("page not found in session: %s", pageID)
+	}
+
+	// Perform each action, later actions usually depend on earlier ones
+	results := make([]*ActionResult, 0, len(actions))
+	for i, action := range actions {
+		result, err := session.PerformAction(ctx, pageID, action)
+		if err != nil {
+			session.UpdateActivity()
+			return results, fmt.Errorf("action %d: %w", i, err)
+		}
+		results = append(results, result)
+	}
+
+	// Update the last activity time of the session
+	session.UpdateActivity()
+
+	// Return the results
+	return results, nil
+}
+
+// Wait blocks until a condition holds on a page
+func (m *Manager) Wait(ctx context.Context, sessionID string, pageID string, condition WaitCondition) (*WaitResult, error) {
+	// Get the session from the manager
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	// Verify that the page ID is in the session
+	if !session.HasPage(pageID) {
+		return nil, fmt.Errorf("page not found in session: %s", pageID)
+	}
+
+	// Wait for the condition
+	result, err := session.Wait(ctx, pageID, condition)
+	if err != nil {
+		return nil, err
+	}
+
+	// Update the last activity time of the session
+	session.UpdateActivity()
+
+	// Return the result
+	return result, nil
+}
+
 // CaptureScreenshot captures a screenshot of a given page
-func (m *Manager) CaptureScreenshot(sessionID string, pageID string) ([]byte, error) {
+func (m *Manager) CaptureScreenshot(ctx context.Context, sessionID string, pageID string, opts ScreenshotOptions) (*Screenshot, error) {
 	// Get the session from the manager
 	session, err := m.GetSession(sessionID)
 	if err != nil {
@@ -42,12 +228,12 @@ func (m *Manager) CaptureScreenshot(sessionID string, pageID string) ([]byte, er
 	}
 
 	// Verify that the page ID is in the session
-	if !slices.Contains(session.PageIDs, pageID) {
+	if !session.HasPage(pageID) {
 		return nil, fmt.Errorf("page not found in session: %s", pageID)
 	}
 
 	// Capture screenshot of the page
-	screenshot, err := session.CaptureScreenshot(pageID)
+	screenshot, err := session.CaptureScreenshot(ctx, pageID, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to capture screenshot: %w", err)
 	}
@@ -59,8 +245,34 @@ func (m *Manager) CaptureScreenshot(sessionID string, pageID string) ([]byte, er
 	return screenshot, nil
 }
 
+// PrintToPDF renders a page of a session as a PDF document
+func (m *Manager) PrintToPDF(ctx context.Context, sessionID string, pageID string, opts PDFOptions) ([]byte, error) {
+	// Get the session from the manager
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	// Verify that the page ID is in the session
+	if !session.HasPage(pageID) {
+		return nil, fmt.Errorf("page not found in session: %s", pageID)
+	}
+
+	// Print the page
+	pdf, err := session.PrintToPDF(ctx, pageID, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Update the last activity time of the session
+	session.UpdateActivity()
+
+	// Return the document
+	return pdf, nil
+}
+
 // ExecuteJavascript executes JavaScript code on a page
-func (m *Manager) ExecuteJavascript(sessionID string, pageID string, code string) (interface{}, error) {
+func (m *Manager) ExecuteJavascript(ctx context.Context, sessionID string, pageID string, code string) (interface{}, error) {
 	// Get the session from the manager
 	session, err := m.GetSession(sessionID)
 	if err != nil {
@@ -68,12 +280,12 @@ func (m *Manager) ExecuteJavascript(sessionID string, pageID string, code string
 	}
 
 	// Verify that the page ID is in the session
-	if !slices.Contains(session.PageIDs, pageID) {
+	if !session.HasPage(pageID) {
 		return nil, fmt.Errorf("page not found in session: %s", pageID)
 	}
 
 	// Execute the JavaScript code on the page
-	result, err := session.ExecuteJavascript(pageID, code)
+	result, err := session.ExecuteJavascript(ctx, pageID, code)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute javascript: %w", err)
 	}
@@ -86,7 +298,7 @@ func (m *Manager) ExecuteJavascript(sessionID string, pageID string, code string
 }
 
 // GetPageContent gets the HTML content of a page
-func (m *Manager) GetPageContent(sessionID string, pageID string) (string, error) {
+func (m *Manager) GetPageContent(ctx context.Context, sessionID string, pageID string) (string, error) {
 	// Get the session from the manager
 	session, err := m.GetSession(sessionID)
 	if err != nil {
@@ -94,12 +306,12 @@ func (m *Manager) GetPageContent(sessionID string, pageID string) (string, error
 	}
 
 	// Verify that the page ID is in the session
-	if !slices.Contains(session.PageIDs, pageID) {
+	if !session.HasPage(pageID) {
 		return "", fmt.Errorf("page not found in session: %s", pageID)
 	}
 
 	// Get the HTML content of the page
-	content, err := session.GetPageContent(pageID)
+	content, err := session.GetPageContent(ctx, pageID)
 	if err != nil {
 		return "", fmt.Errorf("failed to get page content: %w", err)
 	}
@@ -111,8 +323,34 @@ func (m *Manager) GetPageContent(sessionID string, pageID string) (string, error
 	return content, nil
 }
 
+// ExtractPageContent gets the content of a page as html, text, markdown or readability
+func (m *Manager) ExtractPageContent(ctx context.Context, sessionID string, pageID string, format string) (*PageContent, error) {
+	// Get the session from the manager
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	// Verify that the page ID is in the session
+	if !session.HasPage(pageID) {
+		return nil, fmt.Errorf("page not found in session: %s", pageID)
+	}
+
+	// Get the content in the requested format
+	content, err := session.ExtractPageContent(ctx, pageID, format)
+	if err != nil {
+		return nil, err
+	}
+
+	// Update the last activity time of the session
+	session.UpdateActivity()
+
+	// Return the content
+	return content, nil
+}
+
 // AnalyzePage extracts the structural overview of a page
-func (m *Manager) AnalyzePage(sessionID string, pageID string) (*PageStructure, error) {
+func (m *Manager) AnalyzePage(ctx context.Context, sessionID string, pageID string) (*PageStructure, error) {
 	// Get the session from the manager
 	session, err := m.GetSession(sessionID)
 	if err != nil {
@@ -120,12 +358,12 @@ func (m *Manager) AnalyzePage(sessionID string, pageID string) (*PageStructure,
 	}
 
 	// Verify that the page ID is in the session
-	if !slices.Contains(session.PageIDs, pageID) {
+	if !session.HasPage(pageID) {
 		return nil, fmt.Errorf("page not found in session: %s", pageID)
 	}
 
 	// Analyze the page structure
-	structure, err := session.AnalyzePage(pageID)
+	structure, err := session.AnalyzePage(ctx, pageID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to analyze page: %w", err)
 	}
@@ -151,8 +389,8 @@ func (m *Manager) InvalidatePageAnalysis(sessionID string, pageID string) error
 	return nil
 }
 
-// GetAccessibilityTree retrieves the accessibility tree for a page
-func (m *Manager) GetAccessibilityTree(sessionID string, pageID string) (*AccessibilityTree, error) {
+// GetAccessibilityTree retrieves the accessibility tree for a page, trimmed by the options
+func (m *Manager) GetAccessibilityTree(ctx context.Context, sessionID string, pageID string, opts AXTreeOptions) (*AccessibilityTree, error) {
 	// Get the session from the manager
 	session, err := m.GetSession(sessionID)
 	if err != nil {
@@ -160,12 +398,12 @@ func (m *Manager) GetAccessibilityTree(sessionID string, pageID string) (*Access
 	}
 
 	// Verify that the page ID is in the session
-	if !slices.Contains(session.PageIDs, pageID) {
+	if !session.HasPage(pageID) {
 		return nil, fmt.Errorf("page not found in session: %s", pageID)
 	}
 
 	// Get the accessibility tree
-	tree, err := session.GetAccessibilityTree(pageID)
+	tree, err := session.GetAccessibilityTree(ctx, pageID, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get accessibility tree: %w", err)
 	}
@@ -177,6 +415,167 @@ func (m *Manager) GetAccessibilityTree(sessionID string, pageID string) (*Access
 	return tree, nil
 }
 
+// GetConsoleLog returns what a page of a session has logged
+func (m *Manager) GetConsoleLog(sessionID string, pageID string, query ConsoleQuery) (*ConsoleLog, error) {
+	// Get the session from the manager
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	// Verify that the page ID is in the session
+	if !session.HasPage(pageID) {
+		return nil, fmt.Errorf("page not found in session: %s", pageID)
+	}
+
+	// Read the captured entries
+	log, err := session.GetConsoleLog(pageID, query)
+	if err != nil {
+		return nil, err
+	}
+
+	// Update the last activity time of the session
+	session.UpdateActivity()
+
+	// Return the entries
+	return log, nil
+}
+
+// GetNetworkLog returns the requests a page of a session has made
+func (m *Manager) GetNetworkLog(sessionID string, pageID string, query NetworkQuery) (*NetworkLog, error) {
+	// Get the session from the manager
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	// Verify that the page ID is in the session
+	if !session.HasPage(pageID) {
+		return nil, fmt.Errorf("page not found in session: %s", pageID)
+	}
+
+	// Read the captured requests
+	log, err := session.GetNetworkLog(pageID, query)
+	if err != nil {
+		return nil, err
+	}
+
+	// Update the last activity time of the session
+	session.UpdateActivity()
+
+	// Return the requests
+	return log, nil
+}
+
+// GetResponseBody returns the body of a response a page of a session received
+func (m *Manager) GetResponseBody(ctx context.Context, sessionID string, pageID string, requestID string) (*ResponseBody, error) {
+	// Get the session from the manager
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	// Verify that the page ID is in the session
+	if !session.HasPage(pageID) {
+		return nil, fmt.Errorf("page not found in session: %s", pageID)
+	}
+
+	// Fetch the body from the browser
+	body, err := session.GetResponseBody(ctx, pageID, requestID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Update the last activity time of the session
+	session.UpdateActivity()
+
+	// Return the body
+	return body, nil
+}
+
+// ExportHAR returns the requests of every page of a session as a HAR
+func (m *Manager) ExportHAR(sessionID string) (*HAR, error) {
+	// Get the session from the manager
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	// Build the archive
+	har := session.ExportHAR()
+
+	// Update the last activity time of the session
+	session.UpdateActivity()
+
+	// Return the archive
+	return har, nil
+}
+
+// SetInterceptionRules replaces the interception rules of a session
+func (m *Manager) SetInterceptionRules(ctx context.Context, sessionID string, rules []InterceptionRule) (*InterceptionStats, error) {
+	// Get the session from the manager
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	// Apply the rules to the session and its open pages
+	stats, err := session.SetInterceptionRules(ctx, rules)
+	if err != nil {
+		return nil, err
+	}
+
+	// Update the last activity time of the session
+	session.UpdateActivity()
+
+	// Return the new rules
+	return stats, nil
+}
+
+// GetInterceptionStats returns the interception rules of a session and how often they matched
+func (m *Manager) GetInterceptionStats(sessionID string) (*InterceptionStats, error) {
+	// Get the session from the manager
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	// Read the rule counters
+	stats := session.InterceptionStats()
+
+	// Update the last activity time of the session
+	session.UpdateActivity()
+
+	// Return the rules
+	return stats, nil
+}
+
+// SetEmulation replaces the emulation options of a session and persists them
+func (m *Manager) SetEmulation(ctx context.Context, sessionID string, opts *EmulationOptions) error {
+	// Get the session from the manager
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to get session: %w", err)
+	}
+
+	// Apply the options to the session and its open pages
+	if err := session.SetEmulation(ctx, opts); err != nil {
+		return err
+	}
+
+	// Update the last activity time of the session
+	session.UpdateActivity()
+
+	// Persist so a resumed session keeps the options
+	if m.repo != nil {
+		if err := m.repo.SaveSession(m.sessionToState(session)); err != nil {
+			slog.Warn("failed to persist emulation", "session_id", sessionID, "error", err)
+		}
+	}
+
+	return nil
+}
+
 // ClosePage closes a specific page in the session
 func (m *Manager) ClosePage(sessionID string, pageID string) error {
 	// Get the session from the manager
@@ -186,7 +585,7 @@ func (m *Manager) ClosePage(sessionID string, pageID string) error {
 	}
 
 	// Verify that the page ID is in the session
-	if !slices.Contains(session.PageIDs, pageID) {
+	if !session.HasPage(pageID) {
 		return fmt.Errorf("page not found in session: %s", pageID)
 	}
 
@@ -198,8 +597,20 @@ func (m *Manager) ClosePage(sessionID string, pageID string) error {
 	// Remove the page from the session tracking
 	session.RemovePage(pageID)
 
+	// The closed page must not come back on resume
+	m.savePages(session)
+
 	// Note: We DO update activity via RemovePage (it calls UpdateActivity)
 	// Note: We do NOT dispose context - other pages might still be open
 
 	return nil
 }
+
+// readyTimeout returns how long Navigate waits for page readiness
+// A caller deadline (e.g. a per-request timeout) replaces the default
+func readyTimeout(ctx context.Context) time.Duration {
+	if deadline, ok := ctx.Deadline(); ok {
+		return time.Until(deadline)
+	}
+	return DefaultReadyTimeout
+}
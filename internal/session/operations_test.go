@@ -1,6 +1,9 @@
 package session
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"os"
 	"strings"
 	"testing"
@@ -8,6 +11,7 @@ import (
 
 	"github.com/dhruvsoni1802/browser-query-ai/internal/browser"
 	"github.com/dhruvsoni1802/browser-query-ai/internal/config"
+	"github.com/dhruvsoni1802/browser-query-ai/internal/storage"
 )
 
 // Test helper: Setup browser and manager for operations tests
@@ -59,10 +63,11 @@ func TestNavigate(t *testing.T) {
 	}
 
 	// Navigate to a URL
-	pageID, err := manager.Navigate(session.ID, "https://example.com")
+	nav, err := manager.Navigate(context.Background(), session.ID, "https://example.com", "", nil)
 	if err != nil {
 		t.Fatalf("Navigate failed: %v", err)
 	}
+	pageID := nav.PageID
 
 	// Verify pageID is not empty
 	if pageID == "" {
@@ -100,10 +105,11 @@ func TestNavigateMultiplePages(t *testing.T) {
 
 	pageIDs := make([]string, len(urls))
 	for i, url := range urls {
-		pageID, err := manager.Navigate(session.ID, url)
+		nav, err := manager.Navigate(context.Background(), session.ID, url, "", nil)
 		if err != nil {
 			t.Fatalf("Navigate to %s failed: %v", url, err)
 		}
+		pageID := nav.PageID
 		pageIDs[i] = pageID
 		t.Logf("opened page %d: %s → %s", i+1, url, pageID)
 	}
@@ -123,13 +129,285 @@ func TestNavigateMultiplePages(t *testing.T) {
 	}
 }
 
+// TestNavigateExistingPage tests navigating a page that is already open
+func TestNavigateExistingPage(t *testing.T) {
+	proc, manager, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	session, err := manager.CreateSession(proc.DebugPort)
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	first, err := manager.Navigate(context.Background(), session.ID, "https://example.com", "", nil)
+	if err != nil {
+		t.Fatalf("Navigate failed: %v", err)
+	}
+
+	// Navigate the same page again
+	second, err := manager.Navigate(context.Background(), session.ID, "https://example.org", first.PageID, nil)
+	if err != nil {
+		t.Fatalf("Navigate existing page failed: %v", err)
+	}
+
+	if second.PageID != first.PageID {
+		t.Errorf("expected page %s to be reused, got %s", first.PageID, second.PageID)
+	}
+
+	if len(session.PageIDs) != 1 {
+		t.Errorf("expected 1 page in session, got %d", len(session.PageIDs))
+	}
+
+	if second.Status != 200 {
+		t.Errorf("expected status 200, got %d", second.Status)
+	}
+
+	if !strings.HasPrefix(second.URL, "https://example.org") {
+		t.Errorf("expected final URL on example.org, got %s", second.URL)
+	}
+
+	// Navigating a page that isn't in the session fails
+	_, err = manager.Navigate(context.Background(), session.ID, "https://example.com", "invalid-page-id", nil)
+	if err == nil || !strings.Contains(err.Error(), "page not found") {
+		t.Errorf("expected page not found error, got %v", err)
+	}
+}
+
+// TestNavigateHistory tests back, forward, reload and the history listing
+func TestNavigateHistory(t *testing.T) {
+	proc, manager, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	session, err := manager.CreateSession(proc.DebugPort)
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	ctx := context.Background()
+
+	first, err := manager.Navigate(ctx, session.ID, "https://example.com", "", nil)
+	if err != nil {
+		t.Fatalf("Navigate failed: %v", err)
+	}
+	pageID := first.PageID
+
+	if _, err := manager.Navigate(ctx, session.ID, "https://example.org", pageID, nil); err != nil {
+		t.Fatalf("Navigate existing page failed: %v", err)
+	}
+
+	// Nothing to go forward to yet
+	_, err = manager.NavigateHistory(ctx, session.ID, pageID, 1)
+	if !errors.Is(err, ErrNoHistoryEntry) {
+		t.Errorf("expected ErrNoHistoryEntry, got %v", err)
+	}
+
+	back, err := manager.NavigateHistory(ctx, session.ID, pageID, -1)
+	if err != nil {
+		t.Fatalf("NavigateHistory back failed: %v", err)
+	}
+	if !strings.HasPrefix(back.URL, "https://example.com") {
+		t.Errorf("expected to be back on example.com, got %s", back.URL)
+	}
+
+	history, err := manager.GetNavigationHistory(ctx, session.ID, pageID)
+	if err != nil {
+		t.Fatalf("GetNavigationHistory failed: %v", err)
+	}
+	if history.CurrentIndex+1 >= len(history.Entries) {
+		t.Errorf("expected a forward entry, got index %d of %d", history.CurrentIndex, len(history.Entries))
+	}
+
+	forward, err := manager.NavigateHistory(ctx, session.ID, pageID, 1)
+	if err != nil {
+		t.Fatalf("NavigateHistory forward failed: %v", err)
+	}
+	if !strings.HasPrefix(forward.URL, "https://example.org") {
+		t.Errorf("expected to be forward on example.org, got %s", forward.URL)
+	}
+
+	reloaded, err := manager.Reload(ctx, session.ID, pageID, true)
+	if err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if !strings.HasPrefix(reloaded.URL, "https://example.org") {
+		t.Errorf("expected reload to stay on example.org, got %s", reloaded.URL)
+	}
+}
+
+// TestPerformActions tests clicking, typing and the structured element errors
+func TestPerformActions(t *testing.T) {
+	proc, manager, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	session, err := manager.CreateSession(proc.DebugPort)
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	ctx := context.Background()
+
+	page := `data:text/html,<input id="name"><button id="go" onclick="document.title=document.getElementById('name').value">Go</button>` +
+		`<button id="off" disabled>Off</button><div id="gone" style="display:none">Gone</div>`
+	nav, err := manager.Navigate(ctx, session.ID, page, "", nil)
+	if err != nil {
+		t.Fatalf("Navigate failed: %v", err)
+	}
+
+	actions := []Action{
+		{Type: ActionType, ActionTarget: ActionTarget{Selector: "#name"}, Text: "hello"},
+		{Type: ActionClick, ActionTarget: ActionTarget{XPath: "//button[@id='go']"}},
+	}
+	results, err := manager.PerformActions(ctx, session.ID, nav.PageID, actions)
+	if err != nil {
+		t.Fatalf("PerformActions failed: %v", err)
+	}
+	if len(results) != len(actions) {
+		t.Errorf("expected %d results, got %d", len(actions), len(results))
+	}
+
+	title, err := manager.ExecuteJavascript(ctx, session.ID, nav.PageID, "document.title")
+	if err != nil {
+		t.Fatalf("ExecuteJavascript failed: %v", err)
+	}
+	if title != "hello" {
+		t.Errorf("expected title 'hello' after typing and clicking, got %v", title)
+	}
+
+	tests := []struct {
+		selector string
+		expected error
+	}{
+		{"#missing", ErrElementNotFound},
+		{"#gone", ErrElementNotVisible},
+		{"#off", ErrElementDisabled},
+	}
+
+	for _, tt := range tests {
+		click := Action{Type: ActionClick, ActionTarget: ActionTarget{Selector: tt.selector}}
+		if _, err := manager.PerformActions(ctx, session.ID, nav.PageID, []Action{click}); !errors.Is(err, tt.expected) {
+			t.Errorf("click %s expected %v, got %v", tt.selector, tt.expected, err)
+		}
+	}
+}
+
+// TestPerformActionByRef tests acting on an accessibility tree ref and its staleness after navigation
+func TestPerformActionByRef(t *testing.T) {
+	proc, manager, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	session, err := manager.CreateSession(proc.DebugPort)
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	ctx := context.Background()
+
+	nav, err := manager.Navigate(ctx, session.ID, `data:text/html,<button onclick="document.title='clicked'">Press</button>`, "", nil)
+	if err != nil {
+		t.Fatalf("Navigate failed: %v", err)
+	}
+
+	tree, err := manager.GetAccessibilityTree(ctx, session.ID, nav.PageID, AXTreeOptions{})
+	if err != nil {
+		t.Fatalf("GetAccessibilityTree failed: %v", err)
+	}
+
+	var ref string
+	var find func(nodes []*AXNode)
+	find = func(nodes []*AXNode) {
+		for _, node := range nodes {
+			if node.Role == "button" {
+				ref = node.Ref
+			}
+			find(node.Children)
+		}
+	}
+	find(tree.Nodes)
+	if ref == "" {
+		t.Fatal("expected the button to have a ref")
+	}
+
+	click := Action{Type: ActionClick, ActionTarget: ActionTarget{Ref: ref}}
+	if _, err := manager.PerformActions(ctx, session.ID, nav.PageID, []Action{click}); err != nil {
+		t.Fatalf("click by ref failed: %v", err)
+	}
+
+	if _, err := manager.Navigate(ctx, session.ID, "data:text/html,<button>Other</button>", nav.PageID, nil); err != nil {
+		t.Fatalf("Navigate existing page failed: %v", err)
+	}
+
+	if _, err := manager.PerformActions(ctx, session.ID, nav.PageID, []Action{click}); !errors.Is(err, ErrStaleElementRef) {
+		t.Errorf("expected ErrStaleElementRef after navigation, got %v", err)
+	}
+}
+
+// TestWait tests waiting for conditions and the diagnostics of a timed out wait
+func TestWait(t *testing.T) {
+	proc, manager, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	session, err := manager.CreateSession(proc.DebugPort)
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	ctx := context.Background()
+
+	// The element only shows up after a delay, like content rendered by a SPA
+	page := `data:text/html,<script>setTimeout(function(){document.body.innerHTML='<p id="late">Loaded</p>'}, 500)</script>`
+	wait := &WaitCondition{Selector: "#late", Text: "Loaded", NetworkIdleMs: 200}
+	nav, err := manager.Navigate(ctx, session.ID, page, "", wait)
+	if err != nil {
+		t.Fatalf("Navigate with wait failed: %v", err)
+	}
+
+	result, err := manager.Wait(ctx, session.ID, nav.PageID, WaitCondition{Function: "document.querySelectorAll('p').length === 1"})
+	if err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+	if result.URL == "" {
+		t.Error("expected the page URL in the wait result")
+	}
+
+	_, err = manager.Wait(ctx, session.ID, nav.PageID, WaitCondition{Selector: "#never", TimeoutMs: 300})
+	var waitErr *WaitTimeoutError
+	if !errors.As(err, &waitErr) {
+		t.Fatalf("expected WaitTimeoutError, got %v", err)
+	}
+	if len(waitErr.Diagnostics.Unmet) == 0 || waitErr.Diagnostics.ReadyState != "complete" {
+		t.Errorf("expected diagnostics for the unmet selector, got %+v", waitErr.Diagnostics)
+	}
+}
+
+// TestNavigateError tests that navigation errors are reported instead of failing
+func TestNavigateError(t *testing.T) {
+	proc, manager, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	session, err := manager.CreateSession(proc.DebugPort)
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	nav, err := manager.Navigate(context.Background(), session.ID, "https://does-not-exist.invalid", "", nil)
+	if err != nil {
+		t.Fatalf("Navigate failed: %v", err)
+	}
+
+	if nav.ErrorText == "" {
+		t.Error("expected navigation error text, got none")
+	}
+
+	t.Logf("navigation error: %s", nav.ErrorText)
+}
+
 // TestNavigateInvalidSession tests navigation with invalid session
 func TestNavigateInvalidSession(t *testing.T) {
 	_, manager, cleanup := setupTestManager(t)
 	defer cleanup()
 
 	// Try to navigate with non-existent session
-	_, err := manager.Navigate("invalid-session-id", "https://example.com")
+	_, err := manager.Navigate(context.Background(), "invalid-session-id", "https://example.com", "", nil)
 	if err == nil {
 		t.Error("expected error for invalid session, got nil")
 	}
@@ -150,19 +428,21 @@ func TestCaptureScreenshot(t *testing.T) {
 	}
 
 	// Navigate to a page
-	pageID, err := manager.Navigate(session.ID, "https://example.com")
+	nav, err := manager.Navigate(context.Background(), session.ID, "https://example.com", "", nil)
 	if err != nil {
 		t.Fatalf("Navigate failed: %v", err)
 	}
+	pageID := nav.PageID
 
 	// Wait for page to load
 	time.Sleep(2 * time.Second)
 
 	// Capture screenshot
-	screenshot, err := manager.CaptureScreenshot(session.ID, pageID)
+	shot, err := manager.CaptureScreenshot(context.Background(), session.ID, pageID, ScreenshotOptions{})
 	if err != nil {
 		t.Fatalf("CaptureScreenshot failed: %v", err)
 	}
+	screenshot := shot.Data
 
 	// Verify screenshot is not empty
 	if len(screenshot) == 0 {
@@ -185,6 +465,138 @@ func TestCaptureScreenshot(t *testing.T) {
 	// os.WriteFile("test_screenshot.png", screenshot, 0644)
 }
 
+// TestCaptureScreenshotOptions tests format, full page and element screenshots
+func TestCaptureScreenshotOptions(t *testing.T) {
+	proc, manager, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	session, err := manager.CreateSession(proc.DebugPort)
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	nav, err := manager.Navigate(context.Background(), session.ID, "https://example.com", "", nil)
+	if err != nil {
+		t.Fatalf("Navigate failed: %v", err)
+	}
+	pageID := nav.PageID
+
+	// JPEG of the viewport
+	shot, err := manager.CaptureScreenshot(context.Background(), session.ID, pageID, ScreenshotOptions{Format: ScreenshotJPEG, Quality: 50})
+	if err != nil {
+		t.Fatalf("CaptureScreenshot jpeg failed: %v", err)
+	}
+	if len(shot.Data) < 2 || shot.Data[0] != 0xff || shot.Data[1] != 0xd8 {
+		t.Error("screenshot is not a valid JPEG file")
+	}
+	if shot.Format != ScreenshotJPEG || shot.Width == 0 || shot.Height == 0 {
+		t.Errorf("unexpected screenshot metadata: %s %dx%d", shot.Format, shot.Width, shot.Height)
+	}
+
+	// Element screenshot at double scale is twice the element's CSS size
+	element, err := manager.CaptureScreenshot(context.Background(), session.ID, pageID, ScreenshotOptions{Selector: "h1", Scale: 2})
+	if err != nil {
+		t.Fatalf("CaptureScreenshot element failed: %v", err)
+	}
+	if element.Width >= shot.Width*2 || element.Height >= shot.Height {
+		t.Errorf("element screenshot %dx%d should be smaller than the page", element.Width, element.Height)
+	}
+
+	// Full page is at least as tall as the viewport
+	full, err := manager.CaptureScreenshot(context.Background(), session.ID, pageID, ScreenshotOptions{FullPage: true})
+	if err != nil {
+		t.Fatalf("CaptureScreenshot full page failed: %v", err)
+	}
+	if full.Height == 0 {
+		t.Error("full page screenshot has no height")
+	}
+
+	// Unknown elements are reported as such
+	_, err = manager.CaptureScreenshot(context.Background(), session.ID, pageID, ScreenshotOptions{Selector: "#does-not-exist"})
+	if !errors.Is(err, ErrElementNotFound) {
+		t.Errorf("expected ErrElementNotFound, got %v", err)
+	}
+}
+
+// TestCaptureAnnotatedScreenshot tests that interactive elements are marked and the overlay is removed
+func TestCaptureAnnotatedScreenshot(t *testing.T) {
+	proc, manager, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	session, err := manager.CreateSession(proc.DebugPort)
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	nav, err := manager.Navigate(context.Background(), session.ID, "https://example.com", "", nil)
+	if err != nil {
+		t.Fatalf("Navigate failed: %v", err)
+	}
+	pageID := nav.PageID
+
+	shot, err := manager.CaptureScreenshot(context.Background(), session.ID, pageID, ScreenshotOptions{Annotate: true})
+	if err != nil {
+		t.Fatalf("CaptureScreenshot annotated failed: %v", err)
+	}
+
+	// example.com has a single link
+	if len(shot.Marks) != 1 {
+		t.Fatalf("expected 1 mark, got %d: %+v", len(shot.Marks), shot.Marks)
+	}
+	mark := shot.Marks[0]
+	if mark.ID != 1 || mark.Role != "link" || mark.Ref == "" {
+		t.Errorf("unexpected mark %+v", mark)
+	}
+	if mark.Box.Width <= 0 || mark.Box.X+mark.Box.Width > float64(shot.Width) || mark.Box.Y+mark.Box.Height > float64(shot.Height) {
+		t.Errorf("mark box %+v outside the %dx%d screenshot", mark.Box, shot.Width, shot.Height)
+	}
+
+	// The page is left as it was
+	result, err := manager.ExecuteJavascript(context.Background(), session.ID, pageID, "document.getElementById('"+markOverlayID+"') === null")
+	if err != nil {
+		t.Fatalf("ExecuteJavascript failed: %v", err)
+	}
+	if result != true {
+		t.Error("annotation overlay was not removed")
+	}
+
+	// The legend refs are action targets
+	_, err = manager.PerformActions(context.Background(), session.ID, pageID, []Action{{Type: ActionHover, ActionTarget: ActionTarget{Ref: mark.Ref}}})
+	if err != nil {
+		t.Errorf("hover on mark ref failed: %v", err)
+	}
+}
+
+// TestPrintToPDF tests exporting a page as a PDF
+func TestPrintToPDF(t *testing.T) {
+	proc, manager, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	session, err := manager.CreateSession(proc.DebugPort)
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	nav, err := manager.Navigate(context.Background(), session.ID, "https://example.com", "", nil)
+	if err != nil {
+		t.Fatalf("Navigate failed: %v", err)
+	}
+
+	pdf, err := manager.PrintToPDF(context.Background(), session.ID, nav.PageID, PDFOptions{PaperFormat: "a4", Landscape: true, PrintBackground: true})
+	if err != nil {
+		t.Fatalf("PrintToPDF failed: %v", err)
+	}
+	if !bytes.HasPrefix(pdf, []byte("%PDF-")) {
+		t.Error("output is not a PDF document")
+	}
+
+	// example.com fits on one page
+	_, err = manager.PrintToPDF(context.Background(), session.ID, nav.PageID, PDFOptions{PageRanges: "5-6"})
+	if !errors.Is(err, ErrInvalidPDFOptions) {
+		t.Errorf("expected ErrInvalidPDFOptions for pages past the end, got %v", err)
+	}
+}
+
 // TestCaptureScreenshotInvalidPage tests screenshot with invalid page
 func TestCaptureScreenshotInvalidPage(t *testing.T) {
 	proc, manager, cleanup := setupTestManager(t)
@@ -196,7 +608,7 @@ func TestCaptureScreenshotInvalidPage(t *testing.T) {
 	}
 
 	// Try to screenshot non-existent page
-	_, err = manager.CaptureScreenshot(session.ID, "invalid-page-id")
+	_, err = manager.CaptureScreenshot(context.Background(), session.ID, "invalid-page-id", ScreenshotOptions{})
 	if err == nil {
 		t.Error("expected error for invalid page, got nil")
 	}
@@ -216,16 +628,17 @@ func TestExecuteJavascript(t *testing.T) {
 		t.Fatalf("CreateSession failed: %v", err)
 	}
 
-	pageID, err := manager.Navigate(session.ID, "https://example.com")
+	nav, err := manager.Navigate(context.Background(), session.ID, "https://example.com", "", nil)
 	if err != nil {
 		t.Fatalf("Navigate failed: %v", err)
 	}
+	pageID := nav.PageID
 
 	// Wait for page to load
 	time.Sleep(2 * time.Second)
 
 	// Test 1: Get page title
-	result, err := manager.ExecuteJavascript(session.ID, pageID, "document.title")
+	result, err := manager.ExecuteJavascript(context.Background(), session.ID, pageID, "document.title")
 	if err != nil {
 		t.Fatalf("ExecuteJavascript failed: %v", err)
 	}
@@ -242,7 +655,7 @@ func TestExecuteJavascript(t *testing.T) {
 	t.Logf("page title: %s", title)
 
 	// Test 2: Simple arithmetic
-	result, err = manager.ExecuteJavascript(session.ID, pageID, "2 + 2")
+	result, err = manager.ExecuteJavascript(context.Background(), session.ID, pageID, "2 + 2")
 	if err != nil {
 		t.Fatalf("ExecuteJavascript failed: %v", err)
 	}
@@ -258,7 +671,7 @@ func TestExecuteJavascript(t *testing.T) {
 	}
 
 	// Test 3: Return object
-	result, err = manager.ExecuteJavascript(session.ID, pageID, "({name: 'test', value: 42})")
+	result, err = manager.ExecuteJavascript(context.Background(), session.ID, pageID, "({name: 'test', value: 42})")
 	if err != nil {
 		t.Fatalf("ExecuteJavascript failed: %v", err)
 	}
@@ -275,6 +688,346 @@ func TestExecuteJavascript(t *testing.T) {
 	t.Logf("executed JavaScript successfully")
 }
 
+// TestGetConsoleLog tests capturing console messages and uncaught exceptions of a page
+func TestGetConsoleLog(t *testing.T) {
+	proc, manager, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	session, err := manager.CreateSession(proc.DebugPort)
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	nav, err := manager.Navigate(context.Background(), session.ID, "https://example.com", "", nil)
+	if err != nil {
+		t.Fatalf("Navigate failed: %v", err)
+	}
+	pageID := nav.PageID
+
+	script := "console.log('hello', 42); console.warn('careful'); setTimeout(function() { throw new Error('boom'); }, 0)"
+	if _, err := manager.ExecuteJavascript(context.Background(), session.ID, pageID, script); err != nil {
+		t.Fatalf("ExecuteJavascript failed: %v", err)
+	}
+
+	// Events arrive asynchronously
+	time.Sleep(500 * time.Millisecond)
+
+	log, err := manager.GetConsoleLog(session.ID, pageID, ConsoleQuery{})
+	if err != nil {
+		t.Fatalf("GetConsoleLog failed: %v", err)
+	}
+
+	var texts []string
+	for _, entry := range log.Entries {
+		texts = append(texts, entry.Level+": "+entry.Text)
+	}
+	joined := strings.Join(texts, "\n")
+	for _, expected := range []string{"info: hello 42", "warning: careful", "error: Uncaught Error: boom"} {
+		if !strings.Contains(joined, expected) {
+			t.Errorf("expected %q in console log:\n%s", expected, joined)
+		}
+	}
+
+	// Warnings and above since the first entry
+	filtered, err := manager.GetConsoleLog(session.ID, pageID, ConsoleQuery{Level: ConsoleLevelWarning, Since: log.Entries[0].Seq})
+	if err != nil {
+		t.Fatalf("GetConsoleLog filtered failed: %v", err)
+	}
+	for _, entry := range filtered.Entries {
+		if entry.Level != ConsoleLevelWarning && entry.Level != ConsoleLevelError {
+			t.Errorf("unexpected %s entry with level filter", entry.Level)
+		}
+	}
+
+	// Closing the page clears its log
+	if err := manager.ClosePage(session.ID, pageID); err != nil {
+		t.Fatalf("ClosePage failed: %v", err)
+	}
+	if _, err := manager.GetConsoleLog(session.ID, pageID, ConsoleQuery{}); err == nil {
+		t.Error("expected an error for a closed page")
+	}
+}
+
+// TestGetNetworkLog tests capturing requests, fetching a body and exporting a HAR
+func TestGetNetworkLog(t *testing.T) {
+	proc, manager, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	session, err := manager.CreateSession(proc.DebugPort)
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	nav, err := manager.Navigate(context.Background(), session.ID, "https://example.com", "", nil)
+	if err != nil {
+		t.Fatalf("Navigate failed: %v", err)
+	}
+	pageID := nav.PageID
+
+	// Events arrive asynchronously
+	time.Sleep(500 * time.Millisecond)
+
+	log, err := manager.GetNetworkLog(session.ID, pageID, NetworkQuery{Types: []string{"document"}})
+	if err != nil {
+		t.Fatalf("GetNetworkLog failed: %v", err)
+	}
+	if len(log.Entries) == 0 {
+		t.Fatal("expected the document request in the network log")
+	}
+
+	document := log.Entries[0]
+	if !strings.HasPrefix(document.URL, "https://example.com") || document.Method != "GET" {
+		t.Errorf("unexpected document request %s %s", document.Method, document.URL)
+	}
+	if document.State != NetworkComplete || document.Status != 200 || document.Timings == nil {
+		t.Errorf("expected a complete 200 with timings, got %s %d", document.State, document.Status)
+	}
+
+	body, err := manager.GetResponseBody(context.Background(), session.ID, pageID, document.RequestID)
+	if err != nil {
+		t.Fatalf("GetResponseBody failed: %v", err)
+	}
+	if !strings.Contains(string(body.Data), "Example Domain") {
+		t.Error("expected the document body")
+	}
+
+	if _, err := manager.GetResponseBody(context.Background(), session.ID, pageID, "missing"); !errors.Is(err, ErrRequestNotFound) {
+		t.Errorf("expected ErrRequestNotFound, got %v", err)
+	}
+
+	har, err := manager.ExportHAR(session.ID)
+	if err != nil {
+		t.Fatalf("ExportHAR failed: %v", err)
+	}
+	if len(har.Log.Pages) != 1 || len(har.Log.Entries) == 0 {
+		t.Fatalf("expected one page with entries, got %d pages and %d entries", len(har.Log.Pages), len(har.Log.Entries))
+	}
+	if har.Log.Entries[0].Pageref != pageID {
+		t.Errorf("expected entries to refer to %s, got %s", pageID, har.Log.Entries[0].Pageref)
+	}
+}
+
+// TestInterceptionRules tests fulfilling a document with a canned response and counting matches
+func TestInterceptionRules(t *testing.T) {
+	proc, manager, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	session, err := manager.CreateSession(proc.DebugPort)
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	rules := []InterceptionRule{
+		{Action: InterceptHeaders, Headers: map[string]string{"X-Test": "1"}},
+		{Action: InterceptBlock, ResourceTypes: []string{"image", "font"}},
+		{ID: "mock", Action: InterceptFulfill, URL: "https://example.com/", Response: &MockResponse{
+			ContentType: "text/html",
+			Body:        "<html><head><title>Mocked</title></head><body></body></html>",
+		}},
+	}
+	if _, err := manager.SetInterceptionRules(context.Background(), session.ID, rules); err != nil {
+		t.Fatalf("SetInterceptionRules failed: %v", err)
+	}
+
+	nav, err := manager.Navigate(context.Background(), session.ID, "https://example.com/", "", nil)
+	if err != nil {
+		t.Fatalf("Navigate failed: %v", err)
+	}
+
+	title, err := manager.ExecuteJavascript(context.Background(), session.ID, nav.PageID, "document.title")
+	if err != nil {
+		t.Fatalf("ExecuteJavascript failed: %v", err)
+	}
+	if title != "Mocked" {
+		t.Errorf("expected the mocked document, got title %v", title)
+	}
+
+	stats, err := manager.GetInterceptionStats(session.ID)
+	if err != nil {
+		t.Fatalf("GetInterceptionStats failed: %v", err)
+	}
+	if len(stats.Rules) != 3 || stats.Rules[0].Matched == 0 || stats.Rules[2].Matched == 0 {
+		t.Errorf("expected the header and mock rules to match, got %+v", stats.Rules)
+	}
+
+	// Clearing the rules lets requests through again
+	if _, err := manager.SetInterceptionRules(context.Background(), session.ID, nil); err != nil {
+		t.Fatalf("SetInterceptionRules clear failed: %v", err)
+	}
+	if _, err := manager.Navigate(context.Background(), session.ID, "https://example.com/", nav.PageID, nil); err != nil {
+		t.Fatalf("Navigate failed: %v", err)
+	}
+	title, err = manager.ExecuteJavascript(context.Background(), session.ID, nav.PageID, "document.title")
+	if err != nil {
+		t.Fatalf("ExecuteJavascript failed: %v", err)
+	}
+	if title != "Example Domain" {
+		t.Errorf("expected the real document after clearing rules, got title %v", title)
+	}
+}
+
+// TestEmulation tests that pages opened by an emulating session look like the device
+func TestEmulation(t *testing.T) {
+	proc, manager, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	session, err := manager.CreateSession(proc.DebugPort)
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	opts := &EmulationOptions{Device: "iphone-15", Locale: "de-DE", Timezone: "Europe/Berlin", ColorScheme: ColorSchemeDark}
+	if err := manager.SetEmulation(context.Background(), session.ID, opts); err != nil {
+		t.Fatalf("SetEmulation failed: %v", err)
+	}
+
+	nav, err := manager.Navigate(context.Background(), session.ID, "https://example.com", "", nil)
+	if err != nil {
+		t.Fatalf("Navigate failed: %v", err)
+	}
+
+	checks := map[string]interface{}{
+		"window.innerWidth":                                  float64(393),
+		"navigator.userAgent.includes('iPhone')":             true,
+		"navigator.language":                                 "de-DE",
+		"Intl.DateTimeFormat().resolvedOptions().timeZone":   "Europe/Berlin",
+		"matchMedia('(prefers-color-scheme: dark)').matches": true,
+		"navigator.maxTouchPoints > 0":                       true,
+	}
+	for script, expected := range checks {
+		result, err := manager.ExecuteJavascript(context.Background(), session.ID, nav.PageID, script)
+		if err != nil {
+			t.Fatalf("ExecuteJavascript failed: %v", err)
+		}
+		if result != expected {
+			t.Errorf("%s: expected %v, got %v", script, expected, result)
+		}
+	}
+
+	// An invalid timezone is rejected by the browser and the previous options stay
+	if err := manager.SetEmulation(context.Background(), session.ID, &EmulationOptions{Timezone: "Mars/Olympus_Mons"}); !errors.Is(err, ErrInvalidEmulation) {
+		t.Errorf("expected ErrInvalidEmulation, got %v", err)
+	}
+	if current := session.Emulation(); current == nil || current.Device != "iphone-15" {
+		t.Errorf("expected the previous options to stay, got %+v", current)
+	}
+}
+
+// TestBrowserState tests that cookies and localStorage carry over into a new context
+func TestBrowserState(t *testing.T) {
+	proc, manager, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	source, err := manager.CreateSession(proc.DebugPort)
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	nav, err := manager.Navigate(context.Background(), source.ID, "https://example.com", "", nil)
+	if err != nil {
+		t.Fatalf("Navigate failed: %v", err)
+	}
+
+	script := "document.cookie = 'token=abc; max-age=3600'; localStorage.setItem('theme', 'dark'); true"
+	if _, err := manager.ExecuteJavascript(context.Background(), source.ID, nav.PageID, script); err != nil {
+		t.Fatalf("ExecuteJavascript failed: %v", err)
+	}
+
+	state, err := source.captureBrowserState(context.Background())
+	if err != nil {
+		t.Fatalf("captureBrowserState failed: %v", err)
+	}
+	if items := state.localStorage["https://example.com"]; items["theme"] != "dark" {
+		t.Errorf("expected localStorage to be captured, got %v", state.localStorage)
+	}
+
+	// A fresh session has its own context, nothing is shared with the first one
+	target, err := manager.CreateSession(proc.DebugPort)
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	if err := target.restoreBrowserState(context.Background(), state); err != nil {
+		t.Fatalf("restoreBrowserState failed: %v", err)
+	}
+
+	nav, err = manager.Navigate(context.Background(), target.ID, "https://example.com", "", nil)
+	if err != nil {
+		t.Fatalf("Navigate failed: %v", err)
+	}
+
+	checks := map[string]interface{}{
+		"document.cookie.includes('token=abc')": true,
+		"localStorage.getItem('theme')":         "dark",
+	}
+	for script, expected := range checks {
+		result, err := manager.ExecuteJavascript(context.Background(), target.ID, nav.PageID, script)
+		if err != nil {
+			t.Fatalf("ExecuteJavascript failed: %v", err)
+		}
+		if result != expected {
+			t.Errorf("%s: expected %v, got %v", script, expected, result)
+		}
+	}
+}
+
+// TestRestorePages tests tracking page URLs and reopening them with new page IDs
+func TestRestorePages(t *testing.T) {
+	proc, manager, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	session, err := manager.CreateSession(proc.DebugPort)
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	nav, err := manager.Navigate(context.Background(), session.ID, "https://example.com", "", nil)
+	if err != nil {
+		t.Fatalf("Navigate failed: %v", err)
+	}
+
+	// Tracking runs on page events, give it a moment to catch up with the load
+	var pages []storage.PageState
+	for i := 0; i < 20; i++ {
+		pages = session.PageStates()
+		if len(pages) == 1 && pages[0].Title != "" {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if len(pages) != 1 || pages[0].URL != "https://example.com/" || pages[0].Title != "Example Domain" {
+		t.Fatalf("expected the page's URL and title to be tracked, got %+v", pages)
+	}
+
+	// Pretend the session was closed with this page open and resurrected
+	if err := manager.ClosePage(session.ID, nav.PageID); err != nil {
+		t.Fatalf("ClosePage failed: %v", err)
+	}
+	session.restorablePages = pages
+
+	restored, err := manager.RestorePages(context.Background(), session.ID)
+	if err != nil {
+		t.Fatalf("RestorePages failed: %v", err)
+	}
+
+	newPageID := restored[nav.PageID]
+	if newPageID == "" || newPageID == nav.PageID {
+		t.Fatalf("expected a new page for %s, got %v", nav.PageID, restored)
+	}
+
+	href, err := manager.ExecuteJavascript(context.Background(), session.ID, newPageID, "location.href")
+	if err != nil {
+		t.Fatalf("ExecuteJavascript failed: %v", err)
+	}
+	if href != "https://example.com/" {
+		t.Errorf("expected the reopened page on https://example.com/, got %v", href)
+	}
+
+	// Restoring twice reopens nothing
+	if again, err := manager.RestorePages(context.Background(), session.ID); err != nil || len(again) != 0 {
+		t.Errorf("expected nothing left to restore, got %v, %v", again, err)
+	}
+}
+
 // TestExecuteJavascriptInvalidPage tests JS execution with invalid page
 func TestExecuteJavascriptInvalidPage(t *testing.T) {
 	proc, manager, cleanup := setupTestManager(t)
@@ -286,7 +1039,7 @@ func TestExecuteJavascriptInvalidPage(t *testing.T) {
 	}
 
 	// Try to execute on non-existent page
-	_, err = manager.ExecuteJavascript(session.ID, "invalid-page-id", "2 + 2")
+	_, err = manager.ExecuteJavascript(context.Background(), session.ID, "invalid-page-id", "2 + 2")
 	if err == nil {
 		t.Error("expected error for invalid page, got nil")
 	}
@@ -306,16 +1059,17 @@ func TestGetPageContent(t *testing.T) {
 		t.Fatalf("CreateSession failed: %v", err)
 	}
 
-	pageID, err := manager.Navigate(session.ID, "https://example.com")
+	nav, err := manager.Navigate(context.Background(), session.ID, "https://example.com", "", nil)
 	if err != nil {
 		t.Fatalf("Navigate failed: %v", err)
 	}
+	pageID := nav.PageID
 
 	// Wait for page to load
 	time.Sleep(2 * time.Second)
 
 	// Get page content
-	content, err := manager.GetPageContent(session.ID, pageID)
+	content, err := manager.GetPageContent(context.Background(), session.ID, pageID)
 	if err != nil {
 		t.Fatalf("GetPageContent failed: %v", err)
 	}
@@ -348,7 +1102,7 @@ func TestGetPageContentInvalidPage(t *testing.T) {
 	}
 
 	// Try to get content from non-existent page
-	_, err = manager.GetPageContent(session.ID, "invalid-page-id")
+	_, err = manager.GetPageContent(context.Background(), session.ID, "invalid-page-id")
 	if err == nil {
 		t.Error("expected error for invalid page, got nil")
 	}
@@ -369,15 +1123,17 @@ func TestClosePage(t *testing.T) {
 	}
 
 	// Open two pages
-	pageID1, err := manager.Navigate(session.ID, "https://example.com")
+	nav1, err := manager.Navigate(context.Background(), session.ID, "https://example.com", "", nil)
 	if err != nil {
 		t.Fatalf("Navigate failed: %v", err)
 	}
+	pageID1 := nav1.PageID
 
-	pageID2, err := manager.Navigate(session.ID, "https://example.org")
+	nav2, err := manager.Navigate(context.Background(), session.ID, "https://example.org", "", nil)
 	if err != nil {
 		t.Fatalf("Navigate failed: %v", err)
 	}
+	pageID2 := nav2.PageID
 
 	// Verify both pages are tracked
 	if len(session.PageIDs) != 2 {
@@ -437,10 +1193,11 @@ func TestCompleteWorkflow(t *testing.T) {
 	t.Logf("created session: %s", session.ID)
 
 	// Navigate to page
-	pageID, err := manager.Navigate(session.ID, "https://example.com")
+	nav, err := manager.Navigate(context.Background(), session.ID, "https://example.com", "", nil)
 	if err != nil {
 		t.Fatalf("Navigate failed: %v", err)
 	}
+	pageID := nav.PageID
 
 	t.Logf("navigated to example.com, pageID: %s", pageID)
 
@@ -448,7 +1205,7 @@ func TestCompleteWorkflow(t *testing.T) {
 	time.Sleep(2 * time.Second)
 
 	// Get title via JavaScript
-	title, err := manager.ExecuteJavascript(session.ID, pageID, "document.title")
+	title, err := manager.ExecuteJavascript(context.Background(), session.ID, pageID, "document.title")
 	if err != nil {
 		t.Fatalf("ExecuteJavascript failed: %v", err)
 	}
@@ -456,7 +1213,7 @@ func TestCompleteWorkflow(t *testing.T) {
 	t.Logf("page title: %v", title)
 
 	// Get page content
-	content, err := manager.GetPageContent(session.ID, pageID)
+	content, err := manager.GetPageContent(context.Background(), session.ID, pageID)
 	if err != nil {
 		t.Fatalf("GetPageContent failed: %v", err)
 	}
@@ -464,10 +1221,11 @@ func TestCompleteWorkflow(t *testing.T) {
 	t.Logf("page content: %d bytes", len(content))
 
 	// Take screenshot
-	screenshot, err := manager.CaptureScreenshot(session.ID, pageID)
+	shot, err := manager.CaptureScreenshot(context.Background(), session.ID, pageID, ScreenshotOptions{})
 	if err != nil {
 		t.Fatalf("CaptureScreenshot failed: %v", err)
 	}
+	screenshot := shot.Data
 
 	t.Logf("screenshot: %d bytes", len(screenshot))
 
@@ -508,10 +1266,11 @@ func TestActivityTracking(t *testing.T) {
 	time.Sleep(100 * time.Millisecond)
 
 	// Navigate (should update activity via AddPage)
-	pageID, err := manager.Navigate(session.ID, "https://example.com")
+	nav, err := manager.Navigate(context.Background(), session.ID, "https://example.com", "", nil)
 	if err != nil {
 		t.Fatalf("Navigate failed: %v", err)
 	}
+	pageID := nav.PageID
 
 	if !session.LastActivity.After(initialActivity) {
 		t.Error("Navigate did not update LastActivity")
@@ -522,7 +1281,7 @@ func TestActivityTracking(t *testing.T) {
 
 	// Screenshot (should update activity)
 	time.Sleep(2 * time.Second) // Let page load
-	_, err = manager.CaptureScreenshot(session.ID, pageID)
+	_, err = manager.CaptureScreenshot(context.Background(), session.ID, pageID, ScreenshotOptions{})
 	if err != nil {
 		t.Fatalf("CaptureScreenshot failed: %v", err)
 	}
@@ -535,7 +1294,7 @@ func TestActivityTracking(t *testing.T) {
 	time.Sleep(100 * time.Millisecond)
 
 	// ExecuteJS (should update activity)
-	_, err = manager.ExecuteJavascript(session.ID, pageID, "2 + 2")
+	_, err = manager.ExecuteJavascript(context.Background(), session.ID, pageID, "2 + 2")
 	if err != nil {
 		t.Fatalf("ExecuteJavascript failed: %v", err)
 	}
@@ -1,6 +1,7 @@
 package session
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 )
@@ -173,7 +174,7 @@ const pageAnalyzerJS = `(function() {
 
 // AnalyzePage extracts the structural overview of a page.
 // Results are cached per pageID — call InvalidatePageAnalysis to clear.
-func (s *Session) AnalyzePage(targetID string) (*PageStructure, error) {
+func (s *Session) AnalyzePage(ctx context.Context, targetID string) (*PageStructure, error) {
 	// Check cache first
 	if s.pageAnalysisCache != nil {
 		if cached, ok := s.pageAnalysisCache[targetID]; ok {
@@ -182,7 +183,7 @@ func (s *Session) AnalyzePage(targetID string) (*PageStructure, error) {
 	}
 
 	// Execute the analyzer JavaScript
-	result, err := s.ExecuteJavascript(targetID, pageAnalyzerJS)
+	result, err := s.ExecuteJavascript(ctx, targetID, pageAnalyzerJS)
 	if err != nil {
 		return nil, fmt.Errorf("failed to analyze page: %w", err)
 	}
@@ -0,0 +1,184 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/dhruvsoni1802/browser-query-ai/internal/cdp"
+	"github.com/dhruvsoni1802/browser-query-ai/internal/storage"
+)
+
+// pageTracker follows the URL and title of one page
+type pageTracker struct {
+	state  storage.PageState
+	cancel context.CancelFunc
+}
+
+// startPageTracking keeps the page's URL and title current, calling onChange whenever either changes
+func (s *Session) startPageTracking(targetID string, onChange func()) error {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	events, err := s.CDPClient.SubscribeTarget(ctx, targetID, "Page.*", 64)
+	if err != nil {
+		cancel()
+		return fmt.Errorf("failed to subscribe to page events: %w", err)
+	}
+
+	// Replayed after a reconnect, so tracking survives it
+	if _, err := s.CDPClient.SendCommandToTargetContext(ctx, targetID, "Page.enable", nil); err != nil {
+		cancel()
+		return fmt.Errorf("failed to enable page events: %w", err)
+	}
+
+	s.pagesMu.Lock()
+	if s.pageTrackers == nil {
+		s.pageTrackers = make(map[string]*pageTracker)
+	}
+	if old := s.pageTrackers[targetID]; old != nil {
+		old.cancel()
+	}
+	s.pageTrackers[targetID] = &pageTracker{state: storage.PageState{PageID: targetID}, cancel: cancel}
+	s.pagesMu.Unlock()
+
+	go s.trackPage(ctx, targetID, events.C, onChange)
+
+	return nil
+}
+
+// trackPage refreshes the page's state on every event that can change its URL or title
+func (s *Session) trackPage(ctx context.Context, targetID string, events <-chan *cdp.Event, onChange func()) {
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if !changesPageState(targetID, event) {
+				continue
+			}
+
+			changed, err := s.refreshPageState(ctx, targetID)
+			if err != nil {
+				// The page may be closing, the next event will try again
+				slog.Debug("failed to refresh page state", "page_id", targetID, "error", err)
+				continue
+			}
+			if changed && onChange != nil {
+				onChange()
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// changesPageState reports whether an event can leave the page with a new URL or title
+func changesPageState(targetID string, event *cdp.Event) bool {
+	switch event.Method {
+	case "Page.frameNavigated":
+		var params struct {
+			Frame struct {
+				ParentID string `json:"parentId"`
+			} `json:"frame"`
+		}
+		// Only the main frame decides the page's URL
+		return json.Unmarshal(event.Params, &params) == nil && params.Frame.ParentID == ""
+	case "Page.navigatedWithinDocument":
+		var params struct {
+			FrameID string `json:"frameId"`
+		}
+		// The main frame of a page target has the target's ID
+		return json.Unmarshal(event.Params, &params) == nil && params.FrameID == targetID
+	case "Page.domContentEventFired", "Page.loadEventFired":
+		// The title is usually known by now
+		return true
+	default:
+		return false
+	}
+}
+
+// refreshPageState reads the page's URL and title from the browser and reports whether they changed
+func (s *Session) refreshPageState(ctx context.Context, targetID string) (bool, error) {
+	params := map[string]interface{}{"targetId": targetID}
+	result, err := s.CDPClient.SendCommandContext(ctx, "Target.getTargetInfo", params)
+	if err != nil {
+		return false, fmt.Errorf("failed to get target info: %w", err)
+	}
+
+	var response struct {
+		TargetInfo struct {
+			URL   string `json:"url"`
+			Title string `json:"title"`
+		} `json:"targetInfo"`
+	}
+	if err := json.Unmarshal(result, &response); err != nil {
+		return false, fmt.Errorf("failed to parse target info: %w", err)
+	}
+
+	s.pagesMu.Lock()
+	defer s.pagesMu.Unlock()
+
+	tracker := s.pageTrackers[targetID]
+	if tracker == nil {
+		return false, nil
+	}
+	if tracker.state.URL == response.TargetInfo.URL && tracker.state.Title == response.TargetInfo.Title {
+		return false, nil
+	}
+
+	tracker.state.URL = response.TargetInfo.URL
+	tracker.state.Title = response.TargetInfo.Title
+	return true, nil
+}
+
+// stopPageTracking stops following a page
+func (s *Session) stopPageTracking(targetID string) {
+	s.pagesMu.Lock()
+	defer s.pagesMu.Unlock()
+
+	if tracker := s.pageTrackers[targetID]; tracker != nil {
+		tracker.cancel()
+		delete(s.pageTrackers, targetID)
+	}
+}
+
+// stopAllPageTracking stops following every page of the session
+func (s *Session) stopAllPageTracking() {
+	s.pagesMu.Lock()
+	defer s.pagesMu.Unlock()
+
+	for targetID, tracker := range s.pageTrackers {
+		tracker.cancel()
+		delete(s.pageTrackers, targetID)
+	}
+}
+
+// PageStates returns the last known URL and title of every open page, in the order the pages are tracked
+func (s *Session) PageStates() []storage.PageState {
+	pageIDs := s.Pages()
+
+	s.pagesMu.Lock()
+	defer s.pagesMu.Unlock()
+
+	pages := make([]storage.PageState, 0, len(pageIDs))
+	for _, pageID := range pageIDs {
+		state := storage.PageState{PageID: pageID}
+		if tracker := s.pageTrackers[pageID]; tracker != nil {
+			state = tracker.state
+		}
+		pages = append(pages, state)
+	}
+	return pages
+}
+
+// takeRestorablePages returns the pages left open when the session was last closed, once
+func (s *Session) takeRestorablePages() []storage.PageState {
+	s.pagesMu.Lock()
+	defer s.pagesMu.Unlock()
+
+	pages := s.restorablePages
+	s.restorablePages = nil
+	return pages
+}
@@ -0,0 +1,36 @@
+package session
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/dhruvsoni1802/browser-query-ai/internal/cdp"
+)
+
+// TestChangesPageState tests picking the page events that can change a page's URL or title
+func TestChangesPageState(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		params   string
+		expected bool
+	}{
+		{"main frame navigated", "Page.frameNavigated", `{"frame":{"id":"T1","url":"https://example.com"}}`, true},
+		{"iframe navigated", "Page.frameNavigated", `{"frame":{"id":"F2","parentId":"T1","url":"https://ads.example"}}`, false},
+		{"main frame hash change", "Page.navigatedWithinDocument", `{"frameId":"T1","url":"https://example.com/#top"}`, true},
+		{"iframe hash change", "Page.navigatedWithinDocument", `{"frameId":"F2","url":"https://ads.example/#x"}`, false},
+		{"load", "Page.loadEventFired", `{"timestamp":1}`, true},
+		{"dom content loaded", "Page.domContentEventFired", `{"timestamp":1}`, true},
+		{"unrelated", "Page.frameStartedLoading", `{"frameId":"T1"}`, false},
+		{"malformed", "Page.frameNavigated", `not json`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := &cdp.Event{Method: tt.method, Params: json.RawMessage(tt.params)}
+			if got := changesPageState("T1", event); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
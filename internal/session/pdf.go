@@ -0,0 +1,141 @@
+package session
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// paperSizes are the named paper formats as width and height in inches, portrait
+var paperSizes = map[string][2]float64{
+	"letter":  {8.5, 11},
+	"legal":   {8.5, 14},
+	"tabloid": {11, 17},
+	"a3":      {11.69, 16.54},
+	"a4":      {8.27, 11.69},
+	"a5":      {5.83, 8.27},
+}
+
+// pageRangesPattern matches print page ranges such as "1-5, 8, 11-"
+var pageRangesPattern = regexp.MustCompile(`^\s*\d+\s*(-\s*\d*\s*)?(,\s*\d+\s*(-\s*\d*\s*)?)*$`)
+
+// PDFMargins are the page margins in inches
+type PDFMargins struct {
+	Top    float64 `json:"top"`
+	Right  float64 `json:"right"`
+	Bottom float64 `json:"bottom"`
+	Left   float64 `json:"left"`
+}
+
+// PDFOptions controls how a page is printed, the zero value prints letter portrait with Chrome's default margins
+type PDFOptions struct {
+	PaperFormat       string      `json:"paper_format,omitempty"` // letter (default), legal, tabloid, a3, a4 or a5
+	PaperWidth        float64     `json:"paper_width,omitempty"`  // Custom paper size in inches, instead of paper_format
+	PaperHeight       float64     `json:"paper_height,omitempty"`
+	Margins           *PDFMargins `json:"margins,omitempty"`
+	Landscape         bool        `json:"landscape,omitempty"`
+	PrintBackground   bool        `json:"print_background,omitempty"` // Include background colors and images
+	PageRanges        string      `json:"page_ranges,omitempty"`      // e.g. "1-5, 8", empty prints all pages
+	Scale             float64     `json:"scale,omitempty"`            // 0.1 to 2, default 1
+	PreferCSSPageSize bool        `json:"prefer_css_page_size,omitempty"`
+}
+
+// validate checks that the options are well formed
+func (o *PDFOptions) validate() error {
+	if o.PaperFormat != "" {
+		if _, ok := paperSizes[strings.ToLower(o.PaperFormat)]; !ok {
+			return fmt.Errorf("%w: unknown paper_format %q", ErrInvalidPDFOptions, o.PaperFormat)
+		}
+		if o.PaperWidth != 0 || o.PaperHeight != 0 {
+			return fmt.Errorf("%w: set paper_format or paper_width and paper_height, not both", ErrInvalidPDFOptions)
+		}
+	}
+
+	if (o.PaperWidth != 0) != (o.PaperHeight != 0) || o.PaperWidth < 0 || o.PaperHeight < 0 {
+		return fmt.Errorf("%w: paper_width and paper_height must both be positive", ErrInvalidPDFOptions)
+	}
+
+	if m := o.Margins; m != nil && (m.Top < 0 || m.Right < 0 || m.Bottom < 0 || m.Left < 0) {
+		return fmt.Errorf("%w: margins must not be negative", ErrInvalidPDFOptions)
+	}
+
+	if o.PageRanges != "" && !pageRangesPattern.MatchString(o.PageRanges) {
+		return fmt.Errorf("%w: malformed page_ranges %q", ErrInvalidPDFOptions, o.PageRanges)
+	}
+
+	if o.Scale != 0 && (o.Scale < 0.1 || o.Scale > 2) {
+		return fmt.Errorf("%w: scale must be between 0.1 and 2", ErrInvalidPDFOptions)
+	}
+
+	return nil
+}
+
+// params builds the Page.printToPDF parameters
+func (o *PDFOptions) params() map[string]interface{} {
+	params := map[string]interface{}{
+		"landscape":         o.Landscape,
+		"printBackground":   o.PrintBackground,
+		"preferCSSPageSize": o.PreferCSSPageSize,
+	}
+
+	width, height := o.PaperWidth, o.PaperHeight
+	if width == 0 {
+		format := strings.ToLower(o.PaperFormat)
+		if format == "" {
+			format = "letter"
+		}
+		size := paperSizes[format]
+		width, height = size[0], size[1]
+	}
+	params["paperWidth"] = width
+	params["paperHeight"] = height
+
+	if o.Margins != nil {
+		params["marginTop"] = o.Margins.Top
+		params["marginRight"] = o.Margins.Right
+		params["marginBottom"] = o.Margins.Bottom
+		params["marginLeft"] = o.Margins.Left
+	}
+	if o.PageRanges != "" {
+		params["pageRanges"] = o.PageRanges
+	}
+	if o.Scale != 0 {
+		params["scale"] = o.Scale
+	}
+
+	return params
+}
+
+// PrintToPDF renders the page as a PDF document
+func (s *Session) PrintToPDF(ctx context.Context, targetID string, opts PDFOptions) ([]byte, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	result, err := s.CDPClient.SendCommandToTargetContext(ctx, targetID, "Page.printToPDF", opts.params())
+	if err != nil {
+		// Ranges past the last page are only known once the page is laid out
+		if strings.Contains(err.Error(), "Page range") {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPDFOptions, err)
+		}
+		return nil, fmt.Errorf("failed to print page: %w", err)
+	}
+
+	var response struct {
+		Data string `json:"data"`
+	}
+
+	if err := json.Unmarshal(result, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse print response: %w", err)
+	}
+
+	pdf, err := base64.StdEncoding.DecodeString(response.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode PDF: %w", err)
+	}
+
+	return pdf, nil
+}
@@ -0,0 +1,58 @@
+package session
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestPDFOptionsValidate tests that malformed print options are rejected
+func TestPDFOptionsValidate(t *testing.T) {
+	valid := []PDFOptions{
+		{},
+		{PaperFormat: "A4", Landscape: true, PrintBackground: true},
+		{PaperWidth: 4, PaperHeight: 6},
+		{Margins: &PDFMargins{Top: 0.5, Bottom: 0.5}},
+		{PageRanges: "1-5, 8, 11-"},
+		{Scale: 0.5},
+	}
+	for _, opts := range valid {
+		if err := opts.validate(); err != nil {
+			t.Errorf("expected %+v to be valid, got %v", opts, err)
+		}
+	}
+
+	invalid := []PDFOptions{
+		{PaperFormat: "b5"},
+		{PaperFormat: "a4", PaperWidth: 4, PaperHeight: 6},
+		{PaperWidth: 4},
+		{PaperWidth: -4, PaperHeight: 6},
+		{Margins: &PDFMargins{Left: -1}},
+		{PageRanges: "first"},
+		{PageRanges: "1-5,"},
+		{Scale: 3},
+	}
+	for _, opts := range invalid {
+		if err := opts.validate(); !errors.Is(err, ErrInvalidPDFOptions) {
+			t.Errorf("expected %+v to be invalid, got %v", opts, err)
+		}
+	}
+}
+
+// TestPDFOptionsParams tests the paper size defaults sent to Page.printToPDF
+func TestPDFOptionsParams(t *testing.T) {
+	params := (&PDFOptions{}).params()
+	if params["paperWidth"] != 8.5 || params["paperHeight"] != 11.0 {
+		t.Errorf("expected letter paper by default, got %v x %v", params["paperWidth"], params["paperHeight"])
+	}
+	if _, ok := params["marginTop"]; ok {
+		t.Error("expected Chrome's default margins when none are set")
+	}
+
+	params = (&PDFOptions{PaperFormat: "A4", Margins: &PDFMargins{Top: 1}, PageRanges: "2"}).params()
+	if params["paperWidth"] != 8.27 || params["paperHeight"] != 11.69 {
+		t.Errorf("expected a4 paper, got %v x %v", params["paperWidth"], params["paperHeight"])
+	}
+	if params["marginTop"] != 1.0 || params["marginLeft"] != 0.0 || params["pageRanges"] != "2" {
+		t.Errorf("unexpected params %v", params)
+	}
+}
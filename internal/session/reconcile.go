@@ -0,0 +1,258 @@
+package session
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// ReconcileReport summarizes what a reconciliation pass found and fixed
+type ReconcileReport struct {
+	StartedAt        time.Time `json:"started_at"`
+	Duration         string    `json:"duration"`
+	LivePorts        []int     `json:"live_ports"`
+	CheckedSessions  int       `json:"checked_sessions"`  // Stored sessions not held by this server
+	OrphanedSessions []string  `json:"orphaned_sessions"` // Listed as open but their browser context is gone, now idle and resumable
+	MovedSessions    []string  `json:"moved_sessions"`    // Pointed at a browser process that is gone, now at a live one
+	DisposedContexts []string  `json:"disposed_contexts"` // Browser contexts no session owns
+	ReleasedNames    int       `json:"released_names"`    // Names held by sessions whose keys expired
+	Errors           []string  `json:"errors,omitempty"`
+}
+
+// addError records a problem that didn't stop the pass
+func (r *ReconcileReport) addError(format string, args ...any) {
+	message := fmt.Sprintf(format, args...)
+	r.Errors = append(r.Errors, message)
+	slog.Warn("reconciliation problem", "error", message)
+}
+
+// Reconcile brings the session store and the browser processes listening on ports back in line
+// Sessions the store lists as open whose browser context is gone are marked idle, and moved to a
+// live process when theirs is gone, so they can be resumed. Browser contexts no session owns are
+// disposed and names left behind by expired sessions are released.
+func (m *Manager) Reconcile(ports []int) *ReconcileReport {
+	m.reconcileMu.Lock()
+	defer m.reconcileMu.Unlock()
+
+	report := &ReconcileReport{
+		StartedAt:        time.Now(),
+		LivePorts:        ports,
+		OrphanedSessions: []string{},
+		MovedSessions:    []string{},
+		DisposedContexts: []string{},
+	}
+
+	// Step 1: List the browser contexts of every live process
+	contexts := make(map[int][]string, len(ports))
+	for _, port := range ports {
+		if listed, ok := m.listContexts(port, report); ok {
+			contexts[port] = listed
+		}
+	}
+
+	// Step 2: Make stored sessions whose context is gone resumable
+	claimed := m.reconcileStoredSessions(ports, contexts, report)
+
+	// Step 3: Dispose browser contexts left behind by sessions that no longer exist
+	// Skipped when the store couldn't be listed, every context it owns would look leaked
+	if claimed != nil {
+		for port, listed := range contexts {
+			m.disposeLeakedContexts(port, listed, claimed, report)
+		}
+	}
+
+	// Step 4: Release names of sessions whose keys expired
+	if m.repo != nil {
+		released, err := m.repo.ReleaseExpiredNames()
+		if err != nil {
+			report.addError("failed to release expired names: %v", err)
+		}
+		report.ReleasedNames = released
+	}
+
+	report.Duration = time.Since(report.StartedAt).String()
+	m.lastReconcile.Store(report)
+
+	slog.Info("reconciliation finished",
+		"live_ports", len(ports),
+		"checked_sessions", report.CheckedSessions,
+		"orphaned_sessions", len(report.OrphanedSessions),
+		"moved_sessions", len(report.MovedSessions),
+		"disposed_contexts", len(report.DisposedContexts),
+		"released_names", report.ReleasedNames,
+		"errors", len(report.Errors),
+		"duration", report.Duration)
+
+	return report
+}
+
+// LastReconcileReport returns the report of the most recent reconciliation, nil before the first one
+func (m *Manager) LastReconcileReport() *ReconcileReport {
+	return m.lastReconcile.Load()
+}
+
+// StartReconcileWorker reconciles every interval against the ports returned by livePorts
+func (m *Manager) StartReconcileWorker(interval time.Duration, livePorts func() []int) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		slog.Info("reconcile worker started", "check_interval", interval)
+
+		for {
+			select {
+			case <-m.ctx.Done():
+				slog.Info("reconcile worker stopping")
+				return
+
+			case <-ticker.C:
+				m.Reconcile(livePorts())
+			}
+		}
+	}()
+}
+
+// listContexts returns the browser contexts of a process, ok is false when they couldn't be listed
+func (m *Manager) listContexts(port int, report *ReconcileReport) (contexts []string, ok bool) {
+	m.mu.Lock()
+	client, err := m.GetOrCreateCDPClient(port)
+	m.mu.Unlock()
+	if err != nil {
+		report.addError("failed to connect to browser on port %d: %v", port, err)
+		return nil, false
+	}
+
+	contexts, err = client.GetBrowserContexts()
+	if err != nil {
+		report.addError("failed to list browser contexts on port %d: %v", port, err)
+		return nil, false
+	}
+	return contexts, true
+}
+
+// disposeLeakedContexts disposes the listed browser contexts of a process that no session owns
+// claimed holds the contexts of stored sessions this server doesn't hold.
+func (m *Manager) disposeLeakedContexts(port int, contexts []string, claimed map[string]bool, report *ReconcileReport) {
+	m.mu.Lock()
+	client, err := m.GetOrCreateCDPClient(port)
+	m.mu.Unlock()
+	if err != nil {
+		report.addError("failed to connect to browser on port %d: %v", port, err)
+		return
+	}
+
+	// A listed context either belongs to a session in the map by the time the read lock
+	// is held, or is still being set up for one, then the port is left for the next pass
+	m.mu.RLock()
+	if m.contextSetups[port] > 0 {
+		m.mu.RUnlock()
+		slog.Debug("browser contexts being set up, leaving port for the next pass", "port", port)
+		return
+	}
+	owned := make(map[string]bool, len(m.sessions))
+	for _, session := range m.sessions {
+		owned[session.ContextID] = true
+	}
+	m.mu.RUnlock()
+
+	for _, contextID := range contexts {
+		if owned[contextID] || claimed[contextID] {
+			continue
+		}
+
+		if err := client.DisposeBrowserContext(contextID); err != nil {
+			report.addError("failed to dispose browser context %s on port %d: %v", contextID, port, err)
+			continue
+		}
+		report.DisposedContexts = append(report.DisposedContexts, contextID)
+
+		slog.Info("disposed leaked browser context", "context_id", contextID, "port", port)
+	}
+}
+
+// heldSession returns the session with the ID if this server holds it, nil otherwise
+func (m *Manager) heldSession(sessionID string) *Session {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.sessions[sessionID]
+}
+
+// reconcileStoredSessions marks stored sessions whose browser context is gone as idle and points them at a live process
+// contexts holds the listed contexts of the live ports, a session on a port that couldn't be listed is
+// left alone. Returns the contexts of the stored sessions that were left open, nil when the store
+// couldn't be listed.
+func (m *Manager) reconcileStoredSessions(ports []int, contexts map[int][]string, report *ReconcileReport) map[string]bool {
+	claimed := make(map[string]bool)
+	if m.repo == nil {
+		return claimed
+	}
+
+	sessionIDs, err := m.repo.ListActiveSessions()
+	if err != nil {
+		report.addError("failed to list stored sessions: %v", err)
+		return nil
+	}
+
+	next := 0
+	for _, sessionID := range sessionIDs {
+		if m.heldSession(sessionID) != nil {
+			continue
+		}
+
+		state, err := m.repo.GetSession(sessionID)
+		if err != nil {
+			// Expired, its leftovers are cleaned up with the names
+			continue
+		}
+		report.CheckedSessions++
+
+		orphaned := false
+		if state.Status != string(SessionIdle) {
+			listed, ok := contexts[state.ProcessPort]
+			switch {
+			case ok:
+				orphaned = !slices.Contains(listed, state.ContextID)
+			case slices.Contains(ports, state.ProcessPort):
+				// Live but not listed, its context may still be there
+			default:
+				orphaned = true
+			}
+			if !orphaned {
+				claimed[state.ContextID] = true
+			}
+		}
+		moved := len(ports) > 0 && !slices.Contains(ports, state.ProcessPort)
+		if orphaned {
+			state.Status = string(SessionIdle)
+		}
+		if moved {
+			// Spread moved sessions over the live processes
+			state.ProcessPort = ports[next%len(ports)]
+			next++
+		}
+
+		if !orphaned && !moved {
+			continue
+		}
+		if err := m.repo.SaveSession(state); err != nil {
+			report.addError("failed to update session %s: %v", sessionID, err)
+			continue
+		}
+
+		// A resume that got in since the check owns the session, its state may have been overwritten
+		if session := m.heldSession(sessionID); session != nil {
+			if err := m.repo.SaveSession(m.sessionToState(session)); err != nil {
+				report.addError("failed to restore resumed session %s: %v", sessionID, err)
+			}
+			continue
+		}
+		if orphaned {
+			report.OrphanedSessions = append(report.OrphanedSessions, sessionID)
+		}
+		if moved {
+			report.MovedSessions = append(report.MovedSessions, sessionID)
+		}
+	}
+	return claimed
+}
@@ -0,0 +1,163 @@
+package session
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/dhruvsoni1802/browser-query-ai/internal/storage"
+)
+
+// TestReconcileStoredSessions tests that stored sessions whose context is gone are made resumable on a live process
+func TestReconcileStoredSessions(t *testing.T) {
+	store := storage.NewMemoryStore(time.Hour)
+	manager := NewManager(store)
+
+	stored := []*storage.SessionState{
+		{SessionID: "sess_active", SessionName: "active", AgentID: "agent", ProcessPort: 9222, ContextID: "ctx-1", Status: string(SessionActive)},
+		{SessionID: "sess_idle", SessionName: "idle", AgentID: "agent", ProcessPort: 9300, ContextID: "ctx-2", Status: string(SessionIdle)},
+		{SessionID: "sess_moved", SessionName: "moved", AgentID: "agent", ProcessPort: 9223, ContextID: "ctx-3", Status: string(SessionIdle)},
+		{SessionID: "sess_held", SessionName: "held", AgentID: "agent", ProcessPort: 9222, ContextID: "ctx-4", Status: string(SessionActive)},
+		{SessionID: "sess_live", SessionName: "live", AgentID: "agent", ProcessPort: 9300, ContextID: "ctx-5", Status: string(SessionActive)},
+		{SessionID: "sess_gone", SessionName: "gone", AgentID: "agent", ProcessPort: 9300, ContextID: "ctx-6", Status: string(SessionActive)},
+		{SessionID: "sess_unlisted", SessionName: "unlisted", AgentID: "agent", ProcessPort: 9301, ContextID: "ctx-7", Status: string(SessionActive)},
+	}
+	for _, state := range stored {
+		state.CreatedAt = time.Now()
+		if err := store.SaveSession(state); err != nil {
+			t.Fatalf("SaveSession failed: %v", err)
+		}
+	}
+
+	// A session this server holds is left alone
+	manager.sessions["sess_held"] = &Session{ID: "sess_held", ProcessPort: 9222, Status: SessionActive}
+
+	// Port 9301 couldn't be listed
+	contexts := map[int][]string{9300: {"ctx-5"}}
+
+	report := &ReconcileReport{}
+	claimed := manager.reconcileStoredSessions([]int{9300, 9301}, contexts, report)
+
+	if report.CheckedSessions != 6 {
+		t.Errorf("expected 6 sessions checked, got %d", report.CheckedSessions)
+	}
+	slices.Sort(report.OrphanedSessions)
+	if !slices.Equal(report.OrphanedSessions, []string{"sess_active", "sess_gone"}) {
+		t.Errorf("expected sess_active and sess_gone to be orphaned, got %v", report.OrphanedSessions)
+	}
+	if !claimed["ctx-5"] || !claimed["ctx-7"] || len(claimed) != 2 {
+		t.Errorf("expected the contexts of the sessions left open to be claimed, got %v", claimed)
+	}
+	if len(report.MovedSessions) != 2 {
+		t.Errorf("expected 2 sessions moved, got %v", report.MovedSessions)
+	}
+
+	for _, sessionID := range []string{"sess_active", "sess_moved"} {
+		state, err := store.GetSession(sessionID)
+		if err != nil {
+			t.Fatalf("GetSession failed: %v", err)
+		}
+		if state.Status != string(SessionIdle) {
+			t.Errorf("expected %s to be idle, got %s", sessionID, state.Status)
+		}
+		if state.ProcessPort != 9300 && state.ProcessPort != 9301 {
+			t.Errorf("expected %s on a live port, got %d", sessionID, state.ProcessPort)
+		}
+	}
+
+	if state, _ := store.GetSession("sess_idle"); state.ProcessPort != 9300 {
+		t.Errorf("expected sess_idle to stay on its live port, got %d", state.ProcessPort)
+	}
+	for _, sessionID := range []string{"sess_live", "sess_unlisted"} {
+		if state, _ := store.GetSession(sessionID); state.Status != string(SessionActive) {
+			t.Errorf("expected %s to stay active, got %s", sessionID, state.Status)
+		}
+	}
+	if state, _ := store.GetSession("sess_held"); state.Status != string(SessionActive) || state.ProcessPort != 9222 {
+		t.Errorf("expected the held session to be untouched, got %+v", state)
+	}
+}
+
+// TestReconcileKeepsClaimedContexts tests that only contexts no session owns are disposed
+func TestReconcileKeepsClaimedContexts(t *testing.T) {
+	store := storage.NewMemoryStore(time.Hour)
+	manager := NewManager(store)
+
+	browser, client := newFakeBrowser(t, "ctx-held", "ctx-stored", "ctx-leaked")
+	manager.cdpClients[9300] = client
+	manager.sessions["sess_held"] = &Session{ID: "sess_held", ProcessPort: 9300, ContextID: "ctx-held", Status: SessionActive}
+
+	state := &storage.SessionState{SessionID: "sess_stored", SessionName: "stored", AgentID: "agent",
+		ProcessPort: 9300, ContextID: "ctx-stored", Status: string(SessionActive), CreatedAt: time.Now()}
+	if err := store.SaveSession(state); err != nil {
+		t.Fatalf("SaveSession failed: %v", err)
+	}
+
+	report := manager.Reconcile([]int{9300})
+	if !slices.Equal(report.DisposedContexts, []string{"ctx-leaked"}) {
+		t.Errorf("expected only ctx-leaked to be disposed, got %v", report.DisposedContexts)
+	}
+	if len(report.OrphanedSessions) != 0 {
+		t.Errorf("expected no orphaned sessions, got %v", report.OrphanedSessions)
+	}
+	if contexts := browser.contextIDs(); !slices.Equal(contexts, []string{"ctx-held", "ctx-stored"}) {
+		t.Errorf("expected the owned contexts to be left, got %v", contexts)
+	}
+}
+
+// saveHookStore calls onSave before every save of the wrapped store
+type saveHookStore struct {
+	storage.SessionStore
+	onSave func(state *storage.SessionState)
+}
+
+func (s *saveHookStore) SaveSession(state *storage.SessionState) error {
+	s.onSave(state)
+	return s.SessionStore.SaveSession(state)
+}
+
+// TestReconcileStoredSessionsResumed tests that store I/O runs without the manager lock and that a
+// session resumed during the pass keeps its own state
+func TestReconcileStoredSessionsResumed(t *testing.T) {
+	memory := storage.NewMemoryStore(time.Hour)
+	state := &storage.SessionState{SessionID: "sess_resumed", SessionName: "resumed", AgentID: "agent",
+		ProcessPort: 9222, ContextID: "ctx-old", Status: string(SessionActive), CreatedAt: time.Now()}
+	if err := memory.SaveSession(state); err != nil {
+		t.Fatalf("SaveSession failed: %v", err)
+	}
+
+	var manager *Manager
+	resumed := &Session{ID: "sess_resumed", Name: "resumed", AgentID: "agent", ProcessPort: 9300, ContextID: "ctx-new", Status: SessionActive, CreatedAt: time.Now()}
+	store := &saveHookStore{SessionStore: memory, onSave: func(*storage.SessionState) {
+		// A resume adds the session while the pass saves, it needs the manager lock
+		manager.mu.Lock()
+		manager.sessions[resumed.ID] = resumed
+		manager.mu.Unlock()
+	}}
+	manager = NewManager(store)
+
+	done := make(chan *ReconcileReport)
+	go func() {
+		report := &ReconcileReport{}
+		manager.reconcileStoredSessions([]int{9300}, map[int][]string{}, report)
+		done <- report
+	}()
+
+	var report *ReconcileReport
+	select {
+	case report = <-done:
+	case <-time.After(time.Second):
+		t.Fatal("reconciliation held the manager lock across a store save")
+	}
+
+	if len(report.OrphanedSessions) != 0 || len(report.Errors) != 0 {
+		t.Errorf("expected the resumed session not to be reported, got %+v", report)
+	}
+	stored, err := memory.GetSession("sess_resumed")
+	if err != nil {
+		t.Fatalf("GetSession failed: %v", err)
+	}
+	if stored.Status != string(SessionActive) || stored.ProcessPort != 9300 || stored.ContextID != "ctx-new" {
+		t.Errorf("expected the resumed session's state to be kept, got %+v", stored)
+	}
+}
@@ -0,0 +1,218 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/dhruvsoni1802/browser-query-ai/internal/cdp"
+)
+
+// HandleProcessDown marks the sessions of a browser process that died or hung as interrupted
+// The process's CDP client is closed so it stops reconnecting, HandleProcessRestarted creates a new one.
+func (m *Manager) HandleProcessDown(port int) {
+	m.mu.Lock()
+	if client, exists := m.cdpClients[port]; exists {
+		if err := client.Close(); err != nil {
+			slog.Warn("failed to close CDP client", "port", port, "error", err)
+		}
+		delete(m.cdpClients, port)
+	}
+
+	affected := make([]*Session, 0)
+	for _, session := range m.sessions {
+		if session.ProcessPort == port {
+			session.Status = SessionInterrupted
+			affected = append(affected, session)
+		}
+	}
+	m.mu.Unlock()
+
+	slog.Warn("browser process down, sessions interrupted", "port", port, "sessions", len(affected))
+
+	if m.repo != nil {
+		for _, session := range affected {
+			if err := m.repo.UpdateSessionStatus(session.ID, string(SessionInterrupted)); err != nil {
+				slog.Warn("failed to update session status", "session_id", session.ID, "error", err)
+			}
+		}
+	}
+}
+
+// HandleProcessRestarted recreates the sessions of a failed browser process on its replacement
+// Each session gets a new browser context with the cookies and localStorage saved at its last close,
+// and its pages become restorable like on resume. A session that can't be recreated stays interrupted.
+func (m *Manager) HandleProcessRestarted(oldPort, newPort int) {
+	// Step 1: Swap the client and collect the sessions of the old process
+	m.mu.Lock()
+
+	// A client for the old port may have come back if HandleProcessDown wasn't called
+	if client, exists := m.cdpClients[oldPort]; exists {
+		client.Close()
+		delete(m.cdpClients, oldPort)
+	}
+
+	client, connectErr := m.GetOrCreateCDPClient(newPort)
+	affected := make([]*Session, 0)
+	for _, session := range m.sessions {
+		if session.ProcessPort != oldPort {
+			continue
+		}
+		affected = append(affected, session)
+		if connectErr == nil {
+			m.beginContextSetup(newPort)
+		}
+	}
+	m.mu.Unlock()
+
+	recreated := 0
+	for _, session := range affected {
+		// Step 2: Create the session's new context without holding the lock
+		contextID, err := "", connectErr
+		if err != nil {
+			err = fmt.Errorf("failed to connect to restarted browser: %w", err)
+		} else {
+			contextID, err = m.recreateContext(session, client)
+		}
+
+		// Step 3: Point the session at the new process, unless it was closed meanwhile
+		m.mu.Lock()
+		if connectErr == nil {
+			m.endContextSetup(newPort)
+		}
+		current := m.sessions[session.ID] == session
+		if current {
+			session.ProcessPort = newPort
+			session.Status = SessionInterrupted
+			if err == nil {
+				session.CDPClient = client
+				session.ContextID = contextID
+				session.Status = SessionActive
+			}
+		}
+		m.mu.Unlock()
+
+		if !current {
+			if err == nil {
+				if err := client.DisposeBrowserContext(contextID); err != nil {
+					slog.Warn("failed to dispose browser context", "error", err)
+				}
+			}
+			continue
+		}
+
+		if err != nil {
+			slog.Warn("failed to recreate session", "session_id", session.ID, "port", newPort, "error", err)
+		} else {
+			recreated++
+			slog.Info("session recreated on restarted browser",
+				"session_id", session.ID,
+				"port", newPort,
+				"context_id", contextID)
+		}
+
+		m.persistRecoveredSession(session)
+	}
+
+	slog.Info("sessions moved to restarted browser process",
+		"old_port", oldPort,
+		"new_port", newPort,
+		"recreated", recreated)
+}
+
+// recreateContext drops what a session whose browser died had open and creates its new context on client
+// The session's fields are left for the caller to update under m.mu.
+func (m *Manager) recreateContext(session *Session, client *cdp.Client) (string, error) {
+	// The pages died with the browser, remember where they were so RestorePages can reopen them
+	pages := session.PageStates()
+	if len(pages) == 0 {
+		pages = session.takeRestorablePages()
+	}
+
+	session.stopAllConsoleCaptures()
+	session.stopAllNetworkCaptures()
+	session.stopAllInterceptions()
+	session.stopAllPageTracking()
+
+	session.pagesMu.Lock()
+	session.restorablePages = pages
+	session.pagesMu.Unlock()
+	session.clearPages()
+
+	contextID, err := client.CreateBrowserContext()
+	if err != nil {
+		return "", fmt.Errorf("failed to create browser context: %w", err)
+	}
+
+	// Cookies and localStorage are only persisted on close, restore what was saved then
+	if m.repo != nil {
+		if state, err := m.repo.GetSession(session.ID); err == nil && (len(state.Cookies) > 0 || len(state.LocalStorage) > 0) {
+			ctx, cancel := context.WithTimeout(context.Background(), BrowserStateTimeout)
+			// The session still points at the dead browser, restore through one on the new context
+			restored := &Session{
+				ID:                session.ID,
+				ContextID:         contextID,
+				CDPClient:         client,
+				pageAnalysisCache: make(map[string]*PageStructure),
+			}
+			browser := &browserState{cookies: state.Cookies, localStorage: state.LocalStorage}
+			if err := restored.restoreBrowserState(ctx, browser); err != nil {
+				slog.Warn("failed to restore browser state", "session_id", session.ID, "error", err)
+			}
+			cancel()
+		}
+	}
+
+	return contextID, nil
+}
+
+// persistRecoveredSession saves the new port, context and status of a session after a restart
+// The pages are saved too, so a close or a later resume still finds them.
+func (m *Manager) persistRecoveredSession(session *Session) {
+	if m.repo == nil {
+		return
+	}
+
+	state := m.sessionToState(session)
+	session.pagesMu.Lock()
+	state.Pages = session.restorablePages
+	session.pagesMu.Unlock()
+
+	if err := m.repo.SaveSession(state); err != nil {
+		slog.Warn("failed to save recovered session", "session_id", session.ID, "error", err)
+	}
+	if err := m.repo.SavePages(session.ID, state.Pages); err != nil {
+		slog.Warn("failed to save pages of recovered session", "session_id", session.ID, "error", err)
+	}
+}
+
+// HandleProcessRemoved closes the CDP client of a browser process the pool stopped after it went idle
+// Sessions still placed on the port are interrupted like on a crash.
+func (m *Manager) HandleProcessRemoved(port int) {
+	if m.HasSessionsOnPort(port) {
+		m.HandleProcessDown(port)
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if client, exists := m.cdpClients[port]; exists {
+		if err := client.Close(); err != nil {
+			slog.Warn("failed to close CDP client", "port", port, "error", err)
+		}
+		delete(m.cdpClients, port)
+	}
+}
+
+// HasSessionsOnPort reports whether any session in memory is placed on the browser process at port
+func (m *Manager) HasSessionsOnPort(port int) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, session := range m.sessions {
+		if session.ProcessPort == port {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package session
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/dhruvsoni1802/browser-query-ai/internal/cdp"
+	"github.com/dhruvsoni1802/browser-query-ai/internal/storage"
+)
+
+// TestHandleProcessDown tests that only the sessions of the failed process are interrupted
+func TestHandleProcessDown(t *testing.T) {
+	store := storage.NewMemoryStore(time.Hour)
+	manager := NewManager(store)
+
+	for _, session := range []*Session{
+		{ID: "sess_down", Name: "down", AgentID: "agent", ProcessPort: 9300, ContextID: "ctx-1", Status: SessionActive, CreatedAt: time.Now()},
+		{ID: "sess_up", Name: "up", AgentID: "agent", ProcessPort: 9301, ContextID: "ctx-2", Status: SessionActive, CreatedAt: time.Now()},
+	} {
+		manager.sessions[session.ID] = session
+		if err := store.SaveSession(manager.sessionToState(session)); err != nil {
+			t.Fatalf("SaveSession failed: %v", err)
+		}
+	}
+
+	manager.HandleProcessDown(9300)
+
+	if status := manager.sessions["sess_down"].Status; status != SessionInterrupted {
+		t.Errorf("expected the session on the failed process to be interrupted, got %s", status)
+	}
+	if status := manager.sessions["sess_up"].Status; status != SessionActive {
+		t.Errorf("expected the other session to stay active, got %s", status)
+	}
+
+	if state, _ := store.GetSession("sess_down"); state.Status != string(SessionInterrupted) {
+		t.Errorf("expected the stored status to be interrupted, got %s", state.Status)
+	}
+}
+
+// TestHandleConnectionStateChecksContexts tests that a reconnect only restores sessions whose context survived
+func TestHandleConnectionStateChecksContexts(t *testing.T) {
+	manager := NewManager(storage.NewMemoryStore(time.Hour))
+	_, client := newFakeBrowser(t, "ctx-alive")
+
+	for _, session := range []*Session{
+		{ID: "sess_alive", Name: "alive", AgentID: "agent", ProcessPort: 9300, ContextID: "ctx-alive", Status: SessionDegraded, CreatedAt: time.Now()},
+		{ID: "sess_gone", Name: "gone", AgentID: "agent", ProcessPort: 9300, ContextID: "ctx-gone", Status: SessionDegraded, CreatedAt: time.Now()},
+	} {
+		manager.sessions[session.ID] = session
+	}
+
+	manager.handleConnectionState(client, 9300, cdp.StateConnected)
+
+	if status := manager.sessions["sess_alive"].Status; status != SessionActive {
+		t.Errorf("expected the session with a live context to be active, got %s", status)
+	}
+	if status := manager.sessions["sess_gone"].Status; status != SessionInterrupted {
+		t.Errorf("expected the session whose context is gone to be interrupted, got %s", status)
+	}
+}
+
+// TestHandleProcessRestartedUnlocked tests that sessions are recreated on the new process without
+// holding up the others
+func TestHandleProcessRestartedUnlocked(t *testing.T) {
+	browser, client := newFakeBrowser(t)
+	browser.createDelay = 200 * time.Millisecond
+
+	manager := NewManager(storage.NewMemoryStore(time.Hour))
+	manager.cdpClients[9301] = client
+	manager.sessions["sess_other"] = &Session{ID: "sess_other", ProcessPort: 9301, Status: SessionActive}
+	for _, id := range []string{"sess_a", "sess_b"} {
+		manager.sessions[id] = &Session{ID: id, Name: id, AgentID: "agent", ProcessPort: 9300, ContextID: "ctx-dead", Status: SessionInterrupted, CreatedAt: time.Now()}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		manager.HandleProcessRestarted(9300, 9301)
+	}()
+
+	// Other sessions stay usable while the browser works
+	time.Sleep(50 * time.Millisecond)
+	started := time.Now()
+	if _, err := manager.GetSession("sess_other"); err != nil {
+		t.Fatalf("GetSession failed: %v", err)
+	}
+	if elapsed := time.Since(started); elapsed > 100*time.Millisecond {
+		t.Errorf("expected GetSession not to wait for the restart, took %v", elapsed)
+	}
+
+	<-done
+	contexts := browser.contextIDs()
+	for _, id := range []string{"sess_a", "sess_b"} {
+		session := manager.sessions[id]
+		if session.Status != SessionActive || session.ProcessPort != 9301 || session.CDPClient != client {
+			t.Errorf("expected %s active on the new process, got %s on %d", id, session.Status, session.ProcessPort)
+		}
+		if !slices.Contains(contexts, session.ContextID) {
+			t.Errorf("expected the context of %s to exist, got %s", id, session.ContextID)
+		}
+	}
+	if len(contexts) != 2 {
+		t.Errorf("expected one context per recreated session, got %v", contexts)
+	}
+	if setups := len(manager.contextSetups); setups != 0 {
+		t.Errorf("expected no context setups left, got %d", setups)
+	}
+}
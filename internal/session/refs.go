@@ -0,0 +1,151 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// interactiveRoles are the accessibility roles that get an element ref
+var interactiveRoles = map[string]bool{
+	"button":           true,
+	"link":             true,
+	"textbox":          true,
+	"searchbox":        true,
+	"checkbox":         true,
+	"radio":            true,
+	"combobox":         true,
+	"listbox":          true,
+	"option":           true,
+	"menuitem":         true,
+	"menuitemcheckbox": true,
+	"menuitemradio":    true,
+	"tab":              true,
+	"switch":           true,
+	"slider":           true,
+	"spinbutton":       true,
+	"treeitem":         true,
+}
+
+// isInteractive reports whether an agent can act on the node
+// Focusable nodes count too, e.g. a div with a tabindex and a click handler.
+func isInteractive(node *AXNode) bool {
+	if interactiveRoles[node.Role] {
+		return true
+	}
+	return node.Focusable && node.Role != "RootWebArea"
+}
+
+// elementRefs maps the refs handed out for one page to backend DOM nodes
+type elementRefs struct {
+	loaderID string         // Document the current refs belong to
+	next     int            // Last ref number handed out, never reset so refs are not reused across documents
+	refs     map[string]int // Ref to backend node ID, current document only
+	byNode   map[int]string // Backend node ID to ref, so a node keeps its ref across tree requests
+}
+
+// assignElementRefs gives every interactive node in the tree a ref such as "e12"
+// Refs stay the same for the same node until the page loads a new document.
+func (s *Session) assignElementRefs(targetID string, loaderID string, nodes []*AXNode) {
+	s.refsMu.Lock()
+	defer s.refsMu.Unlock()
+
+	if s.elementRefs == nil {
+		s.elementRefs = make(map[string]*elementRefs)
+	}
+
+	page := s.elementRefs[targetID]
+	if page == nil {
+		page = &elementRefs{}
+		s.elementRefs[targetID] = page
+	}
+
+	// A new document invalidates every ref handed out so far
+	if page.loaderID != loaderID || page.refs == nil {
+		page.loaderID = loaderID
+		page.refs = make(map[string]int)
+		page.byNode = make(map[int]string)
+	}
+
+	var walk func(nodes []*AXNode)
+	walk = func(nodes []*AXNode) {
+		for _, node := range nodes {
+			if node.BackendNodeID != 0 && isInteractive(node) {
+				ref, ok := page.byNode[node.BackendNodeID]
+				if !ok {
+					page.next++
+					ref = "e" + strconv.Itoa(page.next)
+					page.refs[ref] = node.BackendNodeID
+					page.byNode[node.BackendNodeID] = ref
+				}
+				node.Ref = ref
+			}
+			walk(node.Children)
+		}
+	}
+	walk(nodes)
+}
+
+// resolveElementRef returns the backend node ID a ref was handed out for
+// Refs from an earlier document of the page are reported as ErrStaleElementRef.
+func (s *Session) resolveElementRef(ctx context.Context, targetID string, ref string) (int, error) {
+	number, err := strconv.Atoi(strings.TrimPrefix(ref, "e"))
+	if !strings.HasPrefix(ref, "e") || err != nil || number <= 0 {
+		return 0, fmt.Errorf("%w: malformed ref %q", ErrInvalidAction, ref)
+	}
+
+	s.refsMu.Lock()
+	page := s.elementRefs[targetID]
+	if page == nil || number > page.next {
+		s.refsMu.Unlock()
+		return 0, fmt.Errorf("%w: unknown ref %s, get the accessibility tree first", ErrElementNotFound, ref)
+	}
+	backendNodeID, ok := page.refs[ref]
+	loaderID := page.loaderID
+	s.refsMu.Unlock()
+
+	if !ok {
+		return 0, fmt.Errorf("%w: %s belongs to a previous document", ErrStaleElementRef, ref)
+	}
+
+	current, err := s.documentLoaderID(ctx, targetID)
+	if err != nil {
+		return 0, err
+	}
+	if current != loaderID {
+		return 0, fmt.Errorf("%w: the page has navigated since %s was issued", ErrStaleElementRef, ref)
+	}
+
+	return backendNodeID, nil
+}
+
+// forgetElementRefs drops the refs of a closed page
+func (s *Session) forgetElementRefs(targetID string) {
+	s.refsMu.Lock()
+	defer s.refsMu.Unlock()
+
+	delete(s.elementRefs, targetID)
+}
+
+// documentLoaderID identifies the document currently loaded in the page's main frame
+func (s *Session) documentLoaderID(ctx context.Context, targetID string) (string, error) {
+	result, err := s.CDPClient.SendCommandToTargetContext(ctx, targetID, "Page.getFrameTree", nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to get frame tree: %w", err)
+	}
+
+	var response struct {
+		FrameTree struct {
+			Frame struct {
+				LoaderID string `json:"loaderId"`
+			} `json:"frame"`
+		} `json:"frameTree"`
+	}
+	if err := json.Unmarshal(result, &response); err != nil {
+		return "", fmt.Errorf("failed to parse frame tree: %w", err)
+	}
+
+	return response.FrameTree.Frame.LoaderID, nil
+}
@@ -0,0 +1,61 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// TestAssignElementRefs tests that interactive nodes get refs that stay stable per document
+func TestAssignElementRefs(t *testing.T) {
+	s := &Session{}
+
+	tree := func() []*AXNode {
+		return []*AXNode{{
+			Role:          "RootWebArea",
+			Focusable:     true,
+			BackendNodeID: 1,
+			Children: []*AXNode{
+				{Role: "heading", BackendNodeID: 2},
+				{Role: "button", BackendNodeID: 3},
+				{Role: "generic", Focusable: true, BackendNodeID: 4},
+			},
+		}}
+	}
+
+	first := tree()
+	s.assignElementRefs("page", "loader-1", first)
+
+	if first[0].Ref != "" || first[0].Children[0].Ref != "" {
+		t.Errorf("expected no refs on the root and heading, got %q and %q", first[0].Ref, first[0].Children[0].Ref)
+	}
+	button, generic := first[0].Children[1].Ref, first[0].Children[2].Ref
+	if button == "" || generic == "" || button == generic {
+		t.Fatalf("expected distinct refs for interactive nodes, got %q and %q", button, generic)
+	}
+
+	// Same document, same refs
+	second := tree()
+	s.assignElementRefs("page", "loader-1", second)
+	if second[0].Children[1].Ref != button {
+		t.Errorf("expected ref %q to be stable, got %q", button, second[0].Children[1].Ref)
+	}
+
+	// New document, old refs are gone and never reused
+	third := tree()
+	s.assignElementRefs("page", "loader-2", third)
+	if third[0].Children[1].Ref == button {
+		t.Errorf("expected a new ref after navigation, got %q again", button)
+	}
+
+	ctx := context.Background()
+	if _, err := s.resolveElementRef(ctx, "page", button); !errors.Is(err, ErrStaleElementRef) {
+		t.Errorf("expected ErrStaleElementRef for %s, got %v", button, err)
+	}
+	if _, err := s.resolveElementRef(ctx, "page", "e99"); !errors.Is(err, ErrElementNotFound) {
+		t.Errorf("expected ErrElementNotFound for unknown ref, got %v", err)
+	}
+	if _, err := s.resolveElementRef(ctx, "page", "button"); !errors.Is(err, ErrInvalidAction) {
+		t.Errorf("expected ErrInvalidAction for malformed ref, got %v", err)
+	}
+}
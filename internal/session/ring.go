@@ -0,0 +1,39 @@
+package session
+
+// ring is a fixed size buffer that overwrites its oldest item when full
+type ring[T any] struct {
+	items []T
+	start int // Index of the oldest item
+	size  int
+}
+
+// newRing creates a ring holding up to capacity items
+func newRing[T any](capacity int) *ring[T] {
+	return &ring[T]{items: make([]T, capacity)}
+}
+
+// push appends an item, returning the item it evicted if the ring was full
+func (r *ring[T]) push(item T) (evicted T, ok bool) {
+	if r.size < len(r.items) {
+		r.items[(r.start+r.size)%len(r.items)] = item
+		r.size++
+		return evicted, false
+	}
+
+	evicted = r.items[r.start]
+	r.items[r.start] = item
+	r.start = (r.start + 1) % len(r.items)
+	return evicted, true
+}
+
+// each calls fn for every item, oldest first
+func (r *ring[T]) each(fn func(T)) {
+	for i := 0; i < r.size; i++ {
+		fn(r.items[(r.start+i)%len(r.items)])
+	}
+}
+
+// len returns the number of items held
+func (r *ring[T]) len() int {
+	return r.size
+}
@@ -0,0 +1,262 @@
+package session
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"image"
+	_ "image/jpeg" // Registers the JPEG decoder for image.DecodeConfig
+	_ "image/png"  // Registers the PNG decoder for image.DecodeConfig
+)
+
+// Screenshot image formats
+const (
+	ScreenshotPNG  = "png"
+	ScreenshotJPEG = "jpeg"
+	ScreenshotWebP = "webp"
+)
+
+// maxScreenshotScale caps the device scale factor, larger captures get huge quickly
+const maxScreenshotScale = 4
+
+// ClipRect is a rectangle of the page in CSS pixels, relative to the top left of the document
+type ClipRect struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// ScreenshotOptions controls what is captured and how it is encoded
+// Set at most one of FullPage, Clip, Selector or Ref, the default is the visible viewport.
+type ScreenshotOptions struct {
+	Format   string    `json:"format,omitempty"`    // png (default), jpeg or webp
+	Quality  int       `json:"quality,omitempty"`   // jpeg and webp only, 1-100
+	FullPage bool      `json:"full_page,omitempty"` // The whole scrollable page
+	Clip     *ClipRect `json:"clip,omitempty"`      // A rectangle of the page
+	Selector string    `json:"selector,omitempty"`  // The bounding box of the element matching this CSS selector
+	Ref      string    `json:"ref,omitempty"`       // The bounding box of an accessibility tree ref
+	Scale    float64   `json:"scale,omitempty"`     // Device scale factor, e.g. 2 for retina sharpness
+	Annotate bool      `json:"annotate,omitempty"`  // Outline and number interactive elements, viewport or full page only
+}
+
+// Screenshot is a captured image
+type Screenshot struct {
+	Data   []byte
+	Format string
+	Width  int // In image pixels
+	Height int
+	Marks  []Mark // Legend of an annotated screenshot
+}
+
+// validate checks that the options are well formed
+func (o *ScreenshotOptions) validate() error {
+	switch o.Format {
+	case "", ScreenshotPNG:
+		if o.Quality != 0 {
+			return fmt.Errorf("%w: quality only applies to jpeg and webp", ErrInvalidScreenshotOptions)
+		}
+	case ScreenshotJPEG, ScreenshotWebP:
+		if o.Quality < 0 || o.Quality > 100 {
+			return fmt.Errorf("%w: quality must be between 1 and 100", ErrInvalidScreenshotOptions)
+		}
+	default:
+		return fmt.Errorf("%w: unknown format %q", ErrInvalidScreenshotOptions, o.Format)
+	}
+
+	regions := 0
+	for _, set := range []bool{o.FullPage, o.Clip != nil, o.Selector != "", o.Ref != ""} {
+		if set {
+			regions++
+		}
+	}
+	if regions > 1 {
+		return fmt.Errorf("%w: set only one of full_page, clip, selector or ref", ErrInvalidScreenshotOptions)
+	}
+
+	if o.Annotate && regions > 0 && !o.FullPage {
+		return fmt.Errorf("%w: annotated screenshots capture the viewport or the full page", ErrInvalidScreenshotOptions)
+	}
+
+	if o.Clip != nil && (o.Clip.Width <= 0 || o.Clip.Height <= 0) {
+		return fmt.Errorf("%w: clip width and height must be positive", ErrInvalidScreenshotOptions)
+	}
+
+	if o.Scale < 0 || o.Scale > maxScreenshotScale {
+		return fmt.Errorf("%w: scale must be between 0 and %d", ErrInvalidScreenshotOptions, maxScreenshotScale)
+	}
+
+	return nil
+}
+
+// screenshotClip works out the region to capture, nil means the visible viewport
+func (s *Session) screenshotClip(ctx context.Context, targetID string, opts ScreenshotOptions) (*ClipRect, error) {
+	switch {
+	case opts.Clip != nil:
+		return opts.Clip, nil
+	case opts.Selector != "" || opts.Ref != "":
+		return s.elementClip(ctx, targetID, ActionTarget{Selector: opts.Selector, Ref: opts.Ref})
+	case opts.FullPage:
+		metrics, err := s.layoutMetrics(ctx, targetID)
+		if err != nil {
+			return nil, err
+		}
+		return &ClipRect{Width: metrics.CSSContentSize.Width, Height: metrics.CSSContentSize.Height}, nil
+	case opts.Scale != 0:
+		// Scaling needs a clip, use the part of the page that is visible
+		metrics, err := s.layoutMetrics(ctx, targetID)
+		if err != nil {
+			return nil, err
+		}
+		viewport := metrics.CSSVisualViewport
+		return &ClipRect{X: viewport.PageX, Y: viewport.PageY, Width: viewport.ClientWidth, Height: viewport.ClientHeight}, nil
+	}
+
+	return nil, nil
+}
+
+// elementClip scrolls an element into view and returns its bounding box in document coordinates
+func (s *Session) elementClip(ctx context.Context, targetID string, target ActionTarget) (*ClipRect, error) {
+	objectID, err := s.resolveElement(ctx, targetID, target)
+	if err != nil {
+		return nil, err
+	}
+	defer s.releaseObject(targetID, objectID)
+
+	value, err := s.callFunctionOn(ctx, targetID, objectID, `function() {
+  var el = this.nodeType === Node.ELEMENT_NODE ? this : this.parentElement;
+  el.scrollIntoView({ block: 'center', inline: 'center', behavior: 'instant' });
+  var rect = el.getBoundingClientRect();
+  return { x: rect.left + scrollX, y: rect.top + scrollY, width: rect.width, height: rect.height };
+}`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get element box: %w", err)
+	}
+
+	var clip ClipRect
+	if err := json.Unmarshal(value, &clip); err != nil {
+		return nil, fmt.Errorf("failed to parse element box: %w", err)
+	}
+	if clip.Width == 0 || clip.Height == 0 {
+		return nil, fmt.Errorf("%w: element has no visible box", ErrElementNotVisible)
+	}
+
+	return &clip, nil
+}
+
+// layoutMetrics is the part of Page.getLayoutMetrics used for screenshots
+type layoutMetrics struct {
+	CSSContentSize struct {
+		Width  float64 `json:"width"`
+		Height float64 `json:"height"`
+	} `json:"cssContentSize"`
+	CSSVisualViewport struct {
+		PageX        float64 `json:"pageX"`
+		PageY        float64 `json:"pageY"`
+		ClientWidth  float64 `json:"clientWidth"`
+		ClientHeight float64 `json:"clientHeight"`
+	} `json:"cssVisualViewport"`
+}
+
+// layoutMetrics returns the size of the page and the visible viewport
+func (s *Session) layoutMetrics(ctx context.Context, targetID string) (*layoutMetrics, error) {
+	result, err := s.CDPClient.SendCommandToTargetContext(ctx, targetID, "Page.getLayoutMetrics", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get layout metrics: %w", err)
+	}
+
+	var metrics layoutMetrics
+	if err := json.Unmarshal(result, &metrics); err != nil {
+		return nil, fmt.Errorf("failed to parse layout metrics: %w", err)
+	}
+
+	return &metrics, nil
+}
+
+// captureScreenshot sends Page.captureScreenshot and decodes the result
+func (s *Session) captureScreenshot(ctx context.Context, targetID string, format string, quality int, clip *ClipRect, scale float64) (*Screenshot, error) {
+	params := map[string]interface{}{
+		"format": format,
+	}
+	if quality > 0 {
+		params["quality"] = quality
+	}
+	if clip != nil {
+		if scale == 0 {
+			scale = 1
+		}
+		params["clip"] = map[string]interface{}{
+			"x":      clip.X,
+			"y":      clip.Y,
+			"width":  clip.Width,
+			"height": clip.Height,
+			"scale":  scale,
+		}
+		// Regions outside the viewport (full page, elements below the fold) need this
+		params["captureBeyondViewport"] = true
+	}
+
+	result, err := s.CDPClient.SendCommandToTargetContext(ctx, targetID, "Page.captureScreenshot", params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to capture screenshot: %w", err)
+	}
+
+	var response struct {
+		Data string `json:"data"`
+	}
+
+	if err := json.Unmarshal(result, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse screenshot response: %w", err)
+	}
+
+	imageBytes, err := base64.StdEncoding.DecodeString(response.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode screenshot: %w", err)
+	}
+
+	screenshot := &Screenshot{
+		Data:   imageBytes,
+		Format: format,
+	}
+	screenshot.Width, screenshot.Height = imageSize(imageBytes)
+
+	return screenshot, nil
+}
+
+// imageSize reads the dimensions from a PNG, JPEG or WebP header, 0x0 if it cannot be read
+func imageSize(data []byte) (int, int) {
+	if config, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+		return config.Width, config.Height
+	}
+	return webpSize(data)
+}
+
+// webpSize reads the canvas size of a WebP image, the standard library has no WebP decoder
+func webpSize(data []byte) (int, int) {
+	if len(data) < 30 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return 0, 0
+	}
+
+	chunk := data[20:]
+	switch string(data[12:16]) {
+	case "VP8X":
+		// 24 bit canvas width and height minus one
+		width := int(chunk[4]) | int(chunk[5])<<8 | int(chunk[6])<<16
+		height := int(chunk[7]) | int(chunk[8])<<8 | int(chunk[9])<<16
+		return width + 1, height + 1
+	case "VP8 ":
+		// Lossy: 14 bit sizes after the frame tag and start code
+		width := int(binary.LittleEndian.Uint16(chunk[6:8]) & 0x3fff)
+		height := int(binary.LittleEndian.Uint16(chunk[8:10]) & 0x3fff)
+		return width, height
+	case "VP8L":
+		// Lossless: 14 bit sizes minus one after the signature byte
+		bits := binary.LittleEndian.Uint32(chunk[1:5])
+		return int(bits&0x3fff) + 1, int(bits>>14&0x3fff) + 1
+	}
+
+	return 0, 0
+}
@@ -0,0 +1,149 @@
+package session
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// TestScreenshotOptionsValidate tests that conflicting or malformed screenshot options are rejected
+func TestScreenshotOptionsValidate(t *testing.T) {
+	valid := []ScreenshotOptions{
+		{},
+		{Format: ScreenshotPNG, FullPage: true},
+		{Format: ScreenshotJPEG, Quality: 80},
+		{Format: ScreenshotWebP, Selector: "#chart", Scale: 2},
+		{Clip: &ClipRect{X: 10, Y: 10, Width: 200, Height: 100}},
+		{Ref: "e4"},
+		{Annotate: true, FullPage: true, Format: ScreenshotJPEG, Quality: 70},
+	}
+	for _, opts := range valid {
+		if err := opts.validate(); err != nil {
+			t.Errorf("expected %+v to be valid, got %v", opts, err)
+		}
+	}
+
+	invalid := []ScreenshotOptions{
+		{Format: "gif"},
+		{Format: ScreenshotPNG, Quality: 80},
+		{Format: ScreenshotJPEG, Quality: 101},
+		{FullPage: true, Selector: "#chart"},
+		{Selector: "#chart", Ref: "e4"},
+		{Clip: &ClipRect{Width: 0, Height: 100}},
+		{Scale: -1},
+		{Scale: 10},
+		{Annotate: true, Selector: "#chart"},
+	}
+	for _, opts := range invalid {
+		if err := opts.validate(); !errors.Is(err, ErrInvalidScreenshotOptions) {
+			t.Errorf("expected %+v to be invalid, got %v", opts, err)
+		}
+	}
+}
+
+// TestImageSize tests reading dimensions from PNG, JPEG and WebP headers
+func TestImageSize(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 320, 240))
+
+	var pngData bytes.Buffer
+	if err := png.Encode(&pngData, img); err != nil {
+		t.Fatalf("png encode failed: %v", err)
+	}
+	var jpegData bytes.Buffer
+	if err := jpeg.Encode(&jpegData, img, nil); err != nil {
+		t.Fatalf("jpeg encode failed: %v", err)
+	}
+
+	// Lossy WebP: frame tag, start code, then 14 bit width and height
+	lossy := webpHeader("VP8 ")
+	lossy = append(lossy, 0, 0, 0, 0x9d, 0x01, 0x2a)
+	lossy = binary.LittleEndian.AppendUint16(lossy, 320)
+	lossy = binary.LittleEndian.AppendUint16(lossy, 240)
+
+	// Lossless WebP: signature byte, then width-1 and height-1 packed in 14 bits each
+	lossless := webpHeader("VP8L")
+	lossless = append(lossless, 0x2f)
+	lossless = binary.LittleEndian.AppendUint32(lossless, uint32(320-1)|uint32(240-1)<<14)
+
+	// Extended WebP: flags, reserved, then 24 bit width-1 and height-1
+	extended := webpHeader("VP8X")
+	extended = append(extended, 0, 0, 0, 0, 0x3f, 0x01, 0, 0xef, 0, 0)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"png", pngData.Bytes()},
+		{"jpeg", jpegData.Bytes()},
+		{"webp lossy", padded(lossy)},
+		{"webp lossless", padded(lossless)},
+		{"webp extended", padded(extended)},
+	}
+
+	for _, tt := range tests {
+		width, height := imageSize(tt.data)
+		if width != 320 || height != 240 {
+			t.Errorf("%s: expected 320x240, got %dx%d", tt.name, width, height)
+		}
+	}
+
+	if width, height := imageSize([]byte("not an image")); width != 0 || height != 0 {
+		t.Errorf("expected 0x0 for garbage, got %dx%d", width, height)
+	}
+}
+
+// webpHeader builds a RIFF header followed by a chunk header of the given type
+func webpHeader(chunk string) []byte {
+	header := []byte("RIFF\x00\x00\x00\x00WEBP" + chunk)
+	return append(header, 0, 0, 0, 0)
+}
+
+// padded makes sure the data is long enough to hold a full header
+func padded(data []byte) []byte {
+	return append(data, make([]byte, 16)...)
+}
+
+// TestQuadBounds tests turning CDP quads into rectangles
+func TestQuadBounds(t *testing.T) {
+	box, ok := quadBounds([]float64{10, 20, 110, 20, 110, 70, 10, 70})
+	if !ok || box != (ClipRect{X: 10, Y: 20, Width: 100, Height: 50}) {
+		t.Errorf("unexpected bounds %+v, ok %v", box, ok)
+	}
+
+	// A rotated quad is bounded by its outermost corners
+	box, ok = quadBounds([]float64{50, 0, 100, 50, 50, 100, 0, 50})
+	if !ok || box != (ClipRect{X: 0, Y: 0, Width: 100, Height: 100}) {
+		t.Errorf("unexpected rotated bounds %+v, ok %v", box, ok)
+	}
+
+	if _, ok := quadBounds([]float64{10, 20, 10, 20, 10, 20, 10, 20}); ok {
+		t.Error("expected an empty quad to have no bounds")
+	}
+}
+
+// TestIntersectRect tests clipping mark boxes to the captured region
+func TestIntersectRect(t *testing.T) {
+	region := ClipRect{X: 0, Y: 100, Width: 800, Height: 600}
+
+	tests := []struct {
+		box      ClipRect
+		expected ClipRect
+		ok       bool
+	}{
+		{ClipRect{X: 10, Y: 200, Width: 50, Height: 20}, ClipRect{X: 10, Y: 200, Width: 50, Height: 20}, true},
+		{ClipRect{X: 780, Y: 690, Width: 50, Height: 20}, ClipRect{X: 780, Y: 690, Width: 20, Height: 10}, true},
+		{ClipRect{X: 10, Y: 50, Width: 50, Height: 20}, ClipRect{}, false},
+		{ClipRect{X: 10, Y: 80, Width: 50, Height: 20}, ClipRect{}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := intersectRect(tt.box, region)
+		if ok != tt.ok || got != tt.expected {
+			t.Errorf("intersect %+v: expected %+v %v, got %+v %v", tt.box, tt.expected, tt.ok, got, ok)
+		}
+	}
+}
@@ -1,12 +1,15 @@
 package session
 
 import (
-	"encoding/base64"
+	"context"
 	"encoding/json"
 	"fmt"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/dhruvsoni1802/browser-query-ai/internal/cdp"
+	"github.com/dhruvsoni1802/browser-query-ai/internal/storage"
 )
 
 // SessionStatus represents the current state of a session
@@ -17,22 +20,49 @@ const (
 	SessionClosed  SessionStatus = "closed"   // Session was explicitly closed
 	SessionIdle    SessionStatus = "idle"     // Session is idle
 	SessionExpired SessionStatus = "expired"  // Session timed out
+	SessionDegraded SessionStatus = "degraded" // Browser connection lost, reconnecting
+	SessionInterrupted SessionStatus = "interrupted" // Browser process crashed, waiting to be recreated on its replacement
 )
 
 // Session represents an AI agent's isolated browsing session
 type Session struct {
 	ID           string          // Unique session identifier
-	Name         string          // Session name
+	Name         string          // Session name, read with GetName while the session is in use
 	AgentID      string          // Agent ID
 	ProcessPort  int             // Which browser process (9222, 9223, etc.)
 	ContextID    string          // CDP browser context ID
-	PageIDs      []string        // List of page IDs in this context
+	PageIDs      []string        // List of page IDs in this context, read with Pages while the session is in use
 	CDPClient    *cdp.Client     // WebSocket connection to browser
 	CreatedAt    time.Time       // When session was created
 	LastActivity time.Time       // Last time session was used
 	Status       SessionStatus   // Current session status
 
 	pageAnalysisCache map[string]*PageStructure // Cached page analysis results, keyed by pageID
+
+	refsMu      sync.Mutex              // Protects elementRefs
+	elementRefs map[string]*elementRefs // Element refs handed out in accessibility trees, keyed by pageID
+
+	consoleMu sync.Mutex                // Protects consoles
+	consoles  map[string]*consoleBuffer // Captured console entries, keyed by pageID
+
+	networkMu sync.Mutex                // Protects networks
+	networks  map[string]*networkBuffer // Captured network requests, keyed by pageID
+
+	interceptMu sync.Mutex                    // Protects interceptor and intercepts
+	interceptor *interceptor                  // Compiled interception rules, nil when interception is off
+	intercepts  map[string]context.CancelFunc // Stops the paused request listener, keyed by pageID
+
+	emulationMu sync.Mutex        // Protects emulation
+	emulation   *EmulationOptions // Applied to every page, nil for browser defaults
+
+	pageIDsMu sync.Mutex // Protects PageIDs
+
+	nameMu   sync.RWMutex // Protects Name
+	renameMu sync.Mutex   // Serializes renames, so Name ends up as the name the store kept
+
+	pagesMu         sync.Mutex              // Protects pageTrackers and restorablePages
+	pageTrackers    map[string]*pageTracker // Last known URL and title, keyed by pageID
+	restorablePages []storage.PageState     // Pages open at the last close, until RestorePages reopens them
 }
 
 // IsExpired checks if the session has been inactive too long
@@ -47,12 +77,50 @@ func (s *Session) UpdateActivity() {
 
 // AddPage tracks a new page in this session
 func (s *Session) AddPage(pageID string) {
+	s.pageIDsMu.Lock()
 	s.PageIDs = append(s.PageIDs, pageID)
+	s.pageIDsMu.Unlock()
 	s.UpdateActivity()
 }
 
+// GetName returns the session name
+func (s *Session) GetName() string {
+	s.nameMu.RLock()
+	defer s.nameMu.RUnlock()
+	return s.Name
+}
+
+// setName changes the session name
+func (s *Session) setName(name string) {
+	s.nameMu.Lock()
+	defer s.nameMu.Unlock()
+	s.Name = name
+}
+
+// Pages returns a copy of the IDs of the pages open in this session
+func (s *Session) Pages() []string {
+	s.pageIDsMu.Lock()
+	defer s.pageIDsMu.Unlock()
+	return slices.Clone(s.PageIDs)
+}
+
+// HasPage reports whether the page is open in this session
+func (s *Session) HasPage(pageID string) bool {
+	s.pageIDsMu.Lock()
+	defer s.pageIDsMu.Unlock()
+	return slices.Contains(s.PageIDs, pageID)
+}
+
+// clearPages forgets every page, after they were destroyed with the browser context
+func (s *Session) clearPages() {
+	s.pageIDsMu.Lock()
+	defer s.pageIDsMu.Unlock()
+	s.PageIDs = []string{}
+}
+
 // RemovePage removes a page from tracking
 func (s *Session) RemovePage(pageID string) {
+	s.pageIDsMu.Lock()
 	for i, id := range s.PageIDs {
 		if id == pageID {
 			// Remove by swapping with last element and truncating
@@ -61,44 +129,46 @@ func (s *Session) RemovePage(pageID string) {
 			break
 		}
 	}
+	s.pageIDsMu.Unlock()
+	s.forgetElementRefs(pageID)
+	s.stopConsoleCapture(pageID)
+	s.stopNetworkCapture(pageID)
+	s.stopInterception(pageID)
+	s.stopPageTracking(pageID)
 	s.UpdateActivity()
 }
 
-// CaptureScreenshot takes a screenshot of the page
-func (s *Session) CaptureScreenshot(targetID string) ([]byte, error) {
-	params := map[string]interface{}{
-		"format": "png",
-	}
-
-	result, err := s.CDPClient.SendCommandToTarget(targetID, "Page.captureScreenshot", params)
-	if err != nil {
-		return nil, fmt.Errorf("failed to capture screenshot: %w", err)
+// CaptureScreenshot takes a screenshot of the page, by default a PNG of the visible viewport
+func (s *Session) CaptureScreenshot(ctx context.Context, targetID string, opts ScreenshotOptions) (*Screenshot, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
 	}
 
-	var response struct {
-		Data string `json:"data"`
+	if opts.Annotate {
+		return s.captureAnnotatedScreenshot(ctx, targetID, opts)
 	}
 
-	if err := json.Unmarshal(result, &response); err != nil {
-		return nil, fmt.Errorf("failed to parse screenshot response: %w", err)
+	format := opts.Format
+	if format == "" {
+		format = ScreenshotPNG
 	}
 
-	imageBytes, err := base64.StdEncoding.DecodeString(response.Data)
+	clip, err := s.screenshotClip(ctx, targetID, opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode screenshot: %w", err)
+		return nil, err
 	}
 
-	return imageBytes, nil
+	return s.captureScreenshot(ctx, targetID, format, opts.Quality, clip, opts.Scale)
 }
 
 // ExecuteJavascript executes JavaScript code on the page
-func (s *Session) ExecuteJavascript(targetID string, code string) (interface{}, error) {
+func (s *Session) ExecuteJavascript(ctx context.Context, targetID string, code string) (interface{}, error) {
 	params := map[string]interface{}{
 		"expression":    code,
 		"returnByValue": true,
 	}
 
-	result, err := s.CDPClient.SendCommandToTarget(targetID, "Runtime.evaluate", params)
+	result, err := s.CDPClient.SendCommandToTargetContext(ctx, targetID, "Runtime.evaluate", params)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute javascript: %w", err)
 	}
@@ -122,11 +192,16 @@ func (s *Session) ExecuteJavascript(targetID string, code string) (interface{},
 	return response.Result.Value, nil
 }
 
-// WaitForReady waits until document.readyState is interactive/complete or timeout.
-func (s *Session) WaitForReady(targetID string, timeout time.Duration) error {
-	deadline := time.Now().Add(timeout)
-	for time.Now().Before(deadline) {
-		result, err := s.ExecuteJavascript(targetID, "document.readyState")
+// WaitForReady waits until document.readyState is interactive/complete, timeout or ctx is done.
+func (s *Session) WaitForReady(ctx context.Context, targetID string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		result, err := s.ExecuteJavascript(ctx, targetID, "document.readyState")
 		if err == nil {
 			if state, ok := result.(string); ok {
 				if state == "interactive" || state == "complete" {
@@ -134,15 +209,19 @@ func (s *Session) WaitForReady(targetID string, timeout time.Duration) error {
 				}
 			}
 		}
-		time.Sleep(200 * time.Millisecond)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("page did not reach ready state within %s: %w", timeout, ctx.Err())
+		case <-ticker.C:
+		}
 	}
-	return fmt.Errorf("page did not reach ready state within %s", timeout)
 }
 
 // GetPageContent gets the HTML content of a page
-func (s *Session) GetPageContent(targetID string) (string, error) {
+func (s *Session) GetPageContent(ctx context.Context, targetID string) (string, error) {
 	// Step 1: Get document
-	result, err := s.CDPClient.SendCommandToTarget(targetID, "DOM.getDocument", nil)
+	result, err := s.CDPClient.SendCommandToTargetContext(ctx, targetID, "DOM.getDocument", nil)
 	if err != nil {
 		return "", fmt.Errorf("failed to get document: %w", err)
 	}
@@ -162,7 +241,7 @@ func (s *Session) GetPageContent(targetID string) (string, error) {
 		"nodeId": docResponse.Root.NodeID,
 	}
 
-	result, err = s.CDPClient.SendCommandToTarget(targetID, "DOM.getOuterHTML", params)
+	result, err = s.CDPClient.SendCommandToTargetContext(ctx, targetID, "DOM.getOuterHTML", params)
 	if err != nil {
 		return "", fmt.Errorf("failed to get outer HTML: %w", err)
 	}
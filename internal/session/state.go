@@ -0,0 +1,359 @@
+package session
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/dhruvsoni1802/browser-query-ai/internal/cdp"
+	"github.com/dhruvsoni1802/browser-query-ai/internal/storage"
+)
+
+// readLocalStorageScript returns the origin and localStorage items of the page's document
+// Opaque origins such as about:blank or sandboxed frames have no storage and return null.
+const readLocalStorageScript = `(() => {
+	try {
+		if (location.origin === 'null') return null;
+		const items = {};
+		for (let i = 0; i < localStorage.length; i++) {
+			const key = localStorage.key(i);
+			items[key] = localStorage.getItem(key);
+		}
+		return { origin: location.origin, items };
+	} catch (e) {
+		return null;
+	}
+})()`
+
+// blankDocument is served for every origin whose localStorage is restored, so nothing reaches the network
+const blankDocument = "<!DOCTYPE html><title></title>"
+
+// browserState is what a closed session keeps of its browser context
+type browserState struct {
+	cookies      []storage.Cookie
+	localStorage map[string]map[string]string // Items keyed by origin
+}
+
+// cdpCookie is a cookie as Storage.getCookies reports it
+type cdpCookie struct {
+	Name     string  `json:"name"`
+	Value    string  `json:"value"`
+	Domain   string  `json:"domain"`
+	Path     string  `json:"path"`
+	Expires  float64 `json:"expires"`
+	HTTPOnly bool    `json:"httpOnly"`
+	Secure   bool    `json:"secure"`
+	Session  bool    `json:"session"`
+	SameSite string  `json:"sameSite"`
+}
+
+// captureBrowserState snapshots the cookies of the session's context and the localStorage of its open pages
+func (s *Session) captureBrowserState(ctx context.Context) (*browserState, error) {
+	cookies, err := s.captureCookies(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &browserState{
+		cookies:      cookies,
+		localStorage: s.captureLocalStorage(ctx),
+	}, nil
+}
+
+// captureCookies reads every cookie of the session's browser context
+func (s *Session) captureCookies(ctx context.Context) ([]storage.Cookie, error) {
+	params := map[string]interface{}{"browserContextId": s.ContextID}
+	result, err := s.CDPClient.SendCommandContext(ctx, "Storage.getCookies", params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cookies: %w", err)
+	}
+
+	var response struct {
+		Cookies []cdpCookie `json:"cookies"`
+	}
+	if err := json.Unmarshal(result, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse cookies: %w", err)
+	}
+
+	cookies := make([]storage.Cookie, 0, len(response.Cookies))
+	for _, cookie := range response.Cookies {
+		cookies = append(cookies, cookie.toStorage())
+	}
+	return cookies, nil
+}
+
+// captureLocalStorage reads the localStorage of the origin each open page is on
+// A page that can't be read is skipped, the other origins are still worth keeping.
+func (s *Session) captureLocalStorage(ctx context.Context) map[string]map[string]string {
+	origins := make(map[string]map[string]string)
+
+	for _, pageID := range s.Pages() {
+		params := map[string]interface{}{
+			"expression":    readLocalStorageScript,
+			"returnByValue": true,
+		}
+
+		result, err := s.CDPClient.SendCommandToTargetContext(ctx, pageID, "Runtime.evaluate", params)
+		if err != nil {
+			slog.Warn("failed to read localStorage", "page_id", pageID, "error", err)
+			continue
+		}
+
+		var response struct {
+			Result struct {
+				Value *struct {
+					Origin string            `json:"origin"`
+					Items  map[string]string `json:"items"`
+				} `json:"value"`
+			} `json:"result"`
+		}
+		if err := json.Unmarshal(result, &response); err != nil {
+			slog.Warn("failed to parse localStorage", "page_id", pageID, "error", err)
+			continue
+		}
+
+		// Pages on the same origin share one storage, any of them will do
+		if value := response.Result.Value; value != nil && len(value.Items) > 0 {
+			origins[value.Origin] = value.Items
+		}
+	}
+
+	return origins
+}
+
+// restoreBrowserState loads a snapshot into the session's new context before any of its pages exist
+func (s *Session) restoreBrowserState(ctx context.Context, state *browserState) error {
+	if err := s.restoreCookies(ctx, state.cookies); err != nil {
+		return err
+	}
+	return s.restoreLocalStorage(ctx, state.localStorage)
+}
+
+// restoreCookies sets the cookies that have not expired in the session's browser context
+func (s *Session) restoreCookies(ctx context.Context, cookies []storage.Cookie) error {
+	params := cookieParams(cookies, time.Now())
+	if len(params) == 0 {
+		return nil
+	}
+
+	command := map[string]interface{}{"browserContextId": s.ContextID, "cookies": params}
+	if _, err := s.CDPClient.SendCommandContext(ctx, "Storage.setCookies", command); err == nil {
+		return nil
+	}
+
+	// The browser rejects the whole batch over one bad cookie, so retry them one by one
+	restored := 0
+	for _, cookie := range params {
+		command["cookies"] = []map[string]interface{}{cookie}
+		if _, err := s.CDPClient.SendCommandContext(ctx, "Storage.setCookies", command); err != nil {
+			slog.Warn("failed to restore cookie", "session_id", s.ID, "name", cookie["name"], "domain", cookie["domain"], "error", err)
+			continue
+		}
+		restored++
+	}
+
+	if restored == 0 {
+		return fmt.Errorf("failed to restore cookies")
+	}
+	return nil
+}
+
+// restoreLocalStorage writes the items of every origin through a temporary page of the session's context
+// The page's requests are answered with an empty document, so the sites themselves are never loaded.
+func (s *Session) restoreLocalStorage(ctx context.Context, localStorage map[string]map[string]string) error {
+	origins := restorableOrigins(localStorage)
+	if len(origins) == 0 {
+		return nil
+	}
+
+	targetID, err := s.CDPClient.CreateTarget("about:blank", s.ContextID)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := s.CDPClient.CloseTarget(targetID); err != nil {
+			slog.Warn("failed to close localStorage restore page", "session_id", s.ID, "error", err)
+		}
+	}()
+
+	listenCtx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	paused, err := s.CDPClient.SubscribeTarget(listenCtx, targetID, "Fetch.requestPaused", 16)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to paused requests: %w", err)
+	}
+	defer paused.Unsubscribe()
+
+	params := map[string]interface{}{
+		"patterns": []map[string]string{{"urlPattern": "*", "requestStage": "Request"}},
+	}
+	if _, err := s.CDPClient.SendCommandToTargetContext(ctx, targetID, "Fetch.enable", params); err != nil {
+		return fmt.Errorf("failed to enable request interception: %w", err)
+	}
+
+	go s.serveBlankDocuments(listenCtx, targetID, paused.C)
+
+	for _, origin := range origins {
+		if err := s.writeLocalStorage(ctx, targetID, origin, localStorage[origin]); err != nil {
+			slog.Warn("failed to restore localStorage", "session_id", s.ID, "origin", origin, "error", err)
+		}
+	}
+	return nil
+}
+
+// serveBlankDocuments answers every paused request of the restore page with an empty document
+func (s *Session) serveBlankDocuments(ctx context.Context, targetID string, paused <-chan *cdp.Event) {
+	body := base64.StdEncoding.EncodeToString([]byte(blankDocument))
+
+	for {
+		select {
+		case event, ok := <-paused:
+			if !ok {
+				return
+			}
+
+			var params cdpRequestPaused
+			if err := json.Unmarshal(event.Params, &params); err != nil {
+				continue
+			}
+
+			command := map[string]interface{}{
+				"requestId":       params.RequestID,
+				"responseCode":    200,
+				"responseHeaders": cdpHeaderEntries(map[string]string{"Content-Type": "text/html; charset=utf-8"}),
+				"body":            body,
+			}
+			if _, err := s.CDPClient.SendCommandToTargetContext(ctx, targetID, "Fetch.fulfillRequest", command); err != nil {
+				slog.Debug("failed to fulfill restore request", "url", params.Request.URL, "error", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// writeLocalStorage opens the origin on the restore page and replaces its localStorage with the items
+func (s *Session) writeLocalStorage(ctx context.Context, targetID, origin string, items map[string]string) error {
+	navigation, err := s.NavigatePage(ctx, targetID, origin+"/", nil)
+	if err != nil {
+		return err
+	}
+	if navigation.ErrorText != "" {
+		return fmt.Errorf("failed to open origin: %s", navigation.ErrorText)
+	}
+
+	script, err := writeLocalStorageScript(items)
+	if err != nil {
+		return err
+	}
+
+	value, err := s.ExecuteJavascript(ctx, targetID, script)
+	if err != nil {
+		return err
+	}
+
+	// A redirect or an opaque origin would put the items somewhere else
+	if value != origin {
+		return fmt.Errorf("restore page ended up on %v", value)
+	}
+	return nil
+}
+
+// writeLocalStorageScript builds the script that replaces the page's localStorage with the items
+// It returns the page's origin so the caller can check where the items went.
+func writeLocalStorageScript(items map[string]string) (string, error) {
+	data, err := json.Marshal(items)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode localStorage: %w", err)
+	}
+
+	return fmt.Sprintf(`((items) => {
+	localStorage.clear();
+	for (const [key, value] of Object.entries(items)) localStorage.setItem(key, value);
+	return location.origin;
+})(%s)`, data), nil
+}
+
+// restorableOrigins returns the http and https origins with items, sorted so restores are repeatable
+func restorableOrigins(localStorage map[string]map[string]string) []string {
+	origins := make([]string, 0, len(localStorage))
+	for origin, items := range localStorage {
+		if len(items) == 0 {
+			continue
+		}
+
+		parsed, err := url.Parse(origin)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			continue
+		}
+		// An origin is scheme and host only
+		if parsed.Path != "" || parsed.RawQuery != "" || parsed.Fragment != "" || parsed.User != nil {
+			continue
+		}
+
+		origins = append(origins, origin)
+	}
+	sort.Strings(origins)
+	return origins
+}
+
+// toStorage converts a CDP cookie into its persisted form, session cookies keep an expiry of -1
+func (c cdpCookie) toStorage() storage.Cookie {
+	expires := c.Expires
+	if c.Session {
+		expires = -1
+	}
+
+	return storage.Cookie{
+		Name:     c.Name,
+		Value:    c.Value,
+		Domain:   c.Domain,
+		Path:     c.Path,
+		Expires:  expires,
+		Secure:   c.Secure,
+		HttpOnly: c.HTTPOnly,
+		SameSite: c.SameSite,
+	}
+}
+
+// cookieParams builds the Storage.setCookies parameters, dropping cookies that expired while the session was closed
+func cookieParams(cookies []storage.Cookie, now time.Time) []map[string]interface{} {
+	params := make([]map[string]interface{}, 0, len(cookies))
+	for _, cookie := range cookies {
+		if cookie.Name == "" || cookie.Domain == "" {
+			continue
+		}
+
+		param := map[string]interface{}{
+			"name":     cookie.Name,
+			"value":    cookie.Value,
+			"domain":   cookie.Domain,
+			"path":     cookie.Path,
+			"secure":   cookie.Secure,
+			"httpOnly": cookie.HttpOnly,
+		}
+		if cookie.Path == "" {
+			param["path"] = "/"
+		}
+		if cookie.SameSite != "" {
+			param["sameSite"] = cookie.SameSite
+		}
+
+		// Zero or negative means a session cookie, which lives until the browser context goes away
+		if cookie.Expires > 0 {
+			if cookie.Expires <= float64(now.Unix()) {
+				continue
+			}
+			param["expires"] = cookie.Expires
+		}
+
+		params = append(params, param)
+	}
+	return params
+}
@@ -0,0 +1,80 @@
+package session
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dhruvsoni1802/browser-query-ai/internal/storage"
+)
+
+// TestCookieParams tests converting stored cookies into Storage.setCookies parameters
+func TestCookieParams(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	cookies := []storage.Cookie{
+		{Name: "session", Value: "a", Domain: ".example.com", Path: "/", Expires: -1, HttpOnly: true},
+		{Name: "token", Value: "b", Domain: "example.com", Expires: 1800000000, Secure: true, SameSite: "None"},
+		{Name: "stale", Value: "c", Domain: "example.com", Path: "/", Expires: 1600000000},
+		{Name: "", Value: "d", Domain: "example.com"},
+	}
+
+	params := cookieParams(cookies, now)
+	if len(params) != 2 {
+		t.Fatalf("expected 2 cookies, got %d: %v", len(params), params)
+	}
+
+	if _, ok := params[0]["expires"]; ok {
+		t.Errorf("expected a session cookie to have no expiry, got %v", params[0])
+	}
+	if params[0]["httpOnly"] != true {
+		t.Errorf("expected httpOnly to carry over, got %v", params[0])
+	}
+
+	if params[1]["expires"] != float64(1800000000) || params[1]["sameSite"] != "None" {
+		t.Errorf("expected expiry and sameSite to carry over, got %v", params[1])
+	}
+	if params[1]["path"] != "/" {
+		t.Errorf("expected an empty path to default to /, got %v", params[1]["path"])
+	}
+}
+
+// TestCookieToStorage tests that session cookies are stored with an expiry of -1
+func TestCookieToStorage(t *testing.T) {
+	cookie := cdpCookie{Name: "id", Value: "1", Domain: "example.com", Path: "/", Expires: 0, Session: true, SameSite: "Lax"}
+
+	expected := storage.Cookie{Name: "id", Value: "1", Domain: "example.com", Path: "/", Expires: -1, SameSite: "Lax"}
+	if got := cookie.toStorage(); got != expected {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+}
+
+// TestRestorableOrigins tests picking the origins whose localStorage can be restored
+func TestRestorableOrigins(t *testing.T) {
+	localStorage := map[string]map[string]string{
+		"https://example.com":      {"a": "1"},
+		"http://localhost:8080":    {"b": "2"},
+		"https://empty.example":    {},
+		"file://":                  {"c": "3"},
+		"chrome-extension://abc":   {"d": "4"},
+		"https://example.com/path": {"e": "5"},
+	}
+
+	expected := []string{"http://localhost:8080", "https://example.com"}
+	if got := restorableOrigins(localStorage); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+// TestWriteLocalStorageScript tests that items are embedded as a literal the script can't be broken out of
+func TestWriteLocalStorageScript(t *testing.T) {
+	script, err := writeLocalStorageScript(map[string]string{"quote": `"); alert(1); ("`})
+	if err != nil {
+		t.Fatalf("writeLocalStorageScript failed: %v", err)
+	}
+
+	if !strings.Contains(script, `{"quote":"\"); alert(1); (\""}`) {
+		t.Errorf("expected the items to be JSON encoded, got %s", script)
+	}
+}
@@ -0,0 +1,437 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/dhruvsoni1802/browser-query-ai/internal/cdp"
+)
+
+// Selector states accepted by WaitCondition
+const (
+	SelectorAttached = "attached" // At least one matching element exists
+	SelectorVisible  = "visible"  // At least one matching element is visible (default)
+	SelectorHidden   = "hidden"   // No matching element is visible
+	SelectorDetached = "detached" // No matching element exists
+)
+
+// waitPollInterval is how often page side conditions are re-checked
+const waitPollInterval = 100 * time.Millisecond
+
+// maxReportedRequests caps the in-flight request URLs listed in diagnostics
+const maxReportedRequests = 10
+
+// WaitCondition describes what to wait for on a page
+// Every condition that is set must hold at the same time.
+type WaitCondition struct {
+	Selector      string `json:"selector,omitempty"`        // CSS selector to wait for
+	State         string `json:"state,omitempty"`           // selector: attached, visible (default), hidden or detached
+	Text          string `json:"text,omitempty"`            // Text that must be present on the page
+	URL           string `json:"url,omitempty"`             // Glob the page URL must match, * stops at "/", ** does not
+	Function      string `json:"function,omitempty"`        // JavaScript expression (or function) that must be truthy
+	NetworkIdleMs int    `json:"network_idle_ms,omitempty"` // Time without network activity
+	MaxInflight   int    `json:"max_inflight,omitempty"`    // network idle: requests allowed to stay open (e.g. long polling)
+	TimeoutMs     int    `json:"timeout_ms,omitempty"`      // Default DefaultWaitTimeout
+}
+
+// WaitResult describes a satisfied wait
+type WaitResult struct {
+	PageID    string `json:"page_id"`
+	URL       string `json:"url"`
+	ElapsedMs int64  `json:"elapsed_ms"`
+}
+
+// WaitDiagnostics describes the state of the page when a wait timed out
+type WaitDiagnostics struct {
+	Condition        WaitCondition `json:"condition"`
+	ElapsedMs        int64         `json:"elapsed_ms"`
+	URL              string        `json:"url,omitempty"`
+	ReadyState       string        `json:"ready_state,omitempty"`
+	Unmet            []string      `json:"unmet"`                       // Conditions that did not hold on the last check
+	InflightRequests []string      `json:"inflight_requests,omitempty"` // URLs of requests still open, network idle only
+	LastError        string        `json:"last_error,omitempty"`        // Last error evaluating the page, e.g. a throwing function
+}
+
+// WaitTimeoutError is returned when a wait condition is not met in time
+type WaitTimeoutError struct {
+	Diagnostics *WaitDiagnostics
+}
+
+func (e *WaitTimeoutError) Error() string {
+	return fmt.Sprintf("%s after %dms: %s", ErrWaitTimeout, e.Diagnostics.ElapsedMs, strings.Join(e.Diagnostics.Unmet, "; "))
+}
+
+func (e *WaitTimeoutError) Unwrap() error {
+	return ErrWaitTimeout
+}
+
+// validate checks that the condition is well formed
+func (c *WaitCondition) validate() error {
+	if c.Selector == "" && c.Text == "" && c.URL == "" && c.Function == "" && c.NetworkIdleMs == 0 {
+		return fmt.Errorf("%w: one of selector, text, url, function or network_idle_ms is required", ErrInvalidWaitCondition)
+	}
+
+	switch c.State {
+	case "", SelectorAttached, SelectorVisible, SelectorHidden, SelectorDetached:
+	default:
+		return fmt.Errorf("%w: unknown selector state %q", ErrInvalidWaitCondition, c.State)
+	}
+	if c.State != "" && c.Selector == "" {
+		return fmt.Errorf("%w: state requires a selector", ErrInvalidWaitCondition)
+	}
+
+	if c.NetworkIdleMs < 0 || c.MaxInflight < 0 || c.TimeoutMs < 0 {
+		return fmt.Errorf("%w: network_idle_ms, max_inflight and timeout_ms must not be negative", ErrInvalidWaitCondition)
+	}
+
+	return nil
+}
+
+// globToRegexp compiles a URL glob: ** matches anything, * anything but "/", ? a single character
+func globToRegexp(glob string) *regexp.Regexp {
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch {
+		case strings.HasPrefix(glob[i:], "**"):
+			pattern.WriteString(".*")
+			i++
+		case glob[i] == '*':
+			pattern.WriteString("[^/]*")
+		case glob[i] == '?':
+			pattern.WriteString(".")
+		default:
+			pattern.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	pattern.WriteString("$")
+
+	return regexp.MustCompile(pattern.String())
+}
+
+// pageCheckJS evaluates the page side of a wait condition
+// Returns the conditions that are not met yet, or `invalid` if the condition can never be evaluated.
+const pageCheckJS = `async function(c) {
+  var unmet = [];
+  var error = '';
+
+  if (c.selector) {
+    var elements;
+    try {
+      elements = Array.prototype.slice.call(document.querySelectorAll(c.selector));
+    } catch (e) {
+      return { invalid: 'invalid selector ' + c.selector + ': ' + e.message };
+    }
+    var visible = elements.filter(function(el) {
+      var rect = el.getBoundingClientRect();
+      var style = getComputedStyle(el);
+      return rect.width > 0 && rect.height > 0 && style.visibility !== 'hidden' && style.display !== 'none';
+    });
+    var state = c.state || 'visible';
+    if (state === 'attached' && elements.length === 0) {
+      unmet.push('selector ' + c.selector + ' matched no elements');
+    } else if (state === 'visible' && visible.length === 0) {
+      unmet.push('selector ' + c.selector + ' matched ' + elements.length + ' elements, none visible');
+    } else if (state === 'hidden' && visible.length > 0) {
+      unmet.push('selector ' + c.selector + ' still has ' + visible.length + ' visible elements');
+    } else if (state === 'detached' && elements.length > 0) {
+      unmet.push('selector ' + c.selector + ' still matches ' + elements.length + ' elements');
+    }
+  }
+
+  if (c.text && !(document.body && document.body.innerText.indexOf(c.text) !== -1)) {
+    unmet.push('text ' + JSON.stringify(c.text) + ' not found');
+  }
+
+  if (c.function) {
+    try {
+      var value = await (0, eval)(c.function);
+      if (typeof value === 'function') value = await value();
+      if (!value) unmet.push('function returned ' + String(value));
+    } catch (e) {
+      unmet.push('function threw');
+      error = String(e);
+    }
+  }
+
+  return { url: location.href, readyState: document.readyState, unmet: unmet, error: error };
+}`
+
+// pageCheck is the result of pageCheckJS
+type pageCheck struct {
+	Invalid    string   `json:"invalid"`
+	URL        string   `json:"url"`
+	ReadyState string   `json:"readyState"`
+	Unmet      []string `json:"unmet"`
+	Error      string   `json:"error"`
+}
+
+// waiter waits for one condition on one page
+// Network tracking starts when the waiter is created, so it can be created before triggering
+// a navigation or action and still see the requests that trigger causes.
+type waiter struct {
+	session   *Session
+	targetID  string
+	condition WaitCondition
+	urlGlob   *regexp.Regexp
+
+	// Network idle tracking
+	subscription *cdp.Subscription
+	inflight     map[string]string // Request ID to URL
+	finished     map[string]bool   // Requests that finished before they were seen starting
+	lastActivity time.Time
+}
+
+// newWaiter validates the condition and starts network tracking if it is needed
+func (s *Session) newWaiter(ctx context.Context, targetID string, condition WaitCondition) (*waiter, error) {
+	if err := condition.validate(); err != nil {
+		return nil, err
+	}
+
+	w := &waiter{
+		session:      s,
+		targetID:     targetID,
+		condition:    condition,
+		inflight:     make(map[string]string),
+		finished:     make(map[string]bool),
+		lastActivity: time.Now(),
+	}
+	if condition.URL != "" {
+		w.urlGlob = globToRegexp(condition.URL)
+	}
+
+	if condition.NetworkIdleMs == 0 {
+		return w, nil
+	}
+
+	if _, err := s.CDPClient.SendCommandToTargetContext(ctx, targetID, "Network.enable", nil); err != nil {
+		return nil, fmt.Errorf("failed to enable network events: %w", err)
+	}
+
+	// One subscription for the whole domain keeps a request's events in order, a missed finish
+	// event would keep a request in flight forever, so the buffer is generous
+	sub, err := s.CDPClient.SubscribeTarget(ctx, targetID, "Network.*", 1024)
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to network events: %w", err)
+	}
+	w.subscription = sub
+
+	return w, nil
+}
+
+// stop releases the network subscription
+func (w *waiter) stop() {
+	if w.subscription != nil {
+		w.subscription.Unsubscribe()
+	}
+}
+
+// wait blocks until the condition holds, the wait times out or ctx is done
+func (w *waiter) wait(ctx context.Context) (*WaitResult, error) {
+	start := time.Now()
+
+	timeout := DefaultWaitTimeout
+	if w.condition.TimeoutMs > 0 {
+		timeout = time.Duration(w.condition.TimeoutMs) * time.Millisecond
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// The subscription is read in the same loop as the polling, a nil channel never fires
+	var events <-chan *cdp.Event
+	if w.subscription != nil {
+		events = w.subscription.C
+	}
+
+	ticker := time.NewTicker(waitPollInterval)
+	defer ticker.Stop()
+
+	var last pageCheck
+	for {
+		check, err := w.check(waitCtx)
+		switch {
+		case err != nil:
+			// The page may be between documents, keep polling
+			last.Error = err.Error()
+		case check.Invalid != "":
+			return nil, fmt.Errorf("%w: %s", ErrInvalidWaitCondition, check.Invalid)
+		default:
+			last = *check
+			if len(last.Unmet) == 0 {
+				return &WaitResult{
+					PageID:    w.targetID,
+					URL:       last.URL,
+					ElapsedMs: time.Since(start).Milliseconds(),
+				}, nil
+			}
+		}
+
+	drain:
+		for {
+			select {
+			case event, ok := <-events:
+				if !ok {
+					events = nil
+					continue
+				}
+				w.networkEvent(event)
+			case <-ticker.C:
+				break drain
+			case <-waitCtx.Done():
+				// Client went away or its own deadline passed, nothing to diagnose for
+				if ctx.Err() != nil && !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					return nil, ctx.Err()
+				}
+				return nil, &WaitTimeoutError{Diagnostics: w.diagnostics(last, time.Since(start))}
+			}
+		}
+	}
+}
+
+// check evaluates every condition once
+func (w *waiter) check(ctx context.Context) (*pageCheck, error) {
+	check := &pageCheck{}
+
+	// Page side conditions need the page, network idle alone does not
+	if w.condition.Selector != "" || w.condition.Text != "" || w.condition.URL != "" || w.condition.Function != "" {
+		arguments, err := json.Marshal(map[string]interface{}{
+			"selector": w.condition.Selector,
+			"state":    w.condition.State,
+			"text":     w.condition.Text,
+			"function": w.condition.Function,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode wait condition: %w", err)
+		}
+
+		params := map[string]interface{}{
+			"expression":    "(" + pageCheckJS + ")(" + string(arguments) + ")",
+			"returnByValue": true,
+			"awaitPromise":  true,
+		}
+
+		result, err := w.session.CDPClient.SendCommandToTargetContext(ctx, w.targetID, "Runtime.evaluate", params)
+		if err != nil {
+			return nil, err
+		}
+
+		var response struct {
+			Result struct {
+				Value pageCheck `json:"value"`
+			} `json:"result"`
+			ExceptionDetails interface{} `json:"exceptionDetails,omitempty"`
+		}
+		if err := json.Unmarshal(result, &response); err != nil {
+			return nil, fmt.Errorf("failed to parse wait check: %w", err)
+		}
+		if response.ExceptionDetails != nil {
+			return nil, fmt.Errorf("javascript execution error: %v", response.ExceptionDetails)
+		}
+		check = &response.Result.Value
+	}
+
+	if w.urlGlob != nil && !w.urlGlob.MatchString(check.URL) {
+		check.Unmet = append(check.Unmet, fmt.Sprintf("url %s does not match %s", check.URL, w.condition.URL))
+	}
+
+	if w.condition.NetworkIdleMs > 0 {
+		idleFor := time.Since(w.lastActivity)
+		required := time.Duration(w.condition.NetworkIdleMs) * time.Millisecond
+		if len(w.inflight) > w.condition.MaxInflight {
+			check.Unmet = append(check.Unmet, fmt.Sprintf("network busy: %d requests in flight", len(w.inflight)))
+		} else if idleFor < required {
+			check.Unmet = append(check.Unmet, fmt.Sprintf("network idle for %dms of %dms", idleFor.Milliseconds(), w.condition.NetworkIdleMs))
+		}
+	}
+
+	return check, nil
+}
+
+// networkEvent updates the requests in flight from a network event
+func (w *waiter) networkEvent(event *cdp.Event) {
+	switch event.Method {
+	case "Network.requestWillBeSent":
+		w.requestStarted(event)
+	case "Network.loadingFinished", "Network.loadingFailed":
+		w.requestDone(event)
+	}
+}
+
+// requestStarted records a request (or redirect) as in flight
+func (w *waiter) requestStarted(event *cdp.Event) {
+	var sent struct {
+		RequestID string `json:"requestId"`
+		Request   struct {
+			URL string `json:"url"`
+		} `json:"request"`
+	}
+	if err := json.Unmarshal(event.Params, &sent); err != nil {
+		return
+	}
+	if w.finished[sent.RequestID] {
+		return
+	}
+
+	w.inflight[sent.RequestID] = sent.Request.URL
+	w.lastActivity = time.Now()
+}
+
+// requestDone records a request as finished or failed
+// Requests that started before tracking began are unknown, they are remembered so a start
+// seen late doesn't keep them in flight.
+func (w *waiter) requestDone(event *cdp.Event) {
+	var done struct {
+		RequestID string `json:"requestId"`
+	}
+	if err := json.Unmarshal(event.Params, &done); err != nil {
+		return
+	}
+
+	if _, ok := w.inflight[done.RequestID]; !ok {
+		w.finished[done.RequestID] = true
+		return
+	}
+	delete(w.inflight, done.RequestID)
+	w.lastActivity = time.Now()
+}
+
+// diagnostics describes the last check for a timed out wait
+func (w *waiter) diagnostics(last pageCheck, elapsed time.Duration) *WaitDiagnostics {
+	diagnostics := &WaitDiagnostics{
+		Condition:  w.condition,
+		ElapsedMs:  elapsed.Milliseconds(),
+		URL:        last.URL,
+		ReadyState: last.ReadyState,
+		Unmet:      last.Unmet,
+		LastError:  last.Error,
+	}
+	if len(diagnostics.Unmet) == 0 {
+		diagnostics.Unmet = []string{"page could not be checked"}
+	}
+
+	for _, url := range w.inflight {
+		if len(diagnostics.InflightRequests) == maxReportedRequests {
+			break
+		}
+		diagnostics.InflightRequests = append(diagnostics.InflightRequests, url)
+	}
+
+	return diagnostics
+}
+
+// Wait blocks until the condition holds on the page
+// A timeout returns a *WaitTimeoutError describing what was still unmet.
+func (s *Session) Wait(ctx context.Context, targetID string, condition WaitCondition) (*WaitResult, error) {
+	w, err := s.newWaiter(ctx, targetID, condition)
+	if err != nil {
+		return nil, err
+	}
+	defer w.stop()
+
+	return w.wait(ctx)
+}
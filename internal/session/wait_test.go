@@ -0,0 +1,92 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dhruvsoni1802/browser-query-ai/internal/cdp"
+)
+
+// TestGlobToRegexp tests URL glob matching for wait conditions
+func TestGlobToRegexp(t *testing.T) {
+	tests := []struct {
+		glob    string
+		url     string
+		matches bool
+	}{
+		{"https://example.com/", "https://example.com/", true},
+		{"https://example.com/*", "https://example.com/search", true},
+		{"https://example.com/*", "https://example.com/a/b", false},
+		{"https://example.com/**", "https://example.com/a/b", true},
+		{"**/checkout?step=?", "https://shop.test/cart/checkout?step=2", true},
+		{"**/checkout", "https://shop.test/checkout/done", false},
+		{"https://example.com/a.b", "https://example.com/aXb", false},
+	}
+
+	for _, tt := range tests {
+		if got := globToRegexp(tt.glob).MatchString(tt.url); got != tt.matches {
+			t.Errorf("glob %q against %q: expected %v, got %v", tt.glob, tt.url, tt.matches, got)
+		}
+	}
+}
+
+// TestWaitConditionValidate tests that malformed wait conditions are rejected
+func TestWaitConditionValidate(t *testing.T) {
+	valid := []WaitCondition{
+		{Selector: "#main"},
+		{Selector: "#spinner", State: SelectorDetached},
+		{Text: "Welcome"},
+		{URL: "**/done"},
+		{Function: "window.ready === true"},
+		{NetworkIdleMs: 500, MaxInflight: 1},
+	}
+	for _, condition := range valid {
+		if err := condition.validate(); err != nil {
+			t.Errorf("expected %+v to be valid, got %v", condition, err)
+		}
+	}
+
+	invalid := []WaitCondition{
+		{},
+		{TimeoutMs: 1000},
+		{State: SelectorVisible},
+		{Selector: "#main", State: "gone"},
+		{NetworkIdleMs: -1},
+	}
+	for _, condition := range invalid {
+		if err := condition.validate(); !errors.Is(err, ErrInvalidWaitCondition) {
+			t.Errorf("expected %+v to be invalid, got %v", condition, err)
+		}
+	}
+}
+
+// requestEvent returns a network event for a request
+func requestEvent(method, requestID string) *cdp.Event {
+	params := fmt.Sprintf(`{"requestId":%q,"request":{"url":"https://example.com/%s"}}`, requestID, requestID)
+	return &cdp.Event{Method: method, Params: json.RawMessage(params)}
+}
+
+// TestWaiterTracksInflight tests that finished requests leave the in flight set in any event order
+func TestWaiterTracksInflight(t *testing.T) {
+	w := &waiter{inflight: make(map[string]string), finished: make(map[string]bool), lastActivity: time.Now()}
+
+	for _, event := range []*cdp.Event{
+		requestEvent("Network.requestWillBeSent", "1"),
+		requestEvent("Network.requestWillBeSent", "2"),
+		// Finished before its start was seen
+		requestEvent("Network.loadingFinished", "3"),
+		requestEvent("Network.requestWillBeSent", "3"),
+		requestEvent("Network.dataReceived", "1"),
+		requestEvent("Network.loadingFinished", "1"),
+		requestEvent("Network.loadingFailed", "2"),
+	} {
+		w.networkEvent(event)
+	}
+
+	if len(w.inflight) != 0 {
+		t.Errorf("expected no requests in flight, got %v", w.inflight)
+	}
+}
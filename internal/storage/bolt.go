@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltBackend keeps the buckets in an embedded BoltDB file, for durable single-node deployments
+type boltBackend struct {
+	db *bolt.DB
+}
+
+// NewBoltStore opens or creates the database file at path
+// Only one process can have the file open, a second one fails after a short wait.
+func NewBoltStore(path string, ttl time.Duration) (*LocalStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open session database: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range []string{sessionsBucket, namesBucket} {
+			if _, err := tx.CreateBucketIfNotExists([]byte(bucket)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create session buckets: %w", err)
+	}
+
+	return newLocalStore(&boltBackend{db: db}, ttl), nil
+}
+
+func (b *boltBackend) view(fn func(tx kvTx) error) error {
+	return b.db.View(func(tx *bolt.Tx) error {
+		return fn(boltTx{tx: tx})
+	})
+}
+
+// update runs fn in a bolt transaction, nothing is written when it returns an error
+func (b *boltBackend) update(fn func(tx kvTx) error) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return fn(boltTx{tx: tx})
+	})
+}
+
+func (b *boltBackend) close() error {
+	return b.db.Close()
+}
+
+// boltTx maps bucket access onto a bolt transaction
+type boltTx struct {
+	tx *bolt.Tx
+}
+
+// get returns a copy, bolt's own slice is only valid until the transaction ends
+func (t boltTx) get(bucket, key string) []byte {
+	value := t.tx.Bucket([]byte(bucket)).Get([]byte(key))
+	if value == nil {
+		return nil
+	}
+	return append([]byte(nil), value...)
+}
+
+func (t boltTx) put(bucket, key string, value []byte) error {
+	if !t.tx.Writable() {
+		return errReadOnly
+	}
+	return t.tx.Bucket([]byte(bucket)).Put([]byte(key), value)
+}
+
+func (t boltTx) delete(bucket, key string) error {
+	if !t.tx.Writable() {
+		return errReadOnly
+	}
+	return t.tx.Bucket([]byte(bucket)).Delete([]byte(key))
+}
+
+func (t boltTx) forEach(bucket string, fn func(key string, value []byte) error) error {
+	return t.tx.Bucket([]byte(bucket)).ForEach(func(key, value []byte) error {
+		return fn(string(key), value)
+	})
+}
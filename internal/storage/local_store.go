@@ -0,0 +1,511 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Buckets of the key-value backends behind a LocalStore
+const (
+	sessionsBucket = "sessions" // Session ID to sessionRecord
+	namesBucket    = "names"    // Agent ID and session name to session ID
+)
+
+// errReadOnly is returned for a write inside a read-only transaction
+var errReadOnly = fmt.Errorf("write in a read-only transaction")
+
+// kvTx reads and writes the buckets of a backend inside one transaction
+type kvTx interface {
+	get(bucket, key string) []byte
+	put(bucket, key string, value []byte) error
+	delete(bucket, key string) error
+	forEach(bucket string, fn func(key string, value []byte) error) error
+}
+
+// kvBackend is where a LocalStore keeps its data
+type kvBackend interface {
+	view(fn func(tx kvTx) error) error
+	update(fn func(tx kvTx) error) error
+	close() error
+}
+
+// sessionRecord is a session with everything saved for it, as stored by a LocalStore
+type sessionRecord struct {
+	State     SessionState `json:"state"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	Reserved  bool         `json:"reserved,omitempty"` // Holds a name and a slot until the session is first saved
+}
+
+// LocalStore is a single-node SessionStore, in memory or in an embedded database file
+// Sessions expire after the TTL like their Redis keys do, refreshed on every save and activity update.
+type LocalStore struct {
+	kv  kvBackend
+	ttl time.Duration
+	now func() time.Time
+}
+
+// newLocalStore wraps a backend
+func newLocalStore(kv kvBackend, ttl time.Duration) *LocalStore {
+	return &LocalStore{kv: kv, ttl: ttl, now: time.Now}
+}
+
+// SaveSession persists session state
+func (s *LocalStore) SaveSession(state *SessionState) error {
+	state.EnsureSessionName()
+	if err := state.Validate(); err != nil {
+		return fmt.Errorf("invalid session state: %w", err)
+	}
+
+	return s.kv.update(func(tx kvTx) error {
+		now := s.now()
+		if err := purgeExpired(tx, now); err != nil {
+			return err
+		}
+
+		record, err := getRecord(tx, state.SessionID, now)
+		if err != nil {
+			return err
+		}
+		if record == nil {
+			record = &sessionRecord{}
+		}
+
+		// Browser state is only written when set, like the separate Redis keys
+		saved := *state
+		if len(saved.Cookies) == 0 {
+			saved.Cookies = record.State.Cookies
+		}
+		if len(saved.LocalStorage) == 0 {
+			saved.LocalStorage = record.State.LocalStorage
+		}
+		if len(saved.Pages) == 0 {
+			saved.Pages = record.State.Pages
+		}
+		record.State = saved
+		record.Reserved = false
+
+		// Reserve the session name
+		if err := reserveName(tx, state.AgentID, state.SessionName, state.SessionID, now); err != nil {
+			slog.Warn("failed to reserve session name", "error", err)
+		}
+
+		return putRecord(tx, record, now.Add(s.ttl))
+	})
+}
+
+// GetSession retrieves session state
+func (s *LocalStore) GetSession(sessionID string) (*SessionState, error) {
+	var state *SessionState
+	err := s.kv.view(func(tx kvTx) error {
+		record, err := getRecord(tx, sessionID, s.now())
+		if err != nil {
+			return err
+		}
+		if record == nil || record.Reserved {
+			return fmt.Errorf("session not found: %s", sessionID)
+		}
+		state = &record.State
+		return nil
+	})
+	return state, err
+}
+
+// DeleteSession removes a session and releases its name
+func (s *LocalStore) DeleteSession(sessionID string) error {
+	return s.kv.update(func(tx kvTx) error {
+		return deleteRecord(tx, sessionID)
+	})
+}
+
+// ListActiveSessions returns all session IDs that have not expired
+func (s *LocalStore) ListActiveSessions() ([]string, error) {
+	sessionIDs := make([]string, 0)
+	err := s.kv.view(func(tx kvTx) error {
+		return forEachRecord(tx, s.now(), func(record *sessionRecord) {
+			if !record.Reserved {
+				sessionIDs = append(sessionIDs, record.State.SessionID)
+			}
+		})
+	})
+	return sessionIDs, err
+}
+
+// UpdateLastActivity updates just the last activity timestamp
+func (s *LocalStore) UpdateLastActivity(sessionID string) error {
+	return s.updateRecord(sessionID, func(state *SessionState) error {
+		state.LastActivity = s.now()
+		return nil
+	})
+}
+
+// UpdateSessionStatus updates just the session status field
+func (s *LocalStore) UpdateSessionStatus(sessionID string, status string) error {
+	return s.updateRecord(sessionID, func(state *SessionState) error {
+		state.Status = status
+		return nil
+	})
+}
+
+// SavePages replaces the pages of a session
+func (s *LocalStore) SavePages(sessionID string, pages []PageState) error {
+	return s.updateRecord(sessionID, func(state *SessionState) error {
+		state.Pages = pages
+		return nil
+	})
+}
+
+// ReplaceBrowserState overwrites the stored cookies and localStorage
+func (s *LocalStore) ReplaceBrowserState(sessionID string, cookies []Cookie, localStorage map[string]map[string]string) error {
+	return s.updateRecord(sessionID, func(state *SessionState) error {
+		state.Cookies = cookies
+		state.LocalStorage = localStorage
+		return nil
+	})
+}
+
+// GetSessionByName retrieves session ID by agent + name
+func (s *LocalStore) GetSessionByName(agentID, sessionName string) (string, error) {
+	var sessionID string
+	err := s.kv.view(func(tx kvTx) error {
+		var err error
+		sessionID, err = lookupName(tx, agentID, sessionName, s.now())
+		if err != nil {
+			return err
+		}
+		if sessionID == "" {
+			return fmt.Errorf("session not found with name '%s'", sessionName)
+		}
+		return nil
+	})
+	return sessionID, err
+}
+
+// CheckSessionNameExists checks if a session name is already taken by an agent
+func (s *LocalStore) CheckSessionNameExists(agentID, sessionName string) (bool, error) {
+	var exists bool
+	err := s.kv.view(func(tx kvTx) error {
+		sessionID, err := lookupName(tx, agentID, sessionName, s.now())
+		exists = sessionID != ""
+		return err
+	})
+	return exists, err
+}
+
+// RenameSession moves a session to a new name in one transaction
+func (s *LocalStore) RenameSession(sessionID, agentID, oldName, newName string) error {
+	return s.kv.update(func(tx kvTx) error {
+		now := s.now()
+
+		holder, err := lookupName(tx, agentID, newName, now)
+		if err != nil {
+			return err
+		}
+		if holder == sessionID {
+			return nil
+		}
+		if holder != "" {
+			return fmt.Errorf("%w: '%s'", ErrNameTaken, newName)
+		}
+
+		record, err := getRecord(tx, sessionID, now)
+		if err != nil {
+			return err
+		}
+		if record == nil {
+			return fmt.Errorf("session not found: %s", sessionID)
+		}
+
+		// Release the name the session holds, oldName may be stale after a concurrent rename
+		oldName = record.State.SessionName
+		if string(tx.get(namesBucket, nameKey(agentID, oldName))) == sessionID {
+			if err := tx.delete(namesBucket, nameKey(agentID, oldName)); err != nil {
+				return err
+			}
+		}
+		if err := tx.put(namesBucket, nameKey(agentID, newName), []byte(sessionID)); err != nil {
+			return err
+		}
+
+		record.State.SessionName = newName
+		if err := putRecord(tx, record, record.ExpiresAt); err != nil {
+			return err
+		}
+
+		slog.Info("session renamed",
+			"session_id", sessionID,
+			"old_name", oldName,
+			"new_name", newName)
+		return nil
+	})
+}
+
+// ReserveSession claims a name and a slot for a session about to be created, in one transaction
+func (s *LocalStore) ReserveSession(agentID, sessionName, sessionID string, maxSessions int) error {
+	return s.kv.update(func(tx kvTx) error {
+		now := s.now()
+
+		holder, err := lookupName(tx, agentID, sessionName, now)
+		if err != nil {
+			return err
+		}
+		if holder == sessionID {
+			return nil
+		}
+		if holder != "" {
+			return fmt.Errorf("%w: '%s'", ErrNameTaken, sessionName)
+		}
+
+		if maxSessions > 0 {
+			count := 0
+			err := forEachRecord(tx, now, func(record *sessionRecord) {
+				if record.State.AgentID == agentID {
+					count++
+				}
+			})
+			if err != nil {
+				return err
+			}
+			if count >= maxSessions {
+				return fmt.Errorf("%w: max %d", ErrAgentLimitReached, maxSessions)
+			}
+		}
+
+		if err := tx.put(namesBucket, nameKey(agentID, sessionName), []byte(sessionID)); err != nil {
+			return err
+		}
+
+		record := &sessionRecord{
+			State:    SessionState{SessionID: sessionID, SessionName: sessionName, AgentID: agentID},
+			Reserved: true,
+		}
+		return putRecord(tx, record, now.Add(s.ttl))
+	})
+}
+
+// ReleaseReservation gives back a reservation whose session was never saved
+func (s *LocalStore) ReleaseReservation(agentID, sessionName, sessionID string) error {
+	return s.kv.update(func(tx kvTx) error {
+		record, err := getRecord(tx, sessionID, s.now())
+		if err != nil {
+			return err
+		}
+		// A saved session is removed with DeleteSession
+		if record != nil && !record.Reserved {
+			return nil
+		}
+		return deleteRecord(tx, sessionID)
+	})
+}
+
+// ReleaseExpiredNames deletes expired sessions and frees the names they held
+// Returns the number of names released.
+func (s *LocalStore) ReleaseExpiredNames() (int, error) {
+	released := 0
+	err := s.kv.update(func(tx kvTx) error {
+		now := s.now()
+
+		var stale []string
+		err := tx.forEach(namesBucket, func(key string, value []byte) error {
+			record, err := getRecord(tx, string(value), now)
+			if err != nil {
+				return err
+			}
+			if record == nil {
+				stale = append(stale, key)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := purgeExpired(tx, now); err != nil {
+			return err
+		}
+		for _, key := range stale {
+			if err := tx.delete(namesBucket, key); err != nil {
+				return err
+			}
+		}
+		released = len(stale)
+		return nil
+	})
+	return released, err
+}
+
+// CountAgentSessions returns the number of stored sessions for an agent, reservations included
+func (s *LocalStore) CountAgentSessions(agentID string) (int, error) {
+	count := 0
+	err := s.kv.view(func(tx kvTx) error {
+		return forEachRecord(tx, s.now(), func(record *sessionRecord) {
+			if record.State.AgentID == agentID {
+				count++
+			}
+		})
+	})
+	return count, err
+}
+
+// ListAgentSessions returns all sessions for an agent with details
+func (s *LocalStore) ListAgentSessions(agentID string) ([]*SessionState, error) {
+	sessions := make([]*SessionState, 0)
+	err := s.kv.view(func(tx kvTx) error {
+		return forEachRecord(tx, s.now(), func(record *sessionRecord) {
+			if record.State.AgentID == agentID && !record.Reserved {
+				sessions = append(sessions, &record.State)
+			}
+		})
+	})
+	return sessions, err
+}
+
+// Close releases the backend
+func (s *LocalStore) Close() error {
+	return s.kv.close()
+}
+
+// updateRecord changes one stored session and refreshes its TTL
+func (s *LocalStore) updateRecord(sessionID string, change func(state *SessionState) error) error {
+	return s.kv.update(func(tx kvTx) error {
+		now := s.now()
+
+		record, err := getRecord(tx, sessionID, now)
+		if err != nil {
+			return err
+		}
+		if record == nil {
+			return fmt.Errorf("session not found: %s", sessionID)
+		}
+
+		if err := change(&record.State); err != nil {
+			return err
+		}
+		return putRecord(tx, record, now.Add(s.ttl))
+	})
+}
+
+// getRecord loads a session, nil when it doesn't exist or has expired
+func getRecord(tx kvTx, sessionID string, now time.Time) (*sessionRecord, error) {
+	data := tx.get(sessionsBucket, sessionID)
+	if data == nil {
+		return nil, nil
+	}
+
+	var record sessionRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal session %s: %w", sessionID, err)
+	}
+	if record.expired(now) {
+		return nil, nil
+	}
+	return &record, nil
+}
+
+// putRecord stores a session until expiresAt
+func putRecord(tx kvTx, record *sessionRecord, expiresAt time.Time) error {
+	record.ExpiresAt = expiresAt
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal session: %w", err)
+	}
+	return tx.put(sessionsBucket, record.State.SessionID, data)
+}
+
+// deleteRecord removes a session and the name pointing at it
+func deleteRecord(tx kvTx, sessionID string) error {
+	data := tx.get(sessionsBucket, sessionID)
+	if data == nil {
+		return nil
+	}
+
+	var record sessionRecord
+	if err := json.Unmarshal(data, &record); err == nil {
+		key := nameKey(record.State.AgentID, record.State.SessionName)
+		if string(tx.get(namesBucket, key)) == sessionID {
+			if err := tx.delete(namesBucket, key); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.delete(sessionsBucket, sessionID)
+}
+
+// forEachRecord calls fn with every session that has not expired
+func forEachRecord(tx kvTx, now time.Time, fn func(record *sessionRecord)) error {
+	return tx.forEach(sessionsBucket, func(key string, value []byte) error {
+		var record sessionRecord
+		if err := json.Unmarshal(value, &record); err != nil {
+			slog.Warn("failed to load session", "session_id", key, "error", err)
+			return nil
+		}
+		if !record.expired(now) {
+			fn(&record)
+		}
+		return nil
+	})
+}
+
+// purgeExpired deletes the sessions whose TTL ran out, along with their names
+func purgeExpired(tx kvTx, now time.Time) error {
+	var expired []string
+	err := tx.forEach(sessionsBucket, func(key string, value []byte) error {
+		var record sessionRecord
+		if err := json.Unmarshal(value, &record); err == nil && record.expired(now) {
+			expired = append(expired, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Deleting while iterating is not allowed by every backend
+	for _, sessionID := range expired {
+		if err := deleteRecord(tx, sessionID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lookupName returns the session holding a name, empty when the name is free
+// A name left behind by an expired session counts as free.
+func lookupName(tx kvTx, agentID, sessionName string, now time.Time) (string, error) {
+	sessionID := string(tx.get(namesBucket, nameKey(agentID, sessionName)))
+	if sessionID == "" {
+		return "", nil
+	}
+
+	record, err := getRecord(tx, sessionID, now)
+	if err != nil || record == nil {
+		return "", err
+	}
+	return sessionID, nil
+}
+
+// reserveName points a name at a session unless another session holds it
+func reserveName(tx kvTx, agentID, sessionName, sessionID string, now time.Time) error {
+	holder, err := lookupName(tx, agentID, sessionName, now)
+	if err != nil {
+		return err
+	}
+	if holder != "" && holder != sessionID {
+		return fmt.Errorf("%w: '%s' for agent '%s'", ErrNameTaken, sessionName, agentID)
+	}
+	return tx.put(namesBucket, nameKey(agentID, sessionName), []byte(sessionID))
+}
+
+// nameKey joins an agent ID and a session name, neither can contain a NUL byte
+func nameKey(agentID, sessionName string) string {
+	return strings.Join([]string{agentID, sessionName}, "\x00")
+}
+
+// expired reports whether the record's TTL ran out
+func (r *sessionRecord) expired(now time.Time) bool {
+	return !r.ExpiresAt.IsZero() && !now.Before(r.ExpiresAt)
+}
@@ -0,0 +1,325 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// localStores returns a memory and a bolt store for tests that must pass on both
+func localStores(t *testing.T, ttl time.Duration) map[string]*LocalStore {
+	t.Helper()
+
+	bolt, err := NewBoltStore(filepath.Join(t.TempDir(), "sessions.db"), ttl)
+	if err != nil {
+		t.Fatalf("NewBoltStore failed: %v", err)
+	}
+	t.Cleanup(func() { bolt.Close() })
+
+	return map[string]*LocalStore{
+		"memory": NewMemoryStore(ttl),
+		"bolt":   bolt,
+	}
+}
+
+// testState returns a valid session state for an agent
+func testState(sessionID, agentID, name string) *SessionState {
+	return &SessionState{
+		SessionID:   sessionID,
+		SessionName: name,
+		AgentID:     agentID,
+		ProcessPort: 9222,
+		ContextID:   "ctx-" + sessionID,
+		CreatedAt:   time.Now().Truncate(time.Second),
+		Status:      "active",
+	}
+}
+
+// TestLocalStoreSaveAndGet tests that a session comes back with its browser state
+func TestLocalStoreSaveAndGet(t *testing.T) {
+	for name, store := range localStores(t, time.Hour) {
+		t.Run(name, func(t *testing.T) {
+			state := testState("s1", "agent", "research")
+			state.Pages = []PageState{{PageID: "p1", URL: "https://example.com/", Title: "Example"}}
+			if err := store.SaveSession(state); err != nil {
+				t.Fatalf("SaveSession failed: %v", err)
+			}
+
+			cookies := []Cookie{{Name: "token", Value: "abc", Domain: "example.com", Path: "/", Expires: -1}}
+			localStorage := map[string]map[string]string{"https://example.com": {"theme": "dark"}}
+			if err := store.ReplaceBrowserState("s1", cookies, localStorage); err != nil {
+				t.Fatalf("ReplaceBrowserState failed: %v", err)
+			}
+
+			// A save without browser state keeps what was stored
+			state.Pages = nil
+			state.Status = "idle"
+			if err := store.SaveSession(state); err != nil {
+				t.Fatalf("SaveSession failed: %v", err)
+			}
+
+			loaded, err := store.GetSession("s1")
+			if err != nil {
+				t.Fatalf("GetSession failed: %v", err)
+			}
+			if loaded.Status != "idle" || loaded.ContextID != "ctx-s1" {
+				t.Errorf("expected the saved metadata, got %+v", loaded)
+			}
+			if len(loaded.Pages) != 1 || loaded.Pages[0].Title != "Example" {
+				t.Errorf("expected the pages to be kept, got %+v", loaded.Pages)
+			}
+			if len(loaded.Cookies) != 1 || loaded.LocalStorage["https://example.com"]["theme"] != "dark" {
+				t.Errorf("expected the browser state to be kept, got %+v %+v", loaded.Cookies, loaded.LocalStorage)
+			}
+
+			// Replacing with nothing clears
+			if err := store.SavePages("s1", nil); err != nil {
+				t.Fatalf("SavePages failed: %v", err)
+			}
+			if loaded, _ := store.GetSession("s1"); len(loaded.Pages) != 0 {
+				t.Errorf("expected the pages to be cleared, got %+v", loaded.Pages)
+			}
+
+			if _, err := store.GetSession("missing"); err == nil {
+				t.Error("expected an error for a missing session")
+			}
+		})
+	}
+}
+
+// TestLocalStoreNames tests name lookup, rename and release on delete
+func TestLocalStoreNames(t *testing.T) {
+	for name, store := range localStores(t, time.Hour) {
+		t.Run(name, func(t *testing.T) {
+			for _, state := range []*SessionState{testState("s1", "agent", "one"), testState("s2", "agent", "two")} {
+				if err := store.SaveSession(state); err != nil {
+					t.Fatalf("SaveSession failed: %v", err)
+				}
+			}
+
+			if id, err := store.GetSessionByName("agent", "one"); err != nil || id != "s1" {
+				t.Errorf("expected s1, got %q, %v", id, err)
+			}
+			if exists, _ := store.CheckSessionNameExists("other-agent", "one"); exists {
+				t.Error("expected names to be per agent")
+			}
+
+			if err := store.RenameSession("s1", "agent", "one", "two"); !errors.Is(err, ErrNameTaken) {
+				t.Errorf("expected a name conflict, got %v", err)
+			}
+			if err := store.RenameSession("s1", "agent", "one", "three"); err != nil {
+				t.Fatalf("RenameSession failed: %v", err)
+			}
+			if exists, _ := store.CheckSessionNameExists("agent", "one"); exists {
+				t.Error("expected the old name to be released")
+			}
+			if loaded, _ := store.GetSession("s1"); loaded.SessionName != "three" {
+				t.Errorf("expected the session to carry the new name, got %q", loaded.SessionName)
+			}
+
+			if count, _ := store.CountAgentSessions("agent"); count != 2 {
+				t.Errorf("expected 2 sessions, got %d", count)
+			}
+
+			if err := store.DeleteSession("s2"); err != nil {
+				t.Fatalf("DeleteSession failed: %v", err)
+			}
+			if exists, _ := store.CheckSessionNameExists("agent", "two"); exists {
+				t.Error("expected the name of a deleted session to be released")
+			}
+			if sessions, _ := store.ListAgentSessions("agent"); len(sessions) != 1 || sessions[0].SessionID != "s1" {
+				t.Errorf("expected only s1 to be left, got %+v", sessions)
+			}
+		})
+	}
+}
+
+// TestLocalStoreExpiry tests that sessions expire after the TTL unless refreshed
+func TestLocalStoreExpiry(t *testing.T) {
+	for name, store := range localStores(t, time.Minute) {
+		t.Run(name, func(t *testing.T) {
+			now := time.Now()
+			store.now = func() time.Time { return now }
+
+			for _, state := range []*SessionState{testState("s1", "agent", "one"), testState("s2", "agent", "two")} {
+				if err := store.SaveSession(state); err != nil {
+					t.Fatalf("SaveSession failed: %v", err)
+				}
+			}
+
+			now = now.Add(45 * time.Second)
+			if err := store.UpdateLastActivity("s1"); err != nil {
+				t.Fatalf("UpdateLastActivity failed: %v", err)
+			}
+
+			now = now.Add(30 * time.Second)
+			if _, err := store.GetSession("s2"); err == nil {
+				t.Error("expected s2 to have expired")
+			}
+			if _, err := store.GetSession("s1"); err != nil {
+				t.Errorf("expected s1 to be refreshed, got %v", err)
+			}
+
+			// The name of an expired session is free again
+			if exists, _ := store.CheckSessionNameExists("agent", "two"); exists {
+				t.Error("expected the name of an expired session to be free")
+			}
+			if ids, _ := store.ListActiveSessions(); len(ids) != 1 || ids[0] != "s1" {
+				t.Errorf("expected only s1 to be listed, got %v", ids)
+			}
+		})
+	}
+}
+
+// TestBoltStoreReopen tests that the bolt store keeps sessions across restarts
+func TestBoltStoreReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sessions.db")
+
+	store, err := NewBoltStore(path, time.Hour)
+	if err != nil {
+		t.Fatalf("NewBoltStore failed: %v", err)
+	}
+	if err := store.SaveSession(testState("s1", "agent", "research")); err != nil {
+		t.Fatalf("SaveSession failed: %v", err)
+	}
+	store.Close()
+
+	store, err = NewBoltStore(path, time.Hour)
+	if err != nil {
+		t.Fatalf("NewBoltStore failed: %v", err)
+	}
+	defer store.Close()
+
+	if id, err := store.GetSessionByName("agent", "research"); err != nil || id != "s1" {
+		t.Errorf("expected s1 after reopening, got %q, %v", id, err)
+	}
+}
+
+// TestLocalStoreReservations tests that concurrent reservations respect names and the agent limit
+func TestLocalStoreReservations(t *testing.T) {
+	for name, store := range localStores(t, time.Hour) {
+		t.Run(name, func(t *testing.T) {
+			// Many sessions race for the same name
+			var wg sync.WaitGroup
+			errs := make([]error, 10)
+			for i := range errs {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					errs[i] = store.ReserveSession("agent", "shared", fmt.Sprintf("s%d", i), 0)
+				}(i)
+			}
+			wg.Wait()
+
+			winners := 0
+			for _, err := range errs {
+				if err == nil {
+					winners++
+				} else if !errors.Is(err, ErrNameTaken) {
+					t.Errorf("expected a name conflict, got %v", err)
+				}
+			}
+			if winners != 1 {
+				t.Fatalf("expected exactly one reservation of the name, got %d", winners)
+			}
+
+			// Reservations hold a slot but aren't listed until saved
+			if count, _ := store.CountAgentSessions("agent"); count != 1 {
+				t.Errorf("expected the reservation to be counted, got %d", count)
+			}
+			if sessions, _ := store.ListAgentSessions("agent"); len(sessions) != 0 {
+				t.Errorf("expected the reservation not to be listed, got %+v", sessions)
+			}
+
+			// Many sessions race for the last slots
+			errs = make([]error, 10)
+			for i := range errs {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					errs[i] = store.ReserveSession("agent", fmt.Sprintf("name-%d", i), fmt.Sprintf("t%d", i), 3)
+				}(i)
+			}
+			wg.Wait()
+
+			winners = 0
+			for _, err := range errs {
+				if err == nil {
+					winners++
+				} else if !errors.Is(err, ErrAgentLimitReached) {
+					t.Errorf("expected the agent limit, got %v", err)
+				}
+			}
+			if winners != 2 {
+				t.Errorf("expected two reservations to fit under the limit, got %d", winners)
+			}
+		})
+	}
+}
+
+// TestLocalStoreReleaseReservation tests that a released name can be reserved again
+func TestLocalStoreReleaseReservation(t *testing.T) {
+	for name, store := range localStores(t, time.Hour) {
+		t.Run(name, func(t *testing.T) {
+			if err := store.ReserveSession("agent", "one", "s1", 1); err != nil {
+				t.Fatalf("ReserveSession failed: %v", err)
+			}
+			if err := store.ReleaseReservation("agent", "one", "s1"); err != nil {
+				t.Fatalf("ReleaseReservation failed: %v", err)
+			}
+			if err := store.ReserveSession("agent", "one", "s2", 1); err != nil {
+				t.Fatalf("expected the released name and slot to be free, got %v", err)
+			}
+
+			// Releasing a saved session leaves it alone
+			if err := store.SaveSession(testState("s2", "agent", "one")); err != nil {
+				t.Fatalf("SaveSession failed: %v", err)
+			}
+			if err := store.ReleaseReservation("agent", "one", "s2"); err != nil {
+				t.Fatalf("ReleaseReservation failed: %v", err)
+			}
+			if id, err := store.GetSessionByName("agent", "one"); err != nil || id != "s2" {
+				t.Errorf("expected s2 to keep its name, got %q, %v", id, err)
+			}
+		})
+	}
+}
+
+// TestLocalStoreReleaseExpiredNames tests that names of expired sessions are released
+func TestLocalStoreReleaseExpiredNames(t *testing.T) {
+	for name, store := range localStores(t, time.Minute) {
+		t.Run(name, func(t *testing.T) {
+			now := time.Now()
+			store.now = func() time.Time { return now }
+
+			for _, state := range []*SessionState{testState("s1", "agent", "one"), testState("s2", "agent", "two")} {
+				if err := store.SaveSession(state); err != nil {
+					t.Fatalf("SaveSession failed: %v", err)
+				}
+			}
+
+			now = now.Add(45 * time.Second)
+			if err := store.UpdateLastActivity("s1"); err != nil {
+				t.Fatalf("UpdateLastActivity failed: %v", err)
+			}
+
+			now = now.Add(30 * time.Second)
+			released, err := store.ReleaseExpiredNames()
+			if err != nil {
+				t.Fatalf("ReleaseExpiredNames failed: %v", err)
+			}
+			if released != 1 {
+				t.Errorf("expected 1 name released, got %d", released)
+			}
+			if count, _ := store.CountAgentSessions("agent"); count != 1 {
+				t.Errorf("expected 1 session left, got %d", count)
+			}
+			if id, err := store.GetSessionByName("agent", "one"); err != nil || id != "s1" {
+				t.Errorf("expected s1 to keep its name, got %q, %v", id, err)
+			}
+		})
+	}
+}
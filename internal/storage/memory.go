@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// memoryBackend keeps the buckets in maps, for tests and single-node development
+type memoryBackend struct {
+	mu      sync.RWMutex
+	buckets map[string]map[string][]byte
+}
+
+// NewMemoryStore creates a store that lives as long as the process
+func NewMemoryStore(ttl time.Duration) *LocalStore {
+	return newLocalStore(&memoryBackend{buckets: make(map[string]map[string][]byte)}, ttl)
+}
+
+func (b *memoryBackend) view(fn func(tx kvTx) error) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return fn(memoryTx{backend: b})
+}
+
+// update holds the write lock for the whole function, so it is atomic with respect to other callers
+// Writes made before an error are not rolled back.
+func (b *memoryBackend) update(fn func(tx kvTx) error) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return fn(memoryTx{backend: b, writable: true})
+}
+
+func (b *memoryBackend) close() error {
+	return nil
+}
+
+// memoryTx accesses the maps under the lock its view or update holds
+type memoryTx struct {
+	backend  *memoryBackend
+	writable bool
+}
+
+func (tx memoryTx) get(bucket, key string) []byte {
+	return tx.backend.buckets[bucket][key]
+}
+
+func (tx memoryTx) put(bucket, key string, value []byte) error {
+	if !tx.writable {
+		return errReadOnly
+	}
+	if tx.backend.buckets[bucket] == nil {
+		tx.backend.buckets[bucket] = make(map[string][]byte)
+	}
+	// Callers may reuse the slice, keep a copy
+	tx.backend.buckets[bucket][key] = append([]byte(nil), value...)
+	return nil
+}
+
+func (tx memoryTx) delete(bucket, key string) error {
+	if !tx.writable {
+		return errReadOnly
+	}
+	delete(tx.backend.buckets[bucket], key)
+	return nil
+}
+
+// forEach visits keys in order, like a bolt cursor does
+func (tx memoryTx) forEach(bucket string, fn func(key string, value []byte) error) error {
+	entries := tx.backend.buckets[bucket]
+
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if err := fn(key, entries[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
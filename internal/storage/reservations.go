@@ -0,0 +1,207 @@
+package storage
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Script results shared by the reservation scripts
+const (
+	scriptOK         = 0
+	scriptNameTaken  = 1
+	scriptAgentLimit = 2
+)
+
+// reserveScript takes a name and a slot in the agent's session set in one step
+// The marker key tells reconciliation the name belongs to a session being created, not an expired one.
+// KEYS: names hash, agent sessions set, reservation marker. ARGV: name, session ID, max sessions (0 for no limit), TTL in seconds.
+var reserveScript = redis.NewScript(`
+local holder = redis.call('HGET', KEYS[1], ARGV[1])
+if holder then
+	if holder == ARGV[2] then return 0 end
+	return 1
+end
+local max = tonumber(ARGV[3])
+if max > 0 and redis.call('SISMEMBER', KEYS[2], ARGV[2]) == 0 and redis.call('SCARD', KEYS[2]) >= max then
+	return 2
+end
+redis.call('HSET', KEYS[1], ARGV[1], ARGV[2])
+redis.call('SADD', KEYS[2], ARGV[2])
+if tonumber(ARGV[4]) > 0 then
+	redis.call('EXPIRE', KEYS[1], ARGV[4])
+	redis.call('SET', KEYS[3], '1', 'EX', ARGV[4])
+end
+return 0
+`)
+
+// releaseScript undoes a reservation, leaving a name alone once another session holds it
+// KEYS: names hash, agent sessions set, reservation marker. ARGV: name, session ID.
+var releaseScript = redis.NewScript(`
+if redis.call('HGET', KEYS[1], ARGV[1]) == ARGV[2] then
+	redis.call('HDEL', KEYS[1], ARGV[1])
+end
+redis.call('SREM', KEYS[2], ARGV[2])
+redis.call('DEL', KEYS[3])
+return 0
+`)
+
+// renameScript moves a session from the name its hash holds to another and updates its hash
+// The caller's old name is only used when the hash is gone, it may be stale after a concurrent rename.
+// KEYS: names hash, session hash. ARGV: old name, new name, session ID.
+var renameScript = redis.NewScript(`
+local holder = redis.call('HGET', KEYS[1], ARGV[2])
+if holder then
+	if holder == ARGV[3] then return 0 end
+	return 1
+end
+local current = redis.call('HGET', KEYS[2], 'session_name') or ARGV[1]
+if redis.call('HGET', KEYS[1], current) == ARGV[3] then
+	redis.call('HDEL', KEYS[1], current)
+end
+redis.call('HSET', KEYS[1], ARGV[2], ARGV[3])
+if redis.call('EXISTS', KEYS[2]) == 1 then
+	redis.call('HSET', KEYS[2], 'session_name', ARGV[2])
+end
+return 0
+`)
+
+// ReserveSession atomically claims a name and counts the session against the agent's limit
+// Two concurrent reservations of the same name can't both succeed, nor can more than maxSessions for one agent.
+func (r *SessionRepository) ReserveSession(agentID, sessionName, sessionID string, maxSessions int) error {
+	keys := []string{
+		fmt.Sprintf("agent:%s:session_names", agentID),
+		fmt.Sprintf("agent:%s:sessions", agentID),
+		fmt.Sprintf("session:%s:reserved", sessionID),
+	}
+
+	result, err := reserveScript.Run(r.redis.ctx, r.redis.client, keys, sessionName, sessionID, maxSessions, int(r.ttl.Seconds())).Int()
+	if err != nil {
+		return fmt.Errorf("failed to reserve session: %w", err)
+	}
+
+	switch result {
+	case scriptNameTaken:
+		return fmt.Errorf("%w: '%s'", ErrNameTaken, sessionName)
+	case scriptAgentLimit:
+		return fmt.Errorf("%w: max %d", ErrAgentLimitReached, maxSessions)
+	}
+	return nil
+}
+
+// ReleaseReservation gives back what ReserveSession took, for a session that could not be created
+func (r *SessionRepository) ReleaseReservation(agentID, sessionName, sessionID string) error {
+	keys := []string{
+		fmt.Sprintf("agent:%s:session_names", agentID),
+		fmt.Sprintf("agent:%s:sessions", agentID),
+		fmt.Sprintf("session:%s:reserved", sessionID),
+	}
+
+	if err := releaseScript.Run(r.redis.ctx, r.redis.client, keys, sessionName, sessionID).Err(); err != nil {
+		return fmt.Errorf("failed to release reservation: %w", err)
+	}
+	return nil
+}
+
+// ReleaseExpiredNames frees the names and set entries of sessions whose keys expired
+// Redis expires the session hash but not the indexes pointing at it. Returns the number of names released.
+func (r *SessionRepository) ReleaseExpiredNames() (int, error) {
+	released := 0
+
+	iter := r.redis.client.Scan(r.redis.ctx, 0, "agent:*:session_names", 100).Iterator()
+	for iter.Next(r.redis.ctx) {
+		namesKey := iter.Val()
+		agentKey := strings.TrimSuffix(namesKey, ":session_names") + ":sessions"
+
+		names, err := r.redis.client.HGetAll(r.redis.ctx, namesKey).Result()
+		if err != nil {
+			return released, fmt.Errorf("failed to read session names: %w", err)
+		}
+
+		for name, sessionID := range names {
+			alive, err := r.sessionAlive(sessionID)
+			if err != nil {
+				return released, err
+			}
+			if alive {
+				continue
+			}
+
+			// The script only deletes the name if it still points at the expired session
+			keys := []string{namesKey, agentKey, fmt.Sprintf("session:%s:reserved", sessionID)}
+			if err := releaseScript.Run(r.redis.ctx, r.redis.client, keys, name, sessionID).Err(); err != nil {
+				return released, fmt.Errorf("failed to release session name: %w", err)
+			}
+			released++
+
+			slog.Debug("released expired session name", "key", namesKey, "session_name", name, "session_id", sessionID)
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return released, fmt.Errorf("failed to scan session names: %w", err)
+	}
+
+	// Drop set members of expired sessions, reserved ones are still being created
+	setKeys := []string{"active:sessions"}
+	iter = r.redis.client.Scan(r.redis.ctx, 0, "agent:*:sessions", 100).Iterator()
+	for iter.Next(r.redis.ctx) {
+		setKeys = append(setKeys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return released, fmt.Errorf("failed to scan agent sessions: %w", err)
+	}
+
+	for _, setKey := range setKeys {
+		members, err := r.redis.client.SMembers(r.redis.ctx, setKey).Result()
+		if err != nil {
+			return released, fmt.Errorf("failed to read %s: %w", setKey, err)
+		}
+		for _, sessionID := range members {
+			alive, err := r.sessionAlive(sessionID)
+			if err != nil {
+				return released, err
+			}
+			if !alive {
+				r.redis.client.SRem(r.redis.ctx, setKey, sessionID)
+			}
+		}
+	}
+
+	return released, nil
+}
+
+// sessionAlive reports whether a session's hash or reservation marker still exists
+func (r *SessionRepository) sessionAlive(sessionID string) (bool, error) {
+	count, err := r.redis.client.Exists(r.redis.ctx,
+		fmt.Sprintf("session:%s", sessionID),
+		fmt.Sprintf("session:%s:reserved", sessionID)).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check session %s: %w", sessionID, err)
+	}
+	return count > 0, nil
+}
+
+// RenameSession updates the session name in one step, so a crash never leaves both names or neither
+func (r *SessionRepository) RenameSession(sessionID, agentID, oldName, newName string) error {
+	keys := []string{
+		fmt.Sprintf("agent:%s:session_names", agentID),
+		fmt.Sprintf("session:%s", sessionID),
+	}
+
+	result, err := renameScript.Run(r.redis.ctx, r.redis.client, keys, oldName, newName, sessionID).Int()
+	if err != nil {
+		return fmt.Errorf("failed to rename session: %w", err)
+	}
+	if result == scriptNameTaken {
+		return fmt.Errorf("%w: '%s'", ErrNameTaken, newName)
+	}
+
+	slog.Info("session renamed",
+		"session_id", sessionID,
+		"old_name", oldName,
+		"new_name", newName)
+
+	return nil
+}
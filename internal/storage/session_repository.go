@@ -1,13 +1,14 @@
 package storage
 
 import (
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"strconv"
 	"time"
 )
 
-// This struct handles session persistence in Redis
+// This struct handles session persistence in Redis, it is the SessionStore for multi-node deployments
 type SessionRepository struct {
 	redis *RedisClient  // The Redis client to use for persistence
 	ttl   time.Duration // Default TTL for sessions
@@ -22,6 +23,11 @@ func NewSessionRepository(redisClient *RedisClient, ttl time.Duration) *SessionR
 	}
 }
 
+// Close closes the Redis connection behind the repository
+func (r *SessionRepository) Close() error {
+	return r.redis.Close()
+}
+
 // SaveSession persists session state to Redis using Hash
 func (r *SessionRepository) SaveSession(state *SessionState) error {
 	state.EnsureSessionName()
@@ -49,11 +55,22 @@ func (r *SessionRepository) SaveSession(state *SessionState) error {
 		"status", state.Status,
 		)
 
+	if len(state.Emulation) > 0 {
+		fields["emulation"] = string(state.Emulation)
+	}
+
 	// Store hash in Redis
 	if err := r.redis.client.HSet(r.redis.ctx, key, fields).Err(); err != nil {
 		return fmt.Errorf("failed to save session: %w", err)
 	}
 
+	// HSet only adds fields, drop emulation explicitly once it is cleared
+	if len(state.Emulation) == 0 {
+		if err := r.redis.client.HDel(r.redis.ctx, key, "emulation").Err(); err != nil {
+			slog.Warn("failed to clear emulation", "error", err)
+		}
+	}
+
 	// Set expiration (TTL)
 	if err := r.redis.client.Expire(r.redis.ctx, key, r.ttl).Err(); err != nil {
 		return fmt.Errorf("failed to set TTL: %w", err)
@@ -129,6 +146,10 @@ func (r *SessionRepository) GetSession(sessionID string) (*SessionState, error)
 		Status:       data["status"],
 	}
 
+	if emulation := data["emulation"]; emulation != "" {
+		state.Emulation = json.RawMessage(emulation)
+	}
+
 	// Parse port
 	if port, err := strconv.Atoi(data["process_port"]); err == nil {
 		state.ProcessPort = port
@@ -83,18 +83,17 @@ func (r *SessionRepository) GetCookies(sessionID string) ([]Cookie, error) {
 	return cookies, nil
 }
 
-// SaveLocalStorage stores localStorage as hash
-func (r *SessionRepository) SaveLocalStorage(sessionID string, localStorage map[string]string) error {
+// SaveLocalStorage stores localStorage as hash, one field per origin holding its items as JSON
+func (r *SessionRepository) SaveLocalStorage(sessionID string, localStorage map[string]map[string]string) error {
 	if len(localStorage) == 0 {
 		return nil
 	}
 
 	key := fmt.Sprintf("session:%s:localStorage", sessionID)
 
-	// Convert map[string]string to map[string]interface{} for HSET
-	fields := make(map[string]interface{})
-	for k, v := range localStorage {
-		fields[k] = v
+	fields, err := localStorageFields(localStorage)
+	if err != nil {
+		return err
 	}
 
 	if err := r.redis.client.HSet(r.redis.ctx, key, fields).Err(); err != nil {
@@ -109,8 +108,8 @@ func (r *SessionRepository) SaveLocalStorage(sessionID string, localStorage map[
 	return nil
 }
 
-// GetLocalStorage retrieves localStorage
-func (r *SessionRepository) GetLocalStorage(sessionID string) (map[string]string, error) {
+// GetLocalStorage retrieves localStorage keyed by origin
+func (r *SessionRepository) GetLocalStorage(sessionID string) (map[string]map[string]string, error) {
 	key := fmt.Sprintf("session:%s:localStorage", sessionID)
 
 	data, err := r.redis.client.HGetAll(r.redis.ctx, key).Result()
@@ -123,17 +122,76 @@ func (r *SessionRepository) GetLocalStorage(sessionID string) (map[string]string
 		return nil, nil
 	}
 
-	return data, nil
+	localStorage := make(map[string]map[string]string, len(data))
+	for origin, encoded := range data {
+		var items map[string]string
+		if err := json.Unmarshal([]byte(encoded), &items); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal localStorage of %s: %w", origin, err)
+		}
+		localStorage[origin] = items
+	}
+
+	return localStorage, nil
 }
 
-// SavePages stores pages as JSON string
+// ReplaceBrowserState overwrites the stored cookies and localStorage in one transaction
+// Whatever the browser no longer had is removed, so a logout survives a close as well as a login does.
+func (r *SessionRepository) ReplaceBrowserState(sessionID string, cookies []Cookie, localStorage map[string]map[string]string) error {
+	cookiesKey := fmt.Sprintf("session:%s:cookies", sessionID)
+	localStorageKey := fmt.Sprintf("session:%s:localStorage", sessionID)
+
+	pipe := r.redis.client.TxPipeline()
+	pipe.Del(r.redis.ctx, cookiesKey, localStorageKey)
+
+	if len(cookies) > 0 {
+		data, err := json.Marshal(cookies)
+		if err != nil {
+			return fmt.Errorf("failed to marshal cookies: %w", err)
+		}
+		pipe.Set(r.redis.ctx, cookiesKey, data, r.ttl)
+	}
+
+	if len(localStorage) > 0 {
+		fields, err := localStorageFields(localStorage)
+		if err != nil {
+			return err
+		}
+		pipe.HSet(r.redis.ctx, localStorageKey, fields)
+		pipe.Expire(r.redis.ctx, localStorageKey, r.ttl)
+	}
+
+	if _, err := pipe.Exec(r.redis.ctx); err != nil {
+		return fmt.Errorf("failed to save browser state: %w", err)
+	}
+
+	return nil
+}
+
+// localStorageFields encodes the items of each origin for HSET
+func localStorageFields(localStorage map[string]map[string]string) (map[string]interface{}, error) {
+	fields := make(map[string]interface{}, len(localStorage))
+	for origin, items := range localStorage {
+		data, err := json.Marshal(items)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal localStorage of %s: %w", origin, err)
+		}
+		fields[origin] = string(data)
+	}
+	return fields, nil
+}
+
+// SavePages stores pages as JSON string, replacing the previous list
 func (r *SessionRepository) SavePages(sessionID string, pages []PageState) error {
+	key := fmt.Sprintf("session:%s:pages", sessionID)
+
+	// No open pages left, nothing to reopen on resume
 	if len(pages) == 0 {
+		if err := r.redis.client.Del(r.redis.ctx, key).Err(); err != nil {
+			return fmt.Errorf("failed to clear pages: %w", err)
+		}
 		return nil
 	}
 
-	key := fmt.Sprintf("session:%s:pages", sessionID)
-
 	data, err := json.Marshal(pages)
 	if err != nil {
 		return fmt.Errorf("failed to marshal pages: %w", err)
@@ -188,29 +246,31 @@ func (r *SessionRepository) CheckSessionNameExists(agentID, sessionName string)
 }
 
 // ReserveSessionName atomically reserves a session name for an agent
+// Reserving a name the session already holds succeeds, so saving a session again is harmless.
 func (r *SessionRepository) ReserveSessionName(agentID, sessionName, sessionID string) error {
 	key := fmt.Sprintf("agent:%s:session_names", agentID)
-	
-	// Check if name already exists
-	exists, err := r.CheckSessionNameExists(agentID, sessionName)
+
+	// Reserve the name unless someone holds it
+	reserved, err := r.redis.client.HSetNX(r.redis.ctx, key, sessionName, sessionID).Result()
 	if err != nil {
-		return err
-	}
-	
-	if exists {
-		return fmt.Errorf("session name '%s' already exists for agent '%s'", sessionName, agentID)
-	}
-	
-	// Reserve the name
-	if err := r.redis.client.HSet(r.redis.ctx, key, sessionName, sessionID).Err(); err != nil {
 		return fmt.Errorf("failed to reserve session name: %w", err)
 	}
-	
+
+	if !reserved {
+		holder, err := r.redis.client.HGet(r.redis.ctx, key, sessionName).Result()
+		if err != nil {
+			return fmt.Errorf("failed to check session name: %w", err)
+		}
+		if holder != sessionID {
+			return fmt.Errorf("%w: '%s' for agent '%s'", ErrNameTaken, sessionName, agentID)
+		}
+	}
+
 	// Set TTL on the hash
 	if err := r.redis.client.Expire(r.redis.ctx, key, r.ttl).Err(); err != nil {
 		slog.Warn("failed to set TTL on session names", "error", err)
 	}
-	
+
 	return nil
 }
 
@@ -225,41 +285,6 @@ func (r *SessionRepository) ReleaseSessionName(agentID, sessionName string) erro
 	return r.redis.client.HDel(r.redis.ctx, key, sessionName).Err()
 }
 
-// RenameSession updates the session name
-func (r *SessionRepository) RenameSession(sessionID, agentID, oldName, newName string) error {
-	// Check if new name is already taken
-	exists, err := r.CheckSessionNameExists(agentID, newName)
-	if err != nil {
-		return err
-	}
-	if exists {
-		return fmt.Errorf("session name '%s' already exists", newName)
-	}
-	
-	// Remove old name mapping
-	if err := r.ReleaseSessionName(agentID, oldName); err != nil {
-		slog.Warn("failed to release old session name", "error", err)
-	}
-	
-	// Add new name mapping
-	if err := r.ReserveSessionName(agentID, newName, sessionID); err != nil {
-		return err
-	}
-	
-	// Update session hash
-	sessionKey := fmt.Sprintf("session:%s", sessionID)
-	if err := r.redis.client.HSet(r.redis.ctx, sessionKey, "session_name", newName).Err(); err != nil {
-		return fmt.Errorf("failed to update session name: %w", err)
-	}
-	
-	slog.Info("session renamed", 
-		"session_id", sessionID, 
-		"old_name", oldName, 
-		"new_name", newName)
-	
-	return nil
-}
-
 // CountAgentSessions returns the number of active sessions for an agent
 func (r *SessionRepository) CountAgentSessions(agentID string) (int, error) {
 	key := fmt.Sprintf("agent:%s:sessions", agentID)
@@ -0,0 +1,256 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// newTestRepository returns a repository backed by an in-process Redis
+func newTestRepository(t *testing.T) (*SessionRepository, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client, err := NewRedisClient(mr.Addr(), "", 0)
+	if err != nil {
+		t.Fatalf("NewRedisClient failed: %v", err)
+	}
+
+	repo := NewSessionRepository(client, time.Hour)
+	t.Cleanup(func() { repo.Close() })
+	return repo, mr
+}
+
+// TestReserveSessionSameName tests that only one of many concurrent reservations of a name succeeds
+func TestReserveSessionSameName(t *testing.T) {
+	repo, mr := newTestRepository(t)
+
+	var wg sync.WaitGroup
+	errs := make([]error, 20)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = repo.ReserveSession("agent", "research", fmt.Sprintf("s%d", i), 0)
+		}(i)
+	}
+	wg.Wait()
+
+	winner := ""
+	for i, err := range errs {
+		if err == nil {
+			if winner != "" {
+				t.Fatalf("both %s and s%d reserved the name", winner, i)
+			}
+			winner = fmt.Sprintf("s%d", i)
+		} else if !errors.Is(err, ErrNameTaken) {
+			t.Errorf("expected a name conflict, got %v", err)
+		}
+	}
+	if winner == "" {
+		t.Fatal("expected one reservation to succeed")
+	}
+
+	if holder := mr.HGet("agent:agent:session_names", "research"); holder != winner {
+		t.Errorf("expected %s to hold the name, got %q", winner, holder)
+	}
+	if members, _ := mr.SMembers("agent:agent:sessions"); len(members) != 1 {
+		t.Errorf("expected only the winner to take a slot, got %v", members)
+	}
+
+	// Reserving again for the holder is harmless
+	if err := repo.ReserveSession("agent", "research", winner, 0); err != nil {
+		t.Errorf("expected the holder to keep its reservation, got %v", err)
+	}
+}
+
+// TestReserveSessionAgentLimit tests that concurrent reservations never exceed the agent limit
+func TestReserveSessionAgentLimit(t *testing.T) {
+	repo, _ := newTestRepository(t)
+
+	var wg sync.WaitGroup
+	errs := make([]error, 20)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = repo.ReserveSession("agent", fmt.Sprintf("name-%d", i), fmt.Sprintf("s%d", i), 5)
+		}(i)
+	}
+	wg.Wait()
+
+	winners := 0
+	for _, err := range errs {
+		if err == nil {
+			winners++
+		} else if !errors.Is(err, ErrAgentLimitReached) {
+			t.Errorf("expected the agent limit, got %v", err)
+		}
+	}
+	if winners != 5 {
+		t.Errorf("expected 5 reservations, got %d", winners)
+	}
+	if count, _ := repo.CountAgentSessions("agent"); count != 5 {
+		t.Errorf("expected 5 sessions counted, got %d", count)
+	}
+
+	// A rejected reservation doesn't keep its name
+	for i, err := range errs {
+		if err != nil {
+			if exists, _ := repo.CheckSessionNameExists("agent", fmt.Sprintf("name-%d", i)); exists {
+				t.Errorf("expected name-%d to stay free", i)
+			}
+		}
+	}
+}
+
+// TestReleaseReservation tests that a release only gives back what the session holds
+func TestReleaseReservation(t *testing.T) {
+	repo, _ := newTestRepository(t)
+
+	if err := repo.ReserveSession("agent", "one", "s1", 1); err != nil {
+		t.Fatalf("ReserveSession failed: %v", err)
+	}
+
+	// Another session's release doesn't free the name
+	if err := repo.ReleaseReservation("agent", "one", "s2"); err != nil {
+		t.Fatalf("ReleaseReservation failed: %v", err)
+	}
+	if id, _ := repo.GetSessionByName("agent", "one"); id != "s1" {
+		t.Errorf("expected s1 to keep the name, got %q", id)
+	}
+
+	if err := repo.ReleaseReservation("agent", "one", "s1"); err != nil {
+		t.Fatalf("ReleaseReservation failed: %v", err)
+	}
+	if err := repo.ReserveSession("agent", "one", "s3", 1); err != nil {
+		t.Errorf("expected the name and slot to be free again, got %v", err)
+	}
+}
+
+// TestRenameSessionConcurrent tests that two sessions renamed to the same name can't both get it
+func TestRenameSessionConcurrent(t *testing.T) {
+	repo, _ := newTestRepository(t)
+
+	for _, state := range []*SessionState{testState("s1", "agent", "one"), testState("s2", "agent", "two")} {
+		if err := repo.SaveSession(state); err != nil {
+			t.Fatalf("SaveSession failed: %v", err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	errs := make(map[string]error)
+	var mu sync.Mutex
+	for id, oldName := range map[string]string{"s1": "one", "s2": "two"} {
+		wg.Add(1)
+		go func(id, oldName string) {
+			defer wg.Done()
+			err := repo.RenameSession(id, "agent", oldName, "shared")
+			mu.Lock()
+			errs[id] = err
+			mu.Unlock()
+		}(id, oldName)
+	}
+	wg.Wait()
+
+	winner, loser, loserName := "s1", "s2", "two"
+	if errs["s1"] != nil {
+		winner, loser, loserName = "s2", "s1", "one"
+	}
+	if errs[winner] != nil || !errors.Is(errs[loser], ErrNameTaken) {
+		t.Fatalf("expected exactly one rename to succeed, got %v", errs)
+	}
+
+	if id, _ := repo.GetSessionByName("agent", "shared"); id != winner {
+		t.Errorf("expected %s to hold the new name, got %q", winner, id)
+	}
+	if id, _ := repo.GetSessionByName("agent", loserName); id != loser {
+		t.Errorf("expected %s to keep its name, got %q", loser, id)
+	}
+	if exists, _ := repo.CheckSessionNameExists("agent", map[string]string{"s1": "one", "s2": "two"}[winner]); exists {
+		t.Error("expected the winner's old name to be released")
+	}
+	if state, _ := repo.GetSession(winner); state.SessionName != "shared" {
+		t.Errorf("expected the session hash to carry the new name, got %q", state.SessionName)
+	}
+}
+
+// TestRenameSessionTwice tests that one session renamed concurrently to two names ends up holding one
+func TestRenameSessionTwice(t *testing.T) {
+	repo, _ := newTestRepository(t)
+
+	if err := repo.SaveSession(testState("s1", "agent", "one")); err != nil {
+		t.Fatalf("SaveSession failed: %v", err)
+	}
+
+	// Both callers still know the session by its first name
+	var wg sync.WaitGroup
+	for _, newName := range []string{"two", "three"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := repo.RenameSession("s1", "agent", "one", newName); err != nil {
+				t.Errorf("RenameSession failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	state, err := repo.GetSession("s1")
+	if err != nil {
+		t.Fatalf("GetSession failed: %v", err)
+	}
+	held := 0
+	for _, name := range []string{"one", "two", "three"} {
+		if id, _ := repo.GetSessionByName("agent", name); id == "s1" {
+			held++
+			if name != state.SessionName {
+				t.Errorf("expected the name %q the session carries to be held, got %q", state.SessionName, name)
+			}
+		}
+	}
+	if held != 1 {
+		t.Errorf("expected exactly one name to map to the session, got %d", held)
+	}
+}
+
+// TestReleaseExpiredNames tests that names and set entries of expired sessions are released
+func TestReleaseExpiredNames(t *testing.T) {
+	repo, mr := newTestRepository(t)
+
+	if err := repo.SaveSession(testState("s1", "agent", "one")); err != nil {
+		t.Fatalf("SaveSession failed: %v", err)
+	}
+	if err := repo.ReserveSession("agent", "pending", "s2", 0); err != nil {
+		t.Fatalf("ReserveSession failed: %v", err)
+	}
+
+	// The session hash expires, its name and set entries don't
+	mr.Del("session:s1")
+
+	released, err := repo.ReleaseExpiredNames()
+	if err != nil {
+		t.Fatalf("ReleaseExpiredNames failed: %v", err)
+	}
+	if released != 1 {
+		t.Errorf("expected 1 name released, got %d", released)
+	}
+	if exists, _ := repo.CheckSessionNameExists("agent", "one"); exists {
+		t.Error("expected the expired session's name to be released")
+	}
+	if ids, _ := repo.ListActiveSessions(); len(ids) != 0 {
+		t.Errorf("expected the expired session to leave the active set, got %v", ids)
+	}
+
+	// A reservation still being created keeps its name and slot
+	if id, _ := repo.GetSessionByName("agent", "pending"); id != "s2" {
+		t.Errorf("expected the reservation to be kept, got %q", id)
+	}
+	if count, _ := repo.CountAgentSessions("agent"); count != 1 {
+		t.Errorf("expected the reservation's slot to be kept, got %d", count)
+	}
+}
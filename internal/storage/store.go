@@ -0,0 +1,61 @@
+package storage
+
+import "fmt"
+
+// Session store backends selectable in the configuration
+const (
+	BackendRedis  = "redis"
+	BackendMemory = "memory"
+	BackendBolt   = "bolt"
+)
+
+// Error definitions
+var (
+	ErrUnknownBackend    = fmt.Errorf("unknown session store backend")
+	ErrNameTaken         = fmt.Errorf("session name already exists")
+	ErrAgentLimitReached = fmt.Errorf("agent session limit reached")
+)
+
+// SessionStore persists sessions so they can be listed, closed and resumed across restarts
+type SessionStore interface {
+	// SaveSession writes the session's metadata, and its cookies, localStorage and pages when they are set
+	SaveSession(state *SessionState) error
+	// GetSession loads a session with everything saved for it
+	GetSession(sessionID string) (*SessionState, error)
+	// DeleteSession removes a session, its browser state and its name
+	DeleteSession(sessionID string) error
+	// ListActiveSessions returns the IDs of every stored session
+	ListActiveSessions() ([]string, error)
+
+	UpdateLastActivity(sessionID string) error
+	UpdateSessionStatus(sessionID string, status string) error
+
+	// SavePages replaces the open pages of a session, an empty list clears them
+	SavePages(sessionID string, pages []PageState) error
+	// ReplaceBrowserState replaces the cookies and localStorage of a session
+	ReplaceBrowserState(sessionID string, cookies []Cookie, localStorage map[string]map[string]string) error
+
+	// ReserveSession claims a name for a session about to be created and counts it against the agent's limit
+	// It fails with ErrNameTaken or ErrAgentLimitReached, a maxSessions of 0 means no limit.
+	ReserveSession(agentID, sessionName, sessionID string, maxSessions int) error
+	// ReleaseReservation gives back a reservation whose session could not be created
+	ReleaseReservation(agentID, sessionName, sessionID string) error
+	// RenameSession moves a session to a new name atomically, failing with ErrNameTaken
+	// The name the stored session holds is released, oldName only when the session isn't stored.
+	RenameSession(sessionID, agentID, oldName, newName string) error
+	// ReleaseExpiredNames frees the names and index entries left behind by sessions whose keys expired
+	ReleaseExpiredNames() (int, error)
+
+	GetSessionByName(agentID, sessionName string) (string, error)
+	CheckSessionNameExists(agentID, sessionName string) (bool, error)
+	CountAgentSessions(agentID string) (int, error)
+	ListAgentSessions(agentID string) ([]*SessionState, error)
+
+	// Close releases the connection or file behind the store
+	Close() error
+}
+
+var (
+	_ SessionStore = (*SessionRepository)(nil)
+	_ SessionStore = (*LocalStore)(nil)
+)
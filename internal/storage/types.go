@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"encoding/json"
 	"fmt"
 	"time"
 )
@@ -17,9 +18,12 @@ type SessionState struct {
 	Status       string            `json:"status"`
 	
 	// Browser state
-	Cookies      []Cookie          `json:"cookies,omitempty"`
-	LocalStorage map[string]string `json:"local_storage,omitempty"`
-	Pages        []PageState       `json:"pages,omitempty"`
+	Cookies      []Cookie                     `json:"cookies,omitempty"`
+	LocalStorage map[string]map[string]string `json:"local_storage,omitempty"` // Items keyed by origin
+	Pages        []PageState                  `json:"pages,omitempty"`
+
+	// Device and environment emulation, encoded and decoded by the session package
+	Emulation json.RawMessage `json:"emulation,omitempty"`
 }
 
 // Cookie represents a browser cookie
@@ -28,7 +32,7 @@ type Cookie struct {
 	Value    string  `json:"value"`
 	Domain   string  `json:"domain"`
 	Path     string  `json:"path"`
-	Expires  float64 `json:"expires"`  // Unix timestamp
+	Expires  float64 `json:"expires"`  // Unix timestamp, -1 for a session cookie
 	Secure   bool    `json:"secure"`
 	HttpOnly bool    `json:"httpOnly"`
 	SameSite string  `json:"sameSite"`